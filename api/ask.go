@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// AskVars are the vars Ask and AskList need exported to the Subproc.
+var AskVars = []string{vars.CommandFifo, vars.ResponseFifo}
+
+// Ask evaluates the commands within Kakoune while the Func is running, and
+// returns the value of the expansion evaluated after them. Eg, the extent
+// of the word under the cursor:
+//
+//    desc, err := kak.Ask("execute-keys <a-i>w", "%val{selection_desc}")
+//
+// The commands are evaluated within `evaluate-commands -draft`, so
+// selections are left as they were, though changes to the buffer are not.
+// A failing command is returned as an error. Expansions of several values,
+// such as %val{selections}, are joined by spaces, see AskList.
+//
+// Commands printed by the Func are still only evaluated once it exits,
+// after any asked. AskVars must be exported to the Subproc.
+func (k *Kak) Ask(commands, expansion string) (string, error) {
+	list, err := k.AskList(commands, expansion)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(list, " "), nil
+}
+
+// AskList returns the values of the expansion as Ask does, as a list.
+func (k *Kak) AskList(commands, expansion string) ([]string, error) {
+	commandFifo, err := k.Var(vars.CommandFifo)
+	if err != nil {
+		return nil, err
+	}
+	responseFifo, err := k.Var(vars.ResponseFifo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFifo(commandFifo, askScript(responseFifo, commands, expansion)); err != nil {
+		return nil, err
+	}
+
+	// NOTE(leeola): blocks until Kakoune writes the response, which it
+	// always does, as failures are caught and written as well.
+	b, err := ioutil.ReadFile(responseFifo)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAnswer(string(b))
+}
+
+// askScript returns the script evaluating the commands and writing the
+// expansion to the response fifo, prefixed by whether they succeeded.
+func askScript(responseFifo, commands, expansion string) string {
+	echo := "echo -quoting kakoune -to-file " + Quote(responseFifo) + " -- "
+
+	script := commands
+	if script != "" {
+		script += "\n"
+	}
+	script += echo + "ok " + expansion
+
+	return "try " + Quote("evaluate-commands -draft "+Quote(script)) +
+		" catch " + Quote(echo+"error %val{error}") + "\n"
+}
+
+// ParseAnswer parses a response written with `echo -quoting kakoune`, as
+// `ok` followed by the values of an expansion, or `error` followed by the
+// error, as written by askScript or by api/client querying a session.
+func ParseAnswer(s string) ([]string, error) {
+	list, err := ParseQuotedList(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, errors.New("empty response from kakoune")
+	}
+
+	switch list[0] {
+	case "ok":
+		return list[1:], nil
+	case "error":
+		return nil, errors.New(strings.Join(list[1:], " "))
+	default:
+		return nil, errors.New("malformed response from kakoune")
+	}
+}
+
+// writeFifo opens the fifo, writes s, and closes it, which Kakoune waits
+// for before evaluating what was written.
+func writeFifo(path, s string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
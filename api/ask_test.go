@@ -0,0 +1,39 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAskScript(t *testing.T) {
+	got := askScript("/tmp/resp", "execute-keys <a-i>w", "%val{selection_desc}")
+	want := `try 'evaluate-commands -draft ''execute-keys <a-i>w
+echo -quoting kakoune -to-file ''''/tmp/resp'''' -- ok %val{selection_desc}''' ` +
+		`catch 'echo -quoting kakoune -to-file ''/tmp/resp'' -- error %val{error}'` + "\n"
+	if got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestParseAnswer(t *testing.T) {
+	got, err := ParseAnswer(`'ok' 'foo' 'it''s'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"foo", "it's"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	if got, err := ParseAnswer(`'ok'`); err != nil || len(got) != 0 {
+		t.Errorf("expected empty answer, got %q, %v", got, err)
+	}
+
+	_, err = ParseAnswer(`'error' 'no selections remaining'`)
+	if err == nil || err.Error() != "no selections remaining" {
+		t.Errorf("expected error, got %v", err)
+	}
+
+	if _, err := ParseAnswer(""); err == nil {
+		t.Error("expected error of empty response")
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// assetsDir is the directory within the plugin state directory holding
+// written assets.
+//
+// NOTE(leeola): the leading dot is what keeps the janitor from treating it
+// as the state of a dead session.
+const assetsDir = ".assets"
+
+// Assets writes every file within fsys to disk, returning the directory
+// they were written to.
+//
+// This is intended for use with go:embed, allowing a plugin to ship word
+// lists, templates and the like within its binary while still giving
+// external tools a path to them:
+//
+//    //go:embed assets
+//    var assets embed.FS
+//
+// The directory is named after the content of fsys and is shared between
+// sessions, so files are only written when the content changes, and files
+// from older versions are never overwritten while in use.
+func (k *Kak) Assets(fsys fs.FS) (string, error) {
+	sum, err := hashFS(fsys)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := stateRoot()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, k.PluginName(), assetsDir, sum)
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return "", err
+	}
+
+	// write into a temporary directory and rename it into place, so that
+	// a concurrent session never observes partially written assets.
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), "tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(tmp, filepath.FromSlash(p))
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0700)
+		}
+
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(dst, b, 0600)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		// another process may have won the race, which is fine.
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return dir, nil
+		}
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// SourceAssets writes fsys with Assets, and sources every .kak file within
+// it in lexical order.
+//
+// The source commands are only emitted when the plugin is initializing.
+func (k *Kak) SourceAssets(fsys fs.FS) error {
+	// noop if func was already called
+	if k.funcCalled || !k.gokakouneInit {
+		return nil
+	}
+
+	dir, err := k.Assets(fsys)
+	if err != nil {
+		return err
+	}
+
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || path.Ext(p) != ".kak" {
+			return nil
+		}
+
+		k.Println("source", Quote(filepath.Join(dir, filepath.FromSlash(p))))
+		return nil
+	})
+}
+
+// hashFS returns a hash of every path and file content within fsys.
+func hashFS(fsys fs.FS) (string, error) {
+	h := sha1.New()
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		io.WriteString(h, p)
+		h.Write([]byte{0})
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
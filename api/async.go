@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// env_async marks the process as running the Async call of the given
+// index, when a Func reruns the binary to run it in the background.
+const env_async = "GOKAKOUNE_ASYNC"
+
+// Async runs the work in the background, once the Func returns, and then
+// onDone with the result of the work as its only argument, see Kak.Arg.
+// Eg, linting without blocking Kakoune:
+//
+//    kak.DefineCommand("myplug-lint", opts, api.Func{
+//        ExportVars: []string{vars.Session, vars.Client, vars.BufFile},
+//        Func: func(kak *api.Kak) error {
+//            file, err := kak.Var(vars.BufFile)
+//            if err != nil {
+//                return err
+//            }
+//            return kak.Async(func() (string, error) {
+//                out, err := exec.Command("golint", file).Output()
+//                return string(out), err
+//            }, api.Subproc{
+//                Func: func(kak *api.Kak) error {
+//                    out, _ := kak.Arg(0)
+//                    kak.Printf("set-option buffer myplug_lint %s\n", api.Quote(out))
+//                    return nil
+//                },
+//            })
+//        },
+//    })
+//
+// What onDone prints is sent to the session with `kak -p`, and evaluated
+// within the invoking client if it still exists. If the work or onDone
+// fails, the error is shown within the client rather than the *debug*
+// buffer, as nothing is waiting on it.
+//
+// vars.Session must be exported to the Func, as well as vars.Client to
+// evaluate within the client. onDone sees the vars of the Func as they
+// were when it was invoked, so its ExportVars must be exported to the Func
+// too.
+//
+// NOTE(leeola): the work runs within a detached process rerunning the
+// invocation, as the Func cannot outlive its own process. The Func runs
+// again up to the Async call, whose work then runs, after which the
+// process exits. So the Func should do nothing before calling Async which
+// must not be repeated. Within a Daemon, the work runs on a goroutine
+// instead, alongside the Funcs run after it.
+func (k *Kak) Async(work func() (string, error), onDone Subproc) error {
+	index := k.asyncCount
+	k.asyncCount++
+
+	session, err := k.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+	for _, v := range onDone.ExportVars {
+		if _, ok := k.funcVars[var_prefix+v]; !ok {
+			return fmt.Errorf("async var not exported to func: %q", v)
+		}
+	}
+
+	if s := os.Getenv(env_async); s != "" {
+		if s != strconv.Itoa(index) {
+			// another Async call of the Func, run by its own process.
+			return nil
+		}
+		k.sendAsync(session, k.asyncCommands(work, onDone))
+		os.Exit(0)
+	}
+
+	if os.Getenv(env_daemon) != "" {
+		go func() {
+			k.sendAsync(session, k.asyncCommands(work, onDone))
+		}()
+		return nil
+	}
+
+	cmd := exec.Command(k.gokakouneBin, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), env_async+"="+strconv.Itoa(index))
+	// detach from this process, so it survives us exiting.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// asyncCommands runs the work and onDone, returning the commands to send
+// to the session.
+func (k *Kak) asyncCommands(work func() (string, error), onDone Subproc) string {
+	var buf bytes.Buffer
+	dk := &Kak{
+		writer:       &buf,
+		gokakouneBin: k.gokakouneBin,
+		funcCalled:   true,
+		funcVars:     k.funcVars,
+		route:        k.route,
+		version:      k.version,
+		stderr:       k.stderr,
+		running:      true,
+	}
+
+	result, err := work()
+	if err == nil {
+		dk.funcArgs = []string{result}
+		err = onDone.Func(dk)
+	}
+	if err == nil {
+		err = dk.failure
+	}
+
+	commands := buf.String()
+	if err != nil {
+		kerr := *kakError(err)
+		if kerr.Severity == SeverityFail {
+			// a fail within kak -p only reaches the *debug* buffer.
+			kerr.Severity = SeverityError
+		}
+		commands = kerr.command()
+	}
+
+	if commands != "" {
+		commands = inClient(k.funcVars[var_prefix+vars.Client], commands)
+	}
+	return commands
+}
+
+// sendAsync sends the commands to the session, logging when it fails as
+// there is no Func to fail.
+func (k *Kak) sendAsync(session, commands string) {
+	if commands == "" {
+		return
+	}
+	if err := Send(session, commands); err != nil {
+		w := k.stderr
+		if w == nil {
+			w = os.Stderr
+		}
+		fmt.Fprintf(w, "%s: async: %s\n", k.PluginName(), err)
+	}
+}
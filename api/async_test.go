@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAsyncCommands(t *testing.T) {
+	k := &Kak{funcVars: map[string]string{
+		"kak_session": "1234",
+		"kak_client":  "client0",
+	}}
+
+	got := k.asyncCommands(func() (string, error) {
+		return "it's done", nil
+	}, Subproc{
+		Func: func(k *Kak) error {
+			result, err := k.Arg(0)
+			if err != nil {
+				return err
+			}
+			k.Printf("echo -- %s\n", Quote(result))
+			return nil
+		},
+	})
+	if want := "evaluate-commands -try-client client0 %{echo -- 'it''s done'\n}"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	called := false
+	got = k.asyncCommands(func() (string, error) {
+		return "", errors.New("lint failed")
+	}, Subproc{
+		Func: func(k *Kak) error {
+			called = true
+			return nil
+		},
+	})
+	if called {
+		t.Error("onDone called after the work failed")
+	}
+	if want := "evaluate-commands -try-client client0 %{echo -markup -- '{Error}lint failed'}"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestAsyncVars(t *testing.T) {
+	k := &Kak{funcVars: map[string]string{"kak_session": "1234"}}
+	err := k.Async(func() (string, error) { return "", nil }, Subproc{
+		ExportVars: []string{"buffile"},
+	})
+	if err == nil {
+		t.Error("expected error of the var not exported to the func")
+	}
+}
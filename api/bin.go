@@ -0,0 +1,83 @@
+package api
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+// resolveBin returns the absolute path of the running binary.
+//
+// NOTE(leeola): os.Executable is deliberately not preferred here. On Linux
+// it resolves symlinks, and a symlink is often exactly what should be kept.
+// Eg, a binary installed through a package manager or `go install` into a
+// versioned directory is usually symlinked into PATH, and the symlink keeps
+// working across upgrades where the resolved path does not. So the name we
+// were invoked with is searched in PATH and made absolute, falling back to
+// os.Executable only when that fails.
+func resolveBin(arg0 string) (string, error) {
+	if path, err := exec.LookPath(arg0); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs, nil
+		}
+	}
+
+	return os.Executable()
+}
+
+// BinOption returns the name of the option which, when set, overrides the
+// path of the binary the generated script invokes.
+//
+// Eg, a plugin binary named my-plugin can be redirected from a kakrc with:
+//
+//    set-option global my_plugin_bin /path/to/my-plugin
+func (k *Kak) BinOption() string {
+	return OptionName(k.PluginName()) + "_bin"
+}
+
+// declareBinOption declares the BinOption.
+//
+// No value is given to the declaration, so that re-sourcing the plugin
+// does not reset a value set by the user.
+func (k *Kak) declareBinOption() {
+	doc := "path of the " + k.PluginName() + " binary, overriding the default"
+	k.Printf("declare-option -docstring %s str %s\n", Quote(doc), k.BinOption())
+
+	k.manifest.Options = append(k.manifest.Options, ManifestEntry{
+		Name:      k.BinOption(),
+		Docstring: doc,
+	})
+}
+
+// binExpr returns the shell word the generated script uses to invoke the
+// binary, which defers to the BinOption if it is set.
+//
+// Referencing kak_opt_ within the script is also what makes Kakoune export
+// the option to the shell.
+//
+// NOTE(leeola): the expansion is intentionally unquoted. Single quotes
+// within a double quoted ${x:-word} are not portable across shells, while
+// in an unquoted expansion the quoted default is never split. The cost is
+// that an overriding path cannot contain spaces.
+func (k *Kak) binExpr() string {
+	return "${kak_opt_" + k.BinOption() + ":-" + util.ShellQuote(k.gokakouneBin) + "}"
+}
+
+// OptionName converts the given name, such as that of a plugin or command,
+// into a valid option name, which must also be a valid shell variable name
+// once Kakoune prefixes it with kak_opt_.
+func OptionName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		default:
+			return '_'
+		}
+		return r
+	}, name)
+}
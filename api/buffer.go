@@ -0,0 +1,145 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+// BufferVars are the vars needed to read the buffer of a BufferFunc,
+// exported to it along with its own.
+var BufferVars = []string{
+	vars.BufFile,
+	vars.Session,
+}
+
+// BufferFunc is a Func given the content of the current buffer, including
+// its unsaved changes, rather than the file on disk. Eg, upper casing the
+// buffer:
+//
+//    kak.DefineCommand("upcase-buffer", api.DefineCommandOptions{}, api.BufferFunc{
+//        Func: func(kak *api.Kak, content string) error {
+//            kak.ReplaceBuffer(strings.ToUpper(content))
+//            return nil
+//        },
+//    })
+//
+// The buffer is written to a file within the StateDir, without running
+// the write hooks, by a first invocation of the binary, and read back by
+// a second running Func. The file is removed once the Func returns.
+type BufferFunc struct {
+	// ExportVars are the vars exported to the Func, as with Func.
+	ExportVars []string
+
+	// Skip, if set, reports whether there is nothing to do, such as when
+	// the buffer is unchanged since it was last linted, in which case the
+	// buffer is not written and the Func is not called. It is given the
+	// ExportVars, and must not print anything.
+	Skip func(k *Kak) bool
+
+	// Func is called with the content of the buffer, as Func.Func is.
+	Func func(k *Kak, content string) error
+
+	// FileFunc is called in place of Func, if set, with the path of the
+	// file the buffer was written to, for tools which read files, such as
+	// linters. The file keeps the base name of the buffer, as such tools
+	// often care about file extensions.
+	FileFunc func(k *Kak, path string) error
+}
+
+func (e BufferFunc) Init(ctx Context) (string, error) {
+	return ctx.Children[0] + "\n" + ctx.Children[1], nil
+}
+
+func (e BufferFunc) Children() []Expansion {
+	exportVars := append(append([]string{}, BufferVars...), e.ExportVars...)
+	return []Expansion{
+		Func{ExportVars: exportVars, Func: func(k *Kak) error {
+			if e.Skip != nil && e.Skip(k) {
+				return nil
+			}
+			return writeBuffer(k)
+		}},
+		Func{ExportVars: exportVars, Func: func(k *Kak) error {
+			if e.Skip != nil && e.Skip(k) {
+				return nil
+			}
+			return e.run(k)
+		}},
+	}
+}
+
+// run calls the Func or FileFunc with the buffer written by writeBuffer,
+// removing its file once done.
+//
+// NOTE(leeola): the file is only removed once the Func returns, as a Func
+// may rerun itself, such as to start a daemon with Kak.SpawnDaemon, and
+// the rerun reads the buffer again.
+func (e BufferFunc) run(k *Kak) error {
+	path, err := bufferFile(k)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	if e.FileFunc != nil {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		return e.FileFunc(k, path)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return e.Func(k, string(b))
+}
+
+// bufferFile returns the file the current buffer is written to for a
+// BufferFunc, within a directory unique to the buffer.
+func bufferFile(k *Kak) (string, error) {
+	buffile, err := k.Var(vars.BufFile)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := k.StateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "buffer", util.HashString(buffile))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, filepath.Base(buffile)), nil
+}
+
+// writeBuffer prints the command writing the current buffer to its
+// bufferFile.
+func writeBuffer(k *Kak) error {
+	path, err := bufferFile(k)
+	if err != nil {
+		return err
+	}
+
+	k.Printf("evaluate-commands -no-hooks %%{ write -force %s }\n", Quote(path))
+	return nil
+}
+
+// ReplaceBuffer prints the commands replacing the whole content of the
+// current buffer, as a single undo step, leaving the registers and
+// selections as they were.
+//
+// Selections are kept by Kakoune as best it can, which for a whole buffer
+// replace often means moving them to its end. Transforms changing few
+// lines, such as formatters, are better applied as edits of those lines.
+func (k *Kak) ReplaceBuffer(content string) {
+	k.Printf("evaluate-commands -draft -save-regs z %s\n", Quote(
+		"set-register z "+Quote(content)+"\nexecute-keys '%\"zR'"))
+}
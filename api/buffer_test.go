@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBufferFunc(t *testing.T) {
+	cache, err := ioutil.TempDir("", "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	os.Setenv("XDG_CACHE_HOME", cache)
+
+	out := &bytes.Buffer{}
+	k := &Kak{
+		writer:       out,
+		gokakouneBin: "/bin/plugin",
+		funcVars: map[string]string{
+			"kak_session": "1",
+			"kak_buffile": "/src/main.go",
+		},
+	}
+
+	var got string
+	children := BufferFunc{Func: func(k *Kak, content string) error {
+		got = content
+		return nil
+	}}.Children()
+
+	if err := children[0].(Runnable).Run(k); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := bufferFile(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "write -force "+Quote(path)) {
+		t.Fatalf("got:\n%s", out.String())
+	}
+
+	// as Kakoune would.
+	if err := ioutil.WriteFile(path, []byte("package main\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := children[1].(Runnable).Run(k); err != nil {
+		t.Fatal(err)
+	}
+	if got != "package main\n" {
+		t.Errorf("got content %q", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("want buffer file removed, got %v", err)
+	}
+}
+
+func TestBufferFuncFile(t *testing.T) {
+	cache, err := ioutil.TempDir("", "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	os.Setenv("XDG_CACHE_HOME", cache)
+
+	out := &bytes.Buffer{}
+	k := &Kak{
+		writer:       out,
+		gokakouneBin: "/bin/plugin",
+		funcVars: map[string]string{
+			"kak_session": "1",
+			"kak_buffile": "/src/main.go",
+		},
+	}
+
+	skip := true
+	var got string
+	children := BufferFunc{
+		Skip: func(k *Kak) bool { return skip },
+		FileFunc: func(k *Kak, path string) error {
+			b, err := ioutil.ReadFile(path)
+			got = filepath.Base(path) + ": " + string(b)
+			return err
+		},
+	}.Children()
+
+	for _, c := range children {
+		if err := c.(Runnable).Run(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.Len() != 0 || got != "" {
+		t.Fatalf("got:%q, want:nothing written or read when skipped", out.String())
+	}
+
+	skip = false
+	if err := children[0].(Runnable).Run(k); err != nil {
+		t.Fatal(err)
+	}
+	path, err := bufferFile(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("package main\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := children[1].(Runnable).Run(k); err != nil {
+		t.Fatal(err)
+	}
+	if want := "main.go: package main\n"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestReplaceBuffer(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out}
+	k.ReplaceBuffer("it's\n")
+
+	want := `evaluate-commands -draft -save-regs z 'set-register z ''it''''s` + "\n" +
+		`''` + "\n" + `execute-keys ''%"zR'''` + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
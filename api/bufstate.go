@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/cmds"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+// ErrStateStale is returned by BufferState.GetFresh when the value was
+// stored against an older timestamp of the buffer.
+var ErrStateStale = errors.New("state stale")
+
+// BufferState is a State bound to the buffer scope, where each value is
+// stamped with the buffer timestamp it was stored at.
+//
+// Buffer scoped options are destroyed by Kakoune along with the buffer, so
+// values never outlive the buffer. Values spilled to the StateDir are
+// removed by a BufClose hook.
+//
+// In addition to the StateVar of each key, vars.Timestamp must be exported
+// to the Subproc.
+type BufferState struct {
+	k *Kak
+}
+
+// BufferState returns the buffer scoped state store.
+func (k *Kak) BufferState() *BufferState {
+	return &BufferState{k: k}
+}
+
+// Get decodes the value of key into v, regardless of its timestamp.
+func (b *BufferState) Get(key string, v interface{}) error {
+	_, err := b.get(key, v)
+	return err
+}
+
+// GetFresh decodes the value of key into v, returning ErrStateStale if the
+// buffer has been modified since the value was stored.
+func (b *BufferState) GetFresh(key string, v interface{}) error {
+	ts, err := b.get(key, v)
+	if err != nil {
+		return err
+	}
+
+	current, err := b.k.VarInt(vars.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	if ts != current {
+		return ErrStateStale
+	}
+
+	return nil
+}
+
+func (b *BufferState) get(key string, v interface{}) (int, error) {
+	if err := validStateKey(key); err != nil {
+		return 0, err
+	}
+
+	return b.k.loadOption(b.k.stateOption(key), v)
+}
+
+// Set v as the value of key, stamped with the current buffer timestamp.
+func (b *BufferState) Set(key string, v interface{}) error {
+	if err := validStateKey(key); err != nil {
+		return err
+	}
+
+	ts, err := b.k.VarInt(vars.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := b.k.encodeOption(v, ts)
+	if err != nil {
+		return err
+	}
+
+	name := b.k.stateOption(key)
+	b.k.declareHiddenStr(name)
+	b.k.Command(cmds.SetOption, ScopeBuffer, name, encoded)
+
+	// replace any previous cleanup hook for this key, as the previous
+	// spill file (if any) is no longer referenced.
+	group := "gokakoune-bufstate-" + OptionName(b.k.PluginName()) + "-" + key
+	b.k.Command(cmds.RemoveHooks, ScopeBuffer, group)
+	if strings.HasPrefix(encoded, spill_prefix) {
+		b.k.Printf("hook -group %s buffer BufClose .* %%{ nop %%sh{ rm -f %s } }\n",
+			group, util.ShellQuote(strings.TrimPrefix(encoded, spill_prefix)))
+	}
+
+	return nil
+}
+
+// Invalidate removes the value of key from the buffer.
+func (b *BufferState) Invalidate(key string) error {
+	return b.k.State().Delete(ScopeBuffer, key)
+}
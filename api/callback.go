@@ -0,0 +1,173 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// callbackUnix is the DaemonOptions.Callback listening on a unix socket
+// within the callback directory, rather than on a TCP address.
+const callbackUnix = "unix"
+
+// callbackDir returns the directory of the callback server of this
+// plugin, holding its socket, address and token.
+//
+// NOTE(leeola): the directory is dot prefixed, so the janitor does not
+// mistake it for the state of a dead session.
+func (k *Kak) callbackDir() (string, error) {
+	root, err := stateRoot()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, k.PluginName(), ".callback")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// listenCallback listens on the address of DaemonOptions.Callback, and
+// writes the address and a new token into the callback directory for
+// external tools to read.
+func (k *Kak) listenCallback(addr string) (net.Listener, string, error) {
+	dir, err := k.callbackDir()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var l net.Listener
+	if addr == callbackUnix {
+		sock := filepath.Join(dir, "sock")
+		os.Remove(sock)
+		l, err = net.Listen("unix", sock)
+	} else {
+		if err := loopback(addr); err != nil {
+			return nil, "", err
+		}
+		l, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		l.Close()
+		return nil, "", err
+	}
+	token := hex.EncodeToString(b)
+
+	for name, content := range map[string]string{
+		"token": token,
+		"addr":  l.Addr().String(),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0600); err != nil {
+			l.Close()
+			return nil, "", err
+		}
+	}
+	return l, token, nil
+}
+
+// loopback returns an error unless the TCP address is of the loopback
+// interface, as anything else would expose the sessions to the network.
+func loopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("callback address not of loopback: %q", addr)
+}
+
+// callbackHandler returns the handler of the callback server, authorizing
+// requests by the token and sending the commands they translate to with
+// send, see Send.
+//
+// The following endpoints are served:
+//
+//    POST /open  open file at line and column, within client of session
+//
+// The params are those of the query or of a form body, eg:
+//
+//    curl -H "Authorization: Bearer $(cat ~/.cache/gokakoune/myplug/.callback/token)" \
+//        -d session=1234 -d file=/src/main.go -d line=42 \
+//        http://$(cat ~/.cache/gokakoune/myplug/.callback/addr)/open
+//
+// Without a client, the jumpclient of the session is used, if any.
+func callbackHandler(token string, send func(session, commands string) error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		session, commands, err := openCommands(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := send(session, commands); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// openCommands returns the session and the commands of an open request.
+func openCommands(r *http.Request) (string, string, error) {
+	session := r.FormValue("session")
+	if session == "" || strings.ContainsAny(session, "/ \t\n") {
+		return "", "", fmt.Errorf("invalid session: %q", session)
+	}
+	file := r.FormValue("file")
+	if file == "" {
+		return "", "", errors.New("missing file")
+	}
+
+	args := []string{"edit", "-existing", "--", file}
+	for _, name := range []string{"line", "column"} {
+		v := r.FormValue(name)
+		if v == "" {
+			break
+		}
+		if n, err := strconv.Atoi(v); err != nil || n < 1 {
+			return "", "", fmt.Errorf("invalid %s: %q", name, v)
+		}
+		args = append(args, v)
+	}
+	edit := QuoteArgs(args...)
+
+	client := "%opt{jumpclient}"
+	if c := r.FormValue("client"); c != "" {
+		client = QuoteArg(c)
+	}
+	return session, "evaluate-commands -try-client " + client + " " + QuoteBlock(edit), nil
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	var sent []string
+	h := callbackHandler("secret", func(session, commands string) error {
+		sent = append(sent, session+": "+commands)
+		return nil
+	})
+
+	tests := []struct {
+		token  string
+		form   url.Values
+		status int
+		sent   string
+	}{
+		{"wrong", url.Values{"session": {"1234"}, "file": {"main.go"}}, http.StatusUnauthorized, ""},
+		{"secret", url.Values{"file": {"main.go"}}, http.StatusBadRequest, ""},
+		{"secret", url.Values{"session": {"1234"}, "file": {"main.go"}, "line": {"x"}}, http.StatusBadRequest, ""},
+		{"secret", url.Values{"session": {"1234"}, "file": {"main.go"}}, http.StatusNoContent,
+			"1234: evaluate-commands -try-client %opt{jumpclient} %{edit -existing -- main.go}"},
+		{"secret", url.Values{"session": {"1234"}, "client": {"client0"}, "file": {"my file.go"},
+			"line": {"42"}, "column": {"3"}}, http.StatusNoContent,
+			"1234: evaluate-commands -try-client client0 %{edit -existing -- 'my file.go' 42 3}"},
+	}
+
+	for _, test := range tests {
+		sent = nil
+		r := httptest.NewRequest(http.MethodPost, "/open", strings.NewReader(test.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Authorization", "Bearer "+test.token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%v: got status %d, want %d", test.form, w.Code, test.status)
+		}
+		if got := strings.Join(sent, "\n"); got != test.sent {
+			t.Errorf("%v: got sent %q, want %q", test.form, got, test.sent)
+		}
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:7777": true,
+		"localhost:0":    true,
+		"[::1]:7777":     true,
+		"0.0.0.0:7777":   false,
+		":7777":          false,
+		"10.0.0.1:7777":  false,
+	} {
+		if err := loopback(addr); (err == nil) != ok {
+			t.Errorf("%s: got %v, want ok %t", addr, err, ok)
+		}
+	}
+}
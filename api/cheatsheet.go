@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cheatsheet renders the mappings of the manifest grouped by their mode,
+// in the order the modes were first mapped, as a which-key style
+// cheatsheet. Eg:
+//
+//    user
+//      m  myplug commands
+//
+//    myplug: myplug commands
+//      f      format the buffer
+//      <a-j>  :myplug-jump<ret>
+//
+// A mapping without a docstring shows the keys it is mapped to.
+func (m Manifest) Cheatsheet() string {
+	docstrings := map[string]string{}
+	for _, e := range m.UserModes {
+		docstrings[e.Name] = e.Docstring
+	}
+
+	var modes []string
+	byMode := map[string][]ManifestMapping{}
+	for _, mapping := range m.Mappings {
+		if _, ok := byMode[mapping.Mode]; !ok {
+			modes = append(modes, mapping.Mode)
+		}
+		byMode[mapping.Mode] = append(byMode[mapping.Mode], mapping)
+	}
+
+	var b strings.Builder
+	for i, mode := range modes {
+		if i != 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(mode)
+		if d := docstrings[mode]; d != "" {
+			b.WriteString(": " + d)
+		}
+		b.WriteString("\n")
+
+		width := 0
+		for _, mapping := range byMode[mode] {
+			if len(mapping.Key) > width {
+				width = len(mapping.Key)
+			}
+		}
+		for _, mapping := range byMode[mode] {
+			desc := mapping.Docstring
+			if desc == "" {
+				desc = mapping.Keys
+			}
+			fmt.Fprintf(&b, "  %-*s  %s\n", width, mapping.Key, desc)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// DefineCheatsheetCommand defines a `<plugin>-cheatsheet` command, showing
+// the Cheatsheet of the mappings of the plugin in an info box.
+//
+// The mappings are captured when this is called, so it should be called
+// after the plugin maps its keys.
+func (k *Kak) DefineCheatsheetCommand() error {
+	name := k.PluginName() + "-cheatsheet"
+	opts := DefineCommandOptions{
+		Docstring: "show the mappings of " + k.PluginName(),
+	}
+	return k.DefineCommand(name, opts, cheatsheetInfo{k: k})
+}
+
+// cheatsheetInfo is an expansion rendering the cheatsheet of k when
+// initialized.
+type cheatsheetInfo struct {
+	k *Kak
+}
+
+func (e cheatsheetInfo) Init(ctx Context) (string, error) {
+	m := e.k.Manifest()
+	sheet := m.Cheatsheet()
+	if sheet == "" {
+		sheet = "no mappings"
+	}
+	return fmt.Sprintf("info -title %s -- %s", Quote(m.Name+" mappings"), Quote(sheet)), nil
+}
+
+func (e cheatsheetInfo) Children() []Expansion {
+	return nil
+}
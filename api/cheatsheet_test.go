@@ -0,0 +1,47 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api/keys"
+)
+
+func TestCheatsheet(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	if err := k.DeclareUserMode("myplug", "myplug commands"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct {
+		mode, key, keys, docstring string
+	}{
+		{"user", "m", ":enter-user-mode myplug<ret>", "myplug commands"},
+		{"myplug", "f", keys.Command("myplug-format"), "format the buffer"},
+		{"myplug", keys.Alt("j"), keys.Command("myplug-jump"), ""},
+	} {
+		if err := k.Map("global", m.mode, m.key, m.keys, MapOptions{Docstring: m.docstring}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "user\n" +
+		"  m  myplug commands\n" +
+		"\n" +
+		"myplug: myplug commands\n" +
+		"  f      format the buffer\n" +
+		"  <a-j>  :myplug-jump<ret>"
+	if got := k.Manifest().Cheatsheet(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := k.DefineCheatsheetCommand(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "plugin-cheatsheet %{\n  info -title 'plugin mappings' -- 'user\n") {
+		t.Errorf("got:\n%s", got)
+	}
+}
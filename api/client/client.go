@@ -0,0 +1,189 @@
+// Package client sends commands to a running Kakoune session out of band,
+// as `kak -p` does, for daemons and external tools which are not a Func.
+//
+// Unlike the output of a Func, commands sent by a Client are evaluated as
+// soon as they are sent, and every Client call may be made at any time and
+// from any goroutine.
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// DefaultTimeout is how long Query waits for a response, if the Client has
+// no Timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Client of a Kakoune session.
+type Client struct {
+	// Session is the name of the session, as listed by `kak -l` and given
+	// to Funcs as vars.Session.
+	Session string
+
+	// Client is the name of the client commands are evaluated within, if
+	// any. Commands sent to a session are evaluated without a client, so
+	// those which need one, such as echo, require a Client to be seen.
+	Client string
+
+	// Bin is the kak binary, "kak" if empty.
+	Bin string
+
+	// Timeout is how long Query waits for a response, DefaultTimeout if
+	// zero.
+	Timeout time.Duration
+}
+
+// New returns the Client of the session.
+func New(session string) *Client {
+	return &Client{Session: session}
+}
+
+// WithClient returns a copy of the Client, evaluating within the named
+// client of the session, such as the value of vars.Client.
+func (c *Client) WithClient(name string) *Client {
+	cc := *c
+	cc.Client = name
+	return &cc
+}
+
+// Send sends the commands to the session as is, ignoring the Client, see
+// api.Send.
+func (c *Client) Send(commands string) error {
+	bin := c.Bin
+	if bin == "" {
+		bin = "kak"
+	}
+	return api.SendBin(bin, c.Session, commands)
+}
+
+// Eval sends the commands to the session, evaluated within the Client if
+// set.
+func (c *Client) Eval(commands string) error {
+	return c.Send(c.wrap(commands))
+}
+
+// wrap wraps the commands to be evaluated within the Client, if set.
+func (c *Client) wrap(commands string) string {
+	if c.Client == "" {
+		return commands
+	}
+	return "evaluate-commands -client " + api.Quote(c.Client) + " " + api.Quote(commands)
+}
+
+// Echo echoes the message within the Client.
+func (c *Client) Echo(msg string) error {
+	return c.Eval("echo -- " + api.Quote(msg))
+}
+
+// EchoMarkup echoes the markup within the Client, such as
+// `{Error}build failed`.
+func (c *Client) EchoMarkup(markup string) error {
+	return c.Eval("echo -markup -- " + api.Quote(markup))
+}
+
+// Debug writes the message to the *debug* buffer of the session.
+func (c *Client) Debug(msg string) error {
+	return c.Send("echo -debug -- " + api.Quote(msg))
+}
+
+// SetOption sets the option within the scope to the value, formatted by
+// its Go type, see api.FormatOption.
+//
+// Scopes other than global, such as buffer and window, are those of the
+// Client, unless given with their target, such as `buffer=main.go`.
+func (c *Client) SetOption(scope, name string, value interface{}) error {
+	_, v, err := api.FormatOption(value)
+	if err != nil {
+		return err
+	}
+	return c.Eval(fmt.Sprintf("set-option %s %s %s", scope, name, v))
+}
+
+// Edit opens the path at the given position within the Client, if the
+// position is given.
+func (c *Client) Edit(path string, at api.Coord) error {
+	cmd := "edit -- " + api.Quote(path)
+	if at.Line > 0 {
+		cmd += fmt.Sprintf(" %d", at.Line)
+		if at.Column > 0 {
+			cmd += fmt.Sprintf(" %d", at.Column)
+		}
+	}
+	return c.Eval(cmd)
+}
+
+// Query returns the values of the expansion, such as `%val{bufname}`,
+// within the Client.
+//
+// The response is written by Kakoune to a fifo, so Query blocks until it
+// is, or until the Timeout. Failing to expand is returned as an error.
+func (c *Client) Query(expansion string) ([]string, error) {
+	dir, err := ioutil.TempDir("", "gokakoune-query")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	fifo := filepath.Join(dir, "response")
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		return nil, err
+	}
+
+	echo := "echo -quoting kakoune -to-file " + api.Quote(fifo) + " -- "
+	script := "try " + api.Quote(c.wrap(echo+"ok "+expansion)) +
+		" catch " + api.Quote(echo+"error %val{error}")
+
+	type response struct {
+		b   []byte
+		err error
+	}
+	read := make(chan response, 1)
+	go func() {
+		b, err := ioutil.ReadFile(fifo)
+		read <- response{b, err}
+	}()
+
+	if err := c.Send(script); err != nil {
+		unblock(fifo)
+		return nil, err
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	var res response
+	select {
+	case res = <-read:
+	case <-time.After(timeout):
+		unblock(fifo)
+		return nil, fmt.Errorf("%s: no response from session %s", expansion, c.Session)
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	return api.ParseAnswer(string(res.b))
+}
+
+// unblock opens and closes the fifo for writing, so a reader waiting on
+// it reads nothing rather than waiting forever.
+func unblock(fifo string) {
+	if f, err := os.OpenFile(fifo, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+		f.Close()
+	}
+}
+
+// Sessions returns the names of the running sessions, as listed by
+// `kak -l`, see api.Sessions.
+func Sessions() ([]string, error) {
+	return api.Sessions()
+}
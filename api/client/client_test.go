@@ -0,0 +1,71 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeKak writes a kak to dir which records the commands it is sent, and
+// answers queries with the given response.
+func fakeKak(t *testing.T, dir, response string) string {
+	bin := filepath.Join(dir, "kak")
+	script := `#!/bin/sh
+input=$(cat)
+printf '%s\n' "$input" >> "` + filepath.Join(dir, "sent") + `"
+fifo=$(printf '%s' "$input" | sed -n "s/.*-to-file '*\([^']*\)'.*/\1/p" | head -n 1)
+if [ -n "$fifo" ]; then
+  printf '%s' "` + response + `" > "$fifo"
+fi
+`
+	if err := ioutil.WriteFile(bin, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Client{Session: "1", Bin: fakeKak(t, dir, "'ok' 'main.go'"), Timeout: time.Second}
+
+	if err := c.WithClient("client0").Echo("it's done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOption("global", "plug_list", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "sent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "evaluate-commands -client 'client0' 'echo -- ''it''''s done'''\n" +
+		"set-option global plug_list 'a' 'b'\n"
+	if string(b) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b, want)
+	}
+
+	got, err := c.Query("%val{bufname}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"main.go"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	// true never answers.
+	c := &Client{Session: "1", Bin: "true", Timeout: 50 * time.Millisecond}
+	if _, err := c.Query("%val{bufname}"); err == nil {
+		t.Error("expected error of no response")
+	}
+}
@@ -0,0 +1,128 @@
+// Code generated by gen.go from commands.txt. DO NOT EDIT.
+
+package cmds
+
+const (
+	AddHighlighter    = "add-highlighter"
+	Alias             = "alias"
+	ArrangeBuffers    = "arrange-buffers"
+	Buffer            = "buffer"
+	BufferNext        = "buffer-next"
+	BufferPrevious    = "buffer-previous"
+	ChangeDirectory   = "change-directory"
+	Colorscheme       = "colorscheme"
+	CompleteCommand   = "complete-command"
+	DaemonizeSession  = "daemonize-session"
+	Debug             = "debug"
+	DeclareOption     = "declare-option"
+	DeclareUserMode   = "declare-user-mode"
+	DefineCommand     = "define-command"
+	DeleteBuffer      = "delete-buffer"
+	DeleteBufferForce = "delete-buffer!"
+	Echo              = "echo"
+	Edit              = "edit"
+	EditForce         = "edit!"
+	EnterUserMode     = "enter-user-mode"
+	EvaluateCommands  = "evaluate-commands"
+	ExecuteKeys       = "execute-keys"
+	Fail              = "fail"
+	Hook              = "hook"
+	Info              = "info"
+	Kill              = "kill"
+	KillForce         = "kill!"
+	Map               = "map"
+	Menu              = "menu"
+	Nop               = "nop"
+	OnKey             = "on-key"
+	Prompt            = "prompt"
+	ProvideModule     = "provide-module"
+	Quit              = "quit"
+	QuitForce         = "quit!"
+	RemoveHighlighter = "remove-highlighter"
+	RemoveHooks       = "remove-hooks"
+	RenameBuffer      = "rename-buffer"
+	RenameClient      = "rename-client"
+	RenameSession     = "rename-session"
+	RequireModule     = "require-module"
+	Select            = "select"
+	SetFace           = "set-face"
+	SetOption         = "set-option"
+	SetRegister       = "set-register"
+	Source            = "source"
+	TriggerUserHook   = "trigger-user-hook"
+	Try               = "try"
+	Unalias           = "unalias"
+	Unmap             = "unmap"
+	UnsetFace         = "unset-face"
+	UnsetOption       = "unset-option"
+	UpdateOption      = "update-option"
+	Write             = "write"
+	WriteForce        = "write!"
+	WriteAll          = "write-all"
+	WriteAllQuit      = "write-all-quit"
+	WriteQuit         = "write-quit"
+	WriteQuitForce    = "write-quit!"
+)
+
+// Builtins are the names of every builtin command.
+var Builtins = []string{
+	AddHighlighter,
+	Alias,
+	ArrangeBuffers,
+	Buffer,
+	BufferNext,
+	BufferPrevious,
+	ChangeDirectory,
+	Colorscheme,
+	CompleteCommand,
+	DaemonizeSession,
+	Debug,
+	DeclareOption,
+	DeclareUserMode,
+	DefineCommand,
+	DeleteBuffer,
+	DeleteBufferForce,
+	Echo,
+	Edit,
+	EditForce,
+	EnterUserMode,
+	EvaluateCommands,
+	ExecuteKeys,
+	Fail,
+	Hook,
+	Info,
+	Kill,
+	KillForce,
+	Map,
+	Menu,
+	Nop,
+	OnKey,
+	Prompt,
+	ProvideModule,
+	Quit,
+	QuitForce,
+	RemoveHighlighter,
+	RemoveHooks,
+	RenameBuffer,
+	RenameClient,
+	RenameSession,
+	RequireModule,
+	Select,
+	SetFace,
+	SetOption,
+	SetRegister,
+	Source,
+	TriggerUserHook,
+	Try,
+	Unalias,
+	Unmap,
+	UnsetFace,
+	UnsetOption,
+	UpdateOption,
+	Write,
+	WriteForce,
+	WriteAll,
+	WriteAllQuit,
+	WriteQuit,
+	WriteQuitForce,
+}
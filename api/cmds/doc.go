@@ -0,0 +1,20 @@
+// Package cmds provides the names of the commands built into Kakoune, so a
+// typo of a command name, such as add-highligther, fails to compile rather
+// than failing within Kakoune. Eg:
+//
+//    kak.Command(cmds.SetOption, "buffer", "filetype", "go")
+//
+// The constants are generated from commands.txt.
+package cmds
+
+//go:generate go run gen.go
+
+// IsBuiltin reports whether the name is of a builtin command.
+func IsBuiltin(name string) bool {
+	for _, b := range Builtins {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
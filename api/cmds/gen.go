@@ -0,0 +1,74 @@
+//go:build ignore
+
+// gen generates cmds.go from commands.txt.
+//
+//    go generate ./api/cmds
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	f, err := os.Open("commands.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen.go from commands.txt. DO NOT EDIT.\n\n")
+	b.WriteString("package cmds\n\nconst (\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%s = %q\n", ident(name), name)
+	}
+	b.WriteString(")\n\n// Builtins are the names of every builtin command.\nvar Builtins = []string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%s,\n", ident(name))
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("cmds.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ident returns the Go name of the command, such as AddHighlighter for
+// add-highlighter and EditForce for edit!.
+func ident(name string) string {
+	var s string
+	if strings.HasSuffix(name, "!") {
+		name, s = strings.TrimSuffix(name, "!"), "Force"
+	}
+
+	var b strings.Builder
+	for _, part := range strings.Split(name, "-") {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String() + s
+}
@@ -1,7 +1,12 @@
 package api
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 )
 
 // Subproc executes Go code in a subproc of Kakoune.
@@ -40,12 +45,86 @@ type Subproc struct {
 	// Subproc executions.
 	//
 	// To share memory/state between Func calls, set options within Kakoune
-	// and retrieve them on future subprocs.
+	// and retrieve them on future subprocs, or run the Funcs within a
+	// Daemon.
 	Func func(*Kak) error
 }
 
 type DefineCommandOptions struct {
+	// Params is the number of params the command takes, or the least
+	// number of them if MaxParams is set.
 	Params int
+
+	// MaxParams is the most params the command takes, such as 3 for
+	// `-params 1..3`, or UnlimitedParams for any number. Zero is Params.
+	MaxParams int
+
+	// Docstring is shown by Kakoune when completing the command, and is
+	// included in the plugin Manifest.
+	Docstring string
+
+	// Timeout is how long each Func of the command may run before the
+	// command fails, see Kak.Context. Zero is no timeout.
+	Timeout time.Duration
+
+	// Hidden hides the command from completion, as for commands only run
+	// by hooks and mappings.
+	Hidden bool
+
+	// Override replaces the command if it is already defined, as when it
+	// is defined by another plugin. Commands are always overridden when
+	// initializing a stale script again.
+	Override bool
+
+	// FileCompletion, ClientCompletion and BufferCompletion complete the
+	// params of the command with file paths, client names or buffer names.
+	FileCompletion   bool
+	ClientCompletion bool
+	BufferCompletion bool
+
+	// ShellScriptCandidates is a shell script printing the candidates of
+	// the params, one per line, as the -shell-script-candidates of
+	// define-command, for candidates which need no Func. Eg:
+	//
+	//    ShellScriptCandidates: "git branch --format='%(refname:short)'",
+	ShellScriptCandidates string
+
+	// Completer completes the params of the command, if any.
+	Completer *Completer
+
+	// ErrorHandler handles the errors of the Funcs of the command, over
+	// that of the Kak, see Kak.SetErrorHandler.
+	ErrorHandler ErrorHandler
+}
+
+// completionSwitch returns the switch of the completion of the command,
+// if any, save that of a Completer.
+func (o DefineCommandOptions) completionSwitch() (string, error) {
+	var switches []string
+	if o.FileCompletion {
+		switches = append(switches, "-file-completion")
+	}
+	if o.ClientCompletion {
+		switches = append(switches, "-client-completion")
+	}
+	if o.BufferCompletion {
+		switches = append(switches, "-buffer-completion")
+	}
+	if o.ShellScriptCandidates != "" {
+		block, err := QuoteExpansion("", o.ShellScriptCandidates)
+		if err != nil {
+			return "", err
+		}
+		switches = append(switches, "-shell-script-candidates "+block)
+	}
+
+	if len(switches) > 1 || (len(switches) == 1 && o.Completer != nil) {
+		return "", errors.New("command takes a single completion")
+	}
+	if len(switches) == 0 {
+		return "", nil
+	}
+	return switches[0], nil
 }
 
 // func (k *Kak) initCommand(name string, opts DefineCommandOptions, cs []Subproc) error {
@@ -163,11 +242,17 @@ func (k *Kak) Expansion(exp Expansion) error {
 	}
 
 	if k.gokakouneInit {
+		id := k.expansionCount
 		init, err := k.initExpansion(exp)
 		if err != nil {
 			return err
 		}
+		k.trace(k.writer, "init expansion %d:\n%s", id, strings.TrimPrefix(init, "\n"))
 
+		if header := k.header(exp); header != "" {
+			k.Print("\n" + header)
+			init = strings.TrimPrefix(init, "\n")
+		}
 		k.Println(init)
 
 		// returning here ensures we don't run expansions when gokakoune
@@ -187,13 +272,17 @@ func (k *Kak) runExpansion(exp Expansion) error {
 	expansionCount := k.expansionCount
 	k.expansionCount++
 
+	if cd, ok := exp.(DefineCommand); ok {
+		defer k.withCommand(cd.Name, cd.Options)()
+	}
+
 	for _, cExp := range exp.Children() {
 		if err := k.runExpansion(cExp); err != nil {
 			return err
 		}
 	}
 
-	if expansionCount != k.expansionID {
+	if expansionCount != k.expansionID || k.route != k.funcRoute {
 		return nil
 	}
 
@@ -204,26 +293,118 @@ func (k *Kak) runExpansion(exp Expansion) error {
 		return fmt.Errorf("not runnable expansion: %d", expansionCount)
 	}
 
-	// NOTE(leeola): this behavior of consuming the error and printing
-	// it to the user is debatable. My thought process though is that
-	// gokakoune isn't failing, so the main k.Expansion() or k.DefineCommand()
-	// API shouldn't be failing. The user command is failing, but that's
-	// on their side.
-	//
-	// This differs from the error above, where an expansion is not runnable,
-	// that's clearly related to a gokakoune error.
-	if err := runnable.Run(k); err != nil {
-		// report the error to the user
-		k.Fail(err)
+	// NOTE(leeola): the output of the Func is buffered, as Kakoune only
+	// evaluates it once the process exits anyway. A failing Func then
+	// emits nothing but the fail, rather than a partial run ending in one.
+	// The error may surface as other than a fail, see KakError.
+	k.traceInvocation(k.writer, expansionCount)
+	var buf bytes.Buffer
+	w := k.writer
+	k.writer = &buf
+	k.running = true
+	err, timedOut := k.runContext(runnable)
+	k.running = false
+	k.writer = w
+	if timedOut {
+		k.trace(w, "func %d error: %s", expansionCount, err)
+		fmt.Fprintln(w, kakError(err).command())
+		return &FailError{Err: err}
+	}
+
+	if err == nil {
+		err = k.failure
+	}
+	if err != nil {
+		k.trace(w, "func %d error: %s", expansionCount, err)
+		if buf.Len() != 0 {
+			k.trace(w, "func %d discarded commands:\n%s", expansionCount, buf.String())
+		}
+		if err = k.handleError(err); err == nil {
+			return nil
+		}
+		kerr := kakError(err)
+		k.Println(kerr.command())
+		if kerr.Severity != SeverityFail {
+			return nil
+		}
+		return &FailError{Err: err}
+	}
+
+	if Strict() {
+		if err := CheckBalanced(buf.String()); err != nil {
+			strictf("func %d of %s printed %s", expansionCount, k.PluginName(), err)
+		}
 	}
 
+	k.trace(w, "func %d commands:\n%s", expansionCount, buf.String())
+	k.Print(buf.String())
 	return nil
 }
 
+// FailError is the error of an invoked Func which failed, returned by
+// Expansion and the APIs using it, such as DefineCommand.
+//
+// The failure was already reported to Kakoune, so main need only exit
+// with a non-zero status, see Exit.
+type FailError struct {
+	Err error
+}
+
+func (e *FailError) Error() string {
+	return e.Err.Error()
+}
+
+// Exit exits the process if err is not nil, with a non-zero status. Errors
+// other than a FailError are printed to stderr, which Kakoune shows within
+// the *debug* buffer. Eg:
+//
+//    func main() {
+//        kak := api.New()
+//        api.Exit(kak.DefineCommand("hello", opts, hello))
+//    }
+func Exit(err error) {
+	if err == nil {
+		return
+	}
+
+	var fail *FailError
+	if !errors.As(err, &fail) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(1)
+}
+
 func (k *Kak) initExpansion(exp Expansion) (string, error) {
 	expansionCount := k.expansionCount
 	k.expansionCount++
 
+	if cd, ok := exp.(DefineCommand); ok {
+		k.manifest.Commands = append(k.manifest.Commands, ManifestEntry{
+			Name:      cd.Name,
+			Docstring: cd.Options.Docstring,
+		})
+	}
+	if m, ok := exp.(UserMode); ok {
+		k.manifest.UserModes = append(k.manifest.UserModes, ManifestEntry{
+			Name:      m.Name,
+			Docstring: m.Docstring,
+		})
+	}
+	if m, ok := exp.(Mapping); ok {
+		k.manifest.Mappings = append(k.manifest.Mappings, ManifestMapping{
+			Mode:      m.Mode,
+			Key:       m.Key,
+			Keys:      m.Keys,
+			Docstring: m.Options.Docstring,
+		})
+	}
+	if o, ok := exp.(Option); ok {
+		k.manifest.Options = append(k.manifest.Options, ManifestEntry{
+			Name:      o.Name,
+			Docstring: o.Docstring,
+		})
+	}
+
 	var childInits []string
 	for _, cExp := range exp.Children() {
 		init, err := k.initExpansion(cExp)
@@ -235,39 +416,58 @@ func (k *Kak) initExpansion(exp Expansion) (string, error) {
 	}
 
 	return exp.Init(Context{
-		BinName:  k.gokakouneBin,
+		BinName:  k.binCommand(),
+		Reinit:   k.reinit,
+		Route:    k.route,
 		ID:       expansionCount,
 		Children: childInits,
 	})
 }
 
-// Command calls a kakoune command directly, escaping arguments
-// automatically.
+// Command calls a kakoune command directly, quoting string arguments
+// with QuoteArg. Builtin command names are constants of the api/cmds
+// package, such as cmds.SetOption.
 func (k *Kak) Command(name string, args ...interface{}) {
-	v := make([]interface{}, len(args)+1)
-	v[0] = name
+	k.Println(commandLine(name, args...))
+}
+
+// CommandIn calls a kakoune command within the given client, rather than
+// the context the Func was invoked within, such as to update the UI of the
+// client which started a job. Without a client it is the same as Command.
+//
+// NOTE(leeola): the command is evaluated with -try-client, as with Async,
+// so it falls back to the context of the Func if the client has exited.
+func (k *Kak) CommandIn(client, name string, args ...interface{}) {
+	k.Println(inClient(client, commandLine(name, args...)))
+}
+
+// CommandInSession sends a kakoune command to the given client of the
+// session with `kak -p`, see Send, or to the session itself without a
+// client. Unlike CommandIn nothing is printed, so it serves work outliving
+// the Func, such as the goroutines of a Daemon.
+func (k *Kak) CommandInSession(session, client, name string, args ...interface{}) error {
+	return Send(session, inClient(client, commandLine(name, args...)))
+}
+
+// commandLine returns the command of the name and arguments, quoted as by
+// Command.
+func commandLine(name string, args ...interface{}) string {
+	words := make([]string, len(args)+1)
+	words[0] = name
 	for i, a := range args {
-		// // EscapeRune ensures that the double quote is escaped, but nothing
-		// // else.
-		// //
-		// // This is because kakoune seems to have non-intuitive behavior with
-		// // escaping. If we use something like `Sprintf("%q", a)`, newlines
-		// // will be escaped in kakoune as well. We have to not escape newlines,
-		// // but do escape the surrounding quotes to ensure it is read as a
-		// // single argument.
-		// //
-		// // This feels a bit hacky, but i've not found a better way yet.
-		// v[i+1] = fmt.Sprintf("\"%s\"", util.EscapeRune(a, '"'))
-
-		s, ok := a.(string)
-		if ok {
-			s = escapeString(s)
-		} else {
-			s = fmt.Sprint(a)
+		if s, ok := a.(string); ok {
+			words[i+1] = QuoteArg(s)
+			continue
 		}
-
-		v[i+1] = s
+		words[i+1] = fmt.Sprint(a)
 	}
+	return strings.Join(words, " ")
+}
 
-	k.Println(v...)
+// inClient returns the commands evaluated within the client, if any.
+func inClient(client, commands string) string {
+	if client == "" {
+		return commands
+	}
+	return "evaluate-commands -try-client " + QuoteArg(client) + " " + QuoteBlock(commands)
 }
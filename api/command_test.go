@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunFail(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(*Kak) error
+		want string
+	}{
+		{"ok", func(k *Kak) error {
+			k.Println("echo one")
+			return nil
+		}, "echo one\n"},
+		{"error", func(k *Kak) error {
+			k.Println("echo one")
+			return errors.New("it's broken")
+		}, "fail 'it''s broken'\n"},
+		{"warning", func(k *Kak) error {
+			k.Println("echo one")
+			return WithSeverity(errors.New("not {found}"), SeverityWarning)
+		}, "echo -markup -- '{Information}not \\{found}'\n"},
+		{"failf", func(k *Kak) error {
+			k.Println("echo one")
+			k.Failf("first %d", 1)
+			k.Fail("second")
+			k.Println("echo two")
+			return nil
+		}, "fail 'first 1'\n"},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		k := newTestKak(out)
+		k.expansionID = 1
+
+		err := k.DefineCommand("cmd", DefineCommandOptions{}, Func{Func: test.f})
+		if got := out.String(); got != test.want {
+			t.Errorf("%s: got:%q, want:%q", test.name, got, test.want)
+		}
+
+		var fail *FailError
+		wantFail := test.name != "ok" && test.name != "warning"
+		if failed := errors.As(err, &fail); failed != wantFail {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
+
+func TestDefineCommandSwitches(t *testing.T) {
+	tests := []struct {
+		opts DefineCommandOptions
+		want string
+	}{
+		{DefineCommandOptions{Hidden: true, Override: true, Docstring: "it's"},
+			"define-command -params 0 -override -hidden -docstring 'it''s' cmd %{"},
+		{DefineCommandOptions{Params: 1, FileCompletion: true},
+			"define-command -params 1 -file-completion cmd %{"},
+		{DefineCommandOptions{Params: 1, BufferCompletion: true},
+			"define-command -params 1 -buffer-completion cmd %{"},
+		{DefineCommandOptions{Params: 1, ClientCompletion: true},
+			"define-command -params 1 -client-completion cmd %{"},
+		{DefineCommandOptions{Params: 1, ShellScriptCandidates: "printf '%s\\n' } {"},
+			"define-command -params 1 -shell-script-candidates %(printf '%s\\n' } {) cmd %{"},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		k := newTestKak(out)
+		k.gokakouneInit = true
+		if err := k.DefineCommand("cmd", test.opts, Raw("nop")); err != nil {
+			t.Errorf("%+v: %s", test.opts, err)
+			continue
+		}
+		if got := out.String(); !strings.Contains(got, test.want) {
+			t.Errorf("got:\n%s\nwant within it:\n%s", got, test.want)
+		}
+	}
+
+	k := newTestKak(&bytes.Buffer{})
+	k.gokakouneInit = true
+	err := k.DefineCommand("cmd", DefineCommandOptions{
+		Params:         1,
+		FileCompletion: true,
+		Completer:      &Completer{},
+	}, Raw("nop"))
+	if err == nil {
+		t.Error("expected error of two completions")
+	}
+}
+
+func TestCommandIn(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out}
+
+	k.Command("echo", "it's", 42)
+	k.CommandIn("", "echo", "hello")
+	k.CommandIn("client0", "echo", "it's", 42)
+
+	want := "echo 'it''s' 42\n" +
+		"echo hello\n" +
+		"evaluate-commands -try-client client0 %{echo 'it''s' 42}\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comment prints the text as a comment of the generated script, labelling
+// what follows for users inspecting the script, such as within the output
+// of `kak -debug` or the installed script.
+//
+// Comments are only printed when initializing, as the output of Funcs is
+// never read by a person. Commands and hooks are preceded by a header
+// comment automatically.
+func (k *Kak) Comment(v ...interface{}) {
+	if !k.gokakouneInit || k.funcCalled {
+		return
+	}
+
+	k.Print(comment(fmt.Sprint(v...)))
+}
+
+// comment returns the text as the lines of a Kakoune comment.
+func comment(text string) string {
+	// NOTE(leeola): Kakoune balances the braces of %{ } blocks without
+	// regard for comments, and comments are printed within the blocks of
+	// modules, so unbalanced braces would end the block.
+	if strings.Count(text, "{") != strings.Count(text, "}") {
+		text = strings.NewReplacer("{", "(", "}", ")").Replace(text)
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// header returns the header comment of the expansion, naming what it
+// declares and the plugin it is declared by, or empty if it has none.
+func (k *Kak) header(exp Expansion) string {
+	var what, doc string
+	switch e := exp.(type) {
+	case DefineCommand:
+		what, doc = "command "+e.Name, e.Options.Docstring
+	case Hook:
+		what = fmt.Sprintf("hook %s %s %s", e.Scope, e.Name, Quote(e.Filter))
+		doc = e.Options.Docstring
+	default:
+		return ""
+	}
+
+	by := k.PluginName()
+	if k.route != "" {
+		by += " " + k.route
+	}
+
+	text := fmt.Sprintf("%s, declared by %s", what, by)
+	if doc != "" {
+		text += "\n" + doc
+	}
+	return comment(text)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestComment(t *testing.T) {
+	tests := map[string]string{
+		"one":             "# one\n",
+		"two\n\nlines\n":  "# two\n#\n# lines\n",
+		"balanced %{ x }": "# balanced %{ x }\n",
+		"unbalanced %{ x": "# unbalanced %( x\n",
+	}
+
+	for text, want := range tests {
+		if got := comment(text); got != want {
+			t.Errorf("%q: got:%q, want:%q", text, got, want)
+		}
+	}
+}
+
+func TestCommandHeader(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	k.Comment("plugin setup")
+	err := k.DefineCommand("hello", DefineCommandOptions{Docstring: "say hello"}, Raw("echo hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# plugin setup
+
+# command hello, declared by plugin
+# say hello
+define-command -params 0 -docstring 'say hello' hello %{
+  echo hello
+}
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// comments are not printed by Funcs.
+	out.Reset()
+	k.gokakouneInit = false
+	k.Comment("hidden")
+	if strings.Contains(out.String(), "hidden") {
+		t.Errorf("unexpected comment: %q", out.String())
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// Completer completes the params of a command, as the
+// -shell-script-candidates of define-command. Eg, completing the first
+// param from a list of targets:
+//
+//    kak.DefineCommand("make-target", api.DefineCommandOptions{
+//        Params: 1,
+//        Completer: &api.Completer{
+//            Func: func(kak *api.Kak, params []string, token, pos int) ([]string, error) {
+//                if token != 0 {
+//                    return nil, nil
+//                }
+//                return targets()
+//            },
+//        },
+//    }, makeTarget)
+type Completer struct {
+	// ExportVars are the vars exported to the Func, as with Func.
+	ExportVars []string
+
+	// Func returns the candidates of params[token], the param being
+	// completed, the cursor being pos bytes into it. The params are those
+	// given so far, the last of which may be empty.
+	//
+	// The candidates are filtered by Kakoune against the param, unless
+	// Unfiltered is set.
+	Func func(k *Kak, params []string, token, pos int) ([]string, error)
+
+	// Unfiltered leaves the candidates as given, as the
+	// -shell-script-completion of define-command, for Funcs which filter
+	// and order them on their own.
+	Unfiltered bool
+}
+
+func (e Completer) Init(ctx Context) (string, error) {
+	switches := "-shell-script-candidates"
+	if e.Unfiltered {
+		switches = "-shell-script-completion"
+	}
+
+	exportVars := append([]string{vars.TokenToComplete, vars.PosInToken}, e.ExportVars...)
+	refs := make([]string, len(exportVars))
+	for i, v := range exportVars {
+		refs[i] = "$kak_" + v
+	}
+
+	// NOTE(leeola): as with Func, the vars only need to be referenced
+	// within the script for Kakoune to export them.
+	return fmt.Sprintf(`%s %%{
+    # %s
+    %s "$@"
+  }`, switches, strings.Join(refs, " "), ctx.Command()), nil
+}
+
+func (e Completer) Children() []Expansion {
+	return nil
+}
+
+// Run prints the candidates, one per line.
+//
+// Errors are written to stderr, and so the *debug* buffer, as anything
+// printed would be taken as a candidate.
+func (e Completer) Run(k *Kak) error {
+	token, err := k.VarInt(vars.TokenToComplete)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "completer:", err)
+		return nil
+	}
+	pos, err := k.VarInt(vars.PosInToken)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "completer:", err)
+		return nil
+	}
+
+	candidates, err := e.Func(k, k.Params(), token, pos)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "completer:", err)
+		return nil
+	}
+
+	for _, c := range candidates {
+		// a candidate cannot span lines.
+		if !strings.ContainsRune(c, '\n') {
+			k.Println(c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompleterInit(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	err := k.DefineCommand("pick", DefineCommandOptions{
+		Params:    1,
+		Completer: &Completer{ExportVars: []string{"buffile"}},
+	}, Raw("echo %arg{1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"define-command -params 1 -shell-script-candidates %{\n",
+		"# $kak_token_to_complete $kak_pos_in_token $kak_buffile\n",
+		"'/usr/bin/plugin'} 1 \"$@\"\n  } pick %{\n  echo %arg{1}\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q within:\n%s", want, got)
+		}
+	}
+}
+
+func TestCompleterRun(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.expansionID = 1
+	k.funcArgs = []string{"-x", "fo"}
+	k.funcVars = map[string]string{
+		"kak_token_to_complete": "1",
+		"kak_pos_in_token":      "2",
+	}
+
+	var got []interface{}
+	err := k.DefineCommand("pick", DefineCommandOptions{
+		MaxParams: UnlimitedParams,
+		Completer: &Completer{
+			Func: func(k *Kak, params []string, token, pos int) ([]string, error) {
+				got = []interface{}{params, token, pos}
+				return []string{"foo", "bad\nline", "fob"}, nil
+			},
+		},
+	}, Raw("nop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []interface{}{[]string{"-x", "fo"}, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%v, want:%v", got, want)
+	}
+	if want := "foo\nfob\n"; out.String() != want {
+		t.Errorf("got:%q, want:%q", out, want)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// Context returns the context of the invoked Func, which is done once the
+// invocation times out, see DefineCommandOptions.Timeout.
+//
+// External processes started with the context, such as by
+// exec.CommandContext, are killed when it is done, so a hung tool cannot
+// hold up the editor past the timeout. Eg:
+//
+//    out, err := exec.CommandContext(kak.Context(), "gofmt", file).Output()
+func (k *Kak) Context() context.Context {
+	if k.ctx == nil {
+		return context.Background()
+	}
+	return k.ctx
+}
+
+// runContext runs the runnable within the context of the invocation,
+// returning early if it times out.
+//
+// With a timeout, the runnable runs on a goroutine with a Kak of its own,
+// whose output and failure are only merged into k once it returns in time.
+// A panic of the runnable is returned as an error, as the recover of the
+// caller, such as that of a Daemon, does not reach the goroutine.
+//
+// NOTE(leeola): a Func which times out is not stopped, as goroutines
+// cannot be. It is left to finish, or to be killed as the process exits,
+// with everything it prints discarded.
+func (k *Kak) runContext(r Runnable) (err error, timedOut bool) {
+	if k.timeout <= 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		k.ctx = ctx
+		return r.Run(k), false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+	k.ctx = ctx
+
+	var buf bytes.Buffer
+	rk := *k
+	rk.writer = &buf
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- r.Run(&rk)
+	}()
+
+	select {
+	case err := <-done:
+		k.writer.Write(buf.Bytes())
+		k.failure, k.asyncCount = rk.failure, rk.asyncCount
+		return err, false
+	case <-ctx.Done():
+		return fmt.Errorf("%s timed out after %s", k.command, k.timeout), true
+	}
+}
+
+// withCommand sets the command, and its timeout and error handler, of the
+// Funcs run within the returned func, as runExpansion walks the expansions
+// of a DefineCommand. The returned func restores the previous command.
+func (k *Kak) withCommand(name string, opts DefineCommandOptions) func() {
+	command, prev, errs := k.command, k.timeout, k.commandErrors
+	k.command, k.timeout, k.commandErrors = name, opts.Timeout, opts.ErrorHandler
+	return func() {
+		k.command, k.timeout, k.commandErrors = command, prev, errs
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.expansionID = 1
+
+	killed := make(chan error, 1)
+	err := k.DefineCommand("hang", DefineCommandOptions{Timeout: 50 * time.Millisecond}, Func{
+		ContextFunc: func(ctx context.Context, k *Kak) error {
+			k.Println("echo never")
+			err := exec.CommandContext(ctx, "sleep", "5").Run()
+			killed <- err
+			return err
+		},
+	})
+	if _, ok := err.(*FailError); !ok {
+		t.Fatalf("want a FailError, got %v", err)
+	}
+
+	if got, want := out.String(), "fail 'hang timed out after 50ms'\n"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	select {
+	case err := <-killed:
+		if err == nil {
+			t.Error("want the process killed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("process not killed on timeout")
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.expansionID = 1
+
+	err := k.DefineCommand("crash", DefineCommandOptions{Timeout: time.Second}, Func{
+		Func: func(k *Kak) error {
+			k.Println("echo before")
+			panic("boom")
+		},
+	})
+	if _, ok := err.(*FailError); !ok {
+		t.Fatalf("want a FailError, got %v", err)
+	}
+	if got, want := out.String(), "fail 'panic: boom'\n"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestTimeoutInTime(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.expansionID = 1
+
+	err := k.DefineCommand("quick", DefineCommandOptions{Timeout: time.Second}, Func{
+		Func: func(k *Kak) error {
+			k.Println("echo done")
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "echo done\n"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if k.writer != out || k.running {
+		t.Error("want the writer restored and not running")
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Coord is a position within a buffer, as Kakoune describes them in
+// selections and range-specs.
+//
+// Both the Line and the Column start at 1, and the Column is a byte
+// offset within the line.
+type Coord struct {
+	Line   int
+	Column int
+}
+
+// ParseCoord parses a `line.column` coord.
+func ParseCoord(s string) (Coord, error) {
+	i := strings.IndexByte(s, '.')
+	if i == -1 {
+		return Coord{}, fmt.Errorf("invalid coord: %q", s)
+	}
+
+	line, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return Coord{}, fmt.Errorf("invalid coord: %q", s)
+	}
+
+	column, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return Coord{}, fmt.Errorf("invalid coord: %q", s)
+	}
+
+	return Coord{Line: line, Column: column}, nil
+}
+
+// String returns the coord as `line.column`.
+func (c Coord) String() string {
+	if c.Line < 1 || c.Column < 1 {
+		strictf("coord before 1.1: %d.%d", c.Line, c.Column)
+	}
+	return strconv.Itoa(c.Line) + "." + strconv.Itoa(c.Column)
+}
+
+// Compare returns -1, 0 or 1 if c is before, at or after o.
+func (c Coord) Compare(o Coord) int {
+	switch {
+	case c.Line < o.Line:
+		return -1
+	case c.Line > o.Line:
+		return 1
+	case c.Column < o.Column:
+		return -1
+	case c.Column > o.Column:
+		return 1
+	}
+	return 0
+}
+
+// Less reports whether c is before o.
+func (c Coord) Less(o Coord) bool {
+	return c.Compare(o) < 0
+}
+
+// Shift returns the coord moved by the given number of lines and columns.
+func (c Coord) Shift(lines, columns int) Coord {
+	return Coord{Line: c.Line + lines, Column: c.Column + columns}
+}
+
+// Range is the inclusive range between two coords, as Kakoune describes
+// selections and range-specs.
+//
+// A selection keeps its direction, so Begin is its anchor and End its
+// cursor, and Begin may be after End. See Normalize.
+type Range struct {
+	Begin Coord
+	End   Coord
+}
+
+// ParseRange parses a `line.column,line.column` range, the format of the
+// selection descriptions and range-specs of Kakoune.
+func ParseRange(s string) (Range, error) {
+	i := strings.IndexByte(s, ',')
+	if i == -1 {
+		return Range{}, fmt.Errorf("invalid range: %q", s)
+	}
+
+	begin, err := ParseCoord(s[:i])
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range: %q", s)
+	}
+
+	end, err := ParseCoord(s[i+1:])
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range: %q", s)
+	}
+
+	return Range{Begin: begin, End: end}, nil
+}
+
+// ParseRanges parses the space separated ranges of a selections_desc.
+func ParseRanges(s string) ([]Range, error) {
+	var rs []Range
+	for _, desc := range strings.Fields(s) {
+		r, err := ParseRange(desc)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// String returns the range as `line.column,line.column`, as given to the
+// select command.
+func (r Range) String() string {
+	return r.Begin.String() + "," + r.End.String()
+}
+
+// Normalize returns the range with Begin before or at End, dropping the
+// direction of a selection.
+func (r Range) Normalize() Range {
+	if r.End.Less(r.Begin) {
+		return Range{Begin: r.End, End: r.Begin}
+	}
+	return r
+}
+
+// Contains reports whether the coord is within the range, including its
+// ends.
+func (r Range) Contains(c Coord) bool {
+	r = r.Normalize()
+	return r.Begin.Compare(c) <= 0 && c.Compare(r.End) <= 0
+}
+
+// ContainsRange reports whether o is entirely within the range.
+func (r Range) ContainsRange(o Range) bool {
+	o = o.Normalize()
+	return r.Contains(o.Begin) && r.Contains(o.End)
+}
+
+// Overlaps reports whether the ranges share any coord.
+func (r Range) Overlaps(o Range) bool {
+	r, o = r.Normalize(), o.Normalize()
+	return r.Begin.Compare(o.End) <= 0 && o.Begin.Compare(r.End) <= 0
+}
+
+// Shift returns the range moved by the given number of lines and columns.
+func (r Range) Shift(lines, columns int) Range {
+	return Range{Begin: r.Begin.Shift(lines, columns), End: r.End.Shift(lines, columns)}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	got, err := ParseRanges("1.1,1.5 3.4,2.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Range{
+		{Begin: Coord{1, 1}, End: Coord{1, 5}},
+		{Begin: Coord{3, 4}, End: Coord{2, 10}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:%v, want:%v", got, want)
+	}
+
+	if got := want[1].String(); got != "3.4,2.10" {
+		t.Fatalf("got:%q, want:%q", got, "3.4,2.10")
+	}
+
+	for _, s := range []string{"1.1", "1,1.1", "1.x,1.1"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	// a backward selection, from 3.4 to 2.10.
+	r := Range{Begin: Coord{3, 4}, End: Coord{2, 10}}
+
+	if got, want := r.Normalize(), (Range{Coord{2, 10}, Coord{3, 4}}); got != want {
+		t.Errorf("normalize: got:%v, want:%v", got, want)
+	}
+
+	for c, want := range map[Coord]bool{
+		{2, 10}: true,
+		{2, 99}: true,
+		{3, 4}:  true,
+		{2, 9}:  false,
+		{3, 5}:  false,
+	} {
+		if got := r.Contains(c); got != want {
+			t.Errorf("contains %v: got:%v, want:%v", c, got, want)
+		}
+	}
+
+	overlaps := []struct {
+		o    Range
+		want bool
+	}{
+		{Range{Coord{3, 4}, Coord{4, 1}}, true},
+		{Range{Coord{1, 1}, Coord{2, 10}}, true},
+		{Range{Coord{3, 5}, Coord{4, 1}}, false},
+		{Range{Coord{1, 1}, Coord{2, 9}}, false},
+	}
+	for _, test := range overlaps {
+		if got := r.Overlaps(test.o); got != test.want {
+			t.Errorf("overlaps %v: got:%v, want:%v", test.o, got, test.want)
+		}
+	}
+
+	if !r.ContainsRange(Range{Coord{3, 1}, Coord{2, 11}}) {
+		t.Error("want range contained")
+	}
+
+	if got, want := r.Shift(1, -1), (Range{Coord{4, 3}, Coord{3, 9}}); got != want {
+		t.Errorf("shift: got:%v, want:%v", got, want)
+	}
+}
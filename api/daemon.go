@@ -0,0 +1,385 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// env_daemon marks the process as the daemon, when a Func reruns the
+	// binary to start it.
+	env_daemon = "GOKAKOUNE_DAEMON"
+
+	// DefaultDaemonIdle is how long the daemon waits for a request before
+	// exiting, if DaemonOptions.Idle is zero.
+	DefaultDaemonIdle = 30 * time.Minute
+)
+
+// DaemonOptions are the options of Kak.Daemon.
+type DaemonOptions struct {
+	// Idle is how long the daemon runs without a request before exiting.
+	Idle time.Duration
+
+	// Callback is the address the daemon serves callbacks of external
+	// tools on, such as browser extensions or build systems opening a file
+	// within a session, if not empty. Eg `127.0.0.1:7777`, or `unix` for a
+	// unix socket.
+	//
+	// TCP addresses must be of the loopback interface. The address listened
+	// on, and the token authorizing requests, are written into
+	// `<cache>/gokakoune/<plugin>/.callback/`, see callbackHandler.
+	//
+	// NOTE(leeola): the daemon only runs once a Func has been invoked, so
+	// the callbacks are only served from then on.
+	Callback string
+}
+
+// daemonRequest is an invocation forwarded to the daemon.
+type daemonRequest struct {
+	Version string            `json:"version"`
+	Dir     string            `json:"dir"`
+	ID      int               `json:"id"`
+	Route   string            `json:"route,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Vars    map[string]string `json:"vars,omitempty"`
+}
+
+// daemonResponse is the result of an invocation run by the daemon.
+type daemonResponse struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+
+	// Failed is true when Error is that of a FailError, the fail of which
+	// is already within Output.
+	Failed bool `json:"failed,omitempty"`
+
+	// Stale is true when the daemon is of another version than the
+	// requesting binary, in which case nothing was run.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Daemon runs the Funcs declared by init within a long lived process,
+// rather than a new process per invocation. Init declares everything the
+// plugin provides, as a main func would, and may itself use Suite. Eg:
+//
+//    func main() {
+//        kak := api.New()
+//        api.Exit(kak.Daemon(api.DaemonOptions{}, func(kak *api.Kak) error {
+//            return kak.DefineCommand("myplug-lookup", opts, lookup)
+//        }))
+//    }
+//
+// The generated script is unchanged. Invocations only forward their
+// arguments and vars to the daemon over a unix socket, starting it if it
+// is not running, and print what the Func printed there. As the daemon
+// outlives the invocations, Funcs may keep caches, indexes and clients in
+// memory between them.
+//
+// One daemon serves every session, and runs a single Func at a time, so
+// memory can be shared without locking, save with a Func which timed out
+// and may still be running, see DefineCommandOptions.Timeout. Its os.Args
+// and environment are those of the invocation which started it, its
+// working directory is that of the current invocation, and so a Func
+// which timed out goes on within that of the following invocations.
+//
+// NOTE(leeola): the daemon exits after being idle for DaemonOptions.Idle,
+// and is replaced when the binary is upgraded, so nothing it holds in
+// memory should be the only copy of state worth keeping.
+func (k *Kak) Daemon(opts DaemonOptions, init func(*Kak) error) error {
+	// noop if func was already called
+	if k.funcCalled {
+		return nil
+	}
+
+	if k.gokakouneInit {
+		return init(k)
+	}
+
+	sock, err := k.daemonSocket()
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(env_daemon) != "" {
+		k.funcCalled = true
+		return k.serveDaemon(sock, opts, init)
+	}
+
+	return k.forward(sock)
+}
+
+// daemonSocket returns the socket of the daemon of this plugin.
+func (k *Kak) daemonSocket() (string, error) {
+	root, err := stateRoot()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, k.PluginName())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+// forward forwards the invocation to the daemon listening on sock,
+// starting it if needed, and prints its output.
+func (k *Kak) forward(sock string) error {
+	k.funcCalled = true
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	req := daemonRequest{
+		Version: k.version,
+		Dir:     dir,
+		ID:      k.expansionID,
+		Route:   k.funcRoute,
+		Args:    k.funcArgs,
+		Vars:    k.funcVars,
+	}
+
+	var res daemonResponse
+	// the second attempt is made against a fresh daemon, once a stale one
+	// has exited.
+	for attempt := 0; attempt < 2; attempt++ {
+		res, err = requestDaemon(sock, req)
+		if err == errNoDaemon {
+			if err := k.startDaemon(sock); err != nil {
+				return err
+			}
+			res, err = requestDaemon(sock, req)
+		}
+		if err != nil {
+			return err
+		}
+		if !res.Stale {
+			break
+		}
+	}
+	if res.Stale {
+		return fmt.Errorf("%s daemon version mismatch", k.PluginName())
+	}
+
+	k.Print(res.Output)
+	switch {
+	case res.Failed:
+		return &FailError{Err: errors.New(res.Error)}
+	case res.Error != "":
+		return errors.New(res.Error)
+	}
+	return nil
+}
+
+// errNoDaemon is returned by requestDaemon when no daemon is listening.
+var errNoDaemon = errors.New("no daemon listening")
+
+// requestDaemon sends the request to the daemon listening on sock.
+//
+// NOTE(leeola): only failing to dial is errNoDaemon. A daemon which dies
+// while running the request is not restarted, as rerunning the Func could
+// repeat whatever it did before dying.
+func requestDaemon(sock string, req daemonRequest) (daemonResponse, error) {
+	c, err := net.Dial("unix", sock)
+	if err != nil {
+		return daemonResponse{}, errNoDaemon
+	}
+	defer c.Close()
+
+	if err := json.NewEncoder(c).Encode(req); err != nil {
+		return daemonResponse{}, err
+	}
+
+	var res daemonResponse
+	if err := json.NewDecoder(c).Decode(&res); err != nil {
+		return daemonResponse{}, fmt.Errorf("daemon response: %s", err)
+	}
+	return res, nil
+}
+
+// startDaemon reruns this invocation as the daemon in the background, and
+// waits for it to listen on sock.
+func (k *Kak) startDaemon(sock string) error {
+	if err := k.SpawnDaemon(env_daemon, sock); err != nil {
+		return fmt.Errorf("%s daemon failed to start: %s", k.PluginName(), err)
+	}
+	return nil
+}
+
+// SpawnDaemon reruns this invocation in the background, detached from it,
+// with the environment variable env set to 1, and waits for the rerun to
+// listen on the unix socket sock.
+//
+// It starts the daemons plugins run of their own, rather than with Daemon,
+// such as one owning a language server for the whole session. The Func
+// calls it when it cannot dial sock, while the rerun Func, telling itself
+// apart by env, serves sock instead of calling it again. Eg:
+//
+//    if os.Getenv("MYPLUG_DAEMON") != "" {
+//        return serve(sock)
+//    }
+//    if err := kak.SpawnDaemon("MYPLUG_DAEMON", sock); err != nil {
+//        return err
+//    }
+func (k *Kak) SpawnDaemon(env, sock string) error {
+	cmd := exec.Command(k.gokakouneBin, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), env+"=1")
+	// detach from this process, so it survives us exiting.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	cmd.Process.Release()
+
+	for i := 0; i < 50; i++ {
+		if c, err := net.Dial("unix", sock); err == nil {
+			c.Close()
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return fmt.Errorf("not listening on %s", sock)
+}
+
+// serveDaemon serves invocations on sock, until idle or replaced.
+func (k *Kak) serveDaemon(sock string, opts DaemonOptions, init func(*Kak) error) error {
+	// another invocation may have started a daemon meanwhile.
+	if c, err := net.Dial("unix", sock); err == nil {
+		c.Close()
+		return nil
+	}
+
+	os.Remove(sock)
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+
+	idle := opts.Idle
+	if idle == 0 {
+		idle = DefaultDaemonIdle
+	}
+
+	var (
+		mu    sync.Mutex
+		timer = time.AfterFunc(idle, func() { l.Close() })
+	)
+	shutdown := func() {
+		timer.Stop()
+		l.Close()
+	}
+
+	if opts.Callback != "" {
+		cl, token, err := k.listenCallback(opts.Callback)
+		if err != nil {
+			shutdown()
+			return err
+		}
+		defer cl.Close()
+
+		handler := callbackHandler(token, Send)
+		go http.Serve(cl, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r)
+
+			// a callback is not idle either. Holding mu, no request is
+			// running which would reset the timer itself.
+			mu.Lock()
+			timer.Reset(idle)
+			mu.Unlock()
+		}))
+	}
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			break
+		}
+
+		go func() {
+			defer c.Close()
+
+			var req daemonRequest
+			if err := json.NewDecoder(c).Decode(&req); err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			// the daemon is not idle while running the request.
+			timer.Stop()
+			defer timer.Reset(idle)
+
+			var res daemonResponse
+			if req.Version != k.version {
+				// NOTE(leeola): the socket is removed before responding,
+				// so the requesting binary can start its own daemon.
+				os.Remove(sock)
+				shutdown()
+				res.Stale = true
+			} else {
+				res = k.runRequest(req, init)
+			}
+
+			json.NewEncoder(c).Encode(res)
+		}()
+	}
+
+	// wait for the request being run, if any, to respond. Closing the
+	// listener already removed the socket.
+	mu.Lock()
+	defer mu.Unlock()
+	return nil
+}
+
+// runRequest runs the invocation of the request with a Kak of its own, as
+// the process of the invocation would.
+func (k *Kak) runRequest(req daemonRequest, init func(*Kak) error) (res daemonResponse) {
+	// a panicking Func would otherwise take down every cache of the daemon
+	// with it.
+	defer func() {
+		if r := recover(); r != nil {
+			res = daemonResponse{Error: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+
+	if err := os.Chdir(req.Dir); err != nil {
+		return daemonResponse{Error: err.Error()}
+	}
+
+	var buf bytes.Buffer
+	rk := &Kak{
+		writer:       &buf,
+		gokakouneBin: k.gokakouneBin,
+		expansionID:  req.ID,
+		funcRoute:    req.Route,
+		funcArgs:     req.Args,
+		funcVars:     req.Vars,
+		version:      k.version,
+		debug:        k.debug,
+	}
+	if rk.funcVars == nil {
+		rk.funcVars = map[string]string{}
+	}
+
+	err := init(rk)
+	res.Output = buf.String()
+	if err != nil {
+		var fail *FailError
+		res.Failed = errors.As(err, &fail)
+		res.Error = err.Error()
+	}
+	return res
+}
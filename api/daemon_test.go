@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDaemon(t *testing.T) {
+	cache, err := ioutil.TempDir("", "daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	os.Setenv("XDG_CACHE_HOME", cache)
+
+	// calls is kept by the daemon between invocations.
+	var calls int
+	init := func(k *Kak) error {
+		return k.DefineCommand("count", DefineCommandOptions{}, Func{
+			Func: func(k *Kak) error {
+				calls++
+				if len(k.funcArgs) > 0 {
+					k.Failf("unexpected %s", k.funcArgs[0])
+					return nil
+				}
+				k.Printf("echo %d\n", calls)
+				return nil
+			},
+		})
+	}
+
+	d := newTestKak(nil)
+	d.version = "1"
+	sock, err := d.daemonSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- d.serveDaemon(sock, DaemonOptions{Idle: 200 * time.Millisecond}, init)
+	}()
+
+	request := func(args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		k := newTestKak(out)
+		k.version = "1"
+		k.expansionID = 1
+		k.funcArgs = args
+		err := k.forward(sock)
+		return out.String(), err
+	}
+
+	// wait for the daemon to listen, as startDaemon would.
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(sock); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, want := range []string{"echo 1\n", "echo 2\n"} {
+		out, err := request()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != want {
+			t.Errorf("got:%q, want:%q", out, want)
+		}
+	}
+
+	out, err := request("x")
+	if _, ok := err.(*FailError); !ok {
+		t.Errorf("want a FailError, got %v", err)
+	}
+	if want := "fail 'unexpected x'\n"; out != want {
+		t.Errorf("got:%q, want:%q", out, want)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("daemon did not exit when idle")
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Errorf("want socket removed, got %v", err)
+	}
+}
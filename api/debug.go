@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// env_debug enables debug mode, see Kak.Debugging.
+	env_debug = "GOKAKOUNE_DEBUG"
+
+	// env_debugLog is the file debug mode appends to, as well as the
+	// *debug* buffer, if set.
+	env_debugLog = "GOKAKOUNE_DEBUG_LOG"
+)
+
+// SetDebug enables or disables debug mode, see Debugging.
+func (k *Kak) SetDebug(debug bool) {
+	k.debug = debug
+}
+
+// Debugging reports whether debug mode is enabled, by SetDebug or by
+// setting the GOKAKOUNE_DEBUG environment variable of the Kakoune server
+// to anything but an empty string or 0. Eg:
+//
+//    GOKAKOUNE_DEBUG=1 GOKAKOUNE_DEBUG_LOG=/tmp/gokakoune.log kak
+//
+// In debug mode, the following are written to the *debug* buffer, and
+// appended to the GOKAKOUNE_DEBUG_LOG file if set:
+//
+//    - the script generated by each expansion when initializing
+//    - each invocation of a Func, with its arguments and exported vars
+//    - the commands printed by the Func
+//    - the error of the Func, if any
+//
+// Unlike strict mode, see Strict, nothing behaves differently.
+func (k *Kak) Debugging() bool {
+	if k.debug {
+		return true
+	}
+	v := os.Getenv(env_debug)
+	return v != "" && v != "0"
+}
+
+// trace writes the message to the *debug* buffer, by the command written
+// to w, and the log file if any, when in debug mode.
+//
+// NOTE(leeola): the command is written to w directly, rather than printed,
+// as it is written regardless of the Func failing.
+func (k *Kak) trace(w io.Writer, format string, v ...interface{}) {
+	if !k.Debugging() {
+		return
+	}
+
+	msg := fmt.Sprintf("%s debug: ", k.PluginName()) + fmt.Sprintf(format, v...)
+	fmt.Fprintln(w, "echo -debug --", QuoteArg(msg))
+
+	path := os.Getenv(env_debugLog)
+	if path == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Fprintln(w, "echo -debug --", QuoteArg(k.PluginName()+" debug: log: "+err.Error()))
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s %s\n", time.Now().Format(time.RFC3339), msg)
+}
+
+// traceInvocation traces the invocation of the Func of the expansion, with
+// its arguments and vars.
+func (k *Kak) traceInvocation(w io.Writer, id int) {
+	if !k.Debugging() {
+		return
+	}
+
+	names := make([]string, 0, len(k.funcVars))
+	for name := range k.funcVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "func %d", id)
+	if k.funcRoute != "" {
+		fmt.Fprintf(&b, " of %s", k.funcRoute)
+	}
+	if k.command != "" {
+		fmt.Fprintf(&b, " of command %s", k.command)
+	}
+	if len(k.funcArgs) != 0 {
+		b.WriteString(" with " + QuoteArgs(k.funcArgs...))
+	}
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s=%s", name, k.funcVars[name])
+	}
+	k.trace(w, "%s", b.String())
+}
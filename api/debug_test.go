@@ -0,0 +1,67 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "debug.log")
+	os.Setenv(env_debugLog, log)
+	defer os.Unsetenv(env_debugLog)
+
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.expansionID = 1
+	k.funcArgs = []string{"it's"}
+	k.funcVars = map[string]string{"kak_buffile": "/tmp/a.go"}
+	k.SetDebug(true)
+
+	err = k.DefineCommand("myplug-echo", DefineCommandOptions{}, Func{
+		ExportVars: []string{"buffile"},
+		Func: func(k *Kak) error {
+			k.Echo("hello")
+			k.Fail("nope")
+			return nil
+		},
+	})
+	if _, ok := err.(*FailError); !ok {
+		t.Fatalf("want a FailError, got %v", err)
+	}
+
+	want := "echo -debug -- 'plugin debug: func 1 of command myplug-echo with ''it''''s''\n  kak_buffile=/tmp/a.go'\n" +
+		"echo -debug -- 'plugin debug: func 1 error: nope'\n" +
+		"echo -debug -- 'plugin debug: func 1 discarded commands:\necho -- hello\n'\n" +
+		"fail 'nope'\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if err := CheckBalanced(out.String()); err != nil {
+		t.Error(err)
+	}
+
+	b, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, "plugin debug: func 1 error: nope\n") {
+		t.Errorf("got log:\n%s", got)
+	}
+
+	out.Reset()
+	k = newTestKak(out)
+	k.gokakouneInit = true
+	k.Expansion(Raw("echo init"))
+	if got := out.String(); got != "echo init\n" {
+		t.Errorf("traced while not debugging: %q", got)
+	}
+}
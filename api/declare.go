@@ -0,0 +1,91 @@
+package api
+
+// Vars declares the vars read by a Func, see Declare.
+type Vars struct {
+	names []string
+	seen  map[string]bool
+}
+
+// Var is a var declared by Vars, read by its methods.
+type Var struct {
+	name string
+}
+
+// Name returns the name of the var, as given within ExportVars.
+func (v Var) Name() string {
+	return v.name
+}
+
+// Get returns the value of the var, see Kak.Var.
+func (v Var) Get(k *Kak) (string, error) {
+	return k.Var(v.name)
+}
+
+// Int returns the value of an int var, see Kak.VarInt.
+func (v Var) Int(k *Kak) (int, error) {
+	return k.VarInt(v.name)
+}
+
+// Bool returns the value of a bool var, see Kak.VarBool.
+func (v Var) Bool(k *Kak) (bool, error) {
+	return k.VarBool(v.name)
+}
+
+// List returns the elements of a list var, see Kak.VarStrList.
+func (v Var) List(k *Kak) ([]string, error) {
+	return k.VarStrList(v.name)
+}
+
+// Var declares the var of the name, such as vars.BufName, exporting it to
+// the Func.
+func (d *Vars) Var(name string) Var {
+	if !d.seen[name] {
+		d.seen[name] = true
+		d.names = append(d.names, name)
+	}
+	return Var{name: name}
+}
+
+// Option declares the var of the option, such as `filetype` for
+// `opt_filetype`.
+func (d *Vars) Option(name string) Var {
+	return d.Var(opt_prefix + name)
+}
+
+// QuotedOption declares the quoted var of the option, as read by
+// Kak.GetOption, for list options holding spaces.
+func (d *Vars) QuotedOption(name string) Var {
+	return d.Var(OptionVar(name))
+}
+
+// Reg declares the var of the register, such as `"` for
+// `quoted_reg_dquote`, whose values are read by Var.List.
+func (d *Vars) Reg(name string) Var {
+	return d.Var(RegVar(name))
+}
+
+// Declare returns the Func returned by declare, exporting the vars it
+// declares, so the ExportVars of the Func are never out of sync with the
+// vars it reads. Eg:
+//
+//    k.DefineCommand("hello", opts, api.Declare(func(v *api.Vars) func(*api.Kak) error {
+//        bufname := v.Var(vars.BufName)
+//        filetype := v.Option("filetype")
+//
+//        return func(kak *api.Kak) error {
+//            name, err := bufname.Get(kak)
+//            ...
+//        }
+//    }))
+//
+// Reading a var declared by the same Vars never fails as not exported,
+// unlike a var listed by hand missing from ExportVars.
+//
+// NOTE(leeola): declare is called once, as the Func is constructed, both
+// when initializing and when invoked. It must only declare vars, as it
+// runs regardless of the Func being invoked.
+func Declare(declare func(v *Vars) func(*Kak) error) Func {
+	v := &Vars{seen: map[string]bool{}}
+	f := declare(v)
+	return Func{ExportVars: v.names, Func: f}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeclare(t *testing.T) {
+	var got []interface{}
+	f := Declare(func(v *Vars) func(*Kak) error {
+		bufname := v.Var("bufname")
+		width := v.Option("indentwidth")
+		reg := v.Reg(`"`)
+		v.Var("bufname")
+
+		return func(k *Kak) error {
+			name, err := bufname.Get(k)
+			if err != nil {
+				return err
+			}
+			n, err := width.Int(k)
+			if err != nil {
+				return err
+			}
+			values, err := reg.List(k)
+			if err != nil {
+				return err
+			}
+			got = append(got, name, n, values)
+			return nil
+		}
+	})
+
+	if want := []string{"bufname", "opt_indentwidth", "quoted_reg_dquote"}; !reflect.DeepEqual(f.ExportVars, want) {
+		t.Fatalf("got:%q, want:%q", f.ExportVars, want)
+	}
+
+	k := &Kak{funcVars: map[string]string{
+		"kak_bufname":           "main.go",
+		"kak_opt_indentwidth":   "4",
+		"kak_quoted_reg_dquote": "'a b' 'c'",
+	}}
+	if err := f.Func(k); err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"main.go", 4, []string{"a b", "c"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%v, want:%v", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// Env is a snapshot of the vars exported to the current invocation, for
+// logging, debugging and handing the context of the invocation to
+// background jobs as one value.
+//
+// Fields of vars which were not exported are zero. Every exported var is
+// within Vars, including those without a field.
+type Env struct {
+	Session  string `json:"session,omitempty"`
+	Client   string `json:"client,omitempty"`
+	BufName  string `json:"bufname,omitempty"`
+	BufFile  string `json:"buffile,omitempty"`
+	Filetype string `json:"filetype,omitempty"`
+
+	Timestamp int   `json:"timestamp,omitempty"`
+	Cursor    Coord `json:"cursor"`
+
+	// Selections are the selections_desc, the main selection first.
+	Selections []Range `json:"selections,omitempty"`
+
+	WindowHeight int `json:"window_height,omitempty"`
+	WindowWidth  int `json:"window_width,omitempty"`
+
+	// Args are the arguments of the invoked command.
+	Args []string `json:"args,omitempty"`
+
+	// Vars are the values of every exported var, by name without the
+	// kak_ prefix, such as `opt_filetype`.
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// Env returns the snapshot of the vars exported to the current invocation.
+func (k *Kak) Env() Env {
+	e := Env{
+		Args: append([]string(nil), k.funcArgs...),
+		Vars: make(map[string]string, len(k.funcVars)),
+	}
+	for key, v := range k.funcVars {
+		e.Vars[key[len(var_prefix):]] = v
+	}
+
+	str := func(key string) string {
+		return e.Vars[key]
+	}
+	num := func(key string) int {
+		// NOTE(leeola): unset and malformed vars alike are zero, as the
+		// snapshot is best effort.
+		i, _ := strconv.Atoi(e.Vars[key])
+		return i
+	}
+
+	e.Session = str(vars.Session)
+	e.Client = str(vars.Client)
+	e.BufName = str(vars.BufName)
+	e.BufFile = str(vars.BufFile)
+	e.Filetype = str(vars.OptFiletype)
+	e.Timestamp = num(vars.Timestamp)
+	e.Cursor = Coord{Line: num(vars.CursorLine), Column: num(vars.CursorColumn)}
+	e.WindowHeight = num(vars.WindowHeight)
+	e.WindowWidth = num(vars.WindowWidth)
+
+	if desc := str(vars.SelectionsDesc); desc != "" {
+		e.Selections, _ = ParseRanges(desc)
+	}
+
+	return e
+}
+
+// Environ returns the vars as the environment they were exported as, such
+// as `kak_session=1234`, sorted by name. Background processes given this
+// environment see the same vars through their own Kak.
+func (e Env) Environ() []string {
+	environ := make([]string, 0, len(e.Vars))
+	for key, v := range e.Vars {
+		environ = append(environ, var_prefix+key+"="+v)
+	}
+	sort.Strings(environ)
+	return environ
+}
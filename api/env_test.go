@@ -0,0 +1,55 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	k := &Kak{
+		funcArgs: []string{"arg"},
+		funcVars: map[string]string{
+			"kak_session":         "1234",
+			"kak_client":          "client0",
+			"kak_buffile":         "/src/main.go",
+			"kak_opt_filetype":    "go",
+			"kak_timestamp":       "12",
+			"kak_cursor_line":     "3",
+			"kak_cursor_column":   "5",
+			"kak_selections_desc": "3.5,3.1 1.1,1.4",
+		},
+	}
+
+	e := k.Env()
+	want := Env{
+		Session:   "1234",
+		Client:    "client0",
+		BufFile:   "/src/main.go",
+		Filetype:  "go",
+		Timestamp: 12,
+		Cursor:    Coord{Line: 3, Column: 5},
+		Selections: []Range{
+			{Begin: Coord{Line: 3, Column: 5}, End: Coord{Line: 3, Column: 1}},
+			{Begin: Coord{Line: 1, Column: 1}, End: Coord{Line: 1, Column: 4}},
+		},
+		Args: []string{"arg"},
+		Vars: map[string]string{
+			"session":         "1234",
+			"client":          "client0",
+			"buffile":         "/src/main.go",
+			"opt_filetype":    "go",
+			"timestamp":       "12",
+			"cursor_line":     "3",
+			"cursor_column":   "5",
+			"selections_desc": "3.5,3.1 1.1,1.4",
+		},
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("got:%+v, want:%+v", e, want)
+	}
+
+	environ := e.Environ()
+	if len(environ) != len(k.funcVars) || environ[0] != "kak_buffile=/src/main.go" {
+		t.Errorf("unexpected environ: %q", environ)
+	}
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Severity is how the error of a Func is surfaced to the user, see
+// KakError.
+type Severity int
+
+const (
+	// SeverityFail fails the invoked command with `fail`, aborting the
+	// commands which invoked it. This is the severity of any error which
+	// is not a KakError.
+	SeverityFail Severity = iota
+
+	// SeverityError echoes the error with the Error face, leaving the
+	// commands which invoked the command to continue.
+	SeverityError
+
+	// SeverityWarning echoes the error with the Information face.
+	SeverityWarning
+
+	// SeverityDebug only writes the error to the *debug* buffer.
+	SeverityDebug
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityFail:
+		return "fail"
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityDebug:
+		return "debug"
+	default:
+		return "unknown"
+	}
+}
+
+// KakError is an error of a Func which controls how it is surfaced. Eg,
+// a lookup finding nothing is worth an echo rather than a fail:
+//
+//    return api.WithSeverity(errors.New("no definition found"), api.SeverityWarning)
+//
+// Whatever the severity, nothing the Func printed is evaluated. Only
+// SeverityFail fails the invocation, see FailError.
+type KakError struct {
+	Err      error
+	Severity Severity
+
+	// Markup replaces the message echoed by SeverityError and
+	// SeverityWarning, such as `{Error}build failed{Default}: 3 errors`.
+	// The message of the Err is still used for fail and the *debug*
+	// buffer.
+	Markup string
+
+	// Code is the stable code of the error, if any, which prefixes its
+	// message as `<code>: <message>` so that kak script can react to it,
+	// see CatchCode.
+	//
+	// Codes are the name of the plugin followed by that of the error, such
+	// as `lsp-not-running`, and should never change once published.
+	Code string
+
+	// details are written to the *debug* buffer before the error is
+	// surfaced, see Errors.
+	details []string
+}
+
+// WithSeverity wraps the error as a KakError of the given severity.
+func WithSeverity(err error, s Severity) error {
+	return &KakError{Err: err, Severity: s}
+}
+
+// WithCode wraps the error as a failing KakError of the given code.
+func WithCode(err error, code string) error {
+	return &KakError{Err: err, Code: code}
+}
+
+// ErrorCode returns the code of the error, or an empty string if it has
+// none.
+func ErrorCode(err error) string {
+	var kerr *KakError
+	if errors.As(err, &kerr) {
+		return kerr.Code
+	}
+	return ""
+}
+
+// CatchCode returns the commands of a catch block which run then if the
+// error caught is of the code, and fail with the error otherwise. Eg:
+//
+//    try %{
+//      lsp-definition
+//    } catch %{
+//      # the output of CatchCode("lsp-not-running", "tag-jump")
+//    }
+//
+// The code must be letters, digits, dashes and dots, as codes are.
+func CatchCode(code, then string) (string, error) {
+	if !isCode(code) {
+		return "", fmt.Errorf("invalid error code: %q", code)
+	}
+
+	return fmt.Sprintf(`evaluate-commands %%sh{
+  case "$kak_error" in
+    '%s: '*) ;;
+    *) echo 'fail %%val{error}' ;;
+  esac
+}
+%s`, code, then), nil
+}
+
+// isCode reports whether s is a valid error code.
+func isCode(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case r == '-' || r == '.' || r == '_':
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+func (e *KakError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *KakError) Unwrap() error {
+	return e.Err
+}
+
+// command returns the command surfacing the error.
+func (e *KakError) command() string {
+	msg := e.Err.Error()
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+
+	debug := debugLines(e.details)
+
+	markup := e.Markup
+	switch e.Severity {
+	case SeverityError:
+		if markup == "" {
+			markup = "{Error}" + EscapeMarkup(msg)
+		}
+	case SeverityWarning:
+		if markup == "" {
+			markup = "{Information}" + EscapeMarkup(msg)
+		}
+	case SeverityDebug:
+		return debug + "echo -debug -- " + Quote(msg)
+	default:
+		return debug + "fail " + Quote(msg)
+	}
+
+	return debug + "echo -markup -- " + Quote(markup)
+}
+
+// kakError returns the error as a KakError, of SeverityFail unless it
+// wraps one. The message is that of the error, including what wraps the
+// KakError.
+func kakError(err error) *KakError {
+	e := &KakError{Err: err, Severity: SeverityFail}
+
+	var kerr *KakError
+	if errors.As(err, &kerr) {
+		e.Severity, e.Markup, e.Code, e.details = kerr.Severity, kerr.Markup, kerr.Code, kerr.details
+	}
+
+	var errs *Errors
+	if e.details == nil && errors.As(err, &errs) {
+		e.details = errs.details()
+	}
+	return e
+}
+
+// ErrorHandler handles the error of a Func, returning the error to
+// surface, or nil to recover from it. Eg, echoing the errors of every Func
+// rather than failing their commands:
+//
+//    kak.SetErrorHandler(api.SurfaceAs(api.SeverityError))
+//
+// The commands printed by the handler are evaluated, before the error
+// surfaces if any, though not those the Func printed. A KakError returned
+// surfaces as it would be returned by the Func.
+//
+// NOTE(leeola): a Func timing out always fails, as it may still be
+// printing.
+type ErrorHandler func(k *Kak, err error) error
+
+// SetErrorHandler sets the handler of the errors of Funcs, save those of
+// commands with their own, see DefineCommandOptions.ErrorHandler.
+func (k *Kak) SetErrorHandler(h ErrorHandler) {
+	k.errorHandler = h
+}
+
+// handleError returns the error surfaced by the handler of the command,
+// or else that of the Kak, if any.
+func (k *Kak) handleError(err error) error {
+	h := k.commandErrors
+	if h == nil {
+		h = k.errorHandler
+	}
+	if h == nil {
+		return err
+	}
+	return h(k, err)
+}
+
+// SurfaceAs returns the ErrorHandler surfacing errors with the severity,
+// such as SeverityError to echo them rather than fail. The Code and Markup
+// of the error, if a KakError, are kept.
+func SurfaceAs(s Severity) ErrorHandler {
+	return func(k *Kak, err error) error {
+		e := kakError(err)
+		e.Err, e.Severity = err, s
+		return e
+	}
+}
+
+// Recover returns the ErrorHandler evaluating the commands instead of
+// surfacing errors, such as a fallback command, the error being written
+// to the *debug* buffer. Eg:
+//
+//    api.DefineCommandOptions{
+//        ErrorHandler: api.Recover("ctags-jump"),
+//    }
+func Recover(commands string) ErrorHandler {
+	return func(k *Kak, err error) error {
+		k.Println(commands)
+		return SurfaceAs(SeverityDebug)(k, err)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestKakError(t *testing.T) {
+	err := errors.New("it's {bad}")
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err, `fail 'it''s {bad}'`},
+		{WithSeverity(err, SeverityError), `echo -markup -- '{Error}it''s \{bad}'`},
+		{WithSeverity(err, SeverityDebug), `echo -debug -- 'it''s {bad}'`},
+		{&KakError{Err: err, Severity: SeverityWarning, Markup: "{Information}3 {Default}left"},
+			`echo -markup -- '{Information}3 {Default}left'`},
+		// wrapped KakErrors keep their severity.
+		{fmt.Errorf("lookup: %w", WithSeverity(err, SeverityDebug)), `echo -debug -- 'lookup: it''s {bad}'`},
+	}
+
+	for _, test := range tests {
+		if got := kakError(test.err).command(); got != test.want {
+			t.Errorf("got:%q, want:%q", got, test.want)
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	err := fmt.Errorf("jump: %w", WithCode(errors.New("no tag"), "tags-not-found"))
+	if code := ErrorCode(err); code != "tags-not-found" {
+		t.Errorf("got code %q", code)
+	}
+	if want := `fail 'tags-not-found: jump: no tag'`; kakError(err).command() != want {
+		t.Errorf("got:%q, want:%q", kakError(err).command(), want)
+	}
+
+	catch, err := CatchCode("tags-not-found", "echo fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(catch, "'tags-not-found: '*) ;;") || !strings.HasSuffix(catch, "}\necho fallback") {
+		t.Errorf("unexpected catch:\n%s", catch)
+	}
+	if err := CheckScript(catch); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := CatchCode("it's", "nop"); err == nil {
+		t.Error("expected error of invalid code")
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	broken := Func{Func: func(k *Kak) error {
+		k.Println("echo one")
+		return WithCode(errors.New("it's\nbroken"), "plugin-broken")
+	}}
+	tests := []struct {
+		name    string
+		kak     ErrorHandler
+		command ErrorHandler
+		want    string
+		fail    bool
+	}{
+		{"none", nil, nil, "fail 'plugin-broken: it''s\nbroken'\n", true},
+		{"echo", SurfaceAs(SeverityError), nil, "echo -markup -- '{Error}plugin-broken: it''s\nbroken'\n", false},
+		{"command", SurfaceAs(SeverityError), SurfaceAs(SeverityDebug), "echo -debug -- 'plugin-broken: it''s\nbroken'\n", false},
+		{"recover", nil, Recover("echo fallback"), "echo fallback\necho -debug -- 'plugin-broken: it''s\nbroken'\n", false},
+		{"ignore", func(k *Kak, err error) error { return nil }, nil, "", false},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		k := newTestKak(out)
+		k.expansionID = 1
+		k.SetErrorHandler(test.kak)
+
+		err := k.DefineCommand("cmd", DefineCommandOptions{ErrorHandler: test.command}, broken)
+		if got := out.String(); got != test.want {
+			t.Errorf("%s: got:%q, want:%q", test.name, got, test.want)
+		}
+		if err := CheckBalanced(out.String()); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+
+		var fail *FailError
+		if failed := errors.As(err, &fail); failed != test.fail {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
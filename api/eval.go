@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"strings"
+)
+
+// EvalOptions are the switches of an evaluate-commands expansion, see
+// Eval.
+type EvalOptions struct {
+	// Client, TryClient and Buffer evaluate within the named client, the
+	// client if it exists, or the buffers, `*` being every buffer. At most
+	// one may be set.
+	Client    string
+	TryClient string
+	Buffer    string
+
+	// Draft evaluates within a copy of the context, so the selections
+	// and registers of the user are restored once done.
+	Draft bool
+
+	// Itersel evaluates once per selection, each being the only one.
+	Itersel bool
+
+	// NoHooks disables hooks while evaluating.
+	NoHooks bool
+
+	// SaveRegs are the registers restored once done, such as `az`.
+	SaveRegs string
+}
+
+// switches returns the switches of the options, each followed by a space.
+func (o EvalOptions) switches() (string, error) {
+	var b strings.Builder
+	targets := 0
+	for _, t := range []struct{ name, value string }{
+		{"-client", o.Client},
+		{"-try-client", o.TryClient},
+		{"-buffer", o.Buffer},
+	} {
+		if t.value != "" {
+			targets++
+			b.WriteString(t.name + " " + QuoteArg(t.value) + " ")
+		}
+	}
+	if targets > 1 {
+		return "", errors.New("evaluate-commands takes one of client, try-client and buffer")
+	}
+
+	if o.Draft {
+		b.WriteString("-draft ")
+	}
+	if o.Itersel {
+		b.WriteString("-itersel ")
+	}
+	if o.NoHooks {
+		b.WriteString("-no-hooks ")
+	}
+	if o.SaveRegs != "" {
+		b.WriteString("-save-regs " + QuoteArg(o.SaveRegs) + " ")
+	}
+	return b.String(), nil
+}
+
+// Evaluate is an evaluate-commands expansion, running its expansions
+// with the options. See Eval.
+type Evaluate struct {
+	Options EvalOptions
+
+	Expansions []Expansion
+}
+
+// Eval returns the expansion evaluating the expansions with the options,
+// so a command can mix evaluate-commands structure with Funcs. Eg,
+// uppercasing each word of the selections in Go, without changing them:
+//
+//    k.DefineCommand("myplug-upper-words", opts, api.Draft(
+//        api.Raw(`execute-keys s\w+<ret>`),
+//        api.Func{
+//            ExportVars: []string{vars.QuotedSelections},
+//            Func: func(kak *api.Kak) error {
+//                ...
+//                kak.ReplaceSelections(upper)
+//                return nil
+//            },
+//        },
+//    ))
+func Eval(opts EvalOptions, exps ...Expansion) Evaluate {
+	return Evaluate{Options: opts, Expansions: exps}
+}
+
+// Draft returns the expansion evaluating the expansions within a draft
+// context, see EvalOptions.Draft.
+func Draft(exps ...Expansion) Evaluate {
+	return Eval(EvalOptions{Draft: true}, exps...)
+}
+
+// IterateSelections returns the expansion evaluating the expansions once
+// per selection, within a draft context, see EvalOptions.Itersel.
+//
+// NOTE(leeola): a Func within is invoked once per selection, so reading
+// the selections of each within a single Func is usually faster.
+func IterateSelections(exps ...Expansion) Evaluate {
+	return Eval(EvalOptions{Draft: true, Itersel: true}, exps...)
+}
+
+func (e Evaluate) Init(ctx Context) (string, error) {
+	switches, err := e.Options.switches()
+	if err != nil {
+		return "", err
+	}
+	return "evaluate-commands " + switches +
+		QuoteBlock("\n  "+strings.Join(ctx.Children, "\n")+"\n"), nil
+}
+
+func (e Evaluate) Children() []Expansion {
+	return e.Expansions
+}
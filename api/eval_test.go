@@ -0,0 +1,49 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	err := k.DefineCommand("upper-words", DefineCommandOptions{}, IterateSelections(
+		Raw("execute-keys <a-i>w"),
+		Eval(EvalOptions{TryClient: "client 0", SaveRegs: "a"}, Func{
+			Func: func(k *Kak) error { return nil },
+		}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"evaluate-commands -draft -itersel %{\n  execute-keys <a-i>w\n" +
+			"evaluate-commands -try-client 'client 0' -save-regs a %{\n  ",
+		"'/usr/bin/plugin'} 4 \"$@\"",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got:\n%s\nwant within it:\n%s", got, want)
+		}
+	}
+	if err := CheckBalanced(got); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := Eval(EvalOptions{Client: "a", Buffer: "*"}).Init(Context{}); err == nil {
+		t.Error("expected error of client and buffer")
+	}
+
+	s, err := Draft(Raw("execute-keys '}'")).Init(Context{Children: []string{"execute-keys '}'"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "evaluate-commands -draft %(\n  execute-keys '}'\n)"; s != want {
+		t.Errorf("got:%q, want:%q", s, want)
+	}
+}
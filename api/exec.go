@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// ExecOptions are the options of ExecWith.
+type ExecOptions struct {
+	// Dir is the working directory of the command, the directory of the
+	// buffile if empty and it is exported, otherwise that of the Subproc.
+	// Plugins working on projects pass the project root, such as
+	// project.Root.
+	Dir string
+
+	// Env is added to the environment of the command, as `KEY=value`.
+	Env []string
+
+	// Stdin is the input of the command, if any.
+	Stdin io.Reader
+
+	// Timeout kills the command once passed, if not zero. The command is
+	// killed once the invocation times out regardless, see Kak.Context.
+	Timeout time.Duration
+}
+
+// ExecError is the error of a command run by Exec.
+type ExecError struct {
+	Name string
+	Args []string
+	Err  error
+
+	// Stderr is what the command wrote to stderr.
+	Stderr string
+}
+
+// Error returns the name of the command with its stderr, or the error
+// running it if it wrote none.
+func (e *ExecError) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = e.Err.Error()
+	}
+	return e.Name + ": " + msg
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// Exec runs the command and returns its stdout, as ExecWith with the
+// default options. Eg:
+//
+//    out, err := kak.Exec("go", "list", "-f", "{{.Dir}}", "./...")
+func (k *Kak) Exec(name string, args ...string) (string, error) {
+	return k.ExecWith(ExecOptions{}, name, args...)
+}
+
+// ExecWith runs the command and returns its stdout, failing with an
+// ExecError holding its stderr.
+//
+// The command inherits the environment of the Subproc, including the
+// exported vars, along with KAKOUNE_SESSION and KAKOUNE_CLIENT for tools
+// reaching back to the session. Failing commands are logged to the *debug*
+// buffer, which is seen even if the Func then fails.
+func (k *Kak) ExecWith(o ExecOptions, name string, args ...string) (string, error) {
+	ctx := k.Context()
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = o.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = k.execDir()
+	}
+	cmd.Env = append(os.Environ(), k.execEnv()...)
+	cmd.Env = append(cmd.Env, o.Env...)
+	cmd.Stdin = o.Stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	if err == nil {
+		return stdout.String(), nil
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", time.Since(start).Round(time.Millisecond))
+	}
+
+	eerr := &ExecError{Name: name, Args: args, Err: err, Stderr: stderr.String()}
+	k.logExec(cmd, eerr)
+	return stdout.String(), eerr
+}
+
+// execDir returns the directory of the buffile, if exported and a file,
+// or an empty string.
+func (k *Kak) execDir() string {
+	buffile, ok := k.LookupVar(vars.BufFile)
+	if !ok || !filepath.IsAbs(buffile) {
+		return ""
+	}
+
+	dir := filepath.Dir(buffile)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// execEnv returns the KAKOUNE_ environment of the session and client, of
+// those exported.
+func (k *Kak) execEnv() []string {
+	var env []string
+	if session, ok := k.LookupVar(vars.Session); ok {
+		env = append(env, "KAKOUNE_SESSION="+session)
+	}
+	if client, ok := k.LookupVar(vars.Client); ok {
+		env = append(env, "KAKOUNE_CLIENT="+client)
+	}
+	return env
+}
+
+// logExec writes the failed command and its stderr to the *debug* buffer.
+func (k *Kak) logExec(cmd *exec.Cmd, err *ExecError) {
+	w := k.stderr
+	if w == nil {
+		w = os.Stderr
+	}
+
+	fmt.Fprintf(w, "%s: exec %s", k.PluginName(), strings.Join(cmd.Args, " "))
+	if cmd.Dir != "" {
+		fmt.Fprintf(w, " (in %s)", cmd.Dir)
+	}
+	fmt.Fprintf(w, ": %s\n", err.Err)
+	if s := strings.TrimSpace(err.Stderr); s != "" {
+		fmt.Fprintln(w, s)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExec(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := &Kak{
+		stderr: &bytes.Buffer{},
+		funcVars: map[string]string{
+			"kak_buffile": dir + "/exec.go",
+			"kak_session": "1",
+		},
+	}
+
+	out, err := k.ExecWith(ExecOptions{
+		Env:   []string{"GREETING=hi"},
+		Stdin: strings.NewReader("there"),
+	}, "sh", "-c", `printf '%s %s %s %s' "$GREETING" "$(cat)" "$KAKOUNE_SESSION" "$PWD"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hi there 1 " + dir; out != want {
+		t.Errorf("got:%q, want:%q", out, want)
+	}
+}
+
+func TestExecError(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	k := &Kak{gokakouneBin: "/bin/plugin", stderr: stderr}
+
+	_, err := k.Exec("sh", "-c", "echo 'no such thing' >&2; exit 2")
+	var eerr *ExecError
+	if !errors.As(err, &eerr) {
+		t.Fatalf("want ExecError, got %v", err)
+	}
+	if got := err.Error(); got != "sh: no such thing" {
+		t.Errorf("got %q", got)
+	}
+	if got := stderr.String(); !strings.HasPrefix(got, "plugin: exec sh -c") || !strings.Contains(got, "exit status 2") {
+		t.Errorf("got log:\n%s", got)
+	}
+
+	_, err = k.ExecWith(ExecOptions{Timeout: 10 * time.Millisecond}, "sleep", "5")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("want timeout, got %v", err)
+	}
+}
+
+func TestExecStrict(t *testing.T) {
+	os.Setenv(env_strict, "1")
+	defer os.Unsetenv(env_strict)
+
+	// buffile and session are optional to Exec, so leaving them unexported
+	// is no bug to panic over.
+	k := &Kak{stderr: &bytes.Buffer{}, funcVars: map[string]string{}}
+	out, err := k.Exec("echo", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hi\n"; out != want {
+		t.Errorf("got:%q, want:%q", out, want)
+	}
+}
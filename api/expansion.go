@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -15,11 +16,32 @@ type Runnable interface {
 }
 
 type Context struct {
-	BinName  string
+	// BinName is the shell command invoking the plugin binary, to which
+	// arguments are appended.
+	BinName string
+
+	// Reinit is true when the script is being initialized over a previously
+	// sourced one, as happens after the binary was upgraded. Declarations
+	// must override what already exists.
+	Reinit bool
+
+	// Route is the name of the Suite plugin being initialized, if any.
+	Route string
+
 	ID       int
 	Children []string
 }
 
+// Command returns the shell command invoking this expansion, to which
+// arguments may be appended.
+func (c Context) Command() string {
+	if c.Route == "" {
+		return fmt.Sprintf("%s %d", c.BinName, c.ID)
+	}
+
+	return fmt.Sprintf("%s %s%s%d", c.BinName, c.Route, route_separator, c.ID)
+}
+
 type Expansions []Expansion
 
 type DefineCommand struct {
@@ -47,28 +69,76 @@ type Func struct {
 	// Constants in the api/vars package are also available.
 	ExportVars []string
 
+	// Func is called when the expansion is invoked. If it returns an
+	// error, or calls Fail, nothing it printed is evaluated, and the
+	// invocation fails instead, or surfaces the error as its KakError
+	// severity.
 	Func func(*Kak) error
+
+	// ContextFunc is called instead of Func if set, with the context of
+	// the invocation, see Kak.Context.
+	ContextFunc func(context.Context, *Kak) error
 }
 
 type Sh struct {
 }
 
+// Raw is an expansion of literal Kakoune script, emitted as is.
+//
+// The %sh blocks of the script must be POSIX sh, see CheckScript.
+type Raw string
+
 type Prompt struct {
 	Text       string
 	Expansions []Expansion
 }
 
 func (e DefineCommand) Init(ctx Context) (string, error) {
+	params, err := e.Options.paramsSpec()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", e.Name, err)
+	}
+
+	completion, err := e.Options.completionSwitch()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", e.Name, err)
+	}
+
+	var switches string
+	if ctx.Reinit || e.Options.Override {
+		switches += " -override"
+	}
+	if e.Options.Hidden {
+		switches += " -hidden"
+	}
+	if e.Options.Docstring != "" {
+		switches += " -docstring " + Quote(e.Options.Docstring)
+	}
+	if completion != "" {
+		switches += " " + completion
+	}
+
+	// the init of the Completer is the switch, rather than a part of the
+	// body, see Children.
+	body := ctx.Children
+	if e.Options.Completer != nil {
+		switches += " " + body[0]
+		body = body[1:]
+	}
+
 	return fmt.Sprintf(`
-define-command -params %d %s %%{
+define-command -params %s%s %s %%{
   %s
 }`,
-		e.Options.Params, e.Name,
-		strings.Join(ctx.Children, "\n")), nil
+		params, switches, e.Name,
+		strings.Join(body, "\n")), nil
 }
 
 func (e DefineCommand) Children() []Expansion {
-	return e.Expansions
+	if e.Options.Completer == nil {
+		return e.Expansions
+	}
+	return append([]Expansion{*e.Options.Completer}, e.Expansions...)
 }
 
 func (e Func) Init(ctx Context) (string, error) {
@@ -98,11 +168,11 @@ func (e Func) Init(ctx Context) (string, error) {
     #
     # %s
 
-    %s %d "$@"
+    %s "$@"
   }
 `,
 		vars,
-		ctx.BinName, ctx.ID), nil
+		ctx.Command()), nil
 }
 
 func (e Func) Children() []Expansion {
@@ -110,6 +180,9 @@ func (e Func) Children() []Expansion {
 }
 
 func (e Func) Run(k *Kak) error {
+	if e.ContextFunc != nil {
+		return e.ContextFunc(k.Context(), k)
+	}
 	return e.Func(k)
 }
 
@@ -126,3 +199,16 @@ func (e Prompt) Init(ctx Context) (string, error) {
 func (e Prompt) Children() []Expansion {
 	return e.Expansions
 }
+
+func (e Raw) Init(ctx Context) (string, error) {
+	// shell blocks of the script are run by the sh of the system, which
+	// is frequently not bash.
+	if err := CheckScript(string(e)); err != nil {
+		return "", err
+	}
+	return string(e), nil
+}
+
+func (e Raw) Children() []Expansion {
+	return nil
+}
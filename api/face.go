@@ -0,0 +1,239 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/cmds"
+)
+
+// Face attributes, as given after the `+` of a face spec.
+const (
+	AttrUnderline       = 'u'
+	AttrCurlyUnderline  = 'c'
+	AttrDoubleUnderline = 'U'
+	AttrReverse         = 'r'
+	AttrBold            = 'b'
+	AttrBlink           = 'B'
+	AttrDim             = 'd'
+	AttrItalic          = 'i'
+	AttrStrikethrough   = 's'
+	AttrFinalFg         = 'f'
+	AttrFinalBg         = 'g'
+	AttrFinalAttr       = 'a'
+	AttrFinal           = 'F'
+)
+
+const faceAttributes = "ucUrbBdisfgaF"
+
+// colorNames are the colors of Kakoune given by name rather than rgb,
+// each of which also has a bright- variant.
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// FaceSpec is a face, as given to set-face and highlighters, written as
+// `[fg][,bg[,underline]][+attributes][@base]`. Eg:
+//
+//    red,default+bu
+//    rgb:ff0000+i@Error
+//    PrimarySelection
+//
+// Empty colors are left to the base face, or the default.
+type FaceSpec struct {
+	Fg        string
+	Bg        string
+	Underline string
+
+	// Attributes are the attribute letters, such as "bu" for bold and
+	// underlined, see AttrBold and the like.
+	Attributes string
+
+	// Base is the name of the face this face inherits from, if any.
+	Base string
+}
+
+// ParseFace parses the face spec, such as `red,default+bu`.
+//
+// A spec of a single name which is not a color, such as `Error`, is a
+// reference to that face, and is parsed as the Base.
+func ParseFace(s string) (FaceSpec, error) {
+	var f FaceSpec
+
+	spec := s
+	if i := strings.IndexByte(spec, '@'); i != -1 {
+		f.Base = spec[i+1:]
+		if f.Base == "" {
+			return FaceSpec{}, fmt.Errorf("face spec base missing: %q", s)
+		}
+		spec = spec[:i]
+	}
+
+	if i := strings.IndexByte(spec, '+'); i != -1 {
+		f.Attributes = spec[i+1:]
+		for _, r := range f.Attributes {
+			if !strings.ContainsRune(faceAttributes, r) {
+				return FaceSpec{}, fmt.Errorf("face spec attribute unknown: %q", string(r))
+			}
+		}
+		spec = spec[:i]
+	}
+
+	if spec == "" {
+		return f, nil
+	}
+
+	colors := strings.Split(spec, ",")
+	if len(colors) > 3 {
+		return FaceSpec{}, fmt.Errorf("face spec has too many colors: %q", s)
+	}
+
+	// a lone name referencing another face, rather than a color.
+	if len(colors) == 1 && f.Base == "" && f.Attributes == "" && !IsColor(spec) {
+		if !isFaceName(spec) {
+			return FaceSpec{}, fmt.Errorf("face spec color invalid: %q", spec)
+		}
+		f.Base = spec
+		return f, nil
+	}
+
+	for i, c := range colors {
+		if c != "" && !IsColor(c) {
+			return FaceSpec{}, fmt.Errorf("face spec color invalid: %q", c)
+		}
+		switch i {
+		case 0:
+			f.Fg = c
+		case 1:
+			f.Bg = c
+		case 2:
+			f.Underline = c
+		}
+	}
+
+	return f, nil
+}
+
+func (f FaceSpec) String() string {
+	s := f.Fg
+	switch {
+	case f.Underline != "":
+		s += "," + f.Bg + "," + f.Underline
+	case f.Bg != "":
+		s += "," + f.Bg
+	}
+
+	if f.Attributes != "" {
+		s += "+" + f.Attributes
+	}
+
+	if f.Base != "" {
+		// a lone base is written as the name, as Kakoune shows it.
+		if s == "" {
+			return f.Base
+		}
+		s += "@" + f.Base
+	}
+
+	if s == "" {
+		return "default"
+	}
+	return s
+}
+
+// Has reports whether the face has the attribute, such as AttrBold.
+func (f FaceSpec) Has(attr rune) bool {
+	return strings.ContainsRune(f.Attributes, attr)
+}
+
+// WithAttributes returns the face with the given attributes added, if not
+// already present. Eg, dimming an existing face:
+//
+//    dimmed := face.WithAttributes(string(api.AttrDim))
+func (f FaceSpec) WithAttributes(attrs string) FaceSpec {
+	for _, r := range attrs {
+		if !f.Has(r) {
+			f.Attributes += string(r)
+		}
+	}
+	return f
+}
+
+// WithoutAttributes returns the face with the given attributes removed.
+func (f FaceSpec) WithoutAttributes(attrs string) FaceSpec {
+	f.Attributes = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(attrs, r) {
+			return -1
+		}
+		return r
+	}, f.Attributes)
+	return f
+}
+
+// IsColor reports whether s is a color of a face spec, either named, such
+// as `bright-red`, or `rgb:RRGGBB` and `rgba:RRGGBBAA`.
+func IsColor(s string) bool {
+	if s == "default" {
+		return true
+	}
+	for _, name := range colorNames {
+		if s == name || s == "bright-"+name {
+			return true
+		}
+	}
+
+	var hex string
+	switch {
+	case strings.HasPrefix(s, "rgb:"):
+		hex = s[len("rgb:"):]
+		if len(hex) != 6 {
+			return false
+		}
+	case strings.HasPrefix(s, "rgba:"):
+		hex = s[len("rgba:"):]
+		if len(hex) != 8 {
+			return false
+		}
+	default:
+		return false
+	}
+
+	for _, r := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFaceName reports whether s is a valid name of a face.
+func isFaceName(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case r == '_' || r == '-':
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// SetFace prints the command setting the face within the scope, such as
+// `global` or `window`.
+//
+// As with Command, this prints whenever it is called, so faces declared
+// when initializing are given within a Raw expansion instead.
+func (k *Kak) SetFace(scope, name string, spec FaceSpec) error {
+	switch scope {
+	case "global", "buffer", "window":
+	default:
+		return fmt.Errorf("face scope invalid: %q", scope)
+	}
+	if !isFaceName(name) {
+		return fmt.Errorf("face name invalid: %q", name)
+	}
+
+	k.Command(cmds.SetFace, scope, name, spec.String())
+	return nil
+}
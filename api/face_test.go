@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestParseFace(t *testing.T) {
+	tests := []struct {
+		spec string
+		want FaceSpec
+	}{
+		{"red", FaceSpec{Fg: "red"}},
+		{"red,default+bu", FaceSpec{Fg: "red", Bg: "default", Attributes: "bu"}},
+		{",blue", FaceSpec{Bg: "blue"}},
+		{"default,,rgb:ff0000+c", FaceSpec{Fg: "default", Underline: "rgb:ff0000", Attributes: "c"}},
+		{"rgba:ff0000aa+i@Error", FaceSpec{Fg: "rgba:ff0000aa", Attributes: "i", Base: "Error"}},
+		{"+d@Comment", FaceSpec{Attributes: "d", Base: "Comment"}},
+		{"PrimarySelection", FaceSpec{Base: "PrimarySelection"}},
+		{"bright-magenta", FaceSpec{Fg: "bright-magenta"}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseFace(test.spec)
+		if err != nil {
+			t.Errorf("%q: %s", test.spec, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got:%+v, want:%+v", test.spec, got, test.want)
+		}
+		if s := got.String(); s != test.spec {
+			t.Errorf("%q: got string %q", test.spec, s)
+		}
+	}
+
+	for _, spec := range []string{"red+x", "rgb:ff00", "red,notacolor", "a,b,c,d", "red@", "no spaces"} {
+		if _, err := ParseFace(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestFaceSpecAttributes(t *testing.T) {
+	f := FaceSpec{Fg: "red", Attributes: "b"}
+
+	dim := f.WithAttributes("db")
+	if dim.Attributes != "bd" || !dim.Has(AttrDim) {
+		t.Errorf("unexpected attributes: %q", dim.Attributes)
+	}
+
+	if plain := dim.WithoutAttributes("b"); plain.Attributes != "d" {
+		t.Errorf("unexpected attributes: %q", plain.Attributes)
+	}
+
+	if got := (FaceSpec{}).String(); got != "default" {
+		t.Errorf("got %q, want default", got)
+	}
+}
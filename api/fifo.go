@@ -0,0 +1,89 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+// Fifo is a named pipe within the StateDir, for streaming output into a
+// buffer with `edit -fifo`, or reading the output of `echo -to-file`.
+//
+// Each fifo is created within its own directory, which is removed along
+// with it. Fifos handed to Kakoune are removed once Kakoune closes them,
+// see Edit and RemoveOnClose. Otherwise, such as when failing before
+// handing it over, call Remove. Any left over are removed along with the
+// StateDir when the session ends.
+type Fifo struct {
+	// Path is the path of the fifo.
+	Path string
+
+	dir string
+}
+
+// NewFifo creates a fifo of the given file name, or `fifo` if empty.
+//
+// vars.Session must be exported to the Subproc.
+func (k *Kak) NewFifo(name string) (*Fifo, error) {
+	if name == "" {
+		name = "fifo"
+	}
+
+	state, err := k.StateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(state, "fifo")
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir(root, "")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fifo{Path: filepath.Join(dir, filepath.Base(name)), dir: dir}
+	if err := syscall.Mkfifo(f.Path, 0600); err != nil {
+		f.Remove()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Remove removes the fifo, and its directory.
+func (f *Fifo) Remove() error {
+	return os.RemoveAll(f.dir)
+}
+
+// RemoveOnClose prints the hook removing the fifo once the fifo of the
+// current buffer is closed, which happens when its writer exits.
+func (f *Fifo) RemoveOnClose(k *Kak) {
+	k.Printf("hook -always -once buffer BufCloseFifo .* %%{ nop %%sh{ rm -r %s } }\n",
+		util.ShellQuote(f.dir))
+}
+
+// Edit prints the commands (re)creating the buffer from the fifo, scrolled
+// to its end as it is written, and removing the fifo once closed.
+//
+// NOTE(leeola): opening a fifo for writing blocks until Kakoune opens it
+// for reading, which only happens after the Subproc exits, so the writer
+// must be a background process, or a shell opening the fifo itself.
+func (f *Fifo) Edit(k *Kak, buffer string) {
+	k.Printf("edit! -fifo %s -scroll %s\n", Quote(f.Path), Quote(buffer))
+	f.RemoveOnClose(k)
+}
+
+// Echo prints the command writing the given text to the fifo, which may
+// contain expansions such as `%val{selections}`.
+//
+// Kakoune blocks until the fifo is read, so a reader must already be open,
+// such as a background process started beforehand.
+func (f *Fifo) Echo(k *Kak, text string) {
+	k.Printf("echo -to-file %s -- %s\n", Quote(f.Path), text)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFifo(t *testing.T) {
+	cache, err := ioutil.TempDir("", "fifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	os.Setenv("XDG_CACHE_HOME", cache)
+
+	out := &bytes.Buffer{}
+	k := &Kak{
+		writer:       out,
+		gokakouneBin: "/bin/plugin",
+		funcVars:     map[string]string{"kak_session": "1"},
+	}
+
+	f, err := k.NewFifo("repl.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatalf("want a named pipe, got %s", info.Mode())
+	}
+	if filepath.Base(f.Path) != "repl.go" {
+		t.Fatalf("want name repl.go, got %s", f.Path)
+	}
+
+	out.Reset()
+	f.Edit(k, "*out*")
+	if !strings.Contains(out.String(), "edit! -fifo '"+f.Path+"' -scroll '*out*'") ||
+		!strings.Contains(out.String(), "rm -r '"+filepath.Dir(f.Path)+"'") {
+		t.Fatalf("unexpected edit commands:\n%s", out)
+	}
+
+	if err := f.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(f.Path)); !os.IsNotExist(err) {
+		t.Fatalf("want fifo directory removed, got %v", err)
+	}
+}
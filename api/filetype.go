@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FiletypeMatch describes the buffers a filetype is detected for.
+//
+// Buffers matching any of the fields are detected as the filetype.
+type FiletypeMatch struct {
+	// Extensions are matched against the end of the buffer name, without
+	// the leading dot. Eg, "go".
+	Extensions []string
+
+	// Filenames are matched exactly against the base name of the buffer.
+	// Eg, "Makefile".
+	Filenames []string
+
+	// Patterns are regular expressions matched against the full buffer
+	// name.
+	Patterns []string
+
+	// Interpreters are matched against the shebang of the first line of
+	// the buffer. Eg, "python" matches both `#!/usr/bin/python` and
+	// `#!/usr/bin/env python`.
+	Interpreters []string
+}
+
+// DetectFiletype registers detection of the given filetype, setting the
+// filetype option of matching buffers.
+//
+// This follows the pattern of the filetype scripts bundled with Kakoune,
+// a BufCreate hook matching buffer names, and a BufOpenFile hook checking
+// the shebang of the file.
+func (k *Kak) DetectFiletype(filetype string, m FiletypeMatch) error {
+	group := k.PluginName() + "-filetype-" + filetype
+	setFiletype := "set-option buffer filetype " + Quote(filetype)
+
+	var patterns []string
+	for _, ext := range m.Extensions {
+		patterns = append(patterns, `.*\.`+regexp.QuoteMeta(ext))
+	}
+	for _, name := range m.Filenames {
+		patterns = append(patterns, `(.*/)?`+regexp.QuoteMeta(name))
+	}
+	patterns = append(patterns, m.Patterns...)
+
+	if len(patterns) == 0 && len(m.Interpreters) == 0 {
+		return errors.New("filetype match is empty")
+	}
+
+	lines := []string{"remove-hooks global " + group}
+
+	if len(patterns) != 0 {
+		lines = append(lines, fmt.Sprintf(
+			"hook -group %s global BufCreate %s %%{ %s }",
+			group, Quote("(?:"+strings.Join(patterns, "|")+")"), setFiletype))
+	}
+
+	if len(m.Interpreters) != 0 {
+		quoted := make([]string, len(m.Interpreters))
+		for i, interp := range m.Interpreters {
+			quoted[i] = regexp.QuoteMeta(interp)
+		}
+
+		// NOTE(leeola): <a-k> fails when the first line does not match,
+		// aborting the try before the filetype is set.
+		shebang := `\A#![^\n]*\b(?:` + strings.Join(quoted, "|") + `)\b`
+		lines = append(lines, fmt.Sprintf(
+			"hook -group %s global BufOpenFile .* %%{ try %%{ execute-keys -draft gg x <a-k> %s <ret>; %s } }",
+			group, Quote(shebang), setFiletype))
+	}
+
+	k.RecordHookGroup(group, "detect the "+filetype+" filetype")
+
+	return k.Expansion(Raw(strings.Join(lines, "\n")))
+}
+
+// FiletypeConfig is the configuration of the windows of a filetype,
+// applied once their filetype is set, see Kak.ConfigureFiletypes.
+type FiletypeConfig struct {
+	Filetype string
+
+	// FormatCommand and LintCommand set the formatcmd and lintcmd options
+	// used by the format and lint commands bundled with Kakoune.
+	FormatCommand string
+	LintCommand   string
+
+	// CommentLine, CommentBlockBegin and CommentBlockEnd set the options
+	// used by the comment commands bundled with Kakoune.
+	CommentLine       string
+	CommentBlockBegin string
+	CommentBlockEnd   string
+
+	// Options are any other window options, by name, with values of the
+	// Go types of FormatOption.
+	Options map[string]interface{}
+
+	// Func is called within the window once the options are set, if not
+	// nil, such as to add highlighters or hooks.
+	ExportVars []string
+	Func       func(*Kak) error
+}
+
+// options returns the window options of the config, sorted by name.
+func (c FiletypeConfig) options() ([]string, map[string]interface{}) {
+	options := map[string]interface{}{}
+	for name, v := range c.Options {
+		options[name] = v
+	}
+	for name, v := range map[string]string{
+		"formatcmd":           c.FormatCommand,
+		"lintcmd":             c.LintCommand,
+		"comment_line":        c.CommentLine,
+		"comment_block_begin": c.CommentBlockBegin,
+		"comment_block_end":   c.CommentBlockEnd,
+	} {
+		if v != "" {
+			options[name] = v
+		}
+	}
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, options
+}
+
+// filetypeCommand is the hidden command configuring a window of the
+// filetype, run by the hook of ConfigureFiletypes.
+type filetypeCommand struct {
+	Name   string
+	Config FiletypeConfig
+}
+
+func (e filetypeCommand) Init(ctx Context) (string, error) {
+	names, options := e.Config.options()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "define-command -override -hidden %s %%{\n", e.Name)
+	for _, name := range names {
+		_, v, err := FormatOption(options[name])
+		if err != nil {
+			return "", fmt.Errorf("filetype %s option %s: %s", e.Config.Filetype, name, err)
+		}
+		fmt.Fprintf(&b, "  set-option window %s %s\n", name, v)
+	}
+	if len(names) != 0 {
+		fmt.Fprintf(&b, "  hook -once -always window WinSetOption filetype=.* %%{ unset-option window %s }\n",
+			strings.Join(names, "; unset-option window "))
+	}
+	for _, c := range ctx.Children {
+		b.WriteString("  " + strings.TrimSpace(c) + "\n")
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+func (e filetypeCommand) Children() []Expansion {
+	if e.Config.Func == nil {
+		return nil
+	}
+	return []Expansion{Func{ExportVars: e.Config.ExportVars, Func: e.Config.Func}}
+}
+
+// ConfigureFiletypes configures the windows of each filetype once their
+// filetype is set, by a single WinSetOption hook dispatching to the
+// config of the filetype. Eg:
+//
+//    err := kak.ConfigureFiletypes(api.FiletypeConfig{
+//        Filetype:      "go",
+//        FormatCommand: "gofmt",
+//        CommentLine:   "//",
+//        Options:       map[string]interface{}{"indentwidth": 0},
+//    }, api.FiletypeConfig{
+//        Filetype:    "python",
+//        LintCommand: "pylint --output-format=parseable",
+//        CommentLine: "#",
+//    })
+//
+// The options are set within the window, and unset once its filetype
+// changes again.
+func (k *Kak) ConfigureFiletypes(configs ...FiletypeConfig) error {
+	if len(configs) == 0 {
+		return errors.New("filetype config required")
+	}
+
+	prefix := k.PluginName() + "-filetype-"
+	seen := map[string]bool{}
+	filetypes := make([]string, len(configs))
+	for i, c := range configs {
+		if !isFaceName(c.Filetype) {
+			return fmt.Errorf("filetype invalid: %q", c.Filetype)
+		}
+		if seen[c.Filetype] {
+			return fmt.Errorf("duplicate config for filetype %s", c.Filetype)
+		}
+		seen[c.Filetype] = true
+		filetypes[i] = c.Filetype
+
+		err := k.Expansion(filetypeCommand{Name: prefix + c.Filetype, Config: c})
+		if err != nil {
+			return err
+		}
+	}
+
+	group := k.PluginName() + "-filetypes"
+	k.RecordHookGroup(group, "configure windows of "+strings.Join(filetypes, ", "))
+
+	// NOTE(leeola): the command is expanded within double quotes, so the
+	// capture of the filetype selects the config.
+	return k.Expansion(Raw(fmt.Sprintf(`remove-hooks global %[1]s
+hook -group %[1]s global WinSetOption filetype=(%[2]s) %%{ evaluate-commands "%[3]s%%val{hook_param_capture_1}" }`,
+		group, strings.Join(filetypes, "|"), prefix)))
+}
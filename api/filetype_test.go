@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfigureFiletypes(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	err := k.ConfigureFiletypes(FiletypeConfig{
+		Filetype:      "go",
+		FormatCommand: "gofmt",
+		CommentLine:   "//",
+		Options:       map[string]interface{}{"indentwidth": 0},
+	}, FiletypeConfig{
+		Filetype:    "python",
+		CommentLine: "#",
+		Func: func(k *Kak) error {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"define-command -override -hidden plugin-filetype-go %{\n" +
+			"  set-option window comment_line '//'\n" +
+			"  set-option window formatcmd 'gofmt'\n" +
+			"  set-option window indentwidth 0\n" +
+			"  hook -once -always window WinSetOption filetype=.* %{ unset-option window comment_line; " +
+			"unset-option window formatcmd; unset-option window indentwidth }\n}",
+		"'/usr/bin/plugin'} 2 \"$@\"",
+		"remove-hooks global plugin-filetypes\n" +
+			`hook -group plugin-filetypes global WinSetOption filetype=(go|python) %{ evaluate-commands "plugin-filetype-%val{hook_param_capture_1}" }`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got:\n%s\nwant within it:\n%s", got, want)
+		}
+	}
+	if err := CheckBalanced(got); err != nil {
+		t.Error(err)
+	}
+	if len(k.manifest.Hooks) != 1 {
+		t.Errorf("got hook groups %+v", k.manifest.Hooks)
+	}
+
+	for _, configs := range [][]FiletypeConfig{
+		nil,
+		{{Filetype: "c++"}},
+		{{Filetype: "go"}, {Filetype: "go"}},
+	} {
+		if err := k.ConfigureFiletypes(configs...); err == nil {
+			t.Errorf("%+v: expected error", configs)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Highlighter is a highlighter, as added by add-highlighter given its
+// Type and Params, along with the highlighters it holds. Eg, highlighting
+// the strings and comments of a filetype:
+//
+//    h := api.Regions(
+//        api.Region{Name: "string", Begin: `"`, End: `(?<!\\)"`, Highlighter: api.Fill("string")},
+//        api.Region{Name: "comment", Begin: "//", End: "$", Highlighter: api.Fill("comment")},
+//    )
+//    err := kak.AddHighlighters("shared/myft", h)
+//
+// Highlighters are usually built with the functions of their type, such as
+// Regex and Group, rather than given literally.
+type Highlighter struct {
+	Type   string
+	Params []string
+
+	// Children are the highlighters held by a group or regions, added
+	// under its path.
+	Children []NamedHighlighter
+}
+
+// NamedHighlighter is a highlighter held by another, at the path of its
+// parent followed by the Name.
+type NamedHighlighter struct {
+	Name        string
+	Highlighter Highlighter
+}
+
+// Region is a region of a Regions highlighter, from each match of Begin to
+// the following match of End, highlighted by its Highlighter.
+type Region struct {
+	Name  string
+	Begin string
+	End   string
+
+	// Recurse is the regex of nested regions, so that the region only ends
+	// once each of its matches is itself ended.
+	Recurse string
+
+	// MatchCapture ends the region only with the text of the first capture
+	// of Begin, as with the delimiters of heredocs.
+	MatchCapture bool
+
+	Highlighter Highlighter
+}
+
+// Group returns a group highlighter holding the given highlighters.
+func Group(children ...NamedHighlighter) Highlighter {
+	return Highlighter{Type: "group", Children: children}
+}
+
+// Regex returns a regex highlighter, highlighting each capture of the
+// pattern with its face, capture 0 being the whole match.
+func Regex(pattern string, faces map[int]string) Highlighter {
+	captures := make([]int, 0, len(faces))
+	for c := range faces {
+		captures = append(captures, c)
+	}
+	sort.Ints(captures)
+
+	params := []string{pattern}
+	for _, c := range captures {
+		params = append(params, strconv.Itoa(c)+":"+faces[c])
+	}
+	return Highlighter{Type: "regex", Params: params}
+}
+
+// Regions returns a regions highlighter of the given regions.
+func Regions(regions ...Region) Highlighter {
+	h := Highlighter{Type: "regions"}
+	for _, r := range regions {
+		var params []string
+		if r.MatchCapture {
+			params = append(params, "-match-capture")
+		}
+		if r.Recurse != "" {
+			params = append(params, "-recurse", r.Recurse)
+		}
+		params = append(params, r.Begin, r.End, r.Highlighter.Type)
+		params = append(params, r.Highlighter.Params...)
+
+		// the highlighters of a group region are added under the region,
+		// as it is the group.
+		h.Children = append(h.Children, NamedHighlighter{
+			Name: r.Name,
+			Highlighter: Highlighter{
+				Type:     "region",
+				Params:   params,
+				Children: r.Highlighter.Children,
+			},
+		})
+	}
+	return h
+}
+
+// Fill returns a fill highlighter, highlighting everything with the face.
+func Fill(face string) Highlighter {
+	return Highlighter{Type: "fill", Params: []string{face}}
+}
+
+// Ref returns a ref highlighter, highlighting as the highlighter of the
+// path within the shared scope, such as `myft` for `shared/myft`.
+func Ref(path string) Highlighter {
+	return Highlighter{Type: "ref", Params: []string{path}}
+}
+
+// Ranges returns a ranges highlighter of the range-specs option.
+func Ranges(option string) Highlighter {
+	return Highlighter{Type: "ranges", Params: []string{option}}
+}
+
+// FlagLines returns a flag-lines highlighter of the line-specs option.
+func FlagLines(face, option string) Highlighter {
+	return Highlighter{Type: "flag-lines", Params: []string{face, option}}
+}
+
+// Commands returns the add-highlighter commands adding the highlighter,
+// and its children, at the path, such as `window/myplug`.
+//
+// The commands are those of AddHighlighters, for highlighters declared
+// when initializing within a Raw expansion.
+func (h Highlighter) Commands(path string) (string, error) {
+	if err := CheckHighlighterPath(path); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := h.commands(&b, path); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (h Highlighter) commands(b *strings.Builder, path string) error {
+	if h.Type == "" {
+		return fmt.Errorf("highlighter type missing: %q", path)
+	}
+
+	b.WriteString("add-highlighter " + QuoteArg(path) + " " + QuoteArg(h.Type))
+	for _, p := range h.Params {
+		b.WriteString(" " + QuoteArg(p))
+	}
+	b.WriteString("\n")
+
+	for _, c := range h.Children {
+		if c.Name == "" || strings.ContainsRune(c.Name, '/') {
+			return fmt.Errorf("highlighter name invalid: %q", c.Name)
+		}
+		if err := c.Highlighter.commands(b, path+"/"+c.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddHighlighter prints the command adding the highlighter of the type at
+// the path. Eg:
+//
+//    err := kak.AddHighlighter("window/myplug-column", "column", "81", "Error")
+func (k *Kak) AddHighlighter(path, typ string, params ...string) error {
+	return k.AddHighlighters(path, Highlighter{Type: typ, Params: params})
+}
+
+// AddHighlighters prints the commands adding the highlighter and its
+// children at the path, recording the path in the Manifest.
+//
+// Paths naming the highlighter after its params, with a trailing slash,
+// are not recorded, as Kakoune names them. They are removed along with
+// their parent.
+func (k *Kak) AddHighlighters(path string, h Highlighter) error {
+	s, err := h.Commands(path)
+	if err != nil {
+		return err
+	}
+	k.Print(s)
+
+	if !strings.HasSuffix(path, "/") {
+		k.RecordHighlighter(path, h.Type+" highlighter")
+	}
+	return nil
+}
+
+// CheckHighlighterPath returns an error if the path is not that of a
+// highlighter, being a scope followed by the names of the highlighters
+// leading to it, such as `window/myplug` or `shared/myft/string`.
+//
+// A trailing slash, such as `window/myplug/`, names the highlighter
+// after its params.
+func CheckHighlighterPath(path string) error {
+	parts := strings.Split(path, "/")
+	switch parts[0] {
+	case "global", "buffer", "window", "shared":
+	default:
+		return fmt.Errorf("highlighter path scope invalid: %q", path)
+	}
+
+	if len(parts) < 2 {
+		return fmt.Errorf("highlighter path name missing: %q", path)
+	}
+	for _, p := range parts[1 : len(parts)-1] {
+		if p == "" {
+			return fmt.Errorf("highlighter path has empty name: %q", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestHighlighterCommands(t *testing.T) {
+	h := Group(
+		NamedHighlighter{Name: "keywords", Highlighter: Regex(`\b(func|var)\b`, map[int]string{1: "keyword", 0: "default"})},
+		NamedHighlighter{Name: "code", Highlighter: Regions(
+			Region{Name: "string", Begin: `"`, End: `(?<!\\)"`, Highlighter: Fill("string")},
+			Region{Name: "heredoc", Begin: `<<(\w+)`, End: `^(\w+)$`, MatchCapture: true,
+				Highlighter: Group(NamedHighlighter{Name: "fill", Highlighter: Fill("meta")})},
+		)},
+	)
+
+	got, err := h.Commands("shared/myft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `add-highlighter shared/myft group
+add-highlighter shared/myft/keywords regex '\b(func|var)\b' 0:default 1:keyword
+add-highlighter shared/myft/code regions
+add-highlighter shared/myft/code/string region '"' '(?<!\\)"' fill string
+add-highlighter shared/myft/code/heredoc region -match-capture '<<(\w+)' '^(\w+)$' group
+add-highlighter shared/myft/code/heredoc/fill fill meta
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	bad := Group(NamedHighlighter{Name: "a/b", Highlighter: Fill("red")})
+	if _, err := bad.Commands("window/myplug"); err == nil {
+		t.Error("expected error of invalid name")
+	}
+}
+
+func TestCheckHighlighterPath(t *testing.T) {
+	for _, path := range []string{"window/myplug", "shared/myft/string", "window/results/"} {
+		if err := CheckHighlighterPath(path); err != nil {
+			t.Errorf("%q: %s", path, err)
+		}
+	}
+	for _, path := range []string{"myplug", "window", "client/myplug", "window//myplug"} {
+		if err := CheckHighlighterPath(path); err == nil {
+			t.Errorf("%q: expected error", path)
+		}
+	}
+}
+
+func TestAddHighlighter(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out}
+
+	if err := k.AddHighlighter("window/myplug", "column", "81", "Error"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetFace("window", "MyplugError", FaceSpec{Fg: "red", Attributes: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetFace("session", "MyplugError", FaceSpec{}); err == nil {
+		t.Error("expected error of invalid scope")
+	}
+
+	want := "add-highlighter window/myplug column 81 Error\nset-face window MyplugError red+u\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAddHighlighterManifest(t *testing.T) {
+	k := newTestKak(&bytes.Buffer{})
+	k.gokakouneInit = true
+
+	if err := k.AddHighlighter("window/myplug", "column", "81", "Error"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddHighlighter("window/", "number-lines"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ManifestEntry{{Name: "window/myplug", Docstring: "column highlighter"}}
+	if got := k.Manifest().Highlighters; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
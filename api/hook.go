@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/hooks"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// HookOptions are the options of a hook declared with Kak.Hook.
+type HookOptions struct {
+	// Group is the hook group of the hook, or the PluginName if empty.
+	//
+	// The hooks of each group are removed before the first hook of the
+	// group is added, so initializing again never duplicates them.
+	Group string
+
+	// Once removes the hook after it first runs.
+	Once bool
+
+	// Always runs the hook even when hooks are disabled, such as within
+	// `evaluate-commands -no-hooks`.
+	Always bool
+
+	// Docstring describes the hook group in the Manifest.
+	Docstring string
+}
+
+// Hook is an expansion adding a hook, running its expansions when
+// triggered. See Kak.Hook.
+type Hook struct {
+	Scope  string
+	Name   string
+	Filter string
+
+	Options HookOptions
+
+	Expansions []Expansion
+
+	// remove removes the hooks of the group first.
+	remove bool
+}
+
+func (e Hook) Init(ctx Context) (string, error) {
+	var b strings.Builder
+	if e.remove {
+		fmt.Fprintf(&b, "\nremove-hooks %s %s", e.Scope, e.Options.Group)
+	}
+
+	b.WriteString("\nhook")
+	if e.Options.Group != "" {
+		b.WriteString(" -group " + e.Options.Group)
+	}
+	if e.Options.Once {
+		b.WriteString(" -once")
+	}
+	if e.Options.Always {
+		b.WriteString(" -always")
+	}
+
+	fmt.Fprintf(&b, " %s %s %s %%{\n  %s\n}", e.Scope, e.Name, Quote(e.Filter),
+		strings.Join(ctx.Children, "\n"))
+	return b.String(), nil
+}
+
+func (e Hook) Children() []Expansion {
+	return e.Expansions
+}
+
+// Hook adds a hook running the Subproc whenever the hook is triggered
+// within the scope and its parameter matches the filter regex. Eg:
+//
+//    k.Hook("global", "BufWritePost", `.*\.go`, api.HookOptions{
+//        Group: "gofmt",
+//    }, api.Subproc{
+//        Func: func(kak *api.Kak) error {
+//            file, err := kak.Var(vars.HookParam)
+//            ...
+//        },
+//    })
+//
+// The Subproc is dispatched as the Funcs of DefineCommand are, with
+// vars.HookParam exported alongside its ExportVars. Hooks unknown to
+// Kakoune, or which never run within the scope, are an error, see
+// hooks.Name.Validate.
+//
+// NOTE(leeola): hooks are added when the script is sourced, where there is
+// no window, so they're usually global.
+func (k *Kak) Hook(scope, hookName, filterRegex string, opts HookOptions, sp Subproc) error {
+	if err := validateHook(scope, hookName); err != nil {
+		return err
+	}
+
+	if opts.Group == "" {
+		opts.Group = k.PluginName()
+	}
+
+	if k.hookGroups == nil {
+		k.hookGroups = map[string]bool{}
+	}
+	key := scope + " " + opts.Group
+	remove := !k.hookGroups[key]
+	if remove {
+		k.hookGroups[key] = true
+		k.RecordHookGroup(opts.Group, opts.Docstring)
+	}
+
+	return k.Expansion(Hook{
+		Scope:   scope,
+		Name:    hookName,
+		Filter:  filterRegex,
+		Options: opts,
+		Expansions: []Expansion{Func{
+			ExportVars: append([]string{vars.HookParam}, sp.ExportVars...),
+			Func:       sp.Func,
+		}},
+		remove: remove,
+	})
+}
+
+// validateHook returns an error if the hook never runs within the scope,
+// such as `global` or `buffer=<name>`.
+func validateHook(scope, name string) error {
+	base := scope
+	if i := strings.IndexByte(base, '='); i != -1 {
+		base = base[:i]
+	}
+
+	sc, err := hooks.ParseScope(base)
+	if err != nil {
+		return err
+	}
+
+	return hooks.Name(name).Validate(sc)
+}
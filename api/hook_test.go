@@ -0,0 +1,51 @@
+package api
+
+import "testing"
+
+func TestHookInit(t *testing.T) {
+	h := Hook{
+		Scope:   "global",
+		Name:    "BufWritePost",
+		Filter:  `.*\.go`,
+		Options: HookOptions{Group: "gofmt", Always: true},
+		remove:  true,
+	}
+
+	got, err := h.Init(Context{Children: []string{"nop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+remove-hooks global gofmt
+hook -group gofmt -always global BufWritePost '.*\.go' %{
+  nop
+}`
+	if got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestValidateHook(t *testing.T) {
+	valid := [][2]string{
+		{"global", "BufCreate"},
+		{"buffer=*debug*", "BufWritePost"},
+		{"window", "NormalIdle"},
+	}
+	for _, v := range valid {
+		if err := validateHook(v[0], v[1]); err != nil {
+			t.Errorf("%s %s: %s", v[0], v[1], err)
+		}
+	}
+
+	invalid := [][2]string{
+		{"global", "BufOpen"},
+		{"window", "BufCreate"},
+		{"session", "NormalIdle"},
+	}
+	for _, v := range invalid {
+		if err := validateHook(v[0], v[1]); err == nil {
+			t.Errorf("%s %s: expected error", v[0], v[1])
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package hooks provides the names of the hooks of Kakoune, along with the
+// scopes each can be added to and the format of the parameter their filter
+// is matched against.
+//
+// Hooks triggered outside of a window, such as BufCreate, never run hooks
+// of the window scope, and the scopes of hooks triggered outside of a
+// buffer are global only. Adding such hooks to other scopes is accepted by
+// Kakoune, but they never run, so Validate catches them when the script is
+// generated.
+package hooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Name is the name of a hook.
+type Name string
+
+const (
+	NormalIdle           Name = "NormalIdle"
+	NormalKey            Name = "NormalKey"
+	InsertIdle           Name = "InsertIdle"
+	InsertKey            Name = "InsertKey"
+	InsertChar           Name = "InsertChar"
+	InsertDelete         Name = "InsertDelete"
+	InsertMove           Name = "InsertMove"
+	InsertCompletionShow Name = "InsertCompletionShow"
+	InsertCompletionHide Name = "InsertCompletionHide"
+	PromptIdle           Name = "PromptIdle"
+	RawKey               Name = "RawKey"
+	ModeChange           Name = "ModeChange"
+	FocusIn              Name = "FocusIn"
+	FocusOut             Name = "FocusOut"
+
+	WinCreate    Name = "WinCreate"
+	WinClose     Name = "WinClose"
+	WinResize    Name = "WinResize"
+	WinDisplay   Name = "WinDisplay"
+	WinSetOption Name = "WinSetOption"
+
+	BufCreate    Name = "BufCreate"
+	BufNewFile   Name = "BufNewFile"
+	BufOpenFile  Name = "BufOpenFile"
+	BufOpenFifo  Name = "BufOpenFifo"
+	BufReadFifo  Name = "BufReadFifo"
+	BufCloseFifo Name = "BufCloseFifo"
+	BufSetOption Name = "BufSetOption"
+	BufWritePre  Name = "BufWritePre"
+	BufWritePost Name = "BufWritePost"
+	BufReload    Name = "BufReload"
+	BufClose     Name = "BufClose"
+
+	GlobalSetOption  Name = "GlobalSetOption"
+	KakBegin         Name = "KakBegin"
+	KakEnd           Name = "KakEnd"
+	ClientCreate     Name = "ClientCreate"
+	ClientClose      Name = "ClientClose"
+	RuntimeError     Name = "RuntimeError"
+	RegisterModified Name = "RegisterModified"
+	ModuleLoaded     Name = "ModuleLoaded"
+	EnterDirectory   Name = "EnterDirectory"
+	User             Name = "User"
+)
+
+// Scope is a set of the scopes hooks are added to.
+type Scope uint8
+
+const (
+	Global Scope = 1 << iota
+	Buffer
+	Window
+
+	// All is every scope, of hooks triggered within a window.
+	All = Global | Buffer | Window
+)
+
+// ParseScope returns the Scope of the name given to the hook command,
+// such as `buffer`.
+func ParseScope(name string) (Scope, error) {
+	switch name {
+	case "global":
+		return Global, nil
+	case "buffer":
+		return Buffer, nil
+	case "window":
+		return Window, nil
+	}
+	return 0, fmt.Errorf("invalid hook scope: %q", name)
+}
+
+func (s Scope) String() string {
+	var names []string
+	for _, sc := range []struct {
+		scope Scope
+		name  string
+	}{{Global, "global"}, {Buffer, "buffer"}, {Window, "window"}} {
+		if s&sc.scope != 0 {
+			names = append(names, sc.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Info describes a hook.
+type Info struct {
+	// Scopes are the scopes the hook runs in.
+	Scopes Scope
+
+	// Param is the format of the parameter the filter of the hook is
+	// matched against, such as `filetype=<value>`, or empty if the hook
+	// has none.
+	Param string
+}
+
+// Hooks describes every hook by name.
+var Hooks = map[Name]Info{
+	NormalIdle:           {All, ""},
+	NormalKey:            {All, "<key>"},
+	InsertIdle:           {All, ""},
+	InsertKey:            {All, "<key>"},
+	InsertChar:           {All, "<char>"},
+	InsertDelete:         {All, "<char>"},
+	InsertMove:           {All, "<key>"},
+	InsertCompletionShow: {All, ""},
+	InsertCompletionHide: {All, "<range>,<range>..."},
+	PromptIdle:           {All, ""},
+	RawKey:               {All, "<key>"},
+	ModeChange:           {All, "<push|pop>:<old mode>:<new mode>"},
+	FocusIn:              {All, "<client>"},
+	FocusOut:             {All, "<client>"},
+
+	// the window of WinCreate is new, so its scope has no hooks yet.
+	WinCreate:    {Global | Buffer, "<bufname>"},
+	WinClose:     {All, "<bufname>"},
+	WinResize:    {All, "<line>.<column>"},
+	WinDisplay:   {All, "<bufname>"},
+	WinSetOption: {All, "<option>=<value>"},
+
+	// likewise the buffers of BufCreate and BufOpen* hooks are new, as is BufNewFile.
+	BufCreate:    {Global, "<bufname>"},
+	BufNewFile:   {Global, "<filename>"},
+	BufOpenFile:  {Global, "<filename>"},
+	BufOpenFifo:  {Global, "<bufname>"},
+	BufReadFifo:  {Global | Buffer, "<line>.<column>,<line>.<column>"},
+	BufCloseFifo: {Global | Buffer, ""},
+	BufSetOption: {Global | Buffer, "<option>=<value>"},
+	BufWritePre:  {All, "<filename>"},
+	BufWritePost: {All, "<filename>"},
+	BufReload:    {Global | Buffer, "<filename>"},
+	BufClose:     {Global | Buffer, "<bufname>"},
+
+	GlobalSetOption:  {Global, "<option>=<value>"},
+	KakBegin:         {Global, "<session>"},
+	KakEnd:           {Global, ""},
+	ClientCreate:     {Global, "<client>"},
+	ClientClose:      {Global, "<client>"},
+	RuntimeError:     {Global, "<message>"},
+	RegisterModified: {All, "<register>"},
+	ModuleLoaded:     {Global, "<module>"},
+	EnterDirectory:   {Global, "<path>"},
+	User:             {All, "<param>"},
+}
+
+// Validate returns an error if the hook is unknown, or never runs when
+// added to the given scope.
+func (n Name) Validate(scope Scope) error {
+	info, ok := Hooks[n]
+	if !ok {
+		return fmt.Errorf("unknown hook: %q", string(n))
+	}
+
+	if info.Scopes&scope != scope {
+		return fmt.Errorf("hook %s cannot be added to %s scope, only %s", n, scope, info.Scopes)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package hooks
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  Name
+		scope string
+		valid bool
+	}{
+		{BufCreate, "global", true},
+		{BufCreate, "window", false},
+		{BufCreate, "buffer", false},
+		{WinCreate, "buffer", true},
+		{WinCreate, "window", false},
+		{NormalIdle, "window", true},
+		{Name("BufCreated"), "global", false},
+	}
+
+	for _, test := range tests {
+		scope, err := ParseScope(test.scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := test.name.Validate(scope); (err == nil) != test.valid {
+			t.Errorf("%s %s: want valid %v, got %v", test.name, test.scope, test.valid, err)
+		}
+	}
+
+	if got, want := (Global | Buffer).String(), "global|buffer"; got != want {
+		t.Fatalf("got:%q, want:%q", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	// subcommandInit prints the plugin definitions, the same as calling the
+	// binary without any arguments.
+	subcommandInit = "init"
+
+	// subcommandInstall writes a loader into the user autoload directory.
+	subcommandInstall = "install"
+
+	// subcommandPackage writes the layout expected by plugin managers.
+	subcommandPackage = "package"
+)
+
+// install writes a loader .kak file into the user's autoload directory,
+// which runs `<binary> init` when Kakoune sources it.
+//
+// With the -static flag, the script printed by `<binary> init` is written
+// instead, so that Kakoune starts without running the binary, see
+// WriteScript.
+//
+// Running install again updates the loader if needed, for example after
+// the binary has moved. What was changed is reported on stdout.
+func (k *Kak) install(args []string) error {
+	var (
+		content []byte
+		err     error
+	)
+	switch {
+	case len(args) == 0:
+		content = k.loader(k.gokakouneBin)
+	case len(args) == 1 && args[0] == installStatic:
+		content, err = k.staticScript()
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("usage: install [" + installStatic + "]")
+	}
+
+	dir, err := autoloadDir()
+	if err != nil {
+		return err
+	}
+
+	createdDir := false
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		createdDir = true
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, k.PluginName()+".kak")
+
+	existing, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil && bytes.Equal(existing, content):
+		fmt.Printf("%s is up to date\n", path)
+		return nil
+	case err == nil:
+		fmt.Printf("updated %s\n", path)
+	case os.IsNotExist(err):
+		fmt.Printf("created %s\n", path)
+	default:
+		return err
+	}
+
+	if err := writeScript(path, content); err != nil {
+		return err
+	}
+
+	// NOTE(leeola): Kakoune only loads the system autoload directory when the
+	// user has no autoload directory of their own. Creating one silently
+	// disables every bundled script, which is extremely confusing, so warn.
+	if createdDir {
+		fmt.Printf("created %s, Kakoune will no longer load the system autoload "+
+			"directory unless it is symlinked into it\n", dir)
+	}
+
+	return nil
+}
+
+// loader returns the content of the autoload file for the given binary.
+func (k *Kak) loader(bin string) []byte {
+	return []byte(fmt.Sprintf(`# generated by %s install, do not edit.
+evaluate-commands %%sh{ %s %s }
+`, k.PluginName(), util.ShellQuote(bin), subcommandInit))
+}
+
+// autoloadDir returns the user autoload directory of Kakoune.
+func autoloadDir() (string, error) {
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", errors.New("neither XDG_CONFIG_HOME nor HOME set")
+		}
+		config = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(config, "kak", "autoload"), nil
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// PromptOptions are the options of a PromptFunc.
+type PromptOptions struct {
+	// Init is the text the prompt starts with.
+	Init string
+
+	// Password hides the text as it is typed.
+	Password bool
+}
+
+// PromptFunc is an expansion prompting the user for text, given to Func
+// once the prompt is accepted. Eg:
+//
+//    kak.DefineCommand("myplug-rename", api.DefineCommandOptions{}, api.PromptFunc{
+//        Text:       "rename to: ",
+//        ExportVars: []string{vars.BufFile},
+//        Func: func(kak *api.Kak, text string) error {
+//            ...
+//        },
+//    })
+//
+// Unlike a Prompt, the text is quoted for Kakoune, and vars.Text is
+// exported to the Func.
+type PromptFunc struct {
+	Text    string
+	Options PromptOptions
+
+	// ExportVars are the vars exported to the Func, as with Func.
+	ExportVars []string
+
+	Func func(k *Kak, text string) error
+}
+
+func (e PromptFunc) Init(ctx Context) (string, error) {
+	switches := ""
+	if e.Options.Init != "" {
+		switches += " -init " + Quote(e.Options.Init)
+	}
+	if e.Options.Password {
+		switches += " -password"
+	}
+
+	// quoted rather than within %{}, as is the Func.
+	return fmt.Sprintf("\n  prompt%s %s %s\n", switches, Quote(e.Text), Quote(ctx.Children[0])), nil
+}
+
+func (e PromptFunc) Children() []Expansion {
+	return []Expansion{Func{
+		ExportVars: append([]string{vars.Text}, e.ExportVars...),
+		Func: func(k *Kak) error {
+			text, err := k.Var(vars.Text)
+			if err != nil {
+				return err
+			}
+			return e.Func(k, text)
+		},
+	}}
+}
+
+// MenuItem is an entry of a menu, running the Command when selected.
+type MenuItem struct {
+	// Label is the text of the entry, shown as is rather than as markup.
+	Label string
+
+	Command string
+
+	// Select is run when the entry is highlighted, before it is selected,
+	// if not empty.
+	Select string
+}
+
+// Menu prints the command showing the menu of the items.
+func (k *Kak) Menu(items ...MenuItem) error {
+	if len(items) == 0 {
+		return errors.New("menu requires an item")
+	}
+
+	withSelect := false
+	for _, item := range items {
+		if item.Select != "" {
+			withSelect = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("menu")
+	if withSelect {
+		b.WriteString(" -select-cmds")
+	}
+	b.WriteString(" --")
+	for _, item := range items {
+		b.WriteString(" " + Quote(EscapeMarkup(item.Label)) + " " + Quote(item.Command))
+		if withSelect {
+			b.WriteString(" " + Quote(item.Select))
+		}
+	}
+	k.Println(b.String())
+	return nil
+}
+
+// Choice is an entry of a MenuFunc, whose Value is given to the Func of
+// the MenuFunc once selected.
+type Choice struct {
+	Label string
+	Value string
+}
+
+// MenuFunc is an expansion showing a menu of the choices returned by
+// Items, and giving the Value of the one selected to Func. Eg:
+//
+//    kak.DefineCommand("myplug-pick", api.DefineCommandOptions{}, api.MenuFunc{
+//        Items: func(kak *api.Kak) ([]api.Choice, error) {
+//            return []api.Choice{{Label: "first", Value: "1"}}, nil
+//        },
+//        Func: func(kak *api.Kak, value string) error {
+//            kak.Echo("picked", value)
+//            return nil
+//        },
+//    })
+//
+// A single choice is given to Func without a menu, as menu -auto-single
+// does.
+type MenuFunc struct {
+	// ExportVars are the vars exported to both Items and Func.
+	ExportVars []string
+
+	Items func(k *Kak) ([]Choice, error)
+	Func  func(k *Kak, value string) error
+}
+
+func (e MenuFunc) Init(ctx Context) (string, error) {
+	// the Func is only invoked by the commands of the menu, see Children.
+	return ctx.Children[0], nil
+}
+
+func (e MenuFunc) Children() []Expansion {
+	callback := Func{
+		ExportVars: append([]string{"reg_" + menuRegister}, e.ExportVars...),
+		Func: func(k *Kak) error {
+			value, err := k.Var("reg_" + menuRegister)
+			if err != nil {
+				return err
+			}
+			return e.Func(k, value)
+		},
+	}
+
+	items := Func{
+		ExportVars: e.ExportVars,
+		Func: func(k *Kak) error {
+			choices, err := e.Items(k)
+			if err != nil {
+				return err
+			}
+			if len(choices) == 0 {
+				return WithSeverity(errors.New("nothing to choose from"), SeverityWarning)
+			}
+			if len(choices) == 1 {
+				return e.Func(k, choices[0].Value)
+			}
+
+			// the callback follows the items Func, which is the invoked
+			// expansion. As with Func, the vars only need to be referenced
+			// within the script for Kakoune to export them.
+			refs := make([]string, len(callback.ExportVars))
+			for i, v := range callback.ExportVars {
+				refs[i] = "$kak_" + v
+			}
+			command := Context{BinName: k.binCommand(), Route: k.route, ID: k.expansionID + 1}.Command()
+			init := fmt.Sprintf("evaluate-commands %%sh{\n  # %s\n  %s\n}", strings.Join(refs, " "), command)
+
+			// the value is given within a register rather than the script,
+			// which could unbalance the %sh block with its braces.
+			menu := make([]MenuItem, len(choices))
+			for i, c := range choices {
+				menu[i] = MenuItem{
+					Label: c.Label,
+					Command: fmt.Sprintf("evaluate-commands -save-regs %s %s", menuRegister,
+						Quote("set-register "+menuRegister+" "+Quote(c.Value)+"\n"+init)),
+				}
+			}
+			return k.Menu(menu...)
+		},
+	}
+
+	return []Expansion{items, callback}
+}
+
+// menuRegister is the register holding the value of the selected Choice.
+const menuRegister = "z"
+
+// InfoOptions are the options of Kak.Info.
+type InfoOptions struct {
+	// Style is where the info box is shown, such as `modal`, `above` or
+	// `menu`, the default placement if empty.
+	Style string
+
+	// Anchor is the coord the info box is shown at, for the above, below
+	// and inline styles.
+	Anchor *Coord
+
+	// Markup parses the body as markup, rather than showing it as is.
+	Markup bool
+}
+
+// infoStyles are the styles of an info box.
+var infoStyles = map[string]bool{
+	"above": true, "below": true, "menu": true, "modal": true,
+	"inline": true, "inlineAbove": true, "inlineBelow": true,
+}
+
+// Info prints the command showing the body within an info box of the
+// title, if not empty.
+func (k *Kak) Info(title, body string, o InfoOptions) error {
+	var b strings.Builder
+	b.WriteString("info")
+	if title != "" {
+		b.WriteString(" -title " + Quote(title))
+	}
+	if o.Style != "" {
+		if !infoStyles[o.Style] {
+			return fmt.Errorf("info style invalid: %q", o.Style)
+		}
+		b.WriteString(" -style " + o.Style)
+	}
+	if o.Anchor != nil {
+		b.WriteString(" -anchor " + o.Anchor.String())
+	}
+	if o.Markup {
+		b.WriteString(" -markup")
+	}
+	b.WriteString(" -- " + Quote(body))
+
+	k.Println(b.String())
+	return nil
+}
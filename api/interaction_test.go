@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMenuFunc(t *testing.T) {
+	menu := MenuFunc{
+		Items: func(k *Kak) ([]Choice, error) {
+			return []Choice{{Label: "a {b}", Value: "x}"}, {Label: "c", Value: "it's"}}, nil
+		},
+	}
+
+	// DefineCommand is 0, the MenuFunc 1 and its items Func 2.
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.expansionID = 2
+	if err := k.DefineCommand("pick", DefineCommandOptions{}, menu); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		`menu -- 'a \{b}' 'evaluate-commands -save-regs z ''set-register z ''''x}''''`,
+		"# $kak_reg_z\n",
+		"''''/usr/bin/plugin''''} 3\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q within:\n%s", want, got)
+		}
+	}
+	if err := CheckBalanced(got); err != nil {
+		t.Error(err)
+	}
+
+	var picked string
+	menu.Func = func(k *Kak, value string) error {
+		picked = value
+		return nil
+	}
+	k = newTestKak(&bytes.Buffer{})
+	k.expansionID = 3
+	k.funcVars = map[string]string{"kak_reg_z": "x}"}
+	if err := k.DefineCommand("pick", DefineCommandOptions{}, menu); err != nil {
+		t.Fatal(err)
+	}
+	if picked != "x}" {
+		t.Errorf("got %q", picked)
+	}
+}
+
+func TestPromptFunc(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	err := k.DefineCommand("ask", DefineCommandOptions{}, PromptFunc{
+		Text:    "name: ",
+		Options: PromptOptions{Init: "it's", Password: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	if want := "prompt -init 'it''s' -password 'name: ' '"; !strings.Contains(got, want) {
+		t.Errorf("missing %q within:\n%s", want, got)
+	}
+	if !strings.Contains(got, "$kak_text") {
+		t.Errorf("text not exported within:\n%s", got)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out}
+
+	if err := k.Info("help", "a {b}", InfoOptions{Style: "modal", Anchor: &Coord{2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Info("", "x", InfoOptions{Style: "sideways"}); err == nil {
+		t.Error("expected error of invalid style")
+	}
+	if err := k.Menu(MenuItem{Label: "a", Command: "nop", Select: "echo a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "info -title 'help' -style modal -anchor 2.3 -- 'a {b}'\n" +
+		"menu -select-cmds -- 'a' 'nop' 'echo a'\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
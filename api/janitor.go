@@ -0,0 +1,149 @@
+package api
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// janitorInterval is how often the janitor actually runs, regardless
+	// of how often plugins start.
+	janitorInterval = time.Hour
+
+	// janitorTTL is how old state of a dead session must be before it is
+	// removed.
+	//
+	// NOTE(leeola): this grace period exists because a session which is
+	// still starting may not yet be listed by `kak -l`.
+	janitorTTL = 24 * time.Hour
+
+	janitorMarker = ".janitor"
+)
+
+// cleanStaleState removes the state dirs of sessions which no longer exist,
+// and the sockets of daemons which no longer listen.
+//
+// Sessions normally remove their own state on KakEnd, but a crashed
+// session never gets that chance. This is run opportunistically when a
+// plugin is initialized, and is throttled to run at most once per
+// janitorInterval across all plugins.
+func (k *Kak) cleanStaleState() error {
+	root, err := stateRoot()
+	if err != nil {
+		return err
+	}
+
+	marker := filepath.Join(root, janitorMarker)
+	if fi, err := os.Stat(marker); err == nil && time.Since(fi.ModTime()) < janitorInterval {
+		return nil
+	}
+
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(marker, nil, 0600); err != nil {
+		return err
+	}
+
+	live, err := liveSessions()
+	if err != nil {
+		return err
+	}
+
+	plugins, err := ioutil.ReadDir(root)
+	if err != nil {
+		return err
+	}
+
+	for _, plugin := range plugins {
+		if !plugin.IsDir() {
+			continue
+		}
+
+		pluginDir := filepath.Join(root, plugin.Name())
+		if err := cleanStaleSockets(pluginDir); err != nil {
+			return err
+		}
+
+		sessions, err := ioutil.ReadDir(pluginDir)
+		if err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			// dot prefixed directories are not session state, eg assets,
+			// and neither are files, such as the daemon socket.
+			if strings.HasPrefix(session.Name(), ".") || !session.IsDir() {
+				continue
+			}
+
+			if live[session.Name()] || time.Since(session.ModTime()) < janitorTTL {
+				continue
+			}
+
+			if err := os.RemoveAll(filepath.Join(pluginDir, session.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cleanStaleSockets removes the daemon socket and the callback directory
+// of the plugin if nothing listens on them anymore, as left behind by a
+// daemon which was killed. Sockets of session daemons, such as those of
+// lsp, are within the session state and go with it.
+//
+// NOTE(leeola): these are shared by every session, so unlike session
+// state they are checked by dialing them rather than by listing sessions.
+func cleanStaleSockets(pluginDir string) error {
+	sock := filepath.Join(pluginDir, "daemon.sock")
+	if _, err := os.Stat(sock); err == nil && !listening("unix", sock) {
+		if err := os.Remove(sock); err != nil {
+			return err
+		}
+	}
+
+	callback := filepath.Join(pluginDir, ".callback")
+	addr, err := ioutil.ReadFile(filepath.Join(callback, "addr"))
+	if err != nil {
+		return nil
+	}
+	network := "tcp"
+	if filepath.IsAbs(strings.TrimSpace(string(addr))) {
+		network = "unix"
+	}
+	if listening(network, strings.TrimSpace(string(addr))) {
+		return nil
+	}
+	return os.RemoveAll(callback)
+}
+
+// listening reports whether anything accepts connections on the address.
+func listening(network, addr string) bool {
+	c, err := net.DialTimeout(network, addr, time.Second)
+	if err != nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+// liveSessions returns the names of every running Kakoune session.
+func liveSessions() (map[string]bool, error) {
+	sessions, err := Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	live := map[string]bool{}
+	for _, session := range sessions {
+		live[session] = true
+	}
+	return live, nil
+}
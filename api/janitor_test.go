@@ -0,0 +1,72 @@
+package api
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanStaleSockets(t *testing.T) {
+	root, err := ioutil.TempDir("", "janitor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	// listen returns a unix socket within dir, left behind as a killed
+	// daemon leaves it unless live.
+	listen := func(dir string, live bool) string {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		sock := filepath.Join(dir, "daemon.sock")
+		l, err := net.Listen("unix", sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if live {
+			listeners = append(listeners, l)
+			return sock
+		}
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+		return sock
+	}
+
+	stale := filepath.Join(root, "stale")
+	listen(stale, false)
+	callback := filepath.Join(stale, ".callback")
+	if err := os.MkdirAll(callback, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(callback, "addr"), []byte(filepath.Join(callback, "sock")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	live := filepath.Join(root, "live")
+	sock := listen(live, true)
+
+	for _, dir := range []string{stale, live} {
+		if err := cleanStaleSockets(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{filepath.Join(stale, "daemon.sock"), callback} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("got:%v, want %s removed", err, path)
+		}
+	}
+	if _, err := os.Stat(sock); err != nil {
+		t.Errorf("got:%v, want live socket kept", err)
+	}
+}
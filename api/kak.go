@@ -1,11 +1,16 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Kak struct {
@@ -14,8 +19,8 @@ type Kak struct {
 	// gokakouneInit
 	gokakouneInit bool
 
-	// gokakouneBin is the name of the binary using this API, being called by
-	// kakoune itself.
+	// gokakouneBin is the absolute path of the binary using this API, being
+	// called by kakoune itself.
 	gokakouneBin string
 
 	funcArgs []string
@@ -26,6 +31,11 @@ type Kak struct {
 	expansionID    int
 	expansionCount int
 
+	// route is the name of the Suite plugin the expansions belong to, see
+	// Suite. funcRoute is the route passed in alongside expansionID.
+	route     string
+	funcRoute string
+
 	// funcCalled will be true if a func was already called for this process.
 	// If true, every action on Kakoune becomes a noop. This is because if
 	// the func was already called, there is no other action that this
@@ -35,6 +45,56 @@ type Kak struct {
 	// This may be added in the future, but currently it isn't likely to
 	// matter.
 	funcCalled bool
+
+	// running is true while the invoked Func runs, during which Fail and
+	// Failf record the failure of the invocation instead of printing it.
+	running bool
+
+	// failure is the first failure recorded by Fail or Failf, see running.
+	failure error
+
+	// ctx is the context of the running Func, see Context.
+	ctx context.Context
+
+	// command, timeout and commandErrors are those of the DefineCommand
+	// whose Funcs are being run, see withCommand.
+	command       string
+	timeout       time.Duration
+	commandErrors ErrorHandler
+
+	// errorHandler handles the errors of Funcs, unless the command has
+	// its own, see SetErrorHandler.
+	errorHandler ErrorHandler
+
+	// stderr is where Exec logs the commands it runs, os.Stderr if nil,
+	// which Kakoune copies to the *debug* buffer.
+	stderr io.Writer
+
+	// debug enables debug mode, see Debugging.
+	debug bool
+
+	// asyncCount is the number of Async calls of the running Func, see
+	// Async.
+	asyncCount int
+
+	// stateSchema is the schema of all values encoded by the state APIs.
+	stateSchema StateSchema
+
+	// manifest records everything the plugin declares while initializing.
+	manifest Manifest
+
+	// hookGroups are the `<scope> <group>` of hook groups declared by Hook,
+	// which are removed before their first hook is added.
+	hookGroups map[string]bool
+
+	// version is the version of this binary, see Version.
+	version string
+
+	// reinit is true when initializing over a stale script.
+	reinit bool
+
+	// script records what is printed when initializing, see WriteScript.
+	script *bytes.Buffer
 }
 
 func New() *Kak {
@@ -42,6 +102,7 @@ func New() *Kak {
 		notGokakouneInit bool
 		gokakouneBin     string
 		funcID           int
+		funcRoute        string
 		funcArgs         []string
 		funcVars         = map[string]string{}
 	)
@@ -51,21 +112,33 @@ func New() *Kak {
 	// the caller that an error occured.
 	lenArgs := len(os.Args)
 	if lenArgs >= 1 {
-		gokakouneBin = os.Args[0]
+		bin, err := resolveBin(os.Args[0])
+		if err != nil {
+			panic("cannot resolve plugin executable: " + err.Error())
+		}
+		gokakouneBin = bin
 	} else {
 		panic("cannot get plugin executable")
 	}
 
+	var subcommand string
 	if lenArgs >= 2 {
-		id, err := strconv.Atoi(os.Args[1])
-		if err != nil {
-			panic("expansionID is not valid int")
+		switch os.Args[1] {
+		case subcommandInit, subcommandInstall, subcommandPackage:
+			subcommand = os.Args[1]
+		default:
+			route, idStr := splitRoute(os.Args[1])
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				panic("expansionID is not valid int")
+			}
+			funcID = id
+			funcRoute = route
+			notGokakouneInit = true
 		}
-		funcID = id
-		notGokakouneInit = true
 	}
 
-	if lenArgs >= 3 {
+	if notGokakouneInit && lenArgs >= 3 {
 		funcArgs = make([]string, len(os.Args[2:]))
 		copy(funcArgs, os.Args[2:])
 	}
@@ -84,49 +157,139 @@ func New() *Kak {
 		}
 	}
 
-	return &Kak{
+	k := &Kak{
 		writer:        os.Stdout,
 		gokakouneBin:  gokakouneBin,
 		gokakouneInit: !notGokakouneInit,
 		expansionID:   funcID,
+		funcRoute:     funcRoute,
 		funcArgs:      funcArgs,
 		funcVars:      funcVars,
+		version:       binVersion(gokakouneBin),
+		reinit:        os.Getenv(env_reinit) != "",
+	}
+
+	switch subcommand {
+	case subcommandInstall, subcommandPackage:
+		var err error
+		if subcommand == subcommandInstall {
+			err = k.install(os.Args[2:])
+		} else {
+			err = k.packageLayout(os.Args[2:])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", k.PluginName(), subcommand, err)
+			os.Exit(1)
+		}
+
+		// the subcommand is the only thing this process does, so make every
+		// following call a noop.
+		k.funcCalled = true
+		return k
+	}
+
+	if !k.gokakouneInit && k.staleScript() {
+		k.reinitScript()
+
+		// the expansion IDs of a stale script may not match this binary, so
+		// running anything could run the wrong func.
+		k.funcCalled = true
+		return k
+	}
+
+	if k.gokakouneInit {
+		k.recordScript()
+		k.declareBinOption()
+		k.declareRegistry()
+
+		if err := k.cleanStaleState(); err != nil {
+			k.Debugf("gokakoune: state cleanup: %s", err)
+		}
+	}
+
+	return k
+}
+
+// Invocation is an invocation of the plugin by Kakoune, see
+// NewInvocation.
+type Invocation struct {
+	// Bin is the path of the plugin binary, naming the plugin.
+	Bin string
+
+	// ID and Route are those of the invoked expansion, as passed to the
+	// binary by the generated script.
+	ID    int
+	Route string
+
+	// Args are the params of the invocation, and Vars the vars exported
+	// to it without the kak_ prefix, such as `bufname`.
+	Args []string
+	Vars map[string]string
+
+	// Writer is where the commands of the invocation are written, and
+	// Stderr where Exec logs to, os.Stderr if nil.
+	Writer io.Writer
+	Stderr io.Writer
+
+	// Init initializes the plugin rather than invoking an expansion, so
+	// that the expansions print the script defining them.
+	Init bool
+}
+
+// NewInvocation returns a Kak invoked as described by the Invocation,
+// rather than by the arguments and environment of the process as with
+// New, such as to test Funcs, see the api/kaktest package.
+func NewInvocation(inv Invocation) *Kak {
+	funcVars := make(map[string]string, len(inv.Vars))
+	for key, v := range inv.Vars {
+		funcVars[var_prefix+key] = v
+	}
+
+	return &Kak{
+		writer:        inv.Writer,
+		stderr:        inv.Stderr,
+		gokakouneBin:  inv.Bin,
+		gokakouneInit: inv.Init,
+		expansionID:   inv.ID,
+		funcRoute:     inv.Route,
+		funcArgs:      append([]string(nil), inv.Args...),
+		funcVars:      funcVars,
+		version:       Version,
 	}
 }
 
+// PluginName returns the name of the plugin, derived from the name of the
+// binary using this API.
+func (k *Kak) PluginName() string {
+	return filepath.Base(k.gokakouneBin)
+}
+
+// Debug writes the values, joined as fmt.Sprintln does, to the *debug*
+// buffer.
 func (k *Kak) Debug(v ...interface{}) {
-	// TODO(leeola): figure out the fastest way to print the v...
-	// as if Sprintln did it, but WITHOUT the newline at the end.
-	//
-	// Apparently fmt.Sprint() and fmt.Sprintln() have different
-	// behavior, Sprint puts spaces between arguments only if they're
-	// not strings.. so i can't use Sprint() ...sadface.
-	s := Escape(v...)
-	k.Println("echo", "-debug", s)
+	k.Println("echo", "-debug", "--", Escape(v...))
 }
 
+// Debugf writes the formatted message to the *debug* buffer.
 func (k *Kak) Debugf(f string, v ...interface{}) {
-	s := Escape(fmt.Sprintf(f, v...))
-	k.Println("echo", "-debug", s)
+	k.Println("echo", "-debug", "--", QuoteArg(fmt.Sprintf(f, v...)))
 }
 
+// Echo echoes the values, joined as fmt.Sprintln does, in the status line.
 func (k *Kak) Echo(v ...interface{}) {
-	// TODO(leeola): figure out the fastest way to print the v...
-	// as if Sprintln did it, but WITHOUT the newline at the end.
-	//
-	// Apparently fmt.Sprint() and fmt.Sprintln() have different
-	// behavior, Sprint puts spaces between arguments only if they're
-	// not strings.. so i can't use Sprint() ...sadface.
-	s := fmt.Sprintln(v...)
-	l := len(s)
-	s = s[:l-1]
-	k.Printf("echo %q\n", s)
+	k.Println("echo", "--", Escape(v...))
 }
 
+// Echof echoes the formatted message in the status line.
 func (k *Kak) Echof(f string, v ...interface{}) {
-	k.Printf("echo %q\n", fmt.Sprintf(f, v...))
+	k.Println("echo", "--", QuoteArg(fmt.Sprintf(f, v...)))
 }
 
+// Fail fails the invocation with the given message.
+//
+// Within a Func, the Func keeps running, but once it returns everything it
+// printed is discarded in favor of a single fail, as if the Func returned
+// the error itself. Only the first failure is reported.
 func (k *Kak) Fail(v ...interface{}) {
 	// TODO(leeola): figure out the fastest way to print the v...
 	// as if Sprintln did it, but WITHOUT the newline at the end.
@@ -137,11 +300,22 @@ func (k *Kak) Fail(v ...interface{}) {
 	s := fmt.Sprintln(v...)
 	l := len(s)
 	s = s[:l-1]
-	k.Printf("fail %q\n", s)
+	k.fail(s)
 }
 
 func (k *Kak) Failf(f string, v ...interface{}) {
-	k.Println("fail", fmt.Sprintf(f, v...))
+	k.fail(fmt.Sprintf(f, v...))
+}
+
+func (k *Kak) fail(s string) {
+	if !k.running {
+		k.Println("fail", Quote(s))
+		return
+	}
+
+	if k.failure == nil {
+		k.failure = errors.New(s)
+	}
 }
 
 // Print to the internal writer.
@@ -149,6 +323,7 @@ func (k *Kak) Failf(f string, v ...interface{}) {
 // This is a lower level interface, allowing you to send arbitrary
 // commands to Kakoune. Use with caution.
 func (k *Kak) Print(v ...interface{}) {
+	k.checkPrint()
 	fmt.Fprint(k.writer, v...)
 }
 
@@ -157,6 +332,7 @@ func (k *Kak) Print(v ...interface{}) {
 // This is a lower level interface, allowing you to send arbitrary
 // commands to Kakoune. Use with caution.
 func (k *Kak) Println(v ...interface{}) {
+	k.checkPrint()
 	fmt.Fprintln(k.writer, v...)
 }
 
@@ -165,5 +341,6 @@ func (k *Kak) Println(v ...interface{}) {
 // This is a lower level interface, allowing you to send arbitrary
 // commands to Kakoune. Use with caution.
 func (k *Kak) Printf(f string, v ...interface{}) {
+	k.checkPrint()
 	fmt.Fprintf(k.writer, f, v...)
 }
@@ -0,0 +1,10 @@
+package api
+
+import "io"
+
+// newTestKak returns a Kak of the /usr/bin/plugin binary, writing its
+// commands to out. Tests set the fields of the invocation they need, such
+// as gokakouneInit or expansionID, on the returned Kak.
+func newTestKak(out io.Writer) *Kak {
+	return &Kak{writer: out, gokakouneBin: "/usr/bin/plugin"}
+}
@@ -0,0 +1,138 @@
+// Package kaktest runs Funcs as Kakoune would invoke them, without a
+// running Kakoune, for unit testing plugins. Eg:
+//
+//    func TestHello(t *testing.T) {
+//        kak := kaktest.New()
+//        kak.Vars["bufname"] = "main.go"
+//
+//        res, err := kak.Run(hello)
+//        if err != nil {
+//            t.Fatal(err)
+//        }
+//        if want := []string{"echo -- 'hello main.go'"}; !reflect.DeepEqual(res.Commands(), want) {
+//            t.Errorf("got %q, want %q", res.Commands(), want)
+//        }
+//    }
+//
+// As with Kakoune, only the vars within the ExportVars of the Func are
+// exported to it, so a var missing from them fails the test as it would
+// fail within Kakoune.
+package kaktest
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// Kak is a fake Kakoune, holding the vars it exports to the Funcs it
+// runs.
+type Kak struct {
+	// Bin is the path of the plugin binary, naming the plugin.
+	Bin string
+
+	// Vars are the vars of Kakoune, without the kak_ prefix, such as
+	// `bufname` or `opt_filetype`.
+	Vars map[string]string
+
+	// Stderr holds what the Funcs logged, such as by Exec.
+	Stderr bytes.Buffer
+}
+
+// New returns a fake Kakoune without any vars.
+func New() *Kak {
+	return &Kak{Bin: "/usr/bin/plugin", Vars: map[string]string{}}
+}
+
+// SetOption sets the option, as exported to Funcs with the `opt_` prefix.
+func (k *Kak) SetOption(name, value string) {
+	k.Vars["opt_"+name] = value
+}
+
+// SetQuotedList sets the var of the `quoted_` prefix, such as
+// `quoted_selections`, to the values quoted as Kakoune quotes them.
+func (k *Kak) SetQuotedList(name string, values ...string) {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = api.Quote(v)
+	}
+	k.Vars["quoted_"+name] = strings.Join(quoted, " ")
+}
+
+// SetSelections sets the vars of the selections, as read by
+// Kak.Selections and Kak.SelectionTexts, the first being the main
+// selection.
+func (k *Kak) SetSelections(sels []api.Selection, texts []string) {
+	descs := make([]string, len(sels))
+	for i, s := range sels {
+		descs[i] = s.String()
+	}
+	k.Vars["selections_desc"] = strings.Join(descs, " ")
+	k.SetQuotedList("selections", texts...)
+	if len(texts) != 0 {
+		k.Vars["selection"] = texts[0]
+	}
+}
+
+// Result is the outcome of running a Func.
+type Result struct {
+	// Output is everything the Func printed, or the command failing the
+	// invocation if it failed, as Kakoune would evaluate it.
+	Output string
+}
+
+// Commands returns the lines of the output, without empty ones.
+//
+// Commands spanning lines, such as those within blocks, are split as
+// well, so they are best matched within the Output instead.
+func (r Result) Commands() []string {
+	var commands []string
+	for _, line := range strings.Split(r.Output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			commands = append(commands, line)
+		}
+	}
+	return commands
+}
+
+// Init runs the registering function, such as that of a plugin package,
+// as when Kakoune initializes the plugin. The Output is the script it
+// defines, which is not evaluated, so Vars are not exported to it.
+func (k *Kak) Init(register func(*api.Kak) error) (Result, error) {
+	var out bytes.Buffer
+	kak := api.NewInvocation(api.Invocation{
+		Bin:    k.Bin,
+		Writer: &out,
+		Stderr: &k.Stderr,
+		Init:   true,
+	})
+	err := register(kak)
+	return Result{Output: out.String()}, err
+}
+
+// Run runs the Func with the given params, exporting the vars within its
+// ExportVars. A Subproc, such as that of a Hook, is run as the Func of
+// its ExportVars and Func.
+//
+// The error is that of the Func, in which case the Output holds the
+// command reporting it, see api.FailError.
+func (k *Kak) Run(f api.Func, params ...string) (Result, error) {
+	exported := map[string]string{}
+	for _, name := range f.ExportVars {
+		if v, ok := k.Vars[name]; ok {
+			exported[name] = v
+		}
+	}
+
+	var out bytes.Buffer
+	kak := api.NewInvocation(api.Invocation{
+		Bin:    k.Bin,
+		Args:   params,
+		Vars:   exported,
+		Writer: &out,
+		Stderr: &k.Stderr,
+	})
+	err := kak.Expansion(f)
+	return Result{Output: out.String()}, err
+}
@@ -0,0 +1,74 @@
+package kaktest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+func TestRun(t *testing.T) {
+	kak := New()
+	kak.Vars[vars.BufName] = "main.go"
+	kak.Vars[vars.Client] = "client0"
+	kak.SetSelections([]api.Selection{
+		{Anchor: api.Coord{Line: 1, Column: 1}, Cursor: api.Coord{Line: 1, Column: 3}},
+	}, []string{"it's"})
+
+	hello := api.Func{
+		ExportVars: []string{vars.BufName, vars.QuotedSelections},
+		Func: func(kak *api.Kak) error {
+			name, err := kak.Var(vars.BufName)
+			if err != nil {
+				return err
+			}
+			texts, err := kak.SelectionTexts()
+			if err != nil {
+				return err
+			}
+			if _, err := kak.Var(vars.Client); err == nil {
+				return errors.New("client exported without being in ExportVars")
+			}
+			kak.Echo("hello", name, texts[0], kak.Params())
+			return nil
+		},
+	}
+
+	res, err := kak.Run(hello, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"echo -- 'hello main.go it''s [x]'"}; !reflect.DeepEqual(res.Commands(), want) {
+		t.Errorf("got:%q, want:%q", res.Commands(), want)
+	}
+
+	res, err = kak.Run(api.Func{Func: func(kak *api.Kak) error {
+		kak.Println("echo one")
+		return errors.New("broken")
+	}})
+	if _, ok := err.(*api.FailError); !ok {
+		t.Errorf("want a FailError, got %v", err)
+	}
+	if want := "fail 'broken'\n"; res.Output != want {
+		t.Errorf("got:%q, want:%q", res.Output, want)
+	}
+}
+
+func TestInit(t *testing.T) {
+	res, err := New().Init(func(k *api.Kak) error {
+		return k.DefineCommand("hello", api.DefineCommandOptions{}, api.Func{
+			Func: func(kak *api.Kak) error {
+				return errors.New("invoked when initializing")
+			},
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "define-command -params 0 hello %{"; !strings.Contains(res.Output, want) {
+		t.Errorf("want %q within %q", want, res.Output)
+	}
+}
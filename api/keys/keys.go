@@ -0,0 +1,190 @@
+// Package keys provides the names of the special keys of Kakoune, and
+// builders of modified keys, as given to map, on-key and execute-keys.
+//
+// Keys are strings, so they are concatenated into key sequences. Eg:
+//
+//    kak.Printf("execute-keys %s\n", api.Quote(keys.Alt("i")+"w"+keys.Esc))
+package keys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Special keys, named as Kakoune names them.
+const (
+	Ret       = "<ret>"
+	Esc       = "<esc>"
+	Tab       = "<tab>"
+	Space     = "<space>"
+	Backspace = "<backspace>"
+	Del       = "<del>"
+	Ins       = "<ins>"
+
+	Up       = "<up>"
+	Down     = "<down>"
+	Left     = "<left>"
+	Right    = "<right>"
+	Home     = "<home>"
+	End      = "<end>"
+	PageUp   = "<pageup>"
+	PageDown = "<pagedown>"
+
+	// keys which would otherwise be read as part of key names, or
+	// command syntax.
+	Lt        = "<lt>"
+	Gt        = "<gt>"
+	Minus     = "<minus>"
+	Plus      = "<plus>"
+	Semicolon = "<semicolon>"
+	Percent   = "<percent>"
+)
+
+// names are the characters of the single character key names.
+var names = map[string]rune{
+	Ret:       '\n',
+	Tab:       '\t',
+	Space:     ' ',
+	Lt:        '<',
+	Gt:        '>',
+	Minus:     '-',
+	Plus:      '+',
+	Semicolon: ';',
+	Percent:   '%',
+}
+
+// F returns the function key of the given number, such as <F1>.
+func F(n int) string {
+	return "<F" + strconv.Itoa(n) + ">"
+}
+
+// Ctrl returns the key with the control modifier, such as <c-x>.
+func Ctrl(key string) string {
+	return modify("c", key)
+}
+
+// Alt returns the key with the alt modifier, such as <a-x>.
+func Alt(key string) string {
+	return modify("a", key)
+}
+
+// Shift returns the key with the shift modifier, such as <s-tab>. Shifted
+// characters are usually given as their uppercase instead, such as X.
+func Shift(key string) string {
+	return modify("s", key)
+}
+
+// modify adds the modifier to the key, which may be a character, a key
+// name or already modified.
+func modify(mod, key string) string {
+	if r, ok := Char(key); ok {
+		key = Key(r)
+	}
+	if strings.HasPrefix(key, "<") && strings.HasSuffix(key, ">") && len(key) > 2 {
+		key = key[1 : len(key)-1]
+	}
+	// the modifiers of Kakoune are ordered c, a then s.
+	mods := map[string]bool{mod: true}
+	for len(key) > 2 && key[1] == '-' && strings.ContainsRune("cas", rune(key[0])) {
+		mods[key[:1]] = true
+		key = key[2:]
+	}
+
+	var b strings.Builder
+	b.WriteString("<")
+	for _, m := range []string{"c", "a", "s"} {
+		if mods[m] {
+			b.WriteString(m + "-")
+		}
+	}
+	b.WriteString(key)
+	b.WriteString(">")
+	return b.String()
+}
+
+// Key returns the key typing the character, named if it has a name, such
+// as <lt> for '<'.
+func Key(r rune) string {
+	for name, c := range names {
+		if c == r {
+			return name
+		}
+	}
+	return string(r)
+}
+
+// Escape returns the keys typing the given text, for use within
+// execute-keys.
+func Escape(text string) string {
+	return strings.Replace(text, "<", Lt, -1)
+}
+
+// Char returns the character typed by the key, as given by on-key, such as
+// '<' for <lt>. Keys typing no single character, such as <esc> or <c-x>,
+// return false.
+func Char(key string) (rune, bool) {
+	if r, ok := names[key]; ok {
+		return r, true
+	}
+
+	r, size := utf8.DecodeRuneInString(key)
+	if size == 0 || size != len(key) {
+		return 0, false
+	}
+	return r, true
+}
+
+// Command returns the keys running the command from the prompt, such as
+// `:format<ret>` for `format`, as mapped to keys.
+func Command(command string) string {
+	return ":" + Escape(command) + Ret
+}
+
+// Validate returns an error if the key is not a single key of Kakoune,
+// being a character or a key name with its modifiers, such as <c-x>,
+// <a-j> or <s-tab>.
+func Validate(key string) error {
+	if _, ok := Char(key); ok {
+		if key == "<" || key == ">" || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("key must be named: %q", key)
+		}
+		return nil
+	}
+
+	if len(key) < 3 || key[0] != '<' || key[len(key)-1] != '>' {
+		return fmt.Errorf("key invalid: %q", key)
+	}
+
+	name := key[1 : len(key)-1]
+	order := "cas"
+	for len(name) > 2 && name[1] == '-' {
+		i := strings.IndexByte(order, name[0])
+		if i == -1 {
+			return fmt.Errorf("key modifier invalid: %q", key)
+		}
+		order = order[i+1:]
+		name = name[2:]
+	}
+
+	if utf8.RuneCountInString(name) == 1 && name != "<" && name != ">" && name != " " {
+		return nil
+	}
+	if validNames[name] {
+		return nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "F")); err == nil && name[0] == 'F' && n >= 1 && n <= 12 {
+		return nil
+	}
+	return fmt.Errorf("key name unknown: %q", key)
+}
+
+// validNames are the names of keys within angle brackets.
+var validNames = map[string]bool{
+	"ret": true, "esc": true, "tab": true, "space": true, "backspace": true,
+	"del": true, "ins": true, "up": true, "down": true, "left": true,
+	"right": true, "home": true, "end": true, "pageup": true,
+	"pagedown": true, "lt": true, "gt": true, "minus": true, "plus": true,
+	"semicolon": true, "percent": true,
+}
@@ -0,0 +1,58 @@
+package keys
+
+import "testing"
+
+func TestModify(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Ctrl("x"), "<c-x>"},
+		{Alt("i"), "<a-i>"},
+		{Shift(Tab), "<s-tab>"},
+		{Alt(Ctrl("x")), "<c-a-x>"},
+		{Ctrl(Alt(Semicolon)), "<c-a-semicolon>"},
+		{Alt("<"), "<a-lt>"},
+		{Alt(" "), "<a-space>"},
+		{Ctrl(F(5)), "<c-F5>"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("got:%q, want:%q", test.got, test.want)
+		}
+	}
+}
+
+func TestChar(t *testing.T) {
+	for key, want := range map[string]rune{Lt: '<', Space: ' ', "x": 'x', "é": 'é'} {
+		if got, ok := Char(key); !ok || got != want {
+			t.Errorf("%q: got:%q, want:%q", key, got, want)
+		}
+	}
+
+	for _, key := range []string{Esc, Ctrl("x"), "xy"} {
+		if _, ok := Char(key); ok {
+			t.Errorf("%q: want no char", key)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if got, want := Escape("<div>"), "<lt>div>"; got != want {
+		t.Fatalf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, key := range []string{"x", "é", Ret, Ctrl("x"), Alt("j"), Shift(Tab), Ctrl(Alt(Semicolon)), F(12), Alt("<")} {
+		if err := Validate(key); err != nil {
+			t.Errorf("%q: %s", key, err)
+		}
+	}
+
+	for _, key := range []string{"", "xy", "<", " ", "<foo>", "<a-c-x>", "<x-a>", "<F13>", "<c->", "<ret"} {
+		if err := Validate(key); err == nil {
+			t.Errorf("%q: expected error", key)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Manifest describes everything a plugin declared while initializing.
+type Manifest struct {
+	Name     string
+	Version  string
+	Commands []ManifestEntry
+	Options  []ManifestEntry
+
+	// Hooks are recorded by the name of their hook group.
+	Hooks []ManifestEntry
+
+	// Highlighters are recorded by their full path, eg `window/myplugin`.
+	Highlighters []ManifestEntry
+
+	UserModes []ManifestEntry
+
+	// Aliases are recorded by their scope and name, eg `global myalias`.
+	Aliases []ManifestEntry
+
+	// Mappings are recorded in the order they were mapped.
+	Mappings []ManifestMapping
+}
+
+// ManifestMapping is a mapping within the Manifest.
+type ManifestMapping struct {
+	Mode string
+	Key  string
+
+	// Keys are the keys the Key is mapped to.
+	Keys      string
+	Docstring string
+}
+
+// ManifestEntry is a single declaration within the Manifest.
+type ManifestEntry struct {
+	Name      string
+	Docstring string
+}
+
+// Manifest returns the declarations made so far.
+//
+// Declarations are only recorded while the plugin is initializing, as that
+// is the only time they are emitted.
+func (k *Kak) Manifest() Manifest {
+	m := k.manifest
+	m.Name = k.PluginName()
+	m.Version = k.version
+	return m
+}
+
+// String renders the manifest as plain text, suitable for an info box.
+func (m Manifest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", m.Name, m.Version)
+
+	sections := []struct {
+		title   string
+		entries []ManifestEntry
+	}{
+		{"commands", m.Commands},
+		{"options", m.Options},
+		{"hooks", m.Hooks},
+		{"highlighters", m.Highlighters},
+		{"user modes", m.UserModes},
+		{"aliases", m.Aliases},
+	}
+
+	for _, s := range sections {
+		if len(s.entries) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n%s:\n", s.title)
+		for _, e := range s.entries {
+			if e.Docstring == "" {
+				fmt.Fprintf(&b, "  %s\n", e.Name)
+				continue
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", e.Name, e.Docstring)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// DefineInfoCommand defines a `<plugin>-info` command, rendering the
+// Manifest in an info box.
+//
+// The manifest is captured when this is called, so it should be called
+// after everything else the plugin declares.
+func (k *Kak) DefineInfoCommand() error {
+	name := k.PluginName() + "-info"
+	opts := DefineCommandOptions{
+		Docstring: "show the commands, options and hooks of " + k.PluginName(),
+	}
+
+	// NOTE(leeola): the manifest is rendered lazily, as a child expansion,
+	// so that it includes this command as well. Commands are recorded
+	// before their children are initialized.
+	return k.DefineCommand(name, opts, manifestInfo{k: k})
+}
+
+// manifestInfo is an expansion rendering the manifest of k at init time.
+type manifestInfo struct {
+	k *Kak
+}
+
+func (e manifestInfo) Init(ctx Context) (string, error) {
+	m := e.k.Manifest()
+	return fmt.Sprintf("info -title %s %s", Quote(m.Name), Quote(m.String())), nil
+}
+
+func (e manifestInfo) Children() []Expansion {
+	return nil
+}
+
+// RecordHookGroup records a hook group in the Manifest, for plugins which
+// declare hooks in their own script rather than through the API.
+func (k *Kak) RecordHookGroup(group, docstring string) {
+	if !k.gokakouneInit {
+		return
+	}
+
+	k.manifest.Hooks = append(k.manifest.Hooks, ManifestEntry{
+		Name:      group,
+		Docstring: docstring,
+	})
+}
+
+// RecordAlias records an alias in the Manifest, by its scope and name such
+// as `global myalias`, for plugins which declare aliases in their own
+// script.
+func (k *Kak) RecordAlias(scope, name, docstring string) {
+	if !k.gokakouneInit {
+		return
+	}
+
+	k.manifest.Aliases = append(k.manifest.Aliases, ManifestEntry{
+		Name:      scope + " " + name,
+		Docstring: docstring,
+	})
+}
+
+// RecordHighlighter records a highlighter path in the Manifest, for plugins
+// which add highlighters in their own script rather than through the API.
+func (k *Kak) RecordHighlighter(path, docstring string) {
+	if !k.gokakouneInit {
+		return
+	}
+
+	k.manifest.Highlighters = append(k.manifest.Highlighters, ManifestEntry{
+		Name:      path,
+		Docstring: docstring,
+	})
+}
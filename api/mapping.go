@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/keys"
+)
+
+// UserMode is an expansion declaring a user mode, see
+// Kak.DeclareUserMode.
+type UserMode struct {
+	Name      string
+	Docstring string
+}
+
+func (e UserMode) Init(ctx Context) (string, error) {
+	if !isFaceName(e.Name) {
+		return "", fmt.Errorf("user mode name invalid: %q", e.Name)
+	}
+
+	// declaring a mode twice fails, as when initializing again.
+	return "try %{ declare-user-mode " + e.Name + " }", nil
+}
+
+func (e UserMode) Children() []Expansion {
+	return nil
+}
+
+// MapOptions are the options of a mapping, see Kak.Map.
+type MapOptions struct {
+	// Docstring describes the mapping within the info box of its mode.
+	Docstring string
+}
+
+// Mapping is an expansion mapping a key, see Kak.Map.
+type Mapping struct {
+	Scope string
+	Mode  string
+	Key   string
+
+	// Keys are the keys the Key is mapped to.
+	Keys string
+
+	Options MapOptions
+}
+
+func (e Mapping) Init(ctx Context) (string, error) {
+	return e.command()
+}
+
+func (e Mapping) Children() []Expansion {
+	return nil
+}
+
+// command returns the map command of the mapping, validating it.
+func (e Mapping) command() (string, error) {
+	switch e.Scope {
+	case "global", "buffer", "window":
+	default:
+		return "", fmt.Errorf("map scope invalid: %q", e.Scope)
+	}
+	if !isFaceName(e.Mode) {
+		return "", fmt.Errorf("map mode invalid: %q", e.Mode)
+	}
+	if err := keys.Validate(e.Key); err != nil {
+		return "", err
+	}
+	if e.Keys == "" {
+		return "", fmt.Errorf("map of %s missing keys", e.Key)
+	}
+
+	var b strings.Builder
+	b.WriteString("map")
+	if e.Options.Docstring != "" {
+		b.WriteString(" -docstring " + Quote(e.Options.Docstring))
+	}
+	fmt.Fprintf(&b, " %s %s %s %s", e.Scope, e.Mode, e.Key, Quote(e.Keys))
+	return b.String(), nil
+}
+
+// DeclareUserMode declares the user mode, recording it in the Manifest.
+// Keys are mapped within it by Map, and it is entered by mapping a key to
+// `:enter-user-mode <name><ret>` of another mode.
+func (k *Kak) DeclareUserMode(name, docstring string) error {
+	return k.Expansion(UserMode{Name: name, Docstring: docstring})
+}
+
+// Map maps the key within the mode and scope to the target keys, such as
+// those of keys.Command. Eg, building a user mode:
+//
+//    kak.DeclareUserMode("myplug", "myplug commands")
+//    kak.Map("global", "user", "m", ":enter-user-mode myplug<ret>", api.MapOptions{
+//        Docstring: "myplug commands",
+//    })
+//    kak.Map("global", "myplug", keys.Ctrl("f"), keys.Command("myplug-format"), api.MapOptions{
+//        Docstring: "format the buffer",
+//    })
+//
+// The key must be a single key, see keys.Validate. Mappings are declared
+// when initializing, while within a Func the map command is printed, such
+// as to map keys of a buffer the Func creates.
+func (k *Kak) Map(scope, mode, key, target string, o MapOptions) error {
+	m := Mapping{Scope: scope, Mode: mode, Key: key, Keys: target, Options: o}
+	if !k.running {
+		return k.Expansion(m)
+	}
+
+	s, err := m.command()
+	if err != nil {
+		return err
+	}
+	k.Println(s)
+	return nil
+}
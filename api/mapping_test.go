@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api/keys"
+)
+
+func TestMap(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	if err := k.DeclareUserMode("myplug", "myplug commands"); err != nil {
+		t.Fatal(err)
+	}
+	err := k.Map("global", "myplug", keys.Alt("j"), keys.Command("echo it's <here>"), MapOptions{
+		Docstring: "echo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "try %{ declare-user-mode myplug }\n" +
+		`map -docstring 'echo' global myplug <a-j> ':echo it''s <lt>here><ret>'` + "\n"
+	if got := out.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if len(k.manifest.UserModes) != 1 || k.manifest.UserModes[0].Name != "myplug" {
+		t.Errorf("got user modes %+v", k.manifest.UserModes)
+	}
+
+	for _, m := range []Mapping{
+		{Scope: "session", Mode: "user", Key: "a", Keys: "x"},
+		{Scope: "global", Mode: "my mode", Key: "a", Keys: "x"},
+		{Scope: "global", Mode: "user", Key: "<c-foo>", Keys: "x"},
+		{Scope: "global", Mode: "user", Key: "ab", Keys: "x"},
+		{Scope: "global", Mode: "user", Key: "a"},
+	} {
+		if _, err := m.command(); err == nil {
+			t.Errorf("%+v: expected error", m)
+		}
+	}
+}
+
+func TestMapRunning(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out, running: true}
+
+	if err := k.Map("buffer", "normal", keys.Ret, keys.Command("docs-word"), MapOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "map buffer normal <ret> ':docs-word<ret>'\n"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
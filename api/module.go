@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Module wraps everything body defines in a Kakoune module, so that it is
+// only evaluated once the module is required.
+//
+// The body is given the same Kak, and should define commands, options, etc
+// as it normally would. Example:
+//
+//    kak.Module("myplugin", func(kak *api.Kak) error {
+//      return kak.DefineCommand("myplugin-hello", opts, hello)
+//    })
+//
+// Which is emitted as:
+//
+//    provide-module myplugin %{
+//      define-command myplugin-hello ...
+//    }
+func (k *Kak) Module(name string, body func(*Kak) error) error {
+	// noop if func was already called
+	if k.funcCalled {
+		return nil
+	}
+
+	// outside of init the module has no meaning, the body is just run so
+	// that the expansions within it are dispatched as normal.
+	if !k.gokakouneInit {
+		return body(k)
+	}
+
+	var buf bytes.Buffer
+	w := k.writer
+	k.writer = &buf
+	err := body(k)
+	k.writer = w
+	if err != nil {
+		return err
+	}
+
+	k.Print("\n" + comment(fmt.Sprintf("module %s, declared by %s", name, k.PluginName())))
+	k.Printf("provide-module %s %%{\n%s\n}\n", name, buf.String())
+
+	return nil
+}
+
+// RequireModule emits a require-module of the given name.
+func (k *Kak) RequireModule(name string) error {
+	return k.Expansion(RequireModule(name))
+}
+
+// RequireModule returns an expansion requiring the given module, for use
+// within commands which depend on a module being loaded.
+func RequireModule(name string) Raw {
+	return Raw("require-module " + name)
+}
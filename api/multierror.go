@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Errors aggregates the errors of a Func processing many items, such as
+// files or selections, so that one failing item neither stops the rest
+// nor floods the status line. Eg:
+//
+//    errs := &api.Errors{Noun: "files"}
+//    for _, f := range files {
+//        errs.Add(f, format(f))
+//    }
+//    kak.ReportErrors(errs)
+//
+// Returning errs.Err() from the Func reports the errors as well, though
+// it discards what the Func printed, as any error does.
+type Errors struct {
+	// Noun is the plural of the items, such as "files", "items" if empty.
+	Noun string
+
+	items []string
+	errs  []error
+	total int
+}
+
+// Add counts the item, and records its error if not nil, returning
+// whether it was.
+func (e *Errors) Add(item string, err error) bool {
+	e.total++
+	if err == nil {
+		return false
+	}
+
+	e.items = append(e.items, item)
+	e.errs = append(e.errs, err)
+	return true
+}
+
+// Len returns the number of errors recorded.
+func (e *Errors) Len() int {
+	return len(e.errs)
+}
+
+// Err returns the Errors as an error, or nil if none were recorded.
+func (e *Errors) Err() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error returns the summary of the errors.
+func (e *Errors) Error() string {
+	noun := e.Noun
+	if noun == "" {
+		noun = "items"
+	}
+
+	s := fmt.Sprintf("%d of %d %s failed", len(e.errs), e.total, noun)
+	if len(e.errs) == 1 {
+		return s + ": " + e.details()[0]
+	}
+	return s + ", see *debug*"
+}
+
+// details returns the error of each item, as `item: error`.
+func (e *Errors) details() []string {
+	details := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		details[i] = e.items[i] + ": " + err.Error()
+	}
+	return details
+}
+
+// ReportErrors echoes the summary of the errors, if any, writing each of
+// them to the *debug* buffer.
+func (k *Kak) ReportErrors(e *Errors) {
+	if e.Len() == 0 {
+		return
+	}
+
+	kerr := &KakError{Err: e, Severity: SeverityError, details: e.details()}
+	k.Println(kerr.command())
+}
+
+// debugLines returns the commands writing the details to the *debug*
+// buffer.
+func debugLines(details []string) string {
+	var b strings.Builder
+	for _, d := range details {
+		b.WriteString("echo -debug -- " + Quote(d) + "\n")
+	}
+	return b.String()
+}
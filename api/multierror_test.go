@@ -0,0 +1,40 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	errs := &Errors{Noun: "files"}
+	if errs.Add("a.go", nil) || errs.Err() != nil {
+		t.Fatal("want no errors")
+	}
+	errs.Add("b.go", errors.New("syntax error"))
+
+	if want := "1 of 2 files failed: b.go: syntax error"; errs.Error() != want {
+		t.Errorf("got:%q, want:%q", errs.Error(), want)
+	}
+
+	errs.Add("it's.go", errors.New("not found"))
+
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out}
+	k.ReportErrors(errs)
+
+	want := `echo -debug -- 'b.go: syntax error'
+echo -debug -- 'it''s.go: not found'
+echo -markup -- '{Error}2 of 3 files failed, see *debug*'
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	// returned, the errors fail the invocation.
+	if got := kakError(errs.Err()).command(); got != `echo -debug -- 'b.go: syntax error'
+echo -debug -- 'it''s.go: not found'
+fail '2 of 3 files failed, see *debug*'` {
+		t.Errorf("unexpected commands:\n%s", got)
+	}
+}
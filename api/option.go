@@ -0,0 +1,307 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OptionType is the type of a Kakoune option, as given to declare-option.
+type OptionType string
+
+const (
+	OptStr         OptionType = "str"
+	OptInt         OptionType = "int"
+	OptBool        OptionType = "bool"
+	OptStrList     OptionType = "str-list"
+	OptIntList     OptionType = "int-list"
+	OptRangeSpecs  OptionType = "range-specs"
+	OptLineSpecs   OptionType = "line-specs"
+	OptCompletions OptionType = "completions"
+)
+
+// RangeSpecs is the value of a range-specs option.
+type RangeSpecs struct {
+	// Timestamp is the timestamp of the buffer the ranges are of. A
+	// negative Timestamp is given as %val{timestamp}, the current one.
+	Timestamp int
+	Specs     []RangeSpec
+}
+
+// LineSpecs is the value of a line-specs option.
+type LineSpecs struct {
+	// Timestamp is as the Timestamp of RangeSpecs.
+	Timestamp int
+	Specs     []LineSpec
+}
+
+// Completions is the value of a completions option, completing the text
+// from Line and Column, for Length bytes.
+type Completions struct {
+	Line   int
+	Column int
+	Length int
+
+	// Timestamp is as the Timestamp of RangeSpecs.
+	Timestamp int
+
+	Items []Completion
+}
+
+// Completion is a candidate of the completions option.
+type Completion struct {
+	// Text is inserted when the candidate is picked.
+	Text string
+
+	// Select is the command run when the candidate is selected, if any.
+	Select string
+
+	// Menu is the markup shown within the completion menu.
+	Menu string
+}
+
+// Option is an expansion declaring an option. See Kak.DeclareOption.
+type Option struct {
+	Name string
+	Type OptionType
+
+	// Value is the default value, of the Go type of the option type, see
+	// FormatOption. Nil declares the option without one.
+	Value interface{}
+
+	Docstring string
+	Hidden    bool
+}
+
+func (e Option) Init(ctx Context) (string, error) {
+	b := strings.Builder{}
+	b.WriteString("declare-option")
+	if e.Hidden {
+		b.WriteString(" -hidden")
+	}
+	if e.Docstring != "" {
+		b.WriteString(" -docstring " + Quote(e.Docstring))
+	}
+	b.WriteString(" " + string(e.Type) + " " + e.Name)
+
+	if e.Value != nil {
+		typ, value, err := FormatOption(e.Value)
+		if err != nil {
+			return "", err
+		}
+		if typ != e.Type {
+			return "", fmt.Errorf("option %s is %s, default value is %s", e.Name, e.Type, typ)
+		}
+		if value != "" {
+			b.WriteString(" " + value)
+		}
+	}
+
+	return b.String(), nil
+}
+
+func (e Option) Children() []Expansion {
+	return nil
+}
+
+// DeclareOption declares the option of the given type, with a default
+// value unless nil. Eg:
+//
+//    kak.DeclareOption("myplug_cache", api.OptStrList, []string{"a", "b"})
+//
+// Options with docstrings, or hidden options, are declared with the
+// Option expansion instead.
+func (k *Kak) DeclareOption(name string, typ OptionType, value interface{}) error {
+	return k.Expansion(Option{Name: name, Type: typ, Value: value})
+}
+
+// SetOption prints the command setting the option within the scope, such
+// as `global` or `buffer`, to the value, formatted by its Go type. See
+// FormatOption.
+func (k *Kak) SetOption(scope, name string, value interface{}) error {
+	_, v, err := FormatOption(value)
+	if err != nil {
+		return err
+	}
+
+	k.Printf("set-option %s %s %s\n", scope, name, v)
+	return nil
+}
+
+// OptionVar returns the var GetOption reads the option from, to add to the
+// ExportVars of Funcs calling GetOption.
+func OptionVar(name string) string {
+	return quoted_prefix + opt_prefix + name
+}
+
+// GetOption parses the value of the option into v, a pointer to the Go
+// type of the option type, see FormatOption.
+//
+// OptionVar(name) must be exported to the Subproc.
+func (k *Kak) GetOption(name string, v interface{}) error {
+	list, err := k.VarQuotedList(OptionVar(name))
+	if err != nil {
+		return err
+	}
+
+	return ParseOption(list, v)
+}
+
+// FormatOption returns the type and the quoted value of the Go value, as
+// given to set-option. The Go types of each option type are:
+//
+//    str          string
+//    int          int
+//    bool         bool
+//    str-list     []string
+//    int-list     []int
+//    range-specs  RangeSpecs
+//    line-specs   LineSpecs
+//    completions  Completions
+func FormatOption(v interface{}) (OptionType, string, error) {
+	switch v := v.(type) {
+	case string:
+		return OptStr, Quote(v), nil
+	case int:
+		return OptInt, strconv.Itoa(v), nil
+	case bool:
+		return OptBool, strconv.FormatBool(v), nil
+
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = Quote(s)
+		}
+		return OptStrList, strings.Join(quoted, " "), nil
+
+	case []int:
+		ints := make([]string, len(v))
+		for i, n := range v {
+			ints[i] = strconv.Itoa(n)
+		}
+		return OptIntList, strings.Join(ints, " "), nil
+
+	case RangeSpecs:
+		return OptRangeSpecs, joinSpecs(formatTimestamp(v.Timestamp), FormatRangeSpecs(v.Specs)), nil
+
+	case LineSpecs:
+		return OptLineSpecs, joinSpecs(formatTimestamp(v.Timestamp), FormatLineSpecs(v.Specs)), nil
+
+	case Completions:
+		header := fmt.Sprintf("%d.%d", v.Line, v.Column)
+		if v.Length > 0 {
+			header += "+" + strconv.Itoa(v.Length)
+		}
+		// the timestamp is within the quoted header, so it is expanded
+		// with double quotes.
+		if v.Timestamp < 0 {
+			header = `"` + header + `@%val{timestamp}"`
+		} else {
+			header = Quote(header + "@" + strconv.Itoa(v.Timestamp))
+		}
+
+		items := make([]string, len(v.Items))
+		for i, c := range v.Items {
+			items[i] = Quote(JoinList([]string{c.Text, c.Select, c.Menu}, '|'))
+		}
+		return OptCompletions, joinSpecs(header, strings.Join(items, " ")), nil
+
+	default:
+		return "", "", fmt.Errorf("unsupported option value type: %T", v)
+	}
+}
+
+// ParseOption parses the elements of an option value, as given by
+// VarQuotedList of its `quoted_opt_` var, into v, a pointer to the Go type
+// of the option type, see FormatOption.
+func ParseOption(list []string, v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case *string:
+		*v = strings.Join(list, " ")
+	case *int:
+		*v, err = strconv.Atoi(strings.Join(list, " "))
+	case *bool:
+		*v, err = strconv.ParseBool(strings.Join(list, " "))
+	case *[]string:
+		*v = list
+	case *[]int:
+		ints := make([]int, len(list))
+		for i, s := range list {
+			if ints[i], err = strconv.Atoi(s); err != nil {
+				break
+			}
+		}
+		*v = ints
+	case *RangeSpecs:
+		v.Timestamp, v.Specs, err = ParseRangeSpecs(list)
+	case *LineSpecs:
+		v.Timestamp, v.Specs, err = ParseLineSpecs(list)
+	case *Completions:
+		*v, err = parseCompletions(list)
+	default:
+		return fmt.Errorf("unsupported option value type: %T", v)
+	}
+	return err
+}
+
+// parseCompletions parses the elements of a completions option.
+func parseCompletions(list []string) (Completions, error) {
+	if len(list) == 0 {
+		return Completions{}, errors.New("completions missing header")
+	}
+
+	var c Completions
+	header := list[0]
+	at := strings.LastIndexByte(header, '@')
+	if at == -1 {
+		return Completions{}, fmt.Errorf("completions header missing timestamp: %q", header)
+	}
+
+	ts, err := strconv.Atoi(header[at+1:])
+	if err != nil {
+		return Completions{}, fmt.Errorf("completions header invalid: %q", header)
+	}
+	c.Timestamp = ts
+
+	coord := header[:at]
+	if plus := strings.IndexByte(coord, '+'); plus != -1 {
+		if c.Length, err = strconv.Atoi(coord[plus+1:]); err != nil {
+			return Completions{}, fmt.Errorf("completions header invalid: %q", header)
+		}
+		coord = coord[:plus]
+	}
+
+	pos, err := ParseCoord(coord)
+	if err != nil {
+		return Completions{}, err
+	}
+	c.Line, c.Column = pos.Line, pos.Column
+
+	for _, item := range list[1:] {
+		fields := SplitList(item, '|')
+		for len(fields) < 3 {
+			fields = append(fields, "")
+		}
+		c.Items = append(c.Items, Completion{Text: fields[0], Select: fields[1], Menu: fields[2]})
+	}
+
+	return c, nil
+}
+
+// formatTimestamp returns the timestamp of specs, the current one if
+// negative.
+func formatTimestamp(ts int) string {
+	if ts < 0 {
+		return "%val{timestamp}"
+	}
+	return strconv.Itoa(ts)
+}
+
+func joinSpecs(head, specs string) string {
+	if specs == "" {
+		return head
+	}
+	return head + " " + specs
+}
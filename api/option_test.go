@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatOption(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		typ   OptionType
+		want  string
+	}{
+		{"it's", OptStr, `'it''s'`},
+		{42, OptInt, `42`},
+		{true, OptBool, `true`},
+		{[]string{"a b", "c"}, OptStrList, `'a b' 'c'`},
+		{[]int{1, 2}, OptIntList, `1 2`},
+		{RangeSpecs{Timestamp: -1, Specs: []RangeSpec{
+			{Range: Range{Begin: Coord{1, 1}, End: Coord{1, 3}}, Face: "Error"},
+		}}, OptRangeSpecs, `%val{timestamp} '1.1,1.3|Error'`},
+		{LineSpecs{Timestamp: 4}, OptLineSpecs, `4`},
+		{Completions{Line: 2, Column: 5, Timestamp: 7, Items: []Completion{
+			{Text: "a|b", Menu: "{MenuInfo}x"},
+		}}, OptCompletions, `'2.5@7' 'a\|b||{MenuInfo}x'`},
+		{Completions{Line: 1, Column: 1, Length: 3, Timestamp: -1}, OptCompletions, `"1.1+3@%val{timestamp}"`},
+	}
+
+	for _, test := range tests {
+		typ, got, err := FormatOption(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != test.typ || got != test.want {
+			t.Errorf("%#v: got %s %q, want %s %q", test.value, typ, got, test.typ, test.want)
+		}
+	}
+
+	if _, _, err := FormatOption(1.5); err == nil {
+		t.Error("expected error of unsupported type")
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	var ints []int
+	if err := ParseOption([]string{"1", "2"}, &ints); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ints, []int{1, 2}) {
+		t.Errorf("got %v", ints)
+	}
+
+	var b bool
+	if err := ParseOption([]string{"true"}, &b); err != nil || !b {
+		t.Errorf("got %v %v", b, err)
+	}
+
+	var c Completions
+	if err := ParseOption([]string{"2.5+3@7", `a\|b||{MenuInfo}x`, "c"}, &c); err != nil {
+		t.Fatal(err)
+	}
+	want := Completions{Line: 2, Column: 5, Length: 3, Timestamp: 7, Items: []Completion{
+		{Text: "a|b", Menu: "{MenuInfo}x"},
+		{Text: "c"},
+	}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got:%+v, want:%+v", c, want)
+	}
+
+	if err := ParseOption([]string{"2.5"}, &c); err == nil {
+		t.Error("expected error of missing timestamp")
+	}
+}
+
+func TestDeclareOption(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	if err := k.DeclareOption("plugin_cache", OptStrList, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.DeclareOption("plugin_count", OptInt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.DeclareOption("plugin_bad", OptInt, "x"); err == nil {
+		t.Error("expected error of mismatched value")
+	}
+
+	want := "declare-option str-list plugin_cache 'a' 'b'\ndeclare-option int plugin_count\n"
+	if got := out.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if len(k.manifest.Options) < 2 || k.manifest.Options[0].Name != "plugin_cache" {
+		t.Errorf("got options %+v", k.manifest.Options)
+	}
+}
+
+func TestGetOption(t *testing.T) {
+	k := &Kak{funcVars: map[string]string{
+		"kak_quoted_opt_plugin_cache": `'a b' 'c'`,
+	}}
+
+	var list []string
+	if err := k.GetOption("plugin_cache", &list); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(list, []string{"a b", "c"}) {
+		t.Errorf("got %q", list)
+	}
+}
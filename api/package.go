@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// packageLayout writes the repository layout expected by Kakoune plugin
+// managers, such as plug.kak and kak-bundle, into the given directory.
+//
+// Plugin managers source every rc/*.kak file of a plugin repository, so
+// the layout is a single rc/<plugin>.kak which runs `<binary> init`. The
+// binary is expected in bin/ of the repository, built by the plugin
+// manager's post-install hook:
+//
+//    plug "user/myplugin" do %{ go build -o bin/myplugin ./cmd/myplugin }
+//
+// If the binary is not in bin/, the one in PATH is used instead.
+func (k *Kak) packageLayout(args []string) error {
+	dir := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		dir = args[0]
+	default:
+		return errors.New("usage: package [dir]")
+	}
+
+	rcDir := filepath.Join(dir, "rc")
+	if err := os.MkdirAll(rcDir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(rcDir, k.PluginName()+".kak")
+	if err := ioutil.WriteFile(path, PackageLoader(k.PluginName()), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s\n", path)
+	fmt.Printf("build the binary into %s before sourcing, eg with plug.kak:\n\n",
+		filepath.Join(dir, "bin", k.PluginName()))
+	fmt.Printf("    plug \"<repo>\" do %%{ go build -o bin/%s <main package> }\n",
+		k.PluginName())
+
+	return nil
+}
+
+// PackageLoader returns the rc file of the plugin of the name, written by
+// the package subcommand, bootstrapping its binary relative to the
+// sourced file.
+func PackageLoader(name string) []byte {
+	// NOTE(leeola): kak_source is the path of the file being sourced, so the
+	// binary is found relative to the repository no matter where the plugin
+	// manager cloned it.
+	return []byte(fmt.Sprintf(`# generated by %[1]s package, do not edit.
+#
+# The %[1]s binary must be built into bin/ of this repository, or be
+# available in PATH.
+evaluate-commands %%sh{
+  bin="${kak_source%%/*}/../bin/%[1]s"
+  if [ -x "$bin" ]; then
+    "$bin" %[2]s
+  elif command -v %[1]s >/dev/null 2>&1; then
+    %[1]s %[2]s
+  else
+    echo "echo -debug '%[1]s: binary not found, see $kak_source'"
+  fi
+}
+`, name, subcommandInit))
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnlimitedParams is the MaxParams of a command taking Params or more
+// params.
+const UnlimitedParams = -1
+
+// paramsSpec returns the value of the -params switch of the command, such
+// as `1`, `1..3` or `0..`.
+func (o DefineCommandOptions) paramsSpec() (string, error) {
+	switch {
+	case o.Params < 0:
+		return "", fmt.Errorf("params must not be negative: %d", o.Params)
+	case o.MaxParams == 0:
+		return strconv.Itoa(o.Params), nil
+	case o.MaxParams == UnlimitedParams:
+		return strconv.Itoa(o.Params) + "..", nil
+	case o.MaxParams < o.Params:
+		return "", fmt.Errorf("max params %d less than params %d", o.MaxParams, o.Params)
+	default:
+		return strconv.Itoa(o.Params) + ".." + strconv.Itoa(o.MaxParams), nil
+	}
+}
+
+// Params returns the params the invoked command was given.
+func (k *Kak) Params() []string {
+	return append([]string(nil), k.funcArgs...)
+}
+
+// ParseFlags parses the switches within the params of the invoked command
+// into the fields of the struct v points to, returning the remaining
+// positional params. Eg, for `myplug-grep -force -glob *.go TODO`:
+//
+//    var flags struct {
+//        Force bool
+//        Glob  string `flag:"glob"`
+//    }
+//    args, err := kak.ParseFlags(&flags)
+//
+// Switches are named by the flag tag of the field, or else the lowercased
+// field name, and a tag of "-" skips the field. Bool fields take no value,
+// while string, int and []string fields take the following param, given
+// once per element for a []string. Params following `--` are always
+// positional.
+func (k *Kak) ParseFlags(v interface{}) ([]string, error) {
+	return ParseFlags(k.funcArgs, v)
+}
+
+// ParseFlags parses the switches within params, as Kak.ParseFlags does.
+func ParseFlags(params []string, v interface{}) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("flags must be a pointer to a struct")
+	}
+	rv = rv.Elem()
+
+	fields := map[string]reflect.Value{}
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		name := f.Tag.Get("flag")
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		switch fv := rv.Field(i); fv.Interface().(type) {
+		case bool, string, int, []string:
+			fields[name] = fv
+		default:
+			return nil, fmt.Errorf("unsupported flag type of %s: %s", f.Name, f.Type)
+		}
+	}
+
+	var args []string
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		if p == "--" {
+			args = append(args, params[i+1:]...)
+			break
+		}
+		if len(p) < 2 || p[0] != '-' {
+			args = append(args, p)
+			continue
+		}
+
+		fv, ok := fields[p[1:]]
+		if !ok {
+			return nil, fmt.Errorf("unknown switch: %q", p)
+		}
+
+		if fv.Kind() == reflect.Bool {
+			fv.SetBool(true)
+			continue
+		}
+
+		if i+1 >= len(params) {
+			return nil, fmt.Errorf("switch missing value: %q", p)
+		}
+		i++
+		value := params[i]
+
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(value)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("switch %s value not an int: %q", p, value)
+			}
+			fv.SetInt(int64(n))
+		case reflect.Slice:
+			fv.Set(reflect.Append(fv, reflect.ValueOf(value)))
+		}
+	}
+
+	return args, nil
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParamsSpec(t *testing.T) {
+	tests := []struct {
+		opts DefineCommandOptions
+		want string
+	}{
+		{DefineCommandOptions{Params: 1}, "1"},
+		{DefineCommandOptions{Params: 1, MaxParams: 3}, "1..3"},
+		{DefineCommandOptions{MaxParams: UnlimitedParams}, "0.."},
+	}
+	for _, test := range tests {
+		got, err := test.opts.paramsSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%+v: got:%q, want:%q", test.opts, got, test.want)
+		}
+	}
+
+	if _, err := (DefineCommandOptions{Params: 2, MaxParams: 1}).paramsSpec(); err == nil {
+		t.Error("expected error of max params less than params")
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	var flags struct {
+		Force   bool
+		Glob    string `flag:"glob"`
+		Depth   int
+		Exclude []string `flag:"x"`
+		Skipped bool     `flag:"-"`
+	}
+
+	args, err := ParseFlags([]string{
+		"-force", "-glob", "*.go", "a", "-depth", "2", "-x", "vendor", "-x", "testdata", "--", "-b",
+	}, &flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(args, []string{"a", "-b"}) {
+		t.Errorf("got args %q", args)
+	}
+	if !flags.Force || flags.Glob != "*.go" || flags.Depth != 2 ||
+		!reflect.DeepEqual(flags.Exclude, []string{"vendor", "testdata"}) {
+		t.Errorf("got flags %+v", flags)
+	}
+
+	for _, params := range [][]string{{"-skipped"}, {"-glob"}, {"-depth", "two"}} {
+		if _, err := ParseFlags(params, &flags); err == nil {
+			t.Errorf("%q: expected error", params)
+		}
+	}
+}
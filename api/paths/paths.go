@@ -0,0 +1,153 @@
+// Package paths resolves the paths plugins are given, such as arguments of
+// commands, relative to the buffer, and shortens the paths plugins show.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// Home returns the home directory of the user.
+func Home() (string, error) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("HOME not set")
+	}
+	return home, nil
+}
+
+// Expand replaces a leading `~` or `~user` of the path with the home
+// directory of the user, as the shell does.
+func Expand(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+
+	name, rest := path[1:], ""
+	if i := strings.IndexByte(name, '/'); i != -1 {
+		name, rest = name[:i], name[i:]
+	}
+
+	if name == "" {
+		home, err := Home()
+		if err != nil {
+			return "", err
+		}
+		return home + rest, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown user of path: %q", path)
+	}
+	return u.HomeDir + rest, nil
+}
+
+// BufDir returns the directory of the buffer file, or the working
+// directory for buffers without a file, such as *debug*.
+//
+// vars.BufFile must be exported to the Subproc.
+func BufDir(kak *api.Kak) (string, error) {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return "", err
+	}
+
+	// NOTE(leeola): the buffile of buffers without a file is their name,
+	// whereas files are always absolute.
+	if !filepath.IsAbs(buffile) {
+		return os.Getwd()
+	}
+	return filepath.Dir(buffile), nil
+}
+
+// Resolve returns the absolute path of the given path, expanded and
+// relative to the directory of the buffer, as `edit` of a relative path
+// would not be.
+//
+// vars.BufFile must be exported to the Subproc.
+func Resolve(kak *api.Kak, path string) (string, error) {
+	path, err := Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	dir, err := BufDir(kak)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, path), nil
+}
+
+// Shorten returns the path for display, relative to root if within it,
+// such as the project root, or otherwise with the home directory as `~`.
+// Roots which are empty are ignored.
+func Shorten(path, root string) string {
+	path = filepath.Clean(path)
+
+	if rel, ok := within(path, root); ok {
+		return rel
+	}
+
+	if home, err := Home(); err == nil {
+		if rel, ok := within(path, home); ok {
+			if rel == "." {
+				return "~"
+			}
+			return "~/" + rel
+		}
+	}
+
+	return path
+}
+
+// within returns the path relative to dir, if within it.
+func within(path, dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
+// Edit returns the command editing the file at the given coord, or its
+// top if the coord is zero, creating a buffer for the file if it does not
+// exist.
+//
+// The path is quoted and given after `--`, so any path is an argument
+// rather than a switch.
+func Edit(path string, at api.Coord) string {
+	return edit("edit --", path, at)
+}
+
+// EditExisting returns the command as Edit does, failing if the file does
+// not exist.
+func EditExisting(path string, at api.Coord) string {
+	return edit("edit -existing --", path, at)
+}
+
+func edit(cmd, path string, at api.Coord) string {
+	cmd += " " + api.Quote(path)
+	if at.Line > 0 {
+		cmd += fmt.Sprintf(" %d", at.Line)
+		if at.Column > 0 {
+			cmd += fmt.Sprintf(" %d", at.Column)
+		}
+	}
+	return cmd
+}
@@ -0,0 +1,74 @@
+package paths
+
+import (
+	"os"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+func TestExpand(t *testing.T) {
+	os.Setenv("HOME", "/home/kak")
+
+	tests := map[string]string{
+		"~":         "/home/kak",
+		"~/src/x":   "/home/kak/src/x",
+		"/abs/~":    "/abs/~",
+		"rel/path":  "rel/path",
+		"~notauser": "",
+	}
+
+	for path, want := range tests {
+		got, err := Expand(path)
+		if want == "" {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", path, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got:%q, want:%q", path, got, want)
+		}
+	}
+}
+
+func TestShorten(t *testing.T) {
+	os.Setenv("HOME", "/home/kak")
+
+	tests := []struct {
+		path, root, want string
+	}{
+		{"/home/kak/src/proj/main.go", "/home/kak/src/proj", "main.go"},
+		{"/home/kak/src/proj/cmd/x/main.go", "/home/kak/src/proj/", "cmd/x/main.go"},
+		{"/home/kak/src/proj", "/home/kak/src/proj", "."},
+		{"/home/kak/src/projects/main.go", "/home/kak/src/proj", "~/src/projects/main.go"},
+		{"/home/kak", "", "~"},
+		{"/etc/../etc/hosts", "/home/kak/src/proj", "/etc/hosts"},
+	}
+
+	for _, test := range tests {
+		if got := Shorten(test.path, test.root); got != test.want {
+			t.Errorf("%q in %q: got:%q, want:%q", test.path, test.root, got, test.want)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Edit("-x.go", api.Coord{}), "edit -- '-x.go'"},
+		{Edit("it's.go", api.Coord{Line: 3}), "edit -- 'it''s.go' 3"},
+		{EditExisting("/x.go", api.Coord{Line: 3, Column: 7}), "edit -existing -- '/x.go' 3 7"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("got:%q, want:%q", test.got, test.want)
+		}
+	}
+}
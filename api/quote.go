@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuoteArg returns s as a single argument of a command, as is if it needs
+// no quoting, such as `buffer` or `1.1,1.5|Error`, and single quoted
+// otherwise, see Quote.
+func QuoteArg(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case strings.ContainsRune("_-./:,+@=|", r):
+		default:
+			return Quote(s)
+		}
+	}
+	return s
+}
+
+// QuoteArgs returns the values quoted by QuoteArg, joined by spaces.
+func QuoteArgs(values ...string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = QuoteArg(v)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// blockDelims are the delimiters of blocks, tried in order. Bracket pairs
+// nest, while the rest end at the next of the same character.
+var blockDelims = []struct {
+	open, close byte
+}{
+	{'{', '}'}, {'(', ')'}, {'[', ']'}, {'<', '>'},
+	{'|', '|'}, {'~', '~'}, {'^', '^'}, {'@', '@'}, {'!', '!'},
+}
+
+// QuoteBlock returns the commands within a block, such as given to
+// evaluate-commands or hook, as `%{...}` unless their braces are
+// unbalanced, in which case another delimiter is chosen, or else the
+// commands are single quoted. Eg:
+//
+//    kak.Printf("evaluate-commands -draft %s\n", api.QuoteBlock(commands))
+//
+// Unlike a quoted string, a block reads as the commands it holds, even
+// when nested.
+func QuoteBlock(commands string) string {
+	s, err := QuoteExpansion("", commands)
+	if err != nil {
+		return Quote(commands)
+	}
+	return s
+}
+
+// QuoteExpansion returns the content within the expansion of the type,
+// such as `sh` for `%sh{...}` or `opt` for `%opt{...}`, choosing a
+// delimiter the content cannot end early. The empty type is a block.
+//
+// An expansion cannot be single quoted, so content using every delimiter
+// is an error.
+func QuoteExpansion(typ, content string) (string, error) {
+	for _, d := range blockDelims {
+		if delimits(content, d.open, d.close) {
+			return "%" + typ + string(d.open) + content + string(d.close), nil
+		}
+	}
+	return "", fmt.Errorf("no delimiter for %%%s expansion: %q", typ, excerpt(content))
+}
+
+// delimits reports whether the content of a block of the delimiters ends
+// at its closing delimiter, and not before.
+func delimits(content string, open, close byte) bool {
+	return blockEnd(content+string(close), 0, open) == len(content)+1
+}
+
+// blockEnd returns the index following the end of the block opened by
+// open, whose content starts at i, or -1. This is the one block scanner,
+// shared by quoting, CheckBalanced and shellBlocks.
+func blockEnd(s string, i int, open byte) int {
+	close, nests := open, false
+	for _, d := range blockDelims {
+		if d.open == open {
+			close, nests = d.close, d.open != d.close
+			break
+		}
+	}
+
+	depth := 1
+	for ; i < len(s); i++ {
+		switch {
+		case nests && s[i] == open:
+			depth++
+		case s[i] == close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// EscapeMarkup escapes the text so that it is shown as is within markup,
+// such as that of `echo -markup` or an info box.
+func EscapeMarkup(text string) string {
+	return strings.Replace(text, "{", `\{`, -1)
+}
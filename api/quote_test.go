@@ -0,0 +1,68 @@
+package api
+
+import "testing"
+
+func TestQuoteArg(t *testing.T) {
+	tests := map[string]string{
+		"":               "''",
+		"buffer":         "buffer",
+		"1.1,1.5|Error":  "1.1,1.5|Error",
+		"hello world":    "'hello world'",
+		"it's":           "'it''s'",
+		"%val{buffile}":  "'%val{buffile}'",
+		"a;b":            "'a;b'",
+		"/tmp/file.go":   "/tmp/file.go",
+		"{Error}message": "'{Error}message'",
+	}
+	for in, want := range tests {
+		if got := QuoteArg(in); got != want {
+			t.Errorf("QuoteArg(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if got, want := QuoteArgs("echo", "hi there"), "echo 'hi there'"; got != want {
+		t.Errorf("QuoteArgs = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteBlock(t *testing.T) {
+	tests := map[string]string{
+		"echo hi":                "%{echo hi}",
+		"echo %{ nested }":       "%{echo %{ nested }}",
+		"echo '}'":               "%(echo '}')",
+		"echo '}' ')' ']' '>' |": "%~echo '}' ')' ']' '>' |~",
+		"}|~^@!)]> 'x'":          "'}|~^@!)]> ''x'''",
+	}
+	for in, want := range tests {
+		got := QuoteBlock(in)
+		if got != want {
+			t.Errorf("QuoteBlock(%q) = %q, want %q", in, got, want)
+		}
+		if err := CheckBalanced("evaluate-commands " + got); err != nil {
+			t.Errorf("QuoteBlock(%q): %s", in, err)
+		}
+	}
+}
+
+func TestQuoteExpansion(t *testing.T) {
+	got, err := QuoteExpansion("sh", `printf '%s' "}"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `%sh(printf '%s' "}")`; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if err := CheckBalanced("echo " + got); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := QuoteExpansion("sh", "}|~^@!)]>"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestEscapeMarkup(t *testing.T) {
+	if got, want := EscapeMarkup("{Error}x"), `\{Error}x`; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
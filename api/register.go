@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// registerNames are the names of the special registers, as given within
+// var names, where their symbols are not allowed.
+var registerNames = map[rune]string{
+	'"': "dquote",
+	'/': "slash",
+	'@': "arobase",
+	'^': "caret",
+	'|': "pipe",
+	'%': "percent",
+	'.': "dot",
+	'#': "hash",
+	'_': "underscore",
+	':': "colon",
+}
+
+// RegisterName returns the name of the register as given within var names
+// and commands, such as `dquote` for `"`. Letters, digits and names of
+// special registers are returned as is.
+func RegisterName(name string) (string, error) {
+	r := []rune(name)
+	if len(r) != 1 {
+		for _, n := range registerNames {
+			if n == name {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("invalid register: %q", name)
+	}
+
+	if n, ok := registerNames[r[0]]; ok {
+		return n, nil
+	}
+	if r[0] > unicode.MaxASCII || !(unicode.IsLetter(r[0]) || unicode.IsDigit(r[0])) {
+		return "", fmt.Errorf("invalid register: %q", name)
+	}
+	return name, nil
+}
+
+// RegVar returns the var Reg reads the register from, to add to the
+// ExportVars of Funcs calling Reg. Eg, RegVar(`"`) is
+// `quoted_reg_dquote`.
+//
+// NOTE(leeola): the var of an invalid register is returned as is, failing
+// within Reg instead.
+func RegVar(name string) string {
+	if n, err := RegisterName(name); err == nil {
+		name = n
+	}
+	return quoted_prefix + "reg_" + name
+}
+
+// Reg returns the values of the register, one per selection when it was
+// set, such as `"` or `a`.
+//
+// RegVar(name) must be exported to the Subproc.
+func (k *Kak) Reg(name string) ([]string, error) {
+	if _, err := RegisterName(name); err != nil {
+		return nil, err
+	}
+	return k.VarQuotedList(RegVar(name))
+}
+
+// SetReg prints the command setting the register to the given values, one
+// per selection, such as `"` or `a`. Each value is quoted, so they may
+// hold anything.
+func (k *Kak) SetReg(name string, values ...string) error {
+	n, err := RegisterName(name)
+	if err != nil {
+		return err
+	}
+	k.Printf("set-register %s %s\n", n, quoteAll(values))
+	return nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRegisterName(t *testing.T) {
+	tests := map[string]string{
+		"a":      "a",
+		"Z":      "Z",
+		"0":      "0",
+		`"`:      "dquote",
+		"/":      "slash",
+		"@":      "arobase",
+		"#":      "hash",
+		"dquote": "dquote",
+	}
+	for in, want := range tests {
+		got, err := RegisterName(in)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: got:%q, want:%q", in, got, want)
+		}
+	}
+
+	for _, name := range []string{"", "ab", "é", "-"} {
+		if _, err := RegisterName(name); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
+
+func TestReg(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out, funcVars: map[string]string{
+		"kak_quoted_reg_dquote": `'x' 'it''s'`,
+		"kak_quoted_reg_a":      `''`,
+	}}
+
+	if got := RegVar(`"`); got != "quoted_reg_dquote" {
+		t.Errorf("got %q", got)
+	}
+
+	got, err := k.Reg(`"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"x", "it's"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if got, err := k.Reg("a"); err != nil || !reflect.DeepEqual(got, []string{""}) {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := k.Reg("b"); err == nil {
+		t.Error("expected error of register not exported")
+	}
+
+	if err := k.SetReg("/", "a{b", "c d"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetReg("<>"); err == nil {
+		t.Error("expected error of invalid register")
+	}
+	if want := "set-register slash 'a{b' 'c d'\n"; out.String() != want {
+		t.Errorf("got:%q, want:%q", out.String(), want)
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/cmds"
+)
+
+const (
+	// registryOption is the global option shared by every gokakoune plugin
+	// in a session.
+	registryOption = "gokakoune_registry"
+
+	// RegistryVar is the var to add to ExportVars to read the registry.
+	RegistryVar = "quoted_opt_" + registryOption
+)
+
+// RegistryEntry is a single service advertised by a plugin.
+//
+// Kind groups entries of the same sort, such as "daemon" or "usermode",
+// so that plugins can discover what others provide without knowing of
+// them ahead of time.
+type RegistryEntry struct {
+	Plugin string
+	Kind   string
+	Name   string
+	Value  string
+}
+
+// Registry is a session wide registry through which gokakoune plugins
+// discover each other, and share services.
+//
+// It is stored in a hidden global str-list option, with each element
+// formatted as `plugin:kind:name:value`. Reading the registry requires
+// RegistryVar to be exported to the Subproc.
+type Registry struct {
+	k *Kak
+}
+
+// Registry returns the session wide plugin registry.
+func (k *Kak) Registry() *Registry {
+	return &Registry{k: k}
+}
+
+func (e RegistryEntry) String() string {
+	return strings.Join([]string{e.Plugin, e.Kind, e.Name, e.Value}, ":")
+}
+
+func parseRegistryEntry(s string) (RegistryEntry, error) {
+	split := strings.SplitN(s, ":", 4)
+	if len(split) != 4 {
+		return RegistryEntry{}, fmt.Errorf("malformed registry entry: %q", s)
+	}
+
+	return RegistryEntry{
+		Plugin: split[0],
+		Kind:   split[1],
+		Name:   split[2],
+		Value:  split[3],
+	}, nil
+}
+
+// declareRegistry declares the registry option when initializing, so that
+// Kakoune exports RegistryVar to the Funcs of the plugin.
+//
+// No value is given to the declaration, so that re-sourcing the plugin
+// does not clear the entries of other plugins.
+func (k *Kak) declareRegistry() {
+	k.Command(cmds.DeclareOption, "-hidden", "str-list", registryOption)
+}
+
+// Entries returns every entry in the registry.
+//
+// NOTE(leeola): Kakoune exports no var for an undeclared option, so a
+// missing RegistryVar is an empty registry rather than an error, such as
+// within a session no plugin has registered anything in yet.
+func (r *Registry) Entries() ([]RegistryEntry, error) {
+	v, ok := r.k.LookupVar(RegistryVar)
+	if !ok {
+		return nil, nil
+	}
+
+	elems, err := ParseQuotedList(v)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]RegistryEntry, 0, len(elems))
+	for _, elem := range elems {
+		e, err := parseRegistryEntry(elem)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Query returns every entry of the given kind, from any plugin.
+func (r *Registry) Query(kind string) ([]RegistryEntry, error) {
+	entries, err := r.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []RegistryEntry
+	for _, e := range entries {
+		if e.Kind == kind {
+			matched = append(matched, e)
+		}
+	}
+
+	return matched, nil
+}
+
+// Lookup returns the first entry of the given kind and name.
+func (r *Registry) Lookup(kind, name string) (RegistryEntry, bool, error) {
+	entries, err := r.Query(kind)
+	if err != nil {
+		return RegistryEntry{}, false, err
+	}
+
+	for _, e := range entries {
+		if e.Name == name {
+			return e, true, nil
+		}
+	}
+
+	return RegistryEntry{}, false, nil
+}
+
+// Register the given kind, name and value for this plugin, replacing any
+// entry this plugin previously registered under the same kind and name.
+func (r *Registry) Register(kind, name, value string) error {
+	if strings.ContainsRune(kind, ':') || strings.ContainsRune(name, ':') {
+		return fmt.Errorf("registry kind and name cannot contain colons: %s:%s",
+			kind, name)
+	}
+
+	if err := r.Unregister(kind, name); err != nil {
+		return err
+	}
+
+	e := RegistryEntry{
+		Plugin: r.k.PluginName(),
+		Kind:   kind,
+		Name:   name,
+		Value:  value,
+	}
+
+	r.k.Printf("set-option -add global %s %s\n", registryOption, Quote(e.String()))
+
+	return nil
+}
+
+// Unregister removes the entry of the given kind and name registered by
+// this plugin, if any.
+func (r *Registry) Unregister(kind, name string) error {
+	entries, err := r.Entries()
+	if err != nil {
+		return err
+	}
+
+	r.k.Command(cmds.DeclareOption, "-hidden", "str-list", registryOption)
+
+	plugin := r.k.PluginName()
+	for _, e := range entries {
+		if e.Plugin != plugin || e.Kind != kind || e.Name != name {
+			continue
+		}
+
+		r.k.Printf("set-option -remove global %s %s\n", registryOption, Quote(e.String()))
+	}
+
+	return nil
+}
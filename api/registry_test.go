@@ -0,0 +1,49 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistryUndeclared(t *testing.T) {
+	out := &bytes.Buffer{}
+	// no RegistryVar is exported, as within a session where the registry
+	// option was never declared.
+	k := newTestKak(out)
+	k.funcVars = map[string]string{}
+
+	entries, err := k.Registry().Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got:%q, want:no entries", entries)
+	}
+
+	if err := k.Registry().Register("daemon", "lookup", "/tmp/lookup.sock"); err != nil {
+		t.Fatal(err)
+	}
+	want := "declare-option -hidden str-list gokakoune_registry\n" +
+		"set-option -add global gokakoune_registry 'plugin:daemon:lookup:/tmp/lookup.sock'\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestRegistryReplace(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.funcVars = map[string]string{
+		var_prefix + RegistryVar: "'plugin:daemon:lookup:/old.sock' 'other:daemon:lookup:/other.sock'",
+	}
+
+	if err := k.Registry().Register("daemon", "lookup", "/new.sock"); err != nil {
+		t.Fatal(err)
+	}
+	want := "declare-option -hidden str-list gokakoune_registry\n" +
+		"set-option -remove global gokakoune_registry 'plugin:daemon:lookup:/old.sock'\n" +
+		"set-option -add global gokakoune_registry 'plugin:daemon:lookup:/new.sock'\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Retry retries a flaky operation, such as a request to a network service,
+// waiting between attempts for a backoff doubling from Backoff up to
+// MaxBackoff. Eg:
+//
+//    var doc string
+//    err := kak.Retry(api.Retry{Attempts: 5}, func(ctx context.Context) error {
+//        var err error
+//        doc, err = fetch(ctx, url)
+//        return err
+//    })
+//
+// Errors which retrying cannot fix, such as a not found response, are
+// returned as Permanent to stop retrying.
+type Retry struct {
+	// Attempts is the number of attempts made, DefaultRetryAttempts if
+	// zero.
+	Attempts int
+
+	// Backoff is the wait before the second attempt, DefaultRetryBackoff
+	// if zero.
+	Backoff time.Duration
+
+	// MaxBackoff caps the wait between attempts, if not zero.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each wait which is random, from 0 to 1,
+	// so that many clients retrying together spread out.
+	Jitter float64
+}
+
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryBackoff  = 100 * time.Millisecond
+)
+
+// permanentError is an error which is not retried, see Permanent.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error as one which retrying cannot fix, stopping
+// Retry.Do with it. A nil error stays nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls f until it succeeds, returns a Permanent error, the attempts
+// run out or the context is done. The error is that of the last attempt.
+//
+// KakErrors of other than SeverityFail are permanent as well, as they
+// report the expected outcome of the operation, such as nothing found.
+func (r Retry) Do(ctx context.Context, f func(context.Context) error) error {
+	attempts := r.Attempts
+	if attempts <= 0 {
+		attempts = DefaultRetryAttempts
+	}
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i != 0 {
+			t := time.NewTimer(r.jitter(backoff))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return fmt.Errorf("%w, after %d attempts: %s", ctx.Err(), i, err)
+			case <-t.C:
+			}
+
+			backoff *= 2
+			if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+				backoff = r.MaxBackoff
+			}
+		}
+
+		err = f(ctx)
+		if err == nil || !retryable(err) {
+			return unwrapPermanent(err)
+		}
+	}
+
+	if attempts == 1 {
+		return err
+	}
+	return fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+// jitter returns the wait of the backoff, of which the Jitter fraction is
+// random.
+func (r Retry) jitter(d time.Duration) time.Duration {
+	if r.Jitter <= 0 {
+		return d
+	}
+
+	j := r.Jitter
+	if j > 1 {
+		j = 1
+	}
+	random := time.Duration(float64(d) * j * rand.Float64())
+	return d - time.Duration(float64(d)*j) + random
+}
+
+// retryable reports whether the error is worth another attempt.
+func retryable(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+
+	var kerr *KakError
+	if errors.As(err, &kerr) && kerr.Severity != SeverityFail {
+		return false
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// unwrapPermanent returns the error marked by Permanent, if it was.
+func unwrapPermanent(err error) error {
+	if perm, ok := err.(*permanentError); ok {
+		return perm.err
+	}
+	return err
+}
+
+// Retry calls f as Retry.Do does, within the context of the invocation so
+// that retries stop once it times out.
+func (k *Kak) Retry(r Retry, f func(context.Context) error) error {
+	return r.Do(k.Context(), f)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	r := Retry{Attempts: 4, Backoff: time.Millisecond, Jitter: 0.5}
+
+	var calls int
+	err := r.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errors.New("unavailable")
+	})
+	if calls != 4 || err == nil || err.Error() != "after 4 attempts: unavailable" {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+
+	notFound := errors.New("not found")
+	calls = 0
+	err = r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return Permanent(notFound)
+	})
+	if calls != 1 || err != notFound {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return WithSeverity(notFound, SeverityWarning)
+	})
+	if calls != 1 || !errors.Is(err, notFound) {
+		t.Errorf("got %v after %d calls", err, calls)
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	r := Retry{Attempts: 100, Backoff: 5 * time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	err := r.Do(ctx, func(context.Context) error {
+		return errors.New("unavailable")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "unavailable") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schedule runs a task periodically, such as auto saving, snapshotting the
+// session or refreshing a cache.
+//
+// Funcs are short lived, and Kakoune has no timers, so scheduled
+// expansions run once the editor is idle after at least Every has passed
+// since they last ran. Untouched editors never idle, and so never run
+// them. Long lived processes, such as daemons, use a Timer instead.
+type Schedule struct {
+	// Name is the command defined to run the task, which may also be run
+	// directly.
+	Name string
+
+	// Every is the duration between runs, in whole seconds.
+	Every time.Duration
+
+	Docstring string
+}
+
+// option returns the hidden option of the time the schedule last ran.
+func (s Schedule) option() string {
+	b := []byte(s.Name)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		default:
+			b[i] = '_'
+		}
+	}
+	return "schedule_" + string(b) + "_last"
+}
+
+// Schedule defines the command of the schedule, running the given
+// expansions, and the hook running it when due. Eg:
+//
+//    k.Schedule(api.Schedule{
+//        Name:  "autosave",
+//        Every: time.Minute,
+//    }, api.Raw("try %{ write-all }"))
+//
+// NOTE(leeola): the hook checks whether the schedule is due within the
+// shell rather than a Func, as it runs every time any client idles.
+func (k *Kak) Schedule(s Schedule, exps ...Expansion) error {
+	if s.Name == "" {
+		return errors.New("schedule name required")
+	}
+
+	secs := int64((s.Every + time.Second - 1) / time.Second)
+	if secs < 1 {
+		return fmt.Errorf("schedule %s must run at most every second: %q", s.Name, s.Every)
+	}
+
+	err := k.DefineCommand(s.Name, DefineCommandOptions{
+		Docstring: s.Docstring,
+	}, exps...)
+	if err != nil {
+		return err
+	}
+
+	group := "schedule-" + s.Name
+	opt := s.option()
+	setup := fmt.Sprintf(`declare-option -hidden int %[1]s 0
+remove-hooks global %[2]s
+hook -group %[2]s global NormalIdle .* %%{ evaluate-commands %%sh{
+  now=$(date +%%s)
+  if [ $((now - kak_opt_%[1]s)) -ge %[3]d ]; then
+    printf 'set-option global %[1]s %%s\n%[4]s\n' "$now"
+  fi
+} }`, opt, group, secs, s.Name)
+	if err := k.Expansion(Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(group, fmt.Sprintf("run %s every %s when idle", s.Name, s.Every))
+
+	return nil
+}
+
+// Timer runs Func every interval within a long lived process, such as a
+// daemon, evaluating the commands it returns within the session.
+type Timer struct {
+	Session string
+	Every   time.Duration
+
+	// Func returns the commands to evaluate, if any. Errors are shown in
+	// the *debug* buffer rather than stopping the Timer.
+	Func func() (string, error)
+}
+
+// Run runs the Timer until stop is closed.
+func (t Timer) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(t.Every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		commands, err := t.Func()
+		if err != nil {
+			commands = "echo -debug -- " + Quote("timer: "+err.Error())
+		}
+		if commands != "" {
+			Send(t.Session, commands)
+		}
+	}
+}
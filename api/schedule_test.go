@@ -0,0 +1,17 @@
+package api
+
+import "testing"
+
+func TestScheduleOption(t *testing.T) {
+	tests := map[string]string{
+		"autosave":         "schedule_autosave_last",
+		"session-autosave": "schedule_session_autosave_last",
+		"cache.refresh":    "schedule_cache_refresh_last",
+	}
+
+	for name, want := range tests {
+		if got := (Schedule{Name: name}).option(); got != want {
+			t.Errorf("%q: got:%q, want:%q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// StateSchema describes the version of the values a plugin stores with
+// State, BufferState and SaveStruct.
+//
+// Every stored value records the schema version it was encoded with. When
+// a value of a different version is decoded, the Migrations are applied in
+// order to bring it up to Version. If any required migration is missing or
+// fails, the value is discarded and decoded as if it were never stored.
+// This ensures that upgrading a plugin never fails on old state.
+type StateSchema struct {
+	// Version is the current version of the plugin state.
+	Version int
+
+	// Migrations maps a version to the func migrating a value from that
+	// version to the next.
+	//
+	// Eg, Migrations[1] is given a version 1 value and must return the
+	// version 2 equivalent.
+	Migrations map[int]func(json.RawMessage) (json.RawMessage, error)
+}
+
+// stateEnvelope is what is actually encoded into options.
+type stateEnvelope struct {
+	Schema    int             `json:"s"`
+	Timestamp int             `json:"t,omitempty"`
+	Data      json.RawMessage `json:"d"`
+}
+
+// SetStateSchema declares the schema of the plugin state.
+//
+// It should be called before any state is read or written, typically right
+// after New.
+func (k *Kak) SetStateSchema(s StateSchema) {
+	k.stateSchema = s
+}
+
+func (k *Kak) encodeState(v interface{}, timestamp int) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(stateEnvelope{
+		Schema:    k.stateSchema.Version,
+		Timestamp: timestamp,
+		Data:      data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (k *Kak) decodeState(s string, v interface{}) (int, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return 0, fmt.Errorf("state decode: %s", err)
+	}
+
+	var env stateEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return 0, fmt.Errorf("state decode: %s", err)
+	}
+
+	data, err := k.migrateState(env)
+	if err != nil {
+		// NOTE(leeola): old state failing to migrate is not worth failing
+		// the user's command over, so reset it and leave a trace of why.
+		k.Debugf("gokakoune: discarding state: %s", err)
+		return 0, ErrStateNotFound
+	}
+
+	return env.Timestamp, json.Unmarshal(data, v)
+}
+
+// migrateState returns the envelope data migrated to the current schema.
+func (k *Kak) migrateState(env stateEnvelope) (json.RawMessage, error) {
+	current := k.stateSchema.Version
+
+	if env.Schema > current {
+		return nil, fmt.Errorf("schema %d is newer than %d", env.Schema, current)
+	}
+
+	data := env.Data
+	for v := env.Schema; v < current; v++ {
+		migrate, ok := k.stateSchema.Migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema %d", v)
+		}
+
+		var err error
+		data, err = migrate(data)
+		if err != nil {
+			return nil, fmt.Errorf("migration from schema %d: %s", v, err)
+		}
+	}
+
+	return data, nil
+}
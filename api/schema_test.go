@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestStateMigration(t *testing.T) {
+	k := &Kak{writer: &bytes.Buffer{}}
+
+	encoded, err := k.encodeState(map[string]string{"name": "foo"}, 5)
+	if err != nil {
+		t.Fatalf("encode failed: %s", err)
+	}
+
+	k.SetStateSchema(StateSchema{
+		Version: 1,
+		Migrations: map[int]func(json.RawMessage) (json.RawMessage, error){
+			0: func(old json.RawMessage) (json.RawMessage, error) {
+				var v map[string]string
+				if err := json.Unmarshal(old, &v); err != nil {
+					return nil, err
+				}
+				return json.Marshal(map[string]string{"title": v["name"]})
+			},
+		},
+	})
+
+	var got map[string]string
+	ts, err := k.decodeState(encoded, &got)
+	if err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+
+	if ts != 5 {
+		t.Errorf("unexpected timestamp. got:%d, want:%d", ts, 5)
+	}
+
+	if got["title"] != "foo" {
+		t.Errorf("unexpected migrated value. got:%v", got)
+	}
+}
+
+func TestStateMigrationMissing(t *testing.T) {
+	k := &Kak{writer: &bytes.Buffer{}}
+
+	encoded, err := k.encodeState("foo", 0)
+	if err != nil {
+		t.Fatalf("encode failed: %s", err)
+	}
+
+	k.SetStateSchema(StateSchema{Version: 2})
+
+	var got string
+	if _, err := k.decodeState(encoded, &got); err != ErrStateNotFound {
+		t.Fatalf("unexpected error. got:%v, want:%v", err, ErrStateNotFound)
+	}
+}
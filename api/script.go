@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// installStatic is the flag of the install subcommand writing the script
+// of the plugin itself into the autoload directory, rather than a loader
+// running the binary.
+const installStatic = "-static"
+
+// recordScript records everything printed from now on, as the script
+// written by WriteScript.
+func (k *Kak) recordScript() {
+	k.script = &bytes.Buffer{}
+	k.writer = io.MultiWriter(k.writer, k.script)
+}
+
+// WriteScript writes the script of everything declared so far to the
+// file at path, so that Kakoune can source it directly rather than
+// running the binary at startup. Eg, as the last call of main:
+//
+//    if path := os.Getenv("MYPLUG_SCRIPT"); path != "" {
+//        if err := kak.WriteScript(path); err != nil {
+//            log.Fatal(err)
+//        }
+//    }
+//
+// The script is only rendered when initializing. Funcs still run the
+// binary when invoked, and a script written by another version of the
+// binary reinitializes the plugin on its first invocation. So a stale
+// script keeps working, though writing it again after rebuilding spares
+// the reinitialization.
+//
+// The `install -static` subcommand writes the script into the autoload
+// directory, in place of the loader.
+func (k *Kak) WriteScript(path string) error {
+	if k.script == nil {
+		return errors.New("script is only rendered when initializing")
+	}
+
+	header := fmt.Sprintf("# generated by %s %s, do not edit.\n", k.PluginName(), k.version)
+	return writeScript(path, append([]byte(header), k.script.Bytes()...))
+}
+
+// writeScript writes the script to the file at path, replacing it at once
+// so that Kakoune never sources half of it.
+func writeScript(path string, script []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(script); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// staticScript returns the content of the autoload file written by
+// `install -static`, the script printed by running `<binary> init`.
+//
+// NOTE(leeola): the binary is run again, rather than rendering within this
+// process, as the declarations of main follow New which handles the
+// subcommand.
+func (k *Kak) staticScript() ([]byte, error) {
+	cmd := exec.Command(k.gokakouneBin, subcommandInit)
+	cmd.Env = scriptEnv(os.Environ())
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("rendering script: %s", err)
+	}
+
+	header := fmt.Sprintf(`# generated by %s install %s, do not edit.
+#
+# Install again after rebuilding the binary, sparing the reinitialization
+# of the plugin on its first invocation.
+`, k.PluginName(), installStatic)
+	return append([]byte(header), out...), nil
+}
+
+// scriptEnv returns the environment without the vars of an invocation,
+// rendering the same script as Kakoune sourcing the loader would. Debug
+// and strict mode are dropped too, as the traces and checks they add to
+// the script would otherwise be installed with it.
+func scriptEnv(environ []string) []string {
+	var env []string
+	for _, e := range environ {
+		switch {
+		case strings.HasPrefix(e, var_prefix),
+			strings.HasPrefix(e, env_reinit+"="),
+			strings.HasPrefix(e, env_version+"="),
+			strings.HasPrefix(e, env_debug+"="),
+			strings.HasPrefix(e, env_debugLog+"="),
+			strings.HasPrefix(e, env_strict+"="):
+			continue
+		}
+		env = append(env, e)
+	}
+	return env
+}
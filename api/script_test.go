@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugin.kak")
+
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+	k.version = "v1"
+	if err := k.WriteScript(path); err == nil {
+		t.Error("want error writing the script without recording it")
+	}
+
+	k.recordScript()
+	if err := k.Expansion(Raw("echo hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := k.WriteScript(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "# generated by plugin v1, do not edit.\n" + out.String(); string(b) != want {
+		t.Errorf("got:%q, want:%q", b, want)
+	}
+	if !strings.Contains(out.String(), "echo hello") {
+		t.Errorf("got %q, want the script printed as well", out.String())
+	}
+}
+
+func TestScriptEnv(t *testing.T) {
+	got := scriptEnv([]string{
+		"HOME=/home/user",
+		"kak_session=1234",
+		env_reinit + "=1",
+		env_version + "=v1",
+		env_debug + "=1",
+		env_debugLog + "=/tmp/gokakoune.log",
+		env_strict + "=1",
+		"PATH=/usr/bin",
+	})
+	if want := []string{"HOME=/home/user", "PATH=/usr/bin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestInstallUsage(t *testing.T) {
+	k := newTestKak(nil)
+	if err := k.install([]string{"-dynamic"}); err == nil {
+		t.Error("want usage error")
+	}
+}
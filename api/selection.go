@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// SelectionVars are the vars read by Selections, SelectionTexts and
+// SelectionText, to add to the ExportVars of a Subproc.
+var SelectionVars = []string{
+	vars.SelectionsDesc,
+	vars.QuotedSelections,
+	vars.Selection,
+}
+
+// Selection is a selection of a buffer, from its Anchor to its Cursor,
+// both of which are included.
+//
+// The Cursor is before the Anchor of a selection extended backwards, see
+// Range for the selection regardless of direction.
+type Selection struct {
+	Anchor Coord
+	Cursor Coord
+}
+
+// ParseSelection parses the `anchor,cursor` description of a selection,
+// such as `1.1,1.5`.
+func ParseSelection(desc string) (Selection, error) {
+	r, err := ParseRange(desc)
+	if err != nil {
+		return Selection{}, err
+	}
+	return Selection{Anchor: r.Begin, Cursor: r.End}, nil
+}
+
+// ParseSelections parses the space separated descriptions of a
+// selections_desc, the main selection first.
+func ParseSelections(descs string) ([]Selection, error) {
+	var sels []Selection
+	for _, desc := range strings.Fields(descs) {
+		s, err := ParseSelection(desc)
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+	return sels, nil
+}
+
+// String returns the description of the selection, as given to select.
+func (s Selection) String() string {
+	return s.Anchor.String() + "," + s.Cursor.String()
+}
+
+// Range returns the range of the selection, from its first coord to its
+// last, regardless of direction.
+func (s Selection) Range() Range {
+	return Range{Begin: s.Anchor, End: s.Cursor}.Normalize()
+}
+
+// Forward reports whether the Cursor is at or after the Anchor.
+func (s Selection) Forward() bool {
+	return !s.Cursor.Less(s.Anchor)
+}
+
+// Selections returns the selections of the invoking client, the main
+// selection first.
+//
+// vars.SelectionsDesc must be exported to the Subproc.
+func (k *Kak) Selections() ([]Selection, error) {
+	descs, err := k.Var(vars.SelectionsDesc)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSelections(descs)
+}
+
+// SelectionTexts returns the content of each selection, in the order of
+// Selections.
+//
+// vars.QuotedSelections must be exported to the Subproc.
+func (k *Kak) SelectionTexts() ([]string, error) {
+	return k.VarQuotedList(vars.QuotedSelections)
+}
+
+// SelectionText returns the content of the main selection.
+//
+// vars.Selection must be exported to the Subproc.
+func (k *Kak) SelectionText() (string, error) {
+	return k.Var(vars.Selection)
+}
+
+// Select prints the command replacing the selections with the given
+// ones, the first of which becomes the main selection.
+func (k *Kak) Select(sels ...Selection) error {
+	if len(sels) == 0 {
+		return errors.New("select requires a selection")
+	}
+
+	descs := make([]string, len(sels))
+	for i, s := range sels {
+		descs[i] = s.String()
+	}
+	k.Printf("select %s\n", strings.Join(descs, " "))
+	return nil
+}
+
+// SetRegister prints the command setting the register, such as `a` or
+// `dquote`, to the given values, one per selection.
+//
+// Deprecated: use SetReg, which also takes the special registers by their
+// symbols.
+func (k *Kak) SetRegister(name string, values ...string) {
+	k.Printf("set-register %s %s\n", Quote(name), quoteAll(values))
+}
+
+// ReplaceSelections prints the commands replacing each selection with the
+// value of the same index, leaving the registers as they were.
+func (k *Kak) ReplaceSelections(values []string) {
+	// quoted rather than within %{}, as braces in the values would
+	// unbalance it.
+	k.Printf("evaluate-commands -save-regs z %s\n", Quote(
+		"set-register z "+quoteAll(values)+"\nexecute-keys '\"zR'"))
+}
+
+// PreservingVars are the vars read by PreservingSelections, to add to the
+// ExportVars of a Subproc.
+var PreservingVars = []string{
+	vars.SelectionsDesc,
+	vars.Timestamp,
+}
+
+// PreservingSelections runs the body, restoring the selections of the
+// invoking client once the commands it prints are done, even if they fail.
+// Eg:
+//
+//    err := kak.PreservingSelections(func(kak *api.Kak) error {
+//        kak.Println("execute-keys 'xs^\\s+<ret>d'")
+//        return nil
+//    })
+//
+// The selections are restored with select -timestamp, so Kakoune adjusts
+// them to the modifications of the buffer made since they were taken.
+//
+// The PreservingVars must be exported to the Subproc.
+func (k *Kak) PreservingSelections(body func(*Kak) error) error {
+	descs, err := k.Var(vars.SelectionsDesc)
+	if err != nil {
+		return err
+	}
+	timestamp, err := k.Var(vars.Timestamp)
+	if err != nil {
+		return err
+	}
+	if _, err := ParseSelections(descs); err != nil {
+		return err
+	}
+	if _, err := strconv.Atoi(timestamp); err != nil {
+		return fmt.Errorf("timestamp invalid: %q", timestamp)
+	}
+
+	// the commands of the body are held, to wrap them within a try.
+	var b bytes.Buffer
+	w := k.writer
+	k.writer = &b
+	err = body(k)
+	k.writer = w
+	if err != nil {
+		return err
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+
+	restore := "select -timestamp " + timestamp + " " + descs
+	k.Printf("try %s catch %s\n%s\n", QuoteBlock(b.String()),
+		QuoteBlock("\n"+restore+"\nfail %val{error}\n"), restore)
+	return nil
+}
+
+// quoteAll returns the values quoted and joined by spaces.
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = Quote(v)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSelections(t *testing.T) {
+	sels, err := ParseSelections("3.5,1.1 4.1,4.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Selection{
+		{Anchor: Coord{3, 5}, Cursor: Coord{1, 1}},
+		{Anchor: Coord{4, 1}, Cursor: Coord{4, 2}},
+	}
+	if !reflect.DeepEqual(sels, want) {
+		t.Fatalf("got:%v, want:%v", sels, want)
+	}
+
+	if sels[0].Forward() || !sels[1].Forward() {
+		t.Error("got wrong direction")
+	}
+	if got := sels[0].Range(); got != (Range{Begin: Coord{1, 1}, End: Coord{3, 5}}) {
+		t.Errorf("got range %v", got)
+	}
+	if got := sels[0].String(); got != "3.5,1.1" {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := ParseSelections("1.1"); err == nil {
+		t.Error("expected error of missing cursor")
+	}
+}
+
+func TestSelections(t *testing.T) {
+	k := &Kak{funcVars: map[string]string{
+		"kak_selections_desc":   "1.1,1.3",
+		"kak_quoted_selections": `'a{b' 'it''s'`,
+	}}
+
+	sels, err := k.Selections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sels) != 1 || sels[0].Cursor != (Coord{1, 3}) {
+		t.Errorf("got %v", sels)
+	}
+
+	texts, err := k.SelectionTexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(texts, []string{"a{b", "it's"}) {
+		t.Errorf("got %q", texts)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out}
+
+	if err := k.Select(); err == nil {
+		t.Error("expected error of no selections")
+	}
+	if err := k.Select(
+		Selection{Anchor: Coord{2, 1}, Cursor: Coord{2, 4}},
+		Selection{Anchor: Coord{5, 3}, Cursor: Coord{4, 1}},
+	); err != nil {
+		t.Fatal(err)
+	}
+	k.SetRegister("a", "x", "it's")
+	k.ReplaceSelections([]string{"a{b"})
+
+	want := "select 2.1,2.4 5.3,4.1\n" +
+		"set-register 'a' 'x' 'it''s'\n" +
+		`evaluate-commands -save-regs z 'set-register z ''a{b''` + "\n" + `execute-keys ''"zR'''` + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPreservingSelections(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := &Kak{writer: out, funcVars: map[string]string{
+		"kak_selections_desc": "1.1,1.3 2.1,2.1",
+		"kak_timestamp":       "7",
+	}}
+
+	err := k.PreservingSelections(func(k *Kak) error {
+		k.Print("execute-keys 'xsfoo<ret>cbar<esc>'\n")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "try %{execute-keys 'xsfoo<ret>cbar<esc>'\n} catch %{\n" +
+		"select -timestamp 7 1.1,1.3 2.1,2.1\nfail %val{error}\n}\n" +
+		"select -timestamp 7 1.1,1.3 2.1,2.1\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if err := CheckBalanced(out.String()); err != nil {
+		t.Error(err)
+	}
+
+	out.Reset()
+	err = k.PreservingSelections(func(k *Kak) error {
+		k.Println("execute-keys d")
+		return errors.New("failed")
+	})
+	if err == nil || out.Len() != 0 {
+		t.Errorf("got %v, %q", err, out.String())
+	}
+}
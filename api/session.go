@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+// Send evaluates the given commands within the session, with `kak -p`,
+// for processes which are not a Func. The api/client package does the
+// same, along with echoing into clients and querying values.
+func Send(session, commands string) error {
+	return SendBin("kak", session, commands)
+}
+
+// SendBin is like Send, but with the given kak binary, such as a fake one
+// in tests.
+func SendBin(bin, session, commands string) error {
+	cmd := exec.Command(bin, "-p", session)
+	cmd.Stdin = strings.NewReader(commands)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("kak -p %s: %s", session, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Sessions returns the names of the running sessions, as listed by
+// `kak -l`.
+func Sessions() ([]string, error) {
+	return SessionsBin("kak")
+}
+
+// SessionsBin is like Sessions, but with the given kak binary.
+func SessionsBin(bin string) ([]string, error) {
+	stdout, stderr, exit, err := util.Exec(bin, "-l")
+	if err != nil {
+		return nil, err
+	}
+
+	if exit != 0 {
+		return nil, fmt.Errorf("kak -l exit %d: %s", exit, strings.TrimSpace(stderr))
+	}
+
+	var sessions []string
+	for _, line := range strings.Split(stdout, "\n") {
+		// kak -l suffixes sessions it could not connect to, those are dead.
+		if line == "" || strings.HasSuffix(line, "(dead)") {
+			continue
+		}
+		sessions = append(sessions, line)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSessionsBin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bin := filepath.Join(dir, "kak")
+	script := "#!/bin/sh\nprintf '1234\\nwork\\nold (dead)\\n'\n"
+	if err := ioutil.WriteFile(bin, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SessionsBin(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1234", "work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	if _, err := SessionsBin("false"); err == nil {
+		t.Error("got no error of failing kak -l")
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// bashisms are constructs of bash and similar shells which POSIX sh lacks,
+// and with it the sh of many systems, such as dash and busybox ash.
+//
+// NOTE(leeola): these are patterns rather than a parser, in the spirit of
+// checkbashisms, so they err towards the common forms. Single quoted text
+// and comments are removed before matching.
+var bashisms = []struct {
+	re   *regexp.Regexp
+	desc string
+}{
+	{regexp.MustCompile(`\[\[`), "[[ tests, use [ ]"},
+	{regexp.MustCompile(`\[\s[^]\n]*\s==\s`), "== within [ ], use ="},
+	{regexp.MustCompile(`\$'`), "$'' strings, use printf"},
+	{regexp.MustCompile(`\$\{!`), "${!var} indirection"},
+	{regexp.MustCompile(`\$\{#?\w+//?[^}]*\}`), "${var/pattern/replacement}"},
+	{regexp.MustCompile(`\$\{\w+:(?:\s+-)?\d`), "${var:offset} substrings"},
+	{regexp.MustCompile(`\$\{\w+\[`), "arrays"},
+	{regexp.MustCompile(`(?m)(?:^|[\s;&|])\w+=\(`), "arrays"},
+	{regexp.MustCompile(`<<<`), "<<< here strings"},
+	{regexp.MustCompile(`&>`), "&> redirection, use >file 2>&1"},
+	{regexp.MustCompile(`\|&`), "|& pipes, use 2>&1 |"},
+	{regexp.MustCompile(`(?:^|[^$<])[<>]\(`), "process substitution"},
+	{regexp.MustCompile(`\{\w+\.\.\w+\}`), "{a..b} brace expansion"},
+	{regexp.MustCompile(`\$\{?(?:RANDOM|PIPESTATUS|BASH_\w+)\b`), "bash variables"},
+	{regexp.MustCompile(`(?m)(?:^|[;&|({]|\bthen|\bdo|\belse)\s*(?:function|source|declare|typeset|select|shopt)\s`),
+		"bash builtin, use POSIX sh equivalents such as . for source"},
+	{regexp.MustCompile(`(?m)(?:^|[;&|({]|\bthen|\bdo|\belse)\s*echo\s+-e\b`), "echo -e, use printf"},
+}
+
+var (
+	shellQuotedRe  = regexp.MustCompile(`'[^']*'`)
+	shellCommentRe = regexp.MustCompile(`(?m)(?:^|\s)#.*$`)
+)
+
+// CheckShell returns an error describing the first construct of the given
+// shell script not supported by POSIX sh.
+//
+// Generated shell is POSIX sh, as Kakoune runs %sh blocks with the sh of
+// the system, which is frequently not bash.
+func CheckShell(script string) error {
+	s := shellQuotedRe.ReplaceAllString(script, "''")
+	s = shellCommentRe.ReplaceAllString(s, "")
+
+	for _, b := range bashisms {
+		if loc := b.re.FindStringIndex(s); loc != nil {
+			return fmt.Errorf("shell is not POSIX sh, %s: %q", b.desc, strings.TrimSpace(s[loc[0]:loc[1]]))
+		}
+	}
+	return nil
+}
+
+// CheckScript checks each shell block of the Kakoune script, such as
+// %sh{ .. }, with CheckShell.
+func CheckScript(kak string) error {
+	for _, block := range shellBlocks(kak) {
+		if err := CheckShell(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellBlocks returns the content of the %sh blocks of the Kakoune script.
+//
+// Blocks delimited by brackets nest as Kakoune nests them, and blocks
+// delimited by other characters end at the next of the same character.
+func shellBlocks(kak string) []string {
+	var blocks []string
+	for {
+		i := strings.Index(kak, "%sh")
+		if i == -1 || i+3 >= len(kak) {
+			return blocks
+		}
+		kak = kak[i+3:]
+
+		// %sh must be followed by its delimiter, not more of a word.
+		if open := kak[0]; open == ' ' || open == '_' || isAlnum(open) {
+			continue
+		}
+
+		end := blockEnd(kak, 1, kak[0])
+		if end == -1 {
+			return blocks
+		}
+
+		blocks = append(blocks, kak[1:end-1])
+		kak = kak[end:]
+	}
+}
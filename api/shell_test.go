@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckShell(t *testing.T) {
+	posix := []string{
+		`if [ "${kak_opt_x:-0}" = "1" ]; then echo "set-option global x 2"; fi`,
+		`printf '%s\n' "$done" | kak -p "$session" 2>&1`,
+		`echo 'source [[ == ]] <<< &>'`,
+		`n=$(( $# + 1 )); echo "${#n} ${n%%.*}"`,
+		"# function foo, [[ in a comment\n. ./lib.sh",
+	}
+	for _, s := range posix {
+		if err := CheckShell(s); err != nil {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+
+	bashisms := []string{
+		`if [[ -n "$x" ]]; then :; fi`,
+		`[ "$x" == "y" ]`,
+		`echo $'\n'`,
+		`echo "${x//a/b}"`,
+		`echo "${x:1:2}"`,
+		`a=(1 2)`,
+		`cat <<< "$x"`,
+		`cmd &> /dev/null`,
+		`diff <(a) <(b)`,
+		`for i in {1..3}; do :; done`,
+		`source ./lib.sh`,
+		`x; echo -e "a\tb"`,
+	}
+	for _, s := range bashisms {
+		if err := CheckShell(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
+
+func TestCheckScript(t *testing.T) {
+	if err := CheckScript(`echo %sh{ [ "$a" = b ] && echo "%{ x }" } %sh(x) %sh|a|`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckScript(`hook global KakEnd .* %{ nop %sh[ [[ -e x ]] ] }`); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+// TestGeneratedShell checks the shell generated by the API is POSIX sh.
+func TestGeneratedShell(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.version = "1"
+
+	f, err := Func{ExportVars: []string{"buffile", "opt_x"}}.Init(Context{BinName: k.binExpr(), ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k.reinitScript()
+	k.State().CompareAndSwap(ScopeGlobal, "key", 1, "value")
+
+	scripts := []string{f, string(PackageLoader(k.PluginName())), string(k.loader("/usr/bin/plugin")), out.String()}
+	for _, s := range scripts {
+		if len(shellBlocks(s)) == 0 {
+			t.Fatalf("no shell blocks in:\n%s", s)
+		}
+		if err := CheckScript(s); err != nil {
+			t.Errorf("%v:\n%s", err, s)
+		}
+	}
+}
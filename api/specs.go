@@ -0,0 +1,273 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RangeSpec is an element of a range-specs option, written as
+// `<line>.<column>,<line>.<column>|<face>`, such as `1.1,1.5|Error`.
+type RangeSpec struct {
+	Range Range
+
+	// Face is the face highlighting the range, or the markup replacing it
+	// within the replace-ranges highlighter.
+	Face string
+}
+
+// ParseRangeSpec parses the `a.b,c.d|face` form of a RangeSpec.
+func ParseRangeSpec(s string) (RangeSpec, error) {
+	i := strings.IndexByte(s, '|')
+	if i == -1 {
+		return RangeSpec{}, fmt.Errorf("range spec missing face: %q", s)
+	}
+
+	r, err := ParseRange(s[:i])
+	if err != nil {
+		return RangeSpec{}, err
+	}
+
+	return RangeSpec{Range: r, Face: s[i+1:]}, nil
+}
+
+func (s RangeSpec) String() string {
+	return s.Range.String() + "|" + s.Face
+}
+
+// LineSpec is an element of a line-specs option, written as
+// `<line>|<flag>`, such as `3|{Error}●`.
+type LineSpec struct {
+	Line int
+
+	// Flag is the markup shown for the line, by the flag-lines
+	// highlighter.
+	Flag string
+}
+
+// ParseLineSpec parses the `line|flag` form of a LineSpec.
+func ParseLineSpec(s string) (LineSpec, error) {
+	i := strings.IndexByte(s, '|')
+	if i == -1 {
+		return LineSpec{}, fmt.Errorf("line spec missing flag: %q", s)
+	}
+
+	line, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return LineSpec{}, fmt.Errorf("line spec line invalid: %q", s)
+	}
+
+	return LineSpec{Line: line, Flag: s[i+1:]}, nil
+}
+
+func (s LineSpec) String() string {
+	return strconv.Itoa(s.Line) + "|" + s.Flag
+}
+
+// Highlight returns the spec highlighting the range with the face, for
+// the ranges highlighter.
+func Highlight(r Range, face string) RangeSpec {
+	return RangeSpec{Range: r, Face: face}
+}
+
+// Replacement returns the spec replacing the range with the text shown
+// in the face, for the replace-ranges highlighter. The text is escaped,
+// so it is shown as is.
+func Replacement(r Range, face, text string) RangeSpec {
+	return RangeSpec{Range: r, Face: markup(face, text)}
+}
+
+// Flag returns the spec flagging the line with the text shown in the
+// face, for the flag-lines highlighter. Eg, a red dot in the gutter:
+//
+//    api.Flag(12, "Error", "●")
+func Flag(line int, face, text string) LineSpec {
+	return LineSpec{Line: line, Flag: markup(face, text)}
+}
+
+// markup returns the escaped text shown in the face, if any.
+func markup(face, text string) string {
+	if face == "" {
+		return EscapeMarkup(text)
+	}
+	return "{" + face + "}" + EscapeMarkup(text)
+}
+
+// SetRangeSpecs sets the range-specs option of the buffer to the specs,
+// of the current timestamp of the buffer. Eg:
+//
+//    kak.SetRangeSpecs("myplug_ranges", []api.RangeSpec{
+//        api.Highlight(r, "Error"),
+//    })
+//
+// See UpdateRangeSpecs to update an option holding many specs.
+func (k *Kak) SetRangeSpecs(option string, specs []RangeSpec) {
+	k.Printf("set-option buffer %s %s\n", option,
+		joinSpecs(formatTimestamp(-1), FormatRangeSpecs(specs)))
+}
+
+// SetLineSpecs sets the line-specs option of the buffer to the specs, of
+// the current timestamp of the buffer, as SetRangeSpecs does.
+func (k *Kak) SetLineSpecs(option string, specs []LineSpec) {
+	k.Printf("set-option buffer %s %s\n", option,
+		joinSpecs(formatTimestamp(-1), FormatLineSpecs(specs)))
+}
+
+// FormatRangeSpecs returns the quoted specs, given to set-option after
+// the timestamp. Eg:
+//
+//    kak.Printf("set-option buffer my_ranges %%val{timestamp} %s\n",
+//        api.FormatRangeSpecs(specs))
+func FormatRangeSpecs(specs []RangeSpec) string {
+	quoted := make([]string, len(specs))
+	for i, s := range specs {
+		quoted[i] = Quote(s.String())
+	}
+	return strings.Join(quoted, " ")
+}
+
+// FormatLineSpecs returns the quoted specs, as FormatRangeSpecs does.
+func FormatLineSpecs(specs []LineSpec) string {
+	quoted := make([]string, len(specs))
+	for i, s := range specs {
+		quoted[i] = Quote(s.String())
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ParseRangeSpecs parses the value of a range-specs option, as given by
+// VarQuotedList of its `quoted_opt_` var, returning its timestamp and
+// specs.
+func ParseRangeSpecs(list []string) (int, []RangeSpec, error) {
+	timestamp, list, err := specsTimestamp(list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	specs := make([]RangeSpec, len(list))
+	for i, s := range list {
+		if specs[i], err = ParseRangeSpec(s); err != nil {
+			return 0, nil, err
+		}
+	}
+	return timestamp, specs, nil
+}
+
+// ParseLineSpecs parses the value of a line-specs option, as
+// ParseRangeSpecs does.
+func ParseLineSpecs(list []string) (int, []LineSpec, error) {
+	timestamp, list, err := specsTimestamp(list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	specs := make([]LineSpec, len(list))
+	for i, s := range list {
+		if specs[i], err = ParseLineSpec(s); err != nil {
+			return 0, nil, err
+		}
+	}
+	return timestamp, specs, nil
+}
+
+// specsTimestamp returns the timestamp leading the specs, and the rest.
+func specsTimestamp(list []string) (int, []string, error) {
+	if len(list) == 0 {
+		return 0, nil, errors.New("specs missing timestamp")
+	}
+
+	timestamp, err := strconv.Atoi(list[0])
+	if err != nil {
+		return 0, nil, fmt.Errorf("specs timestamp invalid: %q", list[0])
+	}
+	return timestamp, list[1:], nil
+}
+
+// specsBatch is the number of specs per set-option of UpdateRangeSpecs,
+// keeping each command of a reasonable length.
+const specsBatch = 1000
+
+// DiffRangeSpecs returns the specs of next which are not in prev, and
+// those of prev which are not in next. Specs given more than once are
+// counted.
+func DiffRangeSpecs(prev, next []RangeSpec) (added, removed []RangeSpec) {
+	counts := make(map[RangeSpec]int, len(prev))
+	for _, s := range prev {
+		counts[s]++
+	}
+	for _, s := range next {
+		if counts[s] > 0 {
+			counts[s]--
+			continue
+		}
+		added = append(added, s)
+	}
+	for _, s := range prev {
+		if counts[s] > 0 {
+			counts[s]--
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// RangeSpecsUpdate returns the commands updating the range-specs option
+// within the scope from prev, the value it holds, to next. Rather than
+// setting the whole option, only the changed specs are removed and added
+// with `set-option -remove` and `set-option -add`, so that highlighting
+// thousands of ranges, such as the semantic tokens of a large file, stays
+// responsive as few of them change.
+//
+// The option is set whole if the timestamps differ, as the specs of prev
+// were moved by Kakoune as the buffer was edited, or if most specs
+// changed anyway.
+//
+// NOTE(leeola): prev must be what the option holds, such as the value
+// last set by the plugin. Specs removed which it does not hold are
+// ignored by Kakoune, so a wrong prev leaves stale ranges highlighted.
+func RangeSpecsUpdate(scope, name string, prev, next RangeSpecs) (string, error) {
+	if prev.Timestamp != next.Timestamp || next.Timestamp < 0 {
+		return setRangeSpecs(scope, name, next)
+	}
+
+	added, removed := DiffRangeSpecs(prev.Specs, next.Specs)
+	if len(added)+len(removed) >= len(next.Specs) {
+		return setRangeSpecs(scope, name, next)
+	}
+
+	var b strings.Builder
+	batches := func(op string, specs []RangeSpec) {
+		for len(specs) != 0 {
+			n := len(specs)
+			if n > specsBatch {
+				n = specsBatch
+			}
+			fmt.Fprintf(&b, "set-option %s %s %s %d %s\n", op, scope, name,
+				next.Timestamp, FormatRangeSpecs(specs[:n]))
+			specs = specs[n:]
+		}
+	}
+	batches("-remove", removed)
+	batches("-add", added)
+	return b.String(), nil
+}
+
+// setRangeSpecs returns the command setting the whole option.
+func setRangeSpecs(scope, name string, specs RangeSpecs) (string, error) {
+	_, v, err := FormatOption(specs)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("set-option %s %s %s\n", scope, name, v), nil
+}
+
+// UpdateRangeSpecs prints the commands of RangeSpecsUpdate.
+func (k *Kak) UpdateRangeSpecs(scope, name string, prev, next RangeSpecs) error {
+	s, err := RangeSpecsUpdate(scope, name, prev, next)
+	if err != nil {
+		return err
+	}
+	k.Print(s)
+	return nil
+}
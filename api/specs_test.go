@@ -0,0 +1,118 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRangeSpecs(t *testing.T) {
+	list := []string{"12", "1.1,1.5|Error", "2.3,4.1|{Information}a|b"}
+
+	timestamp, specs, err := ParseRangeSpecs(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []RangeSpec{
+		{Range: Range{Begin: Coord{1, 1}, End: Coord{1, 5}}, Face: "Error"},
+		{Range: Range{Begin: Coord{2, 3}, End: Coord{4, 1}}, Face: "{Information}a|b"},
+	}
+	if timestamp != 12 || !reflect.DeepEqual(specs, want) {
+		t.Fatalf("got %d %+v, want 12 %+v", timestamp, specs, want)
+	}
+
+	if got, want := FormatRangeSpecs(specs), `'1.1,1.5|Error' '2.3,4.1|{Information}a|b'`; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	for _, s := range []string{"1.1,1.5", "1.1|Error", "x|Error"} {
+		if _, err := ParseRangeSpec(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestLineSpecs(t *testing.T) {
+	timestamp, specs, err := ParseLineSpecs([]string{"3", "1|{Error}●", "10|it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LineSpec{{Line: 1, Flag: "{Error}●"}, {Line: 10, Flag: "it's"}}
+	if timestamp != 3 || !reflect.DeepEqual(specs, want) {
+		t.Fatalf("got %d %+v, want 3 %+v", timestamp, specs, want)
+	}
+
+	if got, want := FormatLineSpecs(specs), `'1|{Error}●' '10|it''s'`; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	if _, _, err := ParseLineSpecs(nil); err == nil {
+		t.Error("expected error of missing timestamp")
+	}
+	if _, err := ParseLineSpec("one|x"); err == nil {
+		t.Error("expected error of invalid line")
+	}
+}
+
+func TestRangeSpecsUpdate(t *testing.T) {
+	spec := func(line int, face string) RangeSpec {
+		return RangeSpec{Range: Range{Begin: Coord{line, 1}, End: Coord{line, 4}}, Face: face}
+	}
+
+	prev := RangeSpecs{Timestamp: 3}
+	for i := 1; i <= 10; i++ {
+		prev.Specs = append(prev.Specs, spec(i, "keyword"))
+	}
+
+	next := RangeSpecs{Timestamp: 3, Specs: append([]RangeSpec{}, prev.Specs...)}
+	next.Specs[4] = spec(5, "string")
+	next.Specs = append(next.Specs, spec(11, "keyword"))
+
+	got, err := RangeSpecsUpdate("buffer", "myplug_ranges", prev, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "set-option -remove buffer myplug_ranges 3 '5.1,5.4|keyword'\n" +
+		"set-option -add buffer myplug_ranges 3 '5.1,5.4|string' '11.1,11.4|keyword'\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	next.Timestamp = 4
+	got, err = RangeSpecsUpdate("buffer", "myplug_ranges", prev, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "set-option buffer myplug_ranges 4 '1.1,1.4|keyword'") {
+		t.Errorf("want whole option set, got:\n%s", got)
+	}
+}
+
+func TestSpecBuilders(t *testing.T) {
+	r := Range{Begin: Coord{1, 1}, End: Coord{1, 5}}
+
+	if got, want := Highlight(r, "Error").String(), "1.1,1.5|Error"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if got, want := Replacement(r, "comment", "{x}").String(), `1.1,1.5|{comment}\{x}`; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if got, want := Flag(3, "", "{●").String(), `3|\{●`; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+
+	var buf strings.Builder
+	k := &Kak{writer: &buf}
+	k.SetRangeSpecs("my_ranges", []RangeSpec{Highlight(r, "Error")})
+	k.SetLineSpecs("my_flags", []LineSpec{Flag(3, "Error", "●")})
+	k.SetLineSpecs("my_flags", nil)
+
+	want := "set-option buffer my_ranges %val{timestamp} '1.1,1.5|Error'\n" +
+		"set-option buffer my_flags %val{timestamp} '3|{Error}●'\n" +
+		"set-option buffer my_flags %val{timestamp}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
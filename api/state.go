@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leeola/gokakoune/api/cmds"
+)
+
+const (
+	// state_prefix is prepended to every state key to form the name of the
+	// hidden option backing it.
+	state_prefix = "gokakoune_state_"
+)
+
+const (
+	ScopeGlobal = "global"
+	ScopeBuffer = "buffer"
+	ScopeWindow = "window"
+)
+
+// ErrStateNotFound is returned by State.Get when the key has no value.
+var ErrStateNotFound = errors.New("state not found")
+
+// State is a key/value store backed by hidden Kakoune str options.
+//
+// Each key is stored in its own option, with the value JSON encoded and
+// then base64 encoded. The encoding ensures the value never needs any
+// Kakoune quoting, regardless of what is stored. Large values are spilled
+// to the StateDir, see SaveStruct.
+//
+// Because the values live within Kakoune, they are available to future
+// Subproc executions. Reading a value requires the option to be exported
+// to the Subproc, see StateVar.
+type State struct {
+	k *Kak
+}
+
+// State returns the hidden option backed state store.
+func (k *Kak) State() *State {
+	return &State{k: k}
+}
+
+// StateVar returns the var name to add to ExportVars for the given key.
+//
+// Eg, to read the key "count" within a Func:
+//
+//    ExportVars: []string{k.StateVar("count")}
+func (k *Kak) StateVar(key string) string {
+	return opt_prefix + k.stateOption(key)
+}
+
+// Get decodes the value of key into v.
+//
+// The value is resolved the same way Kakoune resolves options, the window
+// scope first, then buffer, then global.
+func (s *State) Get(key string, v interface{}) error {
+	if err := validStateKey(key); err != nil {
+		return err
+	}
+
+	return s.k.LoadStruct(s.k.stateOption(key), v)
+}
+
+// Set v as the value of key in the given scope.
+func (s *State) Set(scope, key string, v interface{}) error {
+	if err := validStateKey(key); err != nil {
+		return err
+	}
+
+	return s.k.SaveStruct(scope, s.k.stateOption(key), v)
+}
+
+// Delete the value of key in the given scope.
+func (s *State) Delete(scope, key string) error {
+	if err := validStateKey(key); err != nil {
+		return err
+	}
+
+	name := s.k.stateOption(key)
+	s.k.declareHiddenStr(name)
+
+	// NOTE(leeola): Kakoune does not allow unsetting options in the
+	// global scope, so the best we can do is empty it.
+	if scope == ScopeGlobal {
+		s.k.Command(cmds.SetOption, scope, name, "")
+		return nil
+	}
+
+	s.k.Command(cmds.UnsetOption, scope, name)
+
+	return nil
+}
+
+// declareHiddenStr declares a hidden str option with the given name.
+//
+// Declaring an option which already exists with the same type is a noop
+// within Kakoune, so this is safe to call before every use.
+func (k *Kak) declareHiddenStr(name string) {
+	k.Command(cmds.DeclareOption, "-hidden", "str", name)
+}
+
+// stateOption returns the name of the option of the key, which includes
+// the name of the plugin so that plugins using the same key do not share
+// its value.
+func (k *Kak) stateOption(key string) string {
+	return state_prefix + OptionName(k.PluginName()) + "_" + key
+}
+
+// validStateKey ensures the key is usable within an option name, and
+// within the kak_opt_ environment variable Kakoune exports for it.
+func validStateKey(key string) error {
+	if key == "" {
+		return errors.New("state key cannot be empty")
+	}
+
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case r == '_':
+		default:
+			return fmt.Errorf("invalid state key: %q", key)
+		}
+	}
+
+	return nil
+}
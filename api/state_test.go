@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStateUndeclared(t *testing.T) {
+	out := &bytes.Buffer{}
+	// no state vars are exported, as before the first write of the key
+	// declares its options.
+	k := newTestKak(out)
+	k.funcVars = map[string]string{}
+
+	var n int
+	if err := k.State().Get("count", &n); err != ErrStateNotFound {
+		t.Errorf("got:%v, want:%v", err, ErrStateNotFound)
+	}
+
+	err := k.State().Update(ScopeGlobal, "count", &n, func() error {
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `:-0}" = "0" ]`; !strings.Contains(got, want) {
+		t.Errorf("got:%q, want within it:%q", got, want)
+	}
+}
+
+func TestStateCompareAndSwapQuoting(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.funcVars = map[string]string{}
+
+	if err := k.State().CompareAndSwap(ScopeGlobal, "key", 0, `it's "$HOME"`); err != nil {
+		t.Fatal(err)
+	}
+	want := `printf '%s\n' 'set-option global gokakoune_state_plugin_key eyJzIjowLCJkIjoiaXQncyBcIiRIT01FXCIifQ=='`
+	if got := out.String(); !strings.Contains(got, want) {
+		t.Errorf("got:%q, want within it:%q", got, want)
+	}
+}
+
+func TestStateVarPlugin(t *testing.T) {
+	a, b := newTestKak(nil), newTestKak(nil)
+	b.gokakouneBin = "/usr/bin/other-plugin"
+
+	if got, want := a.StateVar("count"), "opt_gokakoune_state_plugin_count"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+	if got, want := b.StateVar("count"), "opt_gokakoune_state_other_plugin_count"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
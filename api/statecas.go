@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	// state_version_suffix is appended to a state option name to form the
+	// name of the option holding its version counter.
+	state_version_suffix = "_version"
+)
+
+// StateVersionVar returns the var name to add to ExportVars to read the
+// version of the given key, see State.Version.
+func (k *Kak) StateVersionVar(key string) string {
+	return k.StateVar(key) + state_version_suffix
+}
+
+// Version returns the version counter of the given key.
+//
+// Keys which have never been written with CompareAndSwap are version 0,
+// as are those whose version option is undeclared. StateVersionVar(key)
+// must be exported to the Subproc.
+func (s *State) Version(key string) (int, error) {
+	if err := validStateKey(key); err != nil {
+		return 0, err
+	}
+
+	v, ok := s.k.LookupVar(s.k.StateVersionVar(key))
+	if !ok || v == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(v)
+}
+
+// CompareAndSwap sets v as the value of key, only if the version of key is
+// still the given version when Kakoune evaluates the write.
+//
+// The version is incremented with each successful swap. If the version
+// changed, for example from another hook writing the key after this Subproc
+// read it, the write is not applied and the command fails with a conflict
+// message. Because that fail aborts evaluation, any output after the
+// CompareAndSwap is not evaluated either.
+func (s *State) CompareAndSwap(scope, key string, version int, v interface{}) error {
+	if err := validStateKey(key); err != nil {
+		return err
+	}
+
+	encoded, err := s.k.encodeOption(v, 0)
+	if err != nil {
+		return err
+	}
+
+	name := s.k.stateOption(key)
+	versionName := name + state_version_suffix
+	s.k.declareHiddenStr(name)
+	s.k.declareHiddenStr(versionName)
+
+	// NOTE(leeola): the comparison has to happen at evaluation time, within
+	// Kakoune, which is why it's a shell block rather than something done
+	// here in Go. Writing $kak_opt_ within the block is also what makes
+	// Kakoune export the current version to it. The set-option of the value
+	// is shell quoted as a whole, as encoded state may hold quotes and $.
+	s.k.Printf(`evaluate-commands %%sh{
+  if [ "${kak_opt_%s:-0}" = "%d" ]; then
+    printf '%%s\n' %s
+    echo "set-option %s %s %d"
+  else
+    echo "fail 'gokakoune: state conflict: %s'"
+  fi
+}
+`,
+		versionName, version,
+		util.ShellQuote("set-option "+scope+" "+name+" "+QuoteArg(encoded)),
+		scope, versionName, version+1,
+		key)
+
+	return nil
+}
+
+// Update reads the current value of key into v, calls fn to modify it, and
+// writes it back with CompareAndSwap.
+//
+// A missing key leaves v untouched before calling fn. Both StateVar(key)
+// and StateVersionVar(key) must be exported to the Subproc.
+func (s *State) Update(scope, key string, v interface{}, fn func() error) error {
+	version, err := s.Version(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Get(key, v); err != nil && err != ErrStateNotFound {
+		return fmt.Errorf("state update: %s", err)
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return s.CompareAndSwap(scope, key, version, v)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/leeola/gokakoune/api/cmds"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+// StateDir returns a directory private to this plugin and the current
+// Kakoune session, creating it if needed.
+//
+// The directory lives within the XDG cache directory, and is removed
+// automatically when the session ends. Use it for state larger than
+// options can reasonably hold.
+//
+// vars.Session must be exported to the calling Subproc.
+func (k *Kak) StateDir() (string, error) {
+	session, err := k.Var(vars.Session)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := stateRoot()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, k.PluginName(), session)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	// NOTE(leeola): removing the group before adding the hook ensures
+	// that only a single cleanup hook exists, regardless of how many times
+	// StateDir is called within the session.
+	group := "gokakoune-statedir-" + k.PluginName()
+	k.Command(cmds.RemoveHooks, "global", group)
+	k.Printf("hook -group %s global KakEnd .* %%{ nop %%sh{ rm -rf %s } }\n",
+		group, util.ShellQuote(dir))
+
+	return dir, nil
+}
+
+// stateRoot returns the root directory of all gokakoune state.
+func stateRoot() (string, error) {
+	cache := os.Getenv("XDG_CACHE_HOME")
+	if cache == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", errors.New("neither XDG_CACHE_HOME nor HOME set")
+		}
+		cache = filepath.Join(home, ".cache")
+	}
+
+	return filepath.Join(cache, "gokakoune"), nil
+}
+
+// DataDir returns a directory private to this plugin which outlives the
+// session, creating it if needed.
+//
+// The directory lives within the XDG data directory. Unlike StateDir it is
+// never removed, so plugins are responsible for what they keep there.
+func (k *Kak) DataDir() (string, error) {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", errors.New("neither XDG_DATA_HOME nor HOME set")
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+
+	dir := filepath.Join(data, "gokakoune", k.PluginName())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
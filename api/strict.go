@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// env_strict enables strict mode, see Strict.
+const env_strict = "GOKAKOUNE_STRICT"
+
+// Strict reports whether strict mode is enabled, by setting the
+// GOKAKOUNE_STRICT environment variable of the Kakoune server to anything
+// but an empty string or 0. Eg:
+//
+//    GOKAKOUNE_STRICT=1 kak
+//
+// In strict mode, bugs of plugins which otherwise degrade gracefully panic
+// with a clear message instead, which Kakoune writes to the *debug*
+// buffer:
+//
+//    - reading a var which was not exported to the Func, save with LookupVar
+//    - printing after Fail or Failf, as it is discarded
+//    - printing commands with unterminated strings or blocks
+//    - writing a coord before line or column 1
+//
+// It is meant for developing plugins, not for daily use.
+func Strict() bool {
+	v := os.Getenv(env_strict)
+	return v != "" && v != "0"
+}
+
+// strictf panics with the message if in strict mode.
+func strictf(format string, v ...interface{}) {
+	if Strict() {
+		panic("gokakoune strict: " + fmt.Sprintf(format, v...))
+	}
+}
+
+// checkPrint panics in strict mode if the running Func already failed, as
+// whatever it prints is discarded.
+func (k *Kak) checkPrint() {
+	if k.running && k.failure != nil {
+		strictf("printing after fail: %s", k.failure)
+	}
+}
+
+// CheckBalanced returns an error if the Kakoune script has a string or
+// block which never ends, such as `'it's'` or `%{ echo`, or a command
+// starting with a closing bracket.
+//
+// The commands printed by Funcs are checked in strict mode, see Strict.
+func CheckBalanced(kak string) error {
+	commandStart := true
+	for i := 0; i < len(kak); {
+		c := kak[i]
+		switch c {
+		case ' ', '\t':
+			i++
+			continue
+		case '\n', ';':
+			commandStart = true
+			i++
+			continue
+		case '#':
+			for i < len(kak) && kak[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		start := i
+		switch {
+		case c == '\'' || c == '"':
+			i = quotedEnd(kak, i+1, c)
+			if i == -1 {
+				return fmt.Errorf("unterminated string at byte %d: %q", start, excerpt(kak[start:]))
+			}
+		case commandStart && strings.IndexByte("})]>", c) != -1:
+			return fmt.Errorf("command starts with %q at byte %d: %q", c, start, excerpt(kak[start:]))
+		case c == '%':
+			j := i + 1
+			for j < len(kak) && isLower(kak[j]) {
+				j++
+			}
+			if j >= len(kak) || isWordEnd(kak[j]) || isAlnum(kak[j]) {
+				// a percent not starting a block is literal.
+				i = wordEnd(kak, i)
+				break
+			}
+
+			i = blockEnd(kak, j+1, kak[j])
+			if i == -1 {
+				return fmt.Errorf("unterminated block at byte %d: %q", start, excerpt(kak[start:]))
+			}
+		default:
+			i = wordEnd(kak, i)
+		}
+		commandStart = false
+	}
+	return nil
+}
+
+// quotedEnd returns the index following the end of the quoted string
+// starting at i, in which the quote is escaped by doubling it, or -1.
+func quotedEnd(s string, i int, quote byte) int {
+	for ; i < len(s); i++ {
+		if s[i] != quote {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return -1
+}
+
+// wordEnd returns the index of the end of the word starting at i.
+func wordEnd(s string, i int) int {
+	for i < len(s) && !isWordEnd(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isWordEnd(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == ';'
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isAlnum(c byte) bool {
+	return isLower(c) || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// excerpt returns the start of s, for errors.
+func excerpt(s string) string {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		s = s[:i]
+	}
+	if len(s) > 40 {
+		s = s[:40] + "..."
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckBalanced(t *testing.T) {
+	valid := []string{
+		`echo 'it''s'`,
+		`evaluate-commands %{ echo %{ nested } }; echo "say ""hi"""`,
+		`set-option buffer x %val{timestamp} '1.1,1.3|Error'`,
+		"echo 100% done # a comment's quote\necho %|a}b|",
+		`hook global BufCreate .* %sh{ printf '%s' "$kak_buffile" }`,
+	}
+	for _, s := range valid {
+		if err := CheckBalanced(s); err != nil {
+			t.Errorf("%q: %s", s, err)
+		}
+	}
+
+	invalid := []string{
+		`echo 'it''s`,
+		`hook global BufCreate .* %sh{ printf '%s' "}" }`,
+		`evaluate-commands %{ echo %{ nested }`,
+		`echo "unterminated`,
+		"echo %{ x }\n}",
+	}
+	for _, s := range invalid {
+		if err := CheckBalanced(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	defer os.Unsetenv(env_strict)
+
+	k := &Kak{funcVars: map[string]string{}}
+	if _, err := k.Var("buffile"); err == nil {
+		t.Error("expected error of unexported var")
+	}
+
+	os.Setenv(env_strict, "1")
+	panics := func(name string, f func()) {
+		defer func() {
+			r := recover()
+			if s, _ := r.(string); !strings.HasPrefix(s, "gokakoune strict: ") {
+				t.Errorf("%s: want strict panic, got %v", name, r)
+			}
+		}()
+		f()
+	}
+
+	panics("var", func() { k.Var("buffile") })
+	panics("coord", func() { _ = Coord{Line: 0, Column: 1}.String() })
+	panics("print after fail", func() {
+		k := &Kak{writer: &strings.Builder{}, running: true}
+		k.Fail("oops")
+		k.Println("echo more")
+	})
+}
@@ -1,37 +1,91 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
-// Escape the given values, wrapping it in quotes and escaping and
-// quotes so that Kakoune parses it as a single argument.
+// Escape joins the given values as fmt.Sprintln does, without the trailing
+// newline, quoted by QuoteArg so that Kakoune parses them as a single
+// argument.
 func Escape(v ...interface{}) string {
-	// TODO(leeola): figure out the fastest way to print the v...
-	// as if Sprintln did it, but WITHOUT the newline at the end.
-	//
-	// Apparently fmt.Sprint() and fmt.Sprintln() have different
-	// behavior, Sprint puts spaces between arguments only if they're
-	// not strings.. so i can't use Sprint() ...sadface.
+	// NOTE(leeola): fmt.Sprint only adds spaces between operands when
+	// neither is a string, so Sprintln is trimmed instead.
 	s := fmt.Sprintln(v...)
-	l := len(s)
-	s = s[:l-1]
-	return escapeString(s)
+	return QuoteArg(s[:len(s)-1])
 }
 
-// escapeString escapes the given string.
-func escapeString(s string) string {
-	surroundWithQuotes := strings.IndexByte(s, ' ') != -1
-	s = strings.Replace(s, `"`, `""`, -1)
-
-	if !surroundWithQuotes {
-		return s
-	}
+// EscapeString quotes s as a single argument.
+//
+// Deprecated: use QuoteArg, as EscapeString is.
+func EscapeString(s string) string {
+	return QuoteArg(s)
+}
 
-	return `"` + s + `"`
+// Quote wraps s in Kakoune single quotes, doubling any single quotes within
+// it. Single quoted strings have no expansions, so this is safe for any
+// content.
+func Quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
 }
 
-func EscapeString(s string) string {
-	return escapeString(s)
+// ParseQuotedList parses a Kakoune quoted list, as exported for
+// `kak_quoted_` vars, into its elements.
+//
+// Eg, `'foo' 'bar baz' 'it''s'` is parsed into three elements. Elements
+// quoted for the shell, such as `'it'\''s'`, are parsed as well, as
+// Kakoune quotes the vars of the shell that way.
+func ParseQuotedList(s string) ([]string, error) {
+	var (
+		list []string
+		rs   = []rune(s)
+	)
+
+	for i := 0; i < len(rs); i++ {
+		if rs[i] == ' ' || rs[i] == '\n' {
+			continue
+		}
+
+		var elem []rune
+		for ; i < len(rs) && rs[i] != ' ' && rs[i] != '\n'; i++ {
+			// an escaped quote between quoted parts of the element.
+			if rs[i] == '\\' && i+1 < len(rs) && rs[i+1] == '\'' {
+				elem = append(elem, '\'')
+				i++
+				continue
+			}
+
+			if rs[i] != '\'' {
+				return nil, fmt.Errorf("unexpected unquoted list element at %d", i)
+			}
+
+			closed := false
+			for i++; i < len(rs); i++ {
+				if rs[i] != '\'' {
+					elem = append(elem, rs[i])
+					continue
+				}
+
+				// a doubled quote is an escaped quote, anything else closes
+				// the part.
+				if i+1 < len(rs) && rs[i+1] == '\'' {
+					elem = append(elem, '\'')
+					i++
+					continue
+				}
+
+				closed = true
+				break
+			}
+
+			if !closed {
+				return nil, errors.New("unterminated list element")
+			}
+		}
+
+		list = append(list, string(elem))
+	}
+
+	return list, nil
 }
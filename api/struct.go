@@ -0,0 +1,128 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api/cmds"
+)
+
+const (
+	// maxOptionValue is the largest encoded value SaveStruct will store
+	// directly in an option.
+	//
+	// NOTE(leeola): the limit is not from Kakoune itself, but from the
+	// environment. Option values are exported to subprocs as env vars,
+	// and Linux limits a single env var to 128KiB. This is well under that
+	// to leave headroom for everything else being exported.
+	maxOptionValue = 32 * 1024
+
+	// spill_prefix marks an option value as a path to the real value.
+	//
+	// The base64 alphabet does not include a colon, so this can never be
+	// confused with an encoded value.
+	spill_prefix = "file:"
+)
+
+// SaveStruct marshals v into the given hidden str option.
+//
+// The option is declared if needed. Values too large to reasonably store
+// in an option are written to the StateDir instead, with the option
+// holding a reference to the file. This is transparent to LoadStruct, but
+// requires vars.Session to be exported to the Subproc.
+func (k *Kak) SaveStruct(scope, option string, v interface{}) error {
+	encoded, err := k.encodeOption(v, 0)
+	if err != nil {
+		return err
+	}
+
+	k.declareHiddenStr(option)
+	k.Command(cmds.SetOption, scope, option, encoded)
+
+	return nil
+}
+
+// LoadStruct unmarshals the value of the given option into v.
+//
+// The option must be exported to the Subproc, eg `opt_myoption`.
+// ErrStateNotFound is returned if the option is empty or undeclared, or if
+// the value was spilled and the file has since been removed.
+func (k *Kak) LoadStruct(option string, v interface{}) error {
+	_, err := k.loadOption(option, v)
+	return err
+}
+
+// loadOption decodes the value of the given option into v, returning the
+// timestamp it was encoded with.
+func (k *Kak) loadOption(option string, v interface{}) (int, error) {
+	// NOTE(leeola): Kakoune exports no var for an undeclared option, as is
+	// the option of a key before its first write, so a missing var is no
+	// different than an empty value.
+	encoded, ok := k.LookupVar(opt_prefix + option)
+	if !ok || encoded == "" {
+		return 0, ErrStateNotFound
+	}
+
+	if strings.HasPrefix(encoded, spill_prefix) {
+		b, err := ioutil.ReadFile(strings.TrimPrefix(encoded, spill_prefix))
+		if os.IsNotExist(err) {
+			// the spilled file was cleaned up, which is no different than
+			// the value having been removed.
+			return 0, ErrStateNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		encoded = string(b)
+	}
+
+	return k.decodeState(encoded, v)
+}
+
+// encodeOption encodes v into an option value, spilling it to the state
+// dir if needed.
+//
+// The timestamp is stored alongside the value, and is only meaningful to
+// callers which care about it, eg BufferState.
+func (k *Kak) encodeOption(v interface{}, timestamp int) (string, error) {
+	encoded, err := k.encodeState(v, timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	if len(encoded) > maxOptionValue {
+		return k.spill(encoded)
+	}
+
+	return encoded, nil
+}
+
+// spill writes the encoded value to the state dir, returning the option
+// value referencing it.
+//
+// Files are named after their content, as the same option may hold
+// different values in different buffers or windows. Old files are not
+// removed individually, they are cleaned up with the state dir.
+func (k *Kak) spill(encoded string) (string, error) {
+	dir, err := k.StateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "spill")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(encoded))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	if err := ioutil.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return "", err
+	}
+
+	return spill_prefix + path, nil
+}
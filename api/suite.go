@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// route_separator separates the route from the expansion id in the
+// arguments the generated script invokes the binary with.
+const route_separator = "."
+
+// Plugin is a single plugin within a Suite.
+type Plugin struct {
+	// Name routes invocations to the plugin, and must be unique within the
+	// suite. It is made of letters, digits, _ and -, as it is written into
+	// the shell invoking the binary as is.
+	Name string
+
+	// Init declares everything the plugin provides, as a main func would
+	// for a standalone plugin.
+	Init func(*Kak) error
+}
+
+// Suite composes several plugins into a single binary.
+//
+// Each plugin is given its own expansion IDs, prefixed by the plugin name,
+// so adding or changing one plugin never shifts the IDs of another. When
+// invoked from Kakoune, only the Init of the plugin owning the invoked
+// expansion is run.
+//
+// All plugins share the same Kak, and therefore the same PluginName, state
+// directory and registry identity. Services like a daemon or a leader user
+// mode can be declared once and used by every plugin within the suite.
+func (k *Kak) Suite(plugins ...Plugin) error {
+	seen := map[string]bool{}
+	for _, p := range plugins {
+		if !validRoute(p.Name) {
+			return fmt.Errorf("invalid suite plugin name: %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate suite plugin name: %q", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	route, count := k.route, k.expansionCount
+	defer func() {
+		k.route, k.expansionCount = route, count
+	}()
+
+	for _, p := range plugins {
+		// noop if func was already called
+		if k.funcCalled {
+			return nil
+		}
+
+		if !k.gokakouneInit && p.Name != k.funcRoute {
+			continue
+		}
+
+		k.route = p.Name
+		k.expansionCount = 0
+
+		if err := p.Init(k); err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Route returns the name of the Suite plugin currently being initialized
+// or run, or an empty string outside of a Suite.
+func (k *Kak) Route() string {
+	return k.route
+}
+
+// validRoute reports whether the name is a non-empty word of letters,
+// digits, _ and -, which needs no quoting within the shell.
+func validRoute(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case r == '_' || r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// splitRoute splits an invocation argument into its route and id, eg
+// `grep.3` into `grep` and `3`.
+func splitRoute(arg string) (string, string) {
+	i := strings.LastIndex(arg, route_separator)
+	if i == -1 {
+		return "", arg
+	}
+
+	return arg[:i], arg[i+1:]
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSuiteNames(t *testing.T) {
+	noop := func(*Kak) error { return nil }
+
+	for _, name := range []string{"", "a.b", "a b", "a;rm", "it's", "$(x)"} {
+		k := newTestKak(&bytes.Buffer{})
+		k.gokakouneInit = true
+		if err := k.Suite(Plugin{Name: name, Init: noop}); err == nil {
+			t.Errorf("%q: got no error", name)
+		}
+	}
+
+	k := newTestKak(&bytes.Buffer{})
+	k.gokakouneInit = true
+	if err := k.Suite(Plugin{Name: "git-blame_2", Init: noop}, Plugin{Name: "Lint", Init: noop}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,130 @@
+package terminal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// EscapeVars are the vars to export for Emit, and with it Copy.
+var EscapeVars = append([]string{vars.ClientPid}, Vars...)
+
+// screenChunk is the most screen passes through within a single device
+// control string.
+const screenChunk = 768
+
+// OSC52 returns the sequence setting the clipboard of the terminal to the
+// text, which terminals such as xterm, kitty and wezterm understand, even
+// over SSH.
+func OSC52(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+}
+
+// Wrap returns the sequence wrapped for the multiplexer of the client, if
+// any, to pass it through to the terminal the multiplexer runs within.
+//
+// NOTE(leeola): tmux 3.3 and later only passes sequences through with
+// `set -g allow-passthrough on`. Alternatively, tmux sets the clipboard of
+// its own terminal from OSC 52 with `set -g set-clipboard on`, in which
+// case the sequence need not be wrapped at all.
+func (e Env) Wrap(seq string) string {
+	switch e.Kind() {
+	case Tmux:
+		return "\x1bPtmux;" + strings.Replace(seq, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	case Screen:
+		var b strings.Builder
+		for len(seq) > screenChunk {
+			b.WriteString("\x1bP" + seq[:screenChunk] + "\x1b\\")
+			seq = seq[screenChunk:]
+		}
+		b.WriteString("\x1bP" + seq + "\x1b\\")
+		return b.String()
+	default:
+		return seq
+	}
+}
+
+// Emit writes the raw escape sequence to the terminal of the current
+// client, wrapped for its multiplexer, see Env.Wrap.
+//
+// EscapeVars must be exported to the Subproc. The terminal is that of the
+// client process, so the client must run on the same machine as the
+// Kakoune server, though not the terminal, such as through SSH.
+func Emit(kak *api.Kak, seq string) error {
+	pid, err := kak.Var(vars.ClientPid)
+	if err != nil {
+		return err
+	}
+
+	path, err := clientTTY(pid)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open client terminal: %s", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(ClientEnv(kak).Wrap(seq)); err != nil {
+		return fmt.Errorf("write client terminal: %s", err)
+	}
+	return nil
+}
+
+// Copy sets the clipboard of the terminal of the current client to the
+// text, with OSC 52, for sessions without a clipboard tool such as those
+// over SSH. Eg, copying the main selection:
+//
+//    api.Func{
+//        ExportVars: append([]string{vars.Selection}, terminal.EscapeVars...),
+//        Func: func(kak *api.Kak) error {
+//            sel, err := kak.Var(vars.Selection)
+//            if err != nil {
+//                return err
+//            }
+//            return terminal.Copy(kak, sel)
+//        },
+//    }
+//
+// EscapeVars must be exported to the Subproc. Terminals ignoring OSC 52
+// ignore it silently, so no error is returned for them.
+func Copy(kak *api.Kak, text string) error {
+	return Emit(kak, OSC52(text))
+}
+
+// clientTTY returns the path of the terminal of the client process of the
+// given pid.
+//
+// NOTE(leeola): the client reads its terminal from stdin, so on Linux the
+// terminal is its fd 0. Elsewhere there is no /proc, so ps names the
+// terminal instead.
+func clientTTY(pid string) (string, error) {
+	if _, err := strconv.Atoi(pid); err != nil {
+		return "", fmt.Errorf("invalid client pid: %q", pid)
+	}
+
+	path := "/proc/" + pid + "/fd/0"
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	out, err := exec.Command("ps", "-o", "tty=", "-p", pid).Output()
+	if err != nil {
+		return "", fmt.Errorf("no terminal of client pid: %q", pid)
+	}
+	tty := strings.TrimSpace(string(out))
+	if tty == "" || tty == "?" || tty == "??" {
+		return "", fmt.Errorf("no terminal of client pid: %q", pid)
+	}
+	if !strings.HasPrefix(tty, "/") {
+		tty = "/dev/" + tty
+	}
+	return tty, nil
+}
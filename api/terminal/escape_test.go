@@ -0,0 +1,43 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOSC52(t *testing.T) {
+	if got, want := OSC52("hello"), "\x1b]52;c;aGVsbG8=\x07"; got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestEnvWrap(t *testing.T) {
+	seq := OSC52("hello")
+	tests := []struct {
+		env  Env
+		want string
+	}{
+		{Env{}, seq},
+		{Env{"DISPLAY": ":0"}, seq},
+		{Env{"TMUX": "/tmp/tmux"}, "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\x07\x1b\\"},
+		{Env{"STY": "1.pts-0"}, "\x1bP" + seq + "\x1b\\"},
+	}
+
+	for _, test := range tests {
+		if got := test.env.Wrap(seq); got != test.want {
+			t.Errorf("%v: got:%q, want:%q", test.env, got, test.want)
+		}
+	}
+
+	long := strings.Repeat("x", screenChunk+1)
+	want := "\x1bP" + long[:screenChunk] + "\x1b\\\x1bPx\x1b\\"
+	if got := (Env{"STY": "1.pts-0"}).Wrap(long); got != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
+
+func TestClientTTY(t *testing.T) {
+	if _, err := clientTTY("1; rm -rf /"); err == nil {
+		t.Error("want error of an invalid pid")
+	}
+}
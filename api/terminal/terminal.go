@@ -0,0 +1,266 @@
+// Package terminal detects the terminal, multiplexer or display of a
+// Kakoune client from its environment, and opens new terminals with
+// whichever of them the client runs within. Escape sequences, such as the
+// OSC 52 of Copy, are written to the terminal of the client, see Emit.
+//
+// The environment is that of the client, not of the Kakoune server the
+// Subproc inherits, as the server is usually started by the first client
+// and may run within an entirely different terminal, or none. Eg:
+//
+//    api.Func{
+//        ExportVars: terminal.Vars,
+//        Func: func(kak *api.Kak) error {
+//            return terminal.OpenTerminal(kak, "htop")
+//        },
+//    }
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+// Kind is the mechanism new terminals are opened with.
+type Kind string
+
+const (
+	None    Kind = ""
+	Tmux    Kind = "tmux"
+	Screen  Kind = "screen"
+	Kitty   Kind = "kitty"
+	WezTerm Kind = "wezterm"
+	Wayland Kind = "wayland"
+	X11     Kind = "x11"
+)
+
+// ErrNoTerminal is returned when the client runs within nothing new
+// terminals can be opened with.
+var ErrNoTerminal = errors.New("no terminal detected, not within tmux, screen, kitty, wezterm, X11 or Wayland")
+
+// EnvVars are the environment variables of the client a terminal is
+// detected from, and which are given to the commands opening terminals.
+var EnvVars = []string{
+	"TMUX",
+	"STY",
+	"KITTY_WINDOW_ID",
+	"KITTY_LISTEN_ON",
+	"WEZTERM_PANE",
+	"WEZTERM_UNIX_SOCKET",
+	"WAYLAND_DISPLAY",
+	"DISPLAY",
+	"TERMINAL",
+}
+
+// Vars are the vars to export for ClientEnv, and with it OpenTerminal.
+// NewClient additionally requires vars.Session.
+var Vars = clientEnvVars()
+
+func clientEnvVars() []string {
+	vs := make([]string, len(EnvVars))
+	for i, name := range EnvVars {
+		vs[i] = "client_env_" + name
+	}
+	return vs
+}
+
+// Emulators are the terminal emulators opened within X11 and Wayland, in
+// order of preference, if the client has no $TERMINAL. Each is given the
+// arguments preceding the command to run.
+var Emulators = []struct {
+	Name string
+	Args []string
+
+	// WaylandOnly emulators are not used within X11.
+	WaylandOnly bool
+}{
+	{"foot", nil, true},
+	{"alacritty", []string{"-e"}, false},
+	{"kitty", nil, false},
+	{"wezterm", []string{"start", "--"}, false},
+	{"gnome-terminal", []string{"--"}, false},
+	{"konsole", []string{"-e"}, false},
+	{"xfce4-terminal", []string{"-x"}, false},
+	{"urxvt", []string{"-e"}, false},
+	{"xterm", []string{"-e"}, false},
+}
+
+// Env is the environment of a client, of the variables in EnvVars which
+// are set.
+type Env map[string]string
+
+// ClientEnv returns the environment of the current client.
+//
+// Vars must be exported to the Subproc. Kakoune omits the vars of unset
+// environment variables, so those missing are not an error.
+func ClientEnv(kak *api.Kak) Env {
+	env := Env{}
+	for i, name := range EnvVars {
+		if v, ok := kak.LookupVar(Vars[i]); ok && v != "" {
+			env[name] = v
+		}
+	}
+	return env
+}
+
+// Kind returns the mechanism new terminals of the client are opened with,
+// preferring multiplexers, then terminals with remote control, then the
+// display.
+//
+// NOTE(leeola): kitty is only controlled remotely when it listens on a
+// socket, as the Subproc has no tty of the kitty window to control it
+// through. Otherwise a new kitty is opened through the display as any other
+// emulator.
+func (e Env) Kind() Kind {
+	switch {
+	case e["TMUX"] != "":
+		return Tmux
+	case e["STY"] != "":
+		return Screen
+	case e["KITTY_WINDOW_ID"] != "" && e["KITTY_LISTEN_ON"] != "":
+		return Kitty
+	case e["WEZTERM_PANE"] != "":
+		return WezTerm
+	case e["WAYLAND_DISPLAY"] != "":
+		return Wayland
+	case e["DISPLAY"] != "":
+		return X11
+	default:
+		return None
+	}
+}
+
+// Environ returns the environment of the Subproc with the variables of the
+// client, such that commands such as tmux target the client's server.
+func (e Env) Environ() []string {
+	environ := os.Environ()
+	for _, name := range EnvVars {
+		if v, ok := e[name]; ok {
+			environ = append(environ, name+"="+v)
+		}
+	}
+	return environ
+}
+
+// Command returns the command opening a new terminal running the given
+// command and arguments, with the mechanism of the client environment.
+func (e Env) Command(args ...string) (*exec.Cmd, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given to open a terminal with")
+	}
+
+	var cmdArgs []string
+	switch kind := e.Kind(); kind {
+	case Tmux:
+		// tmux runs commands with the shell, so the arguments are quoted.
+		cmdArgs = []string{"tmux", "split-window", "-h", shellJoin(args)}
+	case Screen:
+		cmdArgs = append([]string{"screen", "-S", e["STY"], "-X", "screen"}, args...)
+	case Kitty:
+		cmdArgs = append([]string{"kitty", "@", "--to", e["KITTY_LISTEN_ON"],
+			"launch", "--no-response", "--type=window", "--cwd=current"}, args...)
+	case WezTerm:
+		cmdArgs = append([]string{"wezterm", "cli", "split-pane", "--pane-id", e["WEZTERM_PANE"], "--"}, args...)
+	case Wayland, X11:
+		em, err := e.emulator(kind)
+		if err != nil {
+			return nil, err
+		}
+		cmdArgs = append(em, args...)
+	default:
+		return nil, ErrNoTerminal
+	}
+
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Env = e.Environ()
+	return cmd, nil
+}
+
+// emulator returns the terminal emulator, and the arguments preceding the
+// command, opened within the display of the given kind.
+func (e Env) emulator(kind Kind) ([]string, error) {
+	if term := e["TERMINAL"]; term != "" {
+		for _, em := range Emulators {
+			if filepath.Base(term) == em.Name {
+				return append([]string{term}, em.Args...), nil
+			}
+		}
+		// most emulators follow xterm.
+		return []string{term, "-e"}, nil
+	}
+
+	for _, em := range Emulators {
+		if em.WaylandOnly && kind != Wayland {
+			continue
+		}
+		if _, err := exec.LookPath(em.Name); err == nil {
+			return append([]string{em.Name}, em.Args...), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no terminal emulator found for %s, set $TERMINAL", kind)
+}
+
+// OpenTerminal opens a new terminal running the given command and
+// arguments, alongside the current client.
+//
+// Vars must be exported to the Subproc. Emulators are started in the
+// background and not waited on, whereas multiplexers and remote controls
+// exit once the terminal is open, reporting any failure to open it.
+func OpenTerminal(kak *api.Kak, args ...string) error {
+	env := ClientEnv(kak)
+	cmd, err := env.Command(args...)
+	if err != nil {
+		return err
+	}
+
+	if kind := env.Kind(); kind != Wayland && kind != X11 {
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("open %s terminal: %s", kind, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	// detach from this process, so the emulator survives us exiting.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open terminal: %s", err)
+	}
+
+	return cmd.Process.Release()
+}
+
+// NewClient opens a new terminal running a new client of the session,
+// which runs the given commands once connected, if any.
+//
+// Vars and vars.Session must be exported to the Subproc.
+func NewClient(kak *api.Kak, commands string) error {
+	session, err := kak.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"kak", "-c", session}
+	if commands != "" {
+		args = append(args, "-e", commands)
+	}
+
+	return OpenTerminal(kak, args...)
+}
+
+// shellJoin quotes each argument for the shell, joined by spaces.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = util.ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
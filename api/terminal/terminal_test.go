@@ -0,0 +1,82 @@
+package terminal
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+func TestEnvKind(t *testing.T) {
+	tests := []struct {
+		env  Env
+		want Kind
+	}{
+		{Env{}, None},
+		{Env{"DISPLAY": ":0"}, X11},
+		{Env{"DISPLAY": ":0", "WAYLAND_DISPLAY": "wayland-0"}, Wayland},
+		{Env{"DISPLAY": ":0", "KITTY_WINDOW_ID": "1"}, X11},
+		{Env{"DISPLAY": ":0", "KITTY_WINDOW_ID": "1", "KITTY_LISTEN_ON": "unix:/tmp/kitty"}, Kitty},
+		{Env{"WEZTERM_PANE": "0", "WAYLAND_DISPLAY": "wayland-0"}, WezTerm},
+		{Env{"STY": "1.pts-0", "WEZTERM_PANE": "0"}, Screen},
+		{Env{"TMUX": "/tmp/tmux-1000/default,1,0", "STY": "1.pts-0", "DISPLAY": ":0"}, Tmux},
+	}
+
+	for _, test := range tests {
+		if got := test.env.Kind(); got != test.want {
+			t.Errorf("%v: got:%q, want:%q", test.env, got, test.want)
+		}
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	tests := []struct {
+		env  Env
+		want []string
+	}{
+		{Env{"TMUX": "/tmp/tmux"}, []string{"tmux", "split-window", "-h", `'kak' '-c' 'it'\''s'`}},
+		{Env{"STY": "1.pts-0"}, []string{"screen", "-S", "1.pts-0", "-X", "screen", "kak", "-c", "it's"}},
+		{Env{"KITTY_WINDOW_ID": "1", "KITTY_LISTEN_ON": "unix:/tmp/kitty"}, []string{"kitty", "@",
+			"--to", "unix:/tmp/kitty", "launch", "--no-response", "--type=window", "--cwd=current",
+			"kak", "-c", "it's"}},
+		{Env{"WEZTERM_PANE": "3"}, []string{"wezterm", "cli", "split-pane", "--pane-id", "3", "--",
+			"kak", "-c", "it's"}},
+		{Env{"DISPLAY": ":0", "TERMINAL": "/usr/bin/foot"}, []string{"/usr/bin/foot", "kak", "-c", "it's"}},
+		{Env{"WAYLAND_DISPLAY": "wayland-0", "TERMINAL": "wezterm"}, []string{"wezterm", "start", "--",
+			"kak", "-c", "it's"}},
+		{Env{"DISPLAY": ":0", "TERMINAL": "st"}, []string{"st", "-e", "kak", "-c", "it's"}},
+	}
+
+	for _, test := range tests {
+		cmd, err := test.env.Command("kak", "-c", "it's")
+		if err != nil {
+			t.Errorf("%v: %s", test.env, err)
+			continue
+		}
+		if !reflect.DeepEqual(cmd.Args, test.want) {
+			t.Errorf("%v: got:%q, want:%q", test.env, cmd.Args, test.want)
+		}
+	}
+
+	if _, err := (Env{}).Command("kak"); err != ErrNoTerminal {
+		t.Errorf("got %v, want ErrNoTerminal", err)
+	}
+}
+
+func TestClientEnvStrict(t *testing.T) {
+	os.Setenv("GOKAKOUNE_STRICT", "1")
+	defer os.Unsetenv("GOKAKOUNE_STRICT")
+
+	// Kakoune omits the vars of unset environment variables, so only TMUX
+	// is exported here.
+	kak := api.NewInvocation(api.Invocation{
+		Bin:    "/usr/bin/plugin",
+		Vars:   map[string]string{"client_env_TMUX": "/tmp/tmux"},
+		Writer: &bytes.Buffer{},
+	})
+	if got, want := ClientEnv(kak), (Env{"TMUX": "/tmp/tmux"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got:%v, want:%v", got, want)
+	}
+}
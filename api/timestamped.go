@@ -0,0 +1,91 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// TimestampedVars are the vars Stamp and StaleAgainst need exported to
+// the Subproc. vars.HistoryID may be exported as well, see StaleAgainst.
+var TimestampedVars = []string{vars.BufName, vars.Timestamp}
+
+// Timestamped is a value computed against a revision of a buffer, such as
+// diagnostics, highlighting ranges or the content sent to a daemon.
+//
+// Async work outlives the revision it was given, so results carry the
+// revision to check against the buffer before being applied.
+type Timestamped[T any] struct {
+	Buffer    string `json:"buffer"`
+	Timestamp int    `json:"timestamp"`
+
+	// HistoryID is the history id of the buffer, or -1 if vars.HistoryID
+	// was not exported when stamped.
+	HistoryID int `json:"history_id"`
+
+	Value T `json:"value"`
+}
+
+// Stamp returns the value stamped with the current revision of the buffer.
+//
+// TimestampedVars must be exported to the Subproc.
+func Stamp[T any](k *Kak, v T) (Timestamped[T], error) {
+	bufname, err := k.Var(vars.BufName)
+	if err != nil {
+		return Timestamped[T]{}, err
+	}
+
+	ts, err := k.VarInt(vars.Timestamp)
+	if err != nil {
+		return Timestamped[T]{}, err
+	}
+
+	return Timestamped[T]{
+		Buffer:    bufname,
+		Timestamp: ts,
+		HistoryID: historyID(k),
+		Value:     v,
+	}, nil
+}
+
+// StaleAgainst reports whether the buffer of the Subproc is a different
+// buffer, or has changed since the value was stamped.
+//
+// If vars.HistoryID was exported both when stamped and now, values stamped
+// before edits that were then undone are not stale, as the content is the
+// same. Otherwise any change of the timestamp is. TimestampedVars must be
+// exported to the Subproc.
+func (t Timestamped[T]) StaleAgainst(k *Kak) (bool, error) {
+	bufname, err := k.Var(vars.BufName)
+	if err != nil {
+		return false, err
+	}
+	if bufname != t.Buffer {
+		return true, nil
+	}
+
+	if id := historyID(k); id != -1 && t.HistoryID != -1 {
+		return id != t.HistoryID, nil
+	}
+
+	ts, err := k.VarInt(vars.Timestamp)
+	if err != nil {
+		return false, err
+	}
+
+	return ts != t.Timestamp, nil
+}
+
+// historyID returns the history id of the buffer, or -1 if not exported.
+func historyID(k *Kak) int {
+	v, ok := k.funcVars[var_prefix+vars.HistoryID]
+	if !ok {
+		return -1
+	}
+
+	id, err := strconv.Atoi(v)
+	if err != nil {
+		return -1
+	}
+	return id
+}
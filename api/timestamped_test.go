@@ -0,0 +1,50 @@
+package api
+
+import "testing"
+
+func TestTimestampedStaleAgainst(t *testing.T) {
+	at := func(bufname, ts, history string) *Kak {
+		vars := map[string]string{"kak_bufname": bufname, "kak_timestamp": ts}
+		if history != "" {
+			vars["kak_history_id"] = history
+		}
+		return &Kak{funcVars: vars}
+	}
+
+	v, err := Stamp(at("a.go", "5", ""), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.HistoryID != -1 {
+		t.Fatalf("want history id -1, got %d", v.HistoryID)
+	}
+
+	h, err := Stamp(at("a.go", "5", "3"), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		v     Timestamped[string]
+		k     *Kak
+		stale bool
+	}{
+		{"same", v, at("a.go", "5", ""), false},
+		{"modified", v, at("a.go", "6", ""), true},
+		{"other buffer", v, at("b.go", "5", ""), true},
+		{"undone", h, at("a.go", "7", "3"), false},
+		{"modified history", h, at("a.go", "7", "4"), true},
+		{"unstamped history", v, at("a.go", "7", "3"), true},
+	}
+
+	for _, test := range tests {
+		stale, err := test.v.StaleAgainst(test.k)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if stale != test.stale {
+			t.Errorf("%s: want stale %v, got %v", test.name, test.stale, stale)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Toggle is a feature toggled per window, such as line numbers, see
+// Kak.DefineToggle.
+type Toggle struct {
+	// Name names the toggle command, `<Name>-toggle`, the highlighter,
+	// `window/<Name>`, and the bool option, Name with underscores rather
+	// than dashes. Eg, `number-lines` defines `number-lines-toggle` and
+	// `number_lines`.
+	Name string
+
+	// Docstring describes the feature, such as `line numbers`.
+	Docstring string
+
+	// Default enables the feature within new windows, unless the option
+	// is set otherwise.
+	Default bool
+
+	// Highlighter is added to the window while enabled, if not empty,
+	// such as `number-lines -hlcursor`.
+	Highlighter string
+
+	// Enable and Disable are the commands run within the window as the
+	// feature is enabled and disabled, if any.
+	Enable  string
+	Disable string
+
+	// Indicator is the text of the `<option>_indicator` option while
+	// enabled, for the modelinefmt, such as `[num]`.
+	Indicator string
+}
+
+// option returns the name of the bool option of the toggle.
+func (t Toggle) option() string {
+	return strings.Replace(t.Name, "-", "_", -1)
+}
+
+// DefineToggle defines the toggle command of the feature, and the bool
+// option and hooks enabling it within windows as the option is set. Eg:
+//
+//    k.DefineToggle(api.Toggle{
+//        Name:        "number-lines",
+//        Docstring:   "line numbers",
+//        Highlighter: "number-lines -hlcursor",
+//        Indicator:   "[num]",
+//    })
+//
+// The option may be set within any scope, such as with `set-option global
+// number_lines true` to number the lines of every window. The indicator
+// is shown by adding its option to the modelinefmt:
+//
+//    set-option global modelinefmt "%opt{number_lines_indicator} %opt{modelinefmt}"
+func (k *Kak) DefineToggle(t Toggle) error {
+	if !isFaceName(t.Name) {
+		return fmt.Errorf("invalid toggle name: %q", t.Name)
+	}
+	opt, group := t.option(), k.PluginName()+"-"+t.Name
+
+	var enable, disable strings.Builder
+	if t.Highlighter != "" {
+		fmt.Fprintf(&enable, "try %%{ add-highlighter window/%s %s }\n", t.Name, t.Highlighter)
+		fmt.Fprintf(&disable, "try %%{ remove-highlighter window/%s }\n", t.Name)
+	}
+	if t.Enable != "" {
+		enable.WriteString(t.Enable + "\n")
+	}
+	if t.Disable != "" {
+		disable.WriteString(t.Disable + "\n")
+	}
+	fmt.Fprintf(&enable, "set-option window %s_indicator %s\n", opt, QuoteArg(t.Indicator))
+	fmt.Fprintf(&disable, "set-option window %s_indicator ''\n", opt)
+
+	for _, e := range []Expansion{
+		Option{Name: opt, Type: OptBool, Value: t.Default, Docstring: "enable " + t.Docstring},
+		Option{Name: opt + "_indicator", Type: OptStr, Hidden: true},
+		Raw(fmt.Sprintf(`remove-hooks global %[1]s
+hook -group %[1]s global WinCreate .* %%{ set-option window %[2]s %%opt{%[2]s} }
+hook -group %[1]s global WinSetOption %[2]s=true %[3]s
+hook -group %[1]s global WinSetOption %[2]s=false %[4]s`,
+			group, opt, QuoteBlock("\n"+enable.String()), QuoteBlock("\n"+disable.String()))),
+	} {
+		if err := k.Expansion(e); err != nil {
+			return err
+		}
+	}
+	k.RecordHookGroup(group, "toggle "+t.Docstring+" as "+opt+" is set")
+	if t.Highlighter != "" {
+		k.RecordHighlighter("window/"+t.Name, t.Docstring)
+	}
+
+	return k.DefineCommand(t.Name+"-toggle", DefineCommandOptions{
+		Docstring: "toggle " + t.Docstring + " within the window",
+	}, Func{
+		ExportVars: []string{opt_prefix + opt},
+		Func: func(k *Kak) error {
+			enabled, err := k.VarBool(opt_prefix + opt)
+			if err != nil {
+				return err
+			}
+			k.Printf("set-option window %s %t\n", opt, !enabled)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDefineToggle(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	err := k.DefineToggle(Toggle{
+		Name:        "number-lines",
+		Docstring:   "line numbers",
+		Highlighter: "number-lines -hlcursor",
+		Disable:     "echo '}'",
+		Indicator:   "[num]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"declare-option -docstring 'enable line numbers' bool number_lines false",
+		"declare-option -hidden str number_lines_indicator",
+		"hook -group plugin-number-lines global WinCreate .* %{ set-option window number_lines %opt{number_lines} }",
+		"hook -group plugin-number-lines global WinSetOption number_lines=true %{\n" +
+			"try %{ add-highlighter window/number-lines number-lines -hlcursor }\n" +
+			"set-option window number_lines_indicator '[num]'\n}",
+		"hook -group plugin-number-lines global WinSetOption number_lines=false %(\n" +
+			"try %{ remove-highlighter window/number-lines }\necho '}'\n",
+		"define-command -params 0 -docstring 'toggle line numbers within the window' number-lines-toggle",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got:\n%s\nwant within it:\n%s", got, want)
+		}
+	}
+	if err := CheckBalanced(got); err != nil {
+		t.Error(err)
+	}
+
+	if err := k.DefineToggle(Toggle{Name: "a b"}); err == nil {
+		t.Error("expected error of invalid name")
+	}
+
+	out.Reset()
+	k = newTestKak(out)
+	k.expansionID = 4
+	k.funcVars = map[string]string{"kak_opt_number_lines": "true"}
+	if err := k.DefineToggle(Toggle{Name: "number-lines"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "set-option window number_lines false\n"; out.String() != want {
+		t.Errorf("got:%q, want:%q", out.String(), want)
+	}
+}
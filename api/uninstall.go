@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+// DefineUninstallCommand defines a `<plugin>-uninstall` command, removing
+// everything recorded in the Manifest from the session, along with the
+// entries of the plugin in the Registry.
+//
+// Hook groups, highlighters and aliases are removed, those of buffer scope
+// from every buffer, and those of window scope from the window of every
+// client. Windows not shown in any client are not reached, Kakoune having
+// no way to list them. Kakoune has no way to remove commands, options or
+// user modes, so commands are overridden with a hidden command failing
+// with a message, and options are left as is.
+//
+// NOTE(leeola): declarations are tracked by the Manifest rather than the
+// Registry, as the Manifest is what records them while initializing. The
+// Registry only holds the services a plugin advertises to others, such as
+// its daemons, which a removed plugin no longer provides.
+//
+// As with DefineInfoCommand, the manifest is captured when this is called,
+// so it should be called after everything else the plugin declares.
+func (k *Kak) DefineUninstallCommand() error {
+	name := k.PluginName() + "-uninstall"
+	opts := DefineCommandOptions{
+		Docstring: "remove the hooks, highlighters and commands of " + k.PluginName(),
+	}
+
+	return k.DefineCommand(name, opts, uninstallScript{k: k})
+}
+
+// uninstallScript is an expansion rendering the removal of everything in
+// the manifest of k at init time.
+type uninstallScript struct {
+	k *Kak
+}
+
+func (e uninstallScript) Init(ctx Context) (string, error) {
+	m := e.k.Manifest()
+
+	// NOTE(leeola): each removal is wrapped in a try, as the user may have
+	// removed some of these themselves. One missing highlighter should not
+	// stop the rest from being removed.
+	try := func(lines *[]string, f string, v ...interface{}) {
+		*lines = append(*lines, "try %{ "+fmt.Sprintf(f, v...)+" }")
+	}
+
+	var lines, buffer, window []string
+	for _, e := range m.Hooks {
+		try(&lines, "remove-hooks global %s", e.Name)
+		try(&buffer, "remove-hooks buffer %s", e.Name)
+		try(&window, "remove-hooks window %s", e.Name)
+	}
+
+	for _, e := range m.Highlighters {
+		switch {
+		case strings.HasPrefix(e.Name, ScopeBuffer+"/"):
+			try(&buffer, "remove-highlighter %s", e.Name)
+		case strings.HasPrefix(e.Name, ScopeWindow+"/"):
+			try(&window, "remove-highlighter %s", e.Name)
+		default:
+			try(&lines, "remove-highlighter %s", e.Name)
+		}
+	}
+
+	for _, e := range m.Aliases {
+		try(&lines, "unalias %s", e.Name)
+	}
+
+	if len(buffer) != 0 {
+		lines = append(lines, "evaluate-commands -buffer * "+QuoteBlock(strings.Join(buffer, "\n")))
+	}
+	if len(window) != 0 {
+		// the removals are held in a register, rather than quoted within
+		// the shell, which only has to evaluate them within each client.
+		lines = append(lines, "evaluate-commands -save-regs w "+QuoteBlock(
+			"set-register w "+Quote(strings.Join(window, "\n"))+`
+evaluate-commands %sh{
+  for client in $kak_client_list; do
+    printf "evaluate-commands -client '%s' %%reg{w}\n" "$(printf '%s' "$client" | sed "s/'/''/g")"
+  done
+}`))
+	}
+
+	lines = append(lines, `evaluate-commands %sh{
+  eval set -- $kak_quoted_opt_`+registryOption+`
+  for entry; do
+    case $entry in
+      `+util.ShellQuote(m.Name)+`:*) printf "set-option -remove global `+registryOption+` '%s'\n" "$(printf '%s' "$entry" | sed "s/'/''/g")" ;;
+    esac
+  done
+}`)
+
+	disabled := Quote(m.Name + " is uninstalled")
+	for _, e := range m.Commands {
+		// overriding the command currently being executed is asking for
+		// trouble, so the uninstall command is left alone.
+		if e.Name == m.Name+"-uninstall" {
+			continue
+		}
+		try(&lines, "define-command -override -hidden %s %%{ fail %s }", e.Name, disabled)
+	}
+
+	lines = append(lines, "echo "+Quote(m.Name+" uninstalled"))
+
+	return strings.Join(lines, "\n  "), nil
+}
+
+func (e uninstallScript) Children() []Expansion {
+	return nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestUninstall(t *testing.T) {
+	out := &bytes.Buffer{}
+	k := newTestKak(out)
+	k.gokakouneInit = true
+
+	k.RecordHookGroup("plugin-lint", "lint on write")
+	k.RecordHighlighter("window/plugin-flags", "gutter flags")
+	k.RecordHighlighter("buffer/plugin-ranges", "underlines")
+	k.RecordHighlighter("shared/plugin", "shared highlighters")
+	k.RecordAlias(ScopeGlobal, "pl", "alias of plugin-lint")
+	if err := k.DefineUninstallCommand(); err != nil {
+		t.Fatal(err)
+	}
+
+	script := out.String()
+	for _, want := range []string{
+		"try %{ remove-hooks global plugin-lint }",
+		"try %{ remove-highlighter shared/plugin }",
+		"try %{ unalias global pl }",
+		"evaluate-commands -buffer * %{try %{ remove-hooks buffer plugin-lint }\ntry %{ remove-highlighter buffer/plugin-ranges }}",
+		"set-register w 'try %{ remove-hooks window plugin-lint }\ntry %{ remove-highlighter window/plugin-flags }'",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("got:%q, want within it:%q", script, want)
+		}
+	}
+	if err := CheckBalanced(script); err != nil {
+		t.Error(err)
+	}
+	if err := CheckScript(script); err != nil {
+		t.Error(err)
+	}
+
+	// the last shell block removes the registry entries of the plugin, as
+	// quoted for the shell by Kakoune.
+	blocks := shellBlocks(script)
+	cmd := exec.Command("sh", "-c", blocks[len(blocks)-1])
+	cmd.Env = []string{"kak_quoted_opt_" + registryOption + "='plugin:daemon:lsp:/a.sock' 'other:daemon:lsp:/b.sock' 'plugin:mode:it'\\''s:x'"}
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "set-option -remove global gokakoune_registry 'plugin:daemon:lsp:/a.sock'\n" +
+		"set-option -remove global gokakoune_registry 'plugin:mode:it''s:x'\n"
+	if string(got) != want {
+		t.Errorf("got:%q, want:%q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -10,6 +11,8 @@ const (
 	// NOTE(leeola): this is combined with var prefix in the method,
 	// so it doesn't need to be prefixed in the string.
 	opt_prefix = "opt_"
+
+	quoted_prefix = "quoted_"
 )
 
 func (k *Kak) Option(key string) (string, error) {
@@ -22,6 +25,7 @@ func (k *Kak) Var(key string) (string, error) {
 		// TODO(leeola): check the current commands to see if the given var
 		// was even specified, so a more informative error can be returned to
 		// the user.
+		strictf("var not exported: %q", key)
 
 		return "", fmt.Errorf("var not available: %q", key)
 	}
@@ -29,6 +33,15 @@ func (k *Kak) Var(key string) (string, error) {
 	return v, nil
 }
 
+// LookupVar returns the var, and whether it was exported, for vars which
+// are optional, such as those Kakoune omits for unset environment
+// variables or undeclared options. Unlike with Var, a missing var is not
+// taken for a bug of the plugin, so strict mode does not panic.
+func (k *Kak) LookupVar(key string) (string, bool) {
+	v, ok := k.funcVars[var_prefix+key]
+	return v, ok
+}
+
 func (k *Kak) VarInt(key string) (int, error) {
 	v, ok := k.funcVars[var_prefix+key]
 	if !ok {
@@ -48,9 +61,126 @@ func (k *Kak) VarInt(key string) (int, error) {
 }
 
 func (k *Kak) Arg(i int) (string, error) {
-	if i > len(k.funcArgs) {
+	if i < 0 || i >= len(k.funcArgs) {
 		return "", fmt.Errorf("argument not given: %d", i)
 	}
 
 	return k.funcArgs[i], nil
 }
+
+// VarQuotedList returns the elements of a quoted list var, such as
+// `quoted_buflist`.
+//
+// The key includes the `quoted_` prefix, and must be exported as such.
+func (k *Kak) VarQuotedList(key string) ([]string, error) {
+	v, err := k.Var(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseQuotedList(v)
+}
+
+// VarBool returns the value of a bool var, such as `opt_autoreload` of a
+// bool option.
+func (k *Kak) VarBool(key string) (bool, error) {
+	v, err := k.Var(key)
+	if err != nil {
+		return false, err
+	}
+
+	switch v {
+	case "true", "yes":
+		return true, nil
+	case "false", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("var %s is not a bool: %q", key, v)
+	}
+}
+
+// VarStrList returns the elements of a list var, such as the
+// `quoted_opt_` var of a str-list option.
+//
+// Quoted vars, with the `quoted_` prefix, are parsed losslessly, see
+// VarQuotedList. Kakoune exports the elements of other list vars joined by
+// spaces, so elements containing spaces cannot be told apart from several,
+// and are split on spaces. Lists within a single value, such as the `:`
+// separated values of some options, are split with SplitList.
+func (k *Kak) VarStrList(key string) ([]string, error) {
+	if strings.HasPrefix(key, quoted_prefix) {
+		return k.VarQuotedList(key)
+	}
+
+	v, err := k.Var(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(v), nil
+}
+
+// VarIntList returns the elements of an int-list var, such as the
+// `opt_` var of an int-list option.
+func (k *Kak) VarIntList(key string) ([]int, error) {
+	list, err := k.VarStrList(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ints := make([]int, len(list))
+	for i, s := range list {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("var %s element is not an int: %q", key, s)
+		}
+		ints[i] = n
+	}
+
+	return ints, nil
+}
+
+// SplitList splits a list within a single value separated by sep, such as
+// `foo:bar\:baz`, into its elements, unescaping escaped separators and
+// backslashes.
+//
+// Kakoune encodes lists within a value this way, such as the `|`
+// separated fields of completions, and the `:` separated lists of options
+// written by older versions.
+func SplitList(s string, sep rune) []string {
+	var (
+		list []string
+		elem []rune
+	)
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		switch {
+		case rs[i] == '\\' && i+1 < len(rs) && (rs[i+1] == sep || rs[i+1] == '\\'):
+			elem = append(elem, rs[i+1])
+			i++
+		case rs[i] == sep:
+			list = append(list, string(elem))
+			elem = elem[:0]
+		default:
+			elem = append(elem, rs[i])
+		}
+	}
+
+	if s == "" {
+		return nil
+	}
+	return append(list, string(elem))
+}
+
+// JoinList joins the elements into a single value separated by sep,
+// escaping any separators and backslashes within them, the inverse of
+// SplitList.
+func JoinList(list []string, sep rune) string {
+	escaped := make([]string, len(list))
+	for i, s := range list {
+		s = strings.Replace(s, `\`, `\\`, -1)
+		escaped[i] = strings.Replace(s, string(sep), `\`+string(sep), -1)
+	}
+	return strings.Join(escaped, string(sep))
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuotedList(t *testing.T) {
+	tests := map[string][]string{
+		``:                        nil,
+		`'foo' 'bar baz' 'it''s'`: {"foo", "bar baz", "it's"},
+		`'it'\''s' '' 'x'`:        {"it's", "", "x"},
+		`''\''' 'a'\'''\''b'`:     {"'", "a''b"},
+	}
+
+	for s, want := range tests {
+		got, err := ParseQuotedList(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got:%q, want:%q", s, got, want)
+		}
+	}
+
+	for _, s := range []string{`'unterminated`, `unquoted`} {
+		if _, err := ParseQuotedList(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestTypedVars(t *testing.T) {
+	k := &Kak{funcVars: map[string]string{
+		"kak_opt_autoreload":      "yes",
+		"kak_opt_indentwidth":     "four",
+		"kak_quoted_opt_markers":  `'.git' 'go mod'`,
+		"kak_opt_markers":         ".git go mod",
+		"kak_opt_widths":          "2 4 8",
+		"kak_opt_incrementalized": "true",
+	}}
+
+	if b, err := k.VarBool("opt_autoreload"); err != nil || !b {
+		t.Errorf("got %v, %v", b, err)
+	}
+	if _, err := k.VarBool("opt_indentwidth"); err == nil {
+		t.Error("expected error of non bool")
+	}
+
+	if l, err := k.VarStrList("quoted_opt_markers"); err != nil || !reflect.DeepEqual(l, []string{".git", "go mod"}) {
+		t.Errorf("got %q, %v", l, err)
+	}
+	if l, err := k.VarStrList("opt_markers"); err != nil || !reflect.DeepEqual(l, []string{".git", "go", "mod"}) {
+		t.Errorf("got %q, %v", l, err)
+	}
+
+	if l, err := k.VarIntList("opt_widths"); err != nil || !reflect.DeepEqual(l, []int{2, 4, 8}) {
+		t.Errorf("got %v, %v", l, err)
+	}
+	if _, err := k.VarIntList("opt_markers"); err == nil {
+		t.Error("expected error of non ints")
+	}
+	if _, err := k.VarIntList("opt_missing"); err == nil {
+		t.Error("expected error of missing var")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		``:             nil,
+		`foo`:          {"foo"},
+		`foo:bar\:baz`: {"foo", "bar:baz"},
+		`a\\:b::`:      {`a\`, "b", "", ""},
+		`c:\\\:d`:      {"c", `\:d`},
+	}
+
+	for s, want := range tests {
+		got := SplitList(s, ':')
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got:%q, want:%q", s, got, want)
+			continue
+		}
+		if got := JoinList(want, ':'); got != s {
+			t.Errorf("%q: joined as %q", s, got)
+		}
+	}
+}
@@ -3,7 +3,24 @@ package vars
 const (
 	BufName          = "bufname"
 	BufFile          = "buffile"
+	Client           = "client"
+	ClientPid        = "client_pid"
+	CommandFifo      = "command_fifo"
 	CursorByteOffset = "cursor_byte_offset"
+	CursorColumn     = "cursor_column"
+	CursorLine       = "cursor_line"
+	HistoryID        = "history_id"
+	HookParam        = "hook_param"
+	OptFiletype      = "opt_filetype"
+	PosInToken       = "pos_in_token"
+	QuotedBufList    = "quoted_buflist"
+	QuotedSelections = "quoted_selections"
+	ResponseFifo     = "response_fifo"
+	Selection        = "selection"
+	SelectionsDesc   = "selections_desc"
+	Session          = "session"
+	Timestamp        = "timestamp"
+	TokenToComplete  = "token_to_complete"
 	WindowHeight     = "window_height"
 	WindowWidth      = "window_width"
 	Text             = "text"
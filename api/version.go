@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	// env_version is set by the generated script to the version of the
+	// binary which generated it.
+	env_version = "GOKAKOUNE_VERSION"
+
+	// env_reinit is set when re-initializing over a stale script.
+	env_reinit = "GOKAKOUNE_REINIT"
+)
+
+// Version of the plugin, embedded into the generated script.
+//
+// It can be set at build time, eg:
+//
+//    go build -ldflags "-X github.com/leeola/gokakoune/api.Version=v1.2.0"
+//
+// If not set, the module version or vcs revision from the build info is
+// used, and failing that the modification time of the binary.
+var Version string
+
+// binVersion returns the version of the given plugin binary.
+func binVersion(bin string) string {
+	if Version != "" {
+		return Version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+
+	// NOTE(leeola): the mtime changes with every build, which is exactly the
+	// granularity needed to detect a rebuilt binary.
+	if fi, err := os.Stat(bin); err == nil {
+		return strconv.FormatInt(fi.ModTime().Unix(), 10)
+	}
+
+	return "unknown"
+}
+
+// binCommand returns the shell command the generated script invokes the
+// binary with, stamped with the version that generated the script.
+func (k *Kak) binCommand() string {
+	return fmt.Sprintf("%s=%s %s", env_version, util.ShellQuote(k.version), k.binExpr())
+}
+
+// staleScript reports whether the script invoking this process was
+// generated by a different version of the binary.
+func (k *Kak) staleScript() bool {
+	v := os.Getenv(env_version)
+	return v != "" && v != k.version
+}
+
+// reinitScript emits a re-initialization of the plugin, replacing the
+// stale script Kakoune has sourced, and fails the current command.
+func (k *Kak) reinitScript() {
+	k.Printf("evaluate-commands %%sh{ %s=1 %s %s }\n",
+		env_reinit, k.binExpr(), subcommandInit)
+
+	k.Printf("fail %s\n", Quote(fmt.Sprintf(
+		"%s: reloaded after upgrading from %s to %s, please retry",
+		k.PluginName(), os.Getenv(env_version), k.version)))
+}
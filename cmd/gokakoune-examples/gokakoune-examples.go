@@ -11,18 +11,18 @@ func main() {
 	err := kak.DefineCommand("gokakoune-hello",
 		api.DefineCommandOptions{}, _examples.Hello)
 	if err != nil {
-		panic(err)
+		api.Exit(err)
 	}
 
 	err = kak.DefineCommand("gokakoune-expressions",
 		api.DefineCommandOptions{}, _examples.Expressions...)
 	if err != nil {
-		panic(err)
+		api.Exit(err)
 	}
 
 	err = kak.DefineCommand("gokakoune-prompt",
 		api.DefineCommandOptions{}, _examples.Prompt...)
 	if err != nil {
-		panic(err)
+		api.Exit(err)
 	}
 }
@@ -12,14 +12,14 @@ func main() {
 	kak := api.New()
 
 	opts := api.DefineCommandOptions{}
-	kak.DefineCommand("gokakoune-compile-check", opts, compilecheck.CompileCheckExpressions...)
+	api.Exit(kak.DefineCommand("gokakoune-compile-check", opts, compilecheck.CompileCheckExpressions...))
 
 	opts = api.DefineCommandOptions{}
-	kak.DefineCommand("gokakoune-jump-def", opts, jumpdef.JumpDefExpressions...)
+	api.Exit(kak.DefineCommand("gokakoune-jump-def", opts, jumpdef.JumpDefExpressions...))
 
 	opts = api.DefineCommandOptions{}
-	kak.DefineCommand("gokakoune-show-doc", opts, showdoc.ShowDocExpressions...)
+	api.Exit(kak.DefineCommand("gokakoune-show-doc", opts, showdoc.ShowDocExpressions...))
 
 	opts = api.DefineCommandOptions{}
-	kak.DefineCommand("gokakoune-rename", opts, rename.RenameExpressions...)
+	api.Exit(kak.DefineCommand("gokakoune-rename", opts, rename.RenameExpressions...))
 }
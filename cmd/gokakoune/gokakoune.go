@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/leeola/gokakoune/scaffold"
+)
+
+const usage = `usage: gokakoune new [-module <path>] <plugin-name> [dir]
+
+Generates the skeleton of a new plugin within dir, the plugin name by
+default.`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "new" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	module := flags.String("module", "", "the Go module path of the plugin, the plugin name if empty")
+	flags.Parse(os.Args[2:])
+
+	args := flags.Args()
+	if len(args) < 1 || len(args) > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	name, dir := args[0], args[0]
+	if len(args) == 2 {
+		dir = args[1]
+	}
+
+	paths, err := scaffold.Generate(dir, scaffold.Plugin{Name: name, Module: *module})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gokakoune:", err)
+		os.Exit(1)
+	}
+	for _, p := range paths {
+		fmt.Println("created", p)
+	}
+	fmt.Printf("\nbuild and install it with:\n\n    cd %s && go mod tidy && make install\n", dir)
+}
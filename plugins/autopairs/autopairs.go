@@ -0,0 +1,216 @@
+package autopairs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/keys"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	hookGroup = "autopairs"
+
+	pairsOption = "autopairs_pairs"
+	pairsVar    = "quoted_opt_" + pairsOption
+)
+
+// Pair is an opening and closing character, which are the same for quotes.
+type Pair struct {
+	Open  rune
+	Close rune
+}
+
+// Pairs are the default pairs, unless the autopairs_pairs option is set.
+var Pairs = []Pair{
+	{'(', ')'},
+	{'[', ']'},
+	{'{', '}'},
+	{'"', '"'},
+	{'\'', '\''},
+	{'`', '`'},
+}
+
+// Filetype overrides the pairs of a single filetype.
+type Filetype struct {
+	Filetype string
+	Pairs    []Pair
+}
+
+// Register the auto-pairs hooks and commands, with the pairs of the given
+// filetypes taking precedence over the autopairs_pairs option.
+//
+// Typing an opening character inserts its closing character, typing a
+// closing character already following the cursor moves over it, and
+// deleting an opening character deletes the closing character following
+// it. The following commands are defined:
+//
+//    autopairs-enable   enable auto-pairs in the window
+//    autopairs-disable  disable auto-pairs in the window
+//    autopairs-wrap     wrap the selections in the pair of the given opening character
+//
+// The pairs are the autopairs_pairs option, as a list of opening and
+// closing characters, such as `( ) [ ]`.
+func Register(k *api.Kak, filetypes ...Filetype) error {
+	setup := fmt.Sprintf(`declare-option -docstring 'opening and closing characters of auto-pairs' str-list %s %s
+remove-hooks global %s
+hook -group %s global WinCreate .* autopairs-enable
+hook -group %s global WinSetOption filetype=.* autopairs-enable`,
+		pairsOption, optionValues(Pairs), hookGroup, hookGroup, hookGroup)
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "insert, skip and delete pairs while typing")
+
+	byFiletype := map[string][]Pair{}
+	for _, ft := range filetypes {
+		byFiletype[ft.Filetype] = ft.Pairs
+	}
+
+	err := k.DefineCommand("autopairs-enable", api.DefineCommandOptions{
+		Docstring: "enable auto-pairs in the window",
+	}, api.Func{
+		ExportVars: []string{vars.OptFiletype, pairsVar},
+		Func: func(kak *api.Kak) error {
+			filetype, err := kak.Var(vars.OptFiletype)
+			if err != nil {
+				return err
+			}
+
+			pairs, ok := byFiletype[filetype]
+			if !ok {
+				if pairs, err = optionPairs(kak); err != nil {
+					return err
+				}
+			}
+
+			kak.Printf("remove-hooks window %s\n", hookGroup)
+			kak.Print(Script(pairs))
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("autopairs-disable", api.DefineCommandOptions{
+		Docstring: "disable auto-pairs in the window",
+	}, api.Raw("remove-hooks window "+hookGroup))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("autopairs-wrap", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "wrap the selections in the pair of the given opening character",
+	}, api.Func{
+		ExportVars: []string{pairsVar},
+		Func: func(kak *api.Kak) error {
+			open, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			pairs, err := optionPairs(kak)
+			if err != nil {
+				return err
+			}
+
+			for _, p := range pairs {
+				if string(p.Open) == open || string(p.Close) == open {
+					kak.Printf("execute-keys %s\n", api.Quote(
+						"i"+keys.Key(p.Open)+keys.Esc+"a"+keys.Key(p.Close)+keys.Esc))
+					return nil
+				}
+			}
+
+			return fmt.Errorf("no pair for: %q", open)
+		},
+	})
+}
+
+// optionPairs returns the pairs of the autopairs_pairs option.
+func optionPairs(kak *api.Kak) ([]Pair, error) {
+	values, err := kak.VarQuotedList(pairsVar)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePairs(values)
+}
+
+// ParsePairs parses a list of alternating opening and closing characters.
+func ParsePairs(values []string) ([]Pair, error) {
+	if len(values)%2 != 0 {
+		return nil, errors.New("auto-pairs need a closing character for every opening")
+	}
+
+	var ps []Pair
+	for i := 0; i < len(values); i += 2 {
+		open, openSize := utf8.DecodeRuneInString(values[i])
+		close, closeSize := utf8.DecodeRuneInString(values[i+1])
+		if openSize != len(values[i]) || closeSize != len(values[i+1]) || openSize == 0 || closeSize == 0 {
+			return nil, fmt.Errorf("invalid pair: %q %q", values[i], values[i+1])
+		}
+
+		ps = append(ps, Pair{Open: open, Close: close})
+	}
+	return ps, nil
+}
+
+// Script returns the window hooks of the given pairs.
+//
+// NOTE(leeola): the hooks run on every key typed, so they are plain
+// Kakoune commands rather than Funcs, as running a process per key is far
+// too slow. Only enabling them runs a Func. Commands are quoted rather
+// than within %{}, as braces in the pairs would unbalance them.
+func Script(pairs []Pair) string {
+	var b strings.Builder
+	hook := func(name string, char rune, commands string) {
+		fmt.Fprintf(&b, "hook -group %s window %s %s %s\n",
+			hookGroup, name, api.Quote(regexQuote(char)), api.Quote(commands))
+	}
+
+	for _, p := range pairs {
+		// moves over the closing character following the cursor, by
+		// deleting it, leaving the one just typed.
+		skip := "execute-keys -draft " + api.Quote(";<a-k>"+keys.Escape(regexQuote(p.Close))+"<ret>d")
+
+		// inserts the closing character, unless a word follows.
+		insert := "try " + api.Quote("execute-keys -draft "+api.Quote(";<a-K>\\w<ret>")+
+			"; execute-keys "+api.Quote(keys.Key(p.Close)+"<left>"))
+
+		if p.Open == p.Close {
+			// quotes are not paired after a word, such as in "don't".
+			quote := "try " + api.Quote("execute-keys -draft "+api.Quote("hh<a-K>\\w<ret>")+"; "+insert)
+			hook("InsertChar", p.Open, "try "+api.Quote(skip)+" catch "+api.Quote(quote))
+		} else {
+			hook("InsertChar", p.Open, insert)
+			hook("InsertChar", p.Close, "try "+api.Quote(skip))
+		}
+
+		hook("InsertDelete", p.Open, "try "+api.Quote(skip))
+	}
+
+	return b.String()
+}
+
+// optionValues returns the quoted option values of the pairs.
+func optionValues(pairs []Pair) string {
+	values := make([]string, 0, len(pairs)*2)
+	for _, p := range pairs {
+		values = append(values, api.Quote(string(p.Open)), api.Quote(string(p.Close)))
+	}
+	return strings.Join(values, " ")
+}
+
+// regexQuote escapes the character for use within a regex.
+func regexQuote(r rune) string {
+	if strings.ContainsRune(`\^$.|?*+()[]{}`, r) {
+		return `\` + string(r)
+	}
+	return string(r)
+}
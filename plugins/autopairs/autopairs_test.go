@@ -0,0 +1,40 @@
+package autopairs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePairs(t *testing.T) {
+	got, err := ParsePairs([]string{"(", ")", "«", "»", "'", "'"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Pair{{'(', ')'}, {'«', '»'}, {'\'', '\''}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	for _, values := range [][]string{{"("}, {"((", ")"}, {"", ")"}} {
+		if _, err := ParsePairs(values); err == nil {
+			t.Errorf("%q: want error", values)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	got := Script([]Pair{{'{', '}'}})
+
+	want := []string{
+		`hook -group autopairs window InsertChar '\{' 'try ''execute-keys -draft '''';<a-K>\w<ret>''''; execute-keys ''''}<left>'''''''`,
+		`hook -group autopairs window InsertChar '\}' 'try ''execute-keys -draft '''';<a-k>\}<ret>d'''''''`,
+		`hook -group autopairs window InsertDelete '\{' 'try ''execute-keys -draft '''';<a-k>\}<ret>d'''''''`,
+	}
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+}
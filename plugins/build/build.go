@@ -0,0 +1,418 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/lint"
+	"github.com/leeola/gokakoune/plugins/project"
+	"github.com/leeola/gokakoune/plugins/results"
+)
+
+// DefaultPattern parses the `file:line:col: message` locations output by
+// most compilers and test runners, the column and severity being optional.
+var DefaultPattern = lint.MustPattern(
+	`^\s*(?P<file>[^:\s]+):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?:(?P<severity>error|warning|note|info)[^:]*:)?\s*(?P<message>.+)$`)
+
+// Runner runs a build or test command in the background.
+//
+// Registering a Runner named "build" defines:
+//
+//    build           run the command, streaming its output into *build*
+//    build-next      jump to the next problem found
+//    build-previous  jump to the previous problem found
+//    build-jump      jump to the problem under the cursor of *build*
+//
+// Note that Kakoune itself defines a make command, so naming a Runner
+// "make" requires the bundled make script to not be loaded.
+type Runner struct {
+	Name string
+
+	// Command is run in the project root of the buffer, or the working
+	// directory of Kakoune outside of a project.
+	Command []string
+
+	// Patterns parse the problems from each line of output, the first
+	// matching pattern winning. Defaults to DefaultPattern.
+	Patterns []lint.Parser
+}
+
+// Problem is a location parsed from the output of a Runner.
+type Problem struct {
+	lint.Diagnostic
+
+	// OutputLine is the line of the output buffer the problem was parsed
+	// from.
+	OutputLine int
+}
+
+// runState is stored in the global State after each run, for the commands
+// navigating the problems.
+type runState struct {
+	Problems []Problem
+	Current  int
+}
+
+func (r Runner) buffer() string {
+	return "*" + r.Name + "*"
+}
+
+func (r Runner) option(suffix string) string {
+	return api.OptionName(r.Name) + "_" + suffix
+}
+
+func (r Runner) stateKey() string {
+	return r.option("problems")
+}
+
+// log returns the file the output of the last run is copied to, for
+// parsing once the run completes.
+func (r Runner) log(kak *api.Kak) (string, error) {
+	dir, err := kak.StateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "build")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, api.OptionName(r.Name)+".log"), nil
+}
+
+// Register the commands, highlighters and mappings of the given runners.
+func Register(k *api.Kak, runners ...Runner) error {
+	for _, r := range runners {
+		if r.Name == "" || len(r.Command) == 0 {
+			return errors.New("runner needs a Name and Command")
+		}
+
+		if err := register(k, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func register(k *api.Kak, r Runner) error {
+	if len(r.Patterns) == 0 {
+		r.Patterns = []lint.Parser{DefaultPattern}
+	}
+
+	setup := fmt.Sprintf(`declare-option -hidden line-specs %[1]s
+declare-option -hidden int %[2]s 0
+try %%{ add-highlighter global/%[3]s-flags flag-lines default %[1]s }
+remove-hooks global %[3]s
+hook -group %[3]s global WinSetOption filetype=%[3]s %%{
+  add-highlighter window/%[3]s line %%{%%opt{%[2]s}} default+b
+  map window normal <ret> ':%[3]s-jump<ret>'
+  hook -once -always window WinSetOption filetype=.* %%{
+    remove-highlighter window/%[3]s
+    unmap window normal <ret> ':%[3]s-jump<ret>'
+  }
+}`, r.option("flags"), r.option("current_line"), r.Name)
+
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(r.Name, "highlight the "+r.buffer()+" buffer")
+	k.RecordHighlighter("global/"+r.Name+"-flags", r.Name+" gutter flags")
+
+	err := k.DefineCommand(r.Name, api.DefineCommandOptions{
+		Docstring: "run " + strings.Join(r.Command, " ") + ", listing problems in " + r.buffer(),
+	}, api.Func{
+		ExportVars: []string{
+			vars.Client,
+			vars.Session,
+			project.RootVar,
+		},
+		Func: func(kak *api.Kak) error {
+			return r.run(kak)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand(r.Name+"-done", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "parse the problems of the completed " + r.Name + ", run in the given directory",
+	}, api.Func{
+		ExportVars: []string{
+			vars.Session,
+			k.StateVar(r.stateKey()),
+		},
+		Func: func(kak *api.Kak) error {
+			return r.done(kak)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	moveVars := []string{
+		vars.Session,
+		k.StateVar(r.stateKey()),
+	}
+
+	err = k.DefineCommand(r.Name+"-next", api.DefineCommandOptions{
+		Docstring: "jump to the next " + r.Name + " problem",
+	}, api.Func{
+		ExportVars: moveVars,
+		Func: func(kak *api.Kak) error {
+			return r.move(kak, 1)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand(r.Name+"-previous", api.DefineCommandOptions{
+		Docstring: "jump to the previous " + r.Name + " problem",
+	}, api.Func{
+		ExportVars: moveVars,
+		Func: func(kak *api.Kak) error {
+			return r.move(kak, -1)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand(r.Name+"-jump", api.DefineCommandOptions{
+		Docstring: "jump to the " + r.Name + " problem under the cursor",
+	}, api.Func{
+		ExportVars: []string{
+			vars.CursorLine,
+			vars.Session,
+			k.StateVar(r.stateKey()),
+		},
+		Func: func(kak *api.Kak) error {
+			var s runState
+			if err := kak.State().Get(r.stateKey(), &s); err != nil && err != api.ErrStateNotFound {
+				return err
+			}
+
+			line, err := kak.VarInt(vars.CursorLine)
+			if err != nil {
+				return err
+			}
+
+			for i, p := range s.Problems {
+				if p.OutputLine == line {
+					s.Current = i
+					return r.jump(kak, s)
+				}
+			}
+
+			return errors.New("no problem on this line")
+		},
+	})
+}
+
+// run starts the command in the background, streaming its output into the
+// output buffer. Once complete, the output is parsed by the done command,
+// called back through kak -p.
+func (r Runner) run(kak *api.Kak) error {
+	client, err := kak.Var(vars.Client)
+	if err != nil {
+		return err
+	}
+
+	session, err := kak.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+
+	log, err := r.log(kak)
+	if err != nil {
+		return err
+	}
+
+	dir, err := project.Root(kak)
+	if err != nil {
+		return err
+	}
+
+	done := r.Name + "-done " + api.Quote(dir)
+	if client != "" {
+		done = fmt.Sprintf("evaluate-commands -try-client %s %s", api.Quote(client), done)
+	}
+
+	// NOTE(leeola): the output is copied into the log as it is streamed,
+	// as the output buffer may be edited or closed by the time the command
+	// completes.
+	script := `log=$1 done=$2 session=$3
+shift 3
+{ "$@"; printf '\n%s exited %d\n' "$1" "$?"; } 2>&1 | tee "$log"
+printf '%s\n' "$done" | kak -p "$session"`
+
+	args := append([]string{"-c", script, "sh", log, done, session}, r.Command...)
+	if err := results.Stream(kak, r.buffer(), dir, "sh", args...); err != nil {
+		return err
+	}
+
+	kak.Printf("set-option buffer filetype %s\n", r.Name)
+	kak.Printf("set-option buffer %s 0\n", r.option("current_line"))
+
+	return nil
+}
+
+// done parses the output of the completed run, replacing the flags of the
+// previous run with those of the problems found.
+func (r Runner) done(kak *api.Kak) error {
+	var prev runState
+	if err := kak.State().Get(r.stateKey(), &prev); err != nil && err != api.ErrStateNotFound {
+		return err
+	}
+
+	log, err := r.log(kak)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(log)
+	if err != nil {
+		return err
+	}
+
+	dir, err := kak.Arg(0)
+	if err != nil {
+		return err
+	}
+
+	problems := parse(string(b), r.Patterns)
+
+	// problems are jumped to from any working directory.
+	for i, p := range problems {
+		if !filepath.IsAbs(p.File) {
+			problems[i].File = filepath.Join(dir, p.File)
+		}
+	}
+
+	for _, file := range files(prev.Problems) {
+		kak.Printf("try %%{ evaluate-commands -buffer %s %%{ unset-option buffer %s } }\n",
+			api.Quote(file), r.option("flags"))
+	}
+
+	var errCount, warnings int
+	flags := map[string][]api.LineSpec{}
+	for _, p := range problems {
+		switch p.Severity {
+		case lint.SeverityError:
+			errCount++
+		case lint.SeverityWarning:
+			warnings++
+		}
+
+		flags[p.File] = append(flags[p.File], api.LineSpec{Line: p.Line, Flag: "{" + face(p.Severity) + "}●"})
+	}
+
+	// the flags are only set on buffers already open, problems in other
+	// files are still listed in the output buffer.
+	for _, file := range files(problems) {
+		kak.Printf("try %%{ evaluate-commands -buffer %s %%{ set-option buffer %s %%val{timestamp} %s } }\n",
+			api.Quote(file), r.option("flags"), api.FormatLineSpecs(flags[file]))
+	}
+
+	if err := kak.State().Set(api.ScopeGlobal, r.stateKey(), runState{
+		Problems: problems,
+		Current:  -1,
+	}); err != nil {
+		return err
+	}
+
+	kak.Printf("echo -- %s\n", api.Quote(fmt.Sprintf(
+		"%s: %d errors, %d warnings", r.Name, errCount, warnings)))
+
+	return nil
+}
+
+// move jumps by delta problems from the current problem, wrapping around.
+func (r Runner) move(kak *api.Kak, delta int) error {
+	var s runState
+	if err := kak.State().Get(r.stateKey(), &s); err != nil && err != api.ErrStateNotFound {
+		return err
+	}
+
+	if len(s.Problems) == 0 {
+		return fmt.Errorf("no %s problems", r.Name)
+	}
+
+	// Current starts before the first problem, so moving backwards first
+	// wraps around to the last.
+	if s.Current < 0 && delta < 0 {
+		s.Current = 0
+	}
+	s.Current = (s.Current + delta + len(s.Problems)) % len(s.Problems)
+
+	return r.jump(kak, s)
+}
+
+// jump opens the current problem of s in the jumpclient, saving s.
+func (r Runner) jump(kak *api.Kak, s runState) error {
+	p := s.Problems[s.Current]
+
+	kak.Printf("evaluate-commands -try-client %%opt{jumpclient} %%{ %s }\n",
+		paths.EditExisting(p.File, api.Coord{Line: p.Line, Column: p.Column}))
+	kak.Printf("try %%{ set-option %s %s %d }\n",
+		api.Quote("buffer="+r.buffer()), r.option("current_line"), p.OutputLine)
+	kak.Printf("echo -- %s\n", api.Quote(fmt.Sprintf("%d/%d %s: %s",
+		s.Current+1, len(s.Problems), p.Severity, p.Message)))
+
+	return kak.State().Set(api.ScopeGlobal, r.stateKey(), s)
+}
+
+// parse parses each line of the output with the first matching pattern.
+func parse(output string, patterns []lint.Parser) []Problem {
+	var problems []Problem
+	for i, line := range strings.Split(output, "\n") {
+		for _, p := range patterns {
+			diags, err := p(line)
+			if err != nil || len(diags) == 0 || diags[0].File == "" {
+				continue
+			}
+
+			problems = append(problems, Problem{
+				Diagnostic: diags[0],
+				OutputLine: i + 1,
+			})
+			break
+		}
+	}
+	return problems
+}
+
+// files returns the distinct files of the problems, in order.
+func files(problems []Problem) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, p := range problems {
+		if !seen[p.File] {
+			seen[p.File] = true
+			files = append(files, p.File)
+		}
+	}
+	return files
+}
+
+// face returns the face of the gutter flag of the severity.
+func face(s lint.Severity) string {
+	switch s {
+	case lint.SeverityWarning:
+		return "yellow"
+	case lint.SeverityInfo:
+		return "cyan"
+	default:
+		return "Error"
+	}
+}
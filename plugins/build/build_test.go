@@ -0,0 +1,63 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/kaktest"
+	"github.com/leeola/gokakoune/plugins/lint"
+)
+
+func TestParse(t *testing.T) {
+	output := `# github.com/leeola/foo
+./main.go:12:5: undefined: x
+--- FAIL: TestFoo (0.00s)
+    foo_test.go:30: expected 1
+src/a.c:3:1: warning: unused variable 'y' [-Wunused-variable]
+FAIL
+
+go exited 2
+`
+
+	want := []Problem{
+		{Diagnostic: lint.Diagnostic{File: "./main.go", Line: 12, Column: 5,
+			Severity: lint.SeverityError, Message: "undefined: x"}, OutputLine: 2},
+		{Diagnostic: lint.Diagnostic{File: "foo_test.go", Line: 30, Column: 1,
+			Severity: lint.SeverityError, Message: "expected 1"}, OutputLine: 4},
+		{Diagnostic: lint.Diagnostic{File: "src/a.c", Line: 3, Column: 1,
+			Severity: lint.SeverityWarning, Message: "unused variable 'y' [-Wunused-variable]"}, OutputLine: 5},
+	}
+
+	got := parse(output, []lint.Parser{DefaultPattern})
+	if len(got) != len(want) {
+		t.Fatalf("want %d problems, got %d: %+v", len(want), len(got), got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problem %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestJump(t *testing.T) {
+	r := Runner{Name: "build"}
+	s := runState{Problems: []Problem{
+		{Diagnostic: lint.Diagnostic{File: "main.go", Line: 3, Column: 2, Message: "undefined: x"}, OutputLine: 7},
+	}}
+
+	res, err := kaktest.New().Run(api.Func{
+		Func: func(kak *api.Kak) error {
+			return r.jump(kak, s)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the scope is quoted as a whole, quotes within a word are literal.
+	if want := "try %{ set-option 'buffer=*build*' build_current_line 7 }\n"; !strings.Contains(res.Output, want) {
+		t.Errorf("want %q within %q", want, res.Output)
+	}
+}
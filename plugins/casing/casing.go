@@ -0,0 +1,150 @@
+package casing
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// Words returns the words of the identifier, split at underscores,
+// hyphens and changes of case. Eg, `parseHTTPRequest2` is split into
+// `parse`, `HTTP` and `Request2`.
+func Words(ident string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) != 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	runes := []rune(ident)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) != 0:
+			prev := word[len(word)-1]
+			// an upper case letter starts a word after a lower case one, or
+			// ends an acronym before a lower case one, as in `HTTPRequest`.
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// CamelCase returns the identifier as camelCase.
+func CamelCase(ident string) string {
+	words := Words(ident)
+	for i, w := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(w)
+		} else {
+			words[i] = title(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// PascalCase returns the identifier as PascalCase.
+func PascalCase(ident string) string {
+	words := Words(ident)
+	for i, w := range words {
+		words[i] = title(w)
+	}
+	return strings.Join(words, "")
+}
+
+// SnakeCase returns the identifier as snake_case.
+func SnakeCase(ident string) string {
+	return strings.ToLower(strings.Join(Words(ident), "_"))
+}
+
+// ScreamingSnakeCase returns the identifier as SCREAMING_SNAKE_CASE.
+func ScreamingSnakeCase(ident string) string {
+	return strings.ToUpper(strings.Join(Words(ident), "_"))
+}
+
+// KebabCase returns the identifier as kebab-case.
+func KebabCase(ident string) string {
+	return strings.ToLower(strings.Join(Words(ident), "-"))
+}
+
+// title returns the word lower cased, save its first letter.
+func title(w string) string {
+	runes := []rune(strings.ToLower(w))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// identRe matches the identifiers of a selection.
+var identRe = regexp.MustCompile(`[\p{L}\p{N}_-]+`)
+
+// Convert returns the text with each identifier within it converted, and
+// anything between them left as is.
+func Convert(text string, f func(string) string) string {
+	return identRe.ReplaceAllStringFunc(text, func(ident string) string {
+		if len(Words(ident)) == 0 {
+			return ident
+		}
+		return f(ident)
+	})
+}
+
+// Register the case conversion commands, converting the identifiers
+// within the selections.
+//
+// The following commands are defined:
+//
+//    case-camel            convert to camelCase
+//    case-pascal           convert to PascalCase
+//    case-snake            convert to snake_case
+//    case-screaming-snake  convert to SCREAMING_SNAKE_CASE
+//    case-kebab            convert to kebab-case
+func Register(k *api.Kak) error {
+	commands := []struct {
+		name, docstring string
+		f               func(string) string
+	}{
+		{"case-camel", "convert the identifiers of the selections to camelCase", CamelCase},
+		{"case-pascal", "convert the identifiers of the selections to PascalCase", PascalCase},
+		{"case-snake", "convert the identifiers of the selections to snake_case", SnakeCase},
+		{"case-screaming-snake", "convert the identifiers of the selections to SCREAMING_SNAKE_CASE", ScreamingSnakeCase},
+		{"case-kebab", "convert the identifiers of the selections to kebab-case", KebabCase},
+	}
+	for _, c := range commands {
+		f := c.f
+		err := k.DefineCommand(c.name, api.DefineCommandOptions{
+			Docstring: c.docstring,
+		}, api.Func{
+			ExportVars: []string{vars.QuotedSelections},
+			Func: func(kak *api.Kak) error {
+				texts, err := kak.SelectionTexts()
+				if err != nil {
+					return err
+				}
+				for i, text := range texts {
+					texts[i] = Convert(text, f)
+				}
+				kak.ReplaceSelections(texts)
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package casing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := map[string][]string{
+		"parseHTTPRequest2": {"parse", "HTTP", "Request2"},
+		"snake_case_name":   {"snake", "case", "name"},
+		"kebab-case":        {"kebab", "case"},
+		"PascalCase":        {"Pascal", "Case"},
+		"ID":                {"ID"},
+		"__x__":             {"x"},
+		"v2Api":             {"v2", "Api"},
+	}
+	for in, want := range tests {
+		if got := Words(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: want %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		f        func(string) string
+		in, want string
+	}{
+		{CamelCase, "parse_http_request", "parseHttpRequest"},
+		{PascalCase, "parse-http-request", "ParseHttpRequest"},
+		{SnakeCase, "parseHTTPRequest", "parse_http_request"},
+		{ScreamingSnakeCase, "maxRetries", "MAX_RETRIES"},
+		{KebabCase, "MyCommand", "my-command"},
+		{SnakeCase, "fooBar(bazQux) - x", "foo_bar(baz_qux) - x"},
+	}
+	for _, test := range tests {
+		if got := Convert(test.in, test.f); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+}
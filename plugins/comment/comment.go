@@ -0,0 +1,281 @@
+package comment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	lineOption       = "comment_toggle_line"
+	blockBeginOption = "comment_toggle_block_begin"
+	blockEndOption   = "comment_toggle_block_end"
+)
+
+// Tokens are the comment tokens of a filetype.
+type Tokens struct {
+	// Line starts a line comment, such as `//`.
+	Line string
+
+	// BlockBegin and BlockEnd surround a block comment, such as `/*` and
+	// `*/`.
+	BlockBegin string
+	BlockEnd   string
+}
+
+// Filetypes are the default tokens of filetypes, unless the options are
+// set.
+var Filetypes = map[string]Tokens{
+	"c":          {Line: "//", BlockBegin: "/*", BlockEnd: "*/"},
+	"cpp":        {Line: "//", BlockBegin: "/*", BlockEnd: "*/"},
+	"css":        {BlockBegin: "/*", BlockEnd: "*/"},
+	"go":         {Line: "//", BlockBegin: "/*", BlockEnd: "*/"},
+	"html":       {BlockBegin: "<!--", BlockEnd: "-->"},
+	"javascript": {Line: "//", BlockBegin: "/*", BlockEnd: "*/"},
+	"kak":        {Line: "#"},
+	"lua":        {Line: "--", BlockBegin: "--[[", BlockEnd: "]]"},
+	"makefile":   {Line: "#"},
+	"python":     {Line: "#"},
+	"rust":       {Line: "//", BlockBegin: "/*", BlockEnd: "*/"},
+	"sh":         {Line: "#"},
+	"sql":        {Line: "--", BlockBegin: "/*", BlockEnd: "*/"},
+	"toml":       {Line: "#"},
+	"typescript": {Line: "//", BlockBegin: "/*", BlockEnd: "*/"},
+	"yaml":       {Line: "#"},
+}
+
+// ToggleLines comments the lines of the text with the line token, or
+// uncomments them if every line but blank ones is already commented.
+//
+// The token is inserted at the least indentation of the lines, followed
+// by a space, so the indentation of the lines relative to each other is
+// preserved. Uncommenting removes the token and the space following it.
+func ToggleLines(text, token string) string {
+	lines := strings.SplitAfter(text, "\n")
+
+	commented, indent := true, -1
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, token) {
+			commented = false
+		}
+		if n := len(line) - len(trimmed); indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	if indent == -1 {
+		return text
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+
+		if commented {
+			rest := strings.TrimPrefix(trimmed, token)
+			rest = strings.TrimPrefix(rest, " ")
+			lines[i] = line[:len(line)-len(trimmed)] + rest
+		} else {
+			lines[i] = line[:indent] + token + " " + line[indent:]
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// ToggleBlock surrounds the text with the block tokens, or strips them if
+// the text, ignoring surrounding whitespace, is already surrounded.
+func ToggleBlock(text, begin, end string) string {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, begin) && strings.HasSuffix(trimmed[len(begin):], end) {
+		start := strings.Index(text, begin)
+		stop := strings.LastIndex(text, end)
+		inner := text[start+len(begin) : stop]
+		if strings.HasPrefix(inner, " ") && strings.HasSuffix(inner, " ") && len(inner) >= 2 {
+			inner = inner[1 : len(inner)-1]
+		}
+		return text[:start] + inner + text[stop+len(end):]
+	}
+
+	// whitespace around the text stays outside of the comment, such as
+	// the newline of a line.
+	start := len(text) - len(strings.TrimLeft(text, " \t\n"))
+	stop := len(strings.TrimRight(text, " \t\n"))
+	if start >= stop {
+		return text
+	}
+	return text[:start] + begin + " " + text[start:stop] + " " + end + text[stop:]
+}
+
+// tokenVars are the vars read by tokens.
+var tokenVars = []string{
+	vars.OptFiletype,
+	"opt_" + lineOption,
+	"opt_" + blockBeginOption,
+	"opt_" + blockEndOption,
+}
+
+// tokens returns the tokens of the buffer, those of the options taking
+// precedence over those of its filetype.
+func tokens(kak *api.Kak, filetypes map[string]Tokens) (Tokens, error) {
+	filetype, err := kak.Var(vars.OptFiletype)
+	if err != nil {
+		return Tokens{}, err
+	}
+	t := filetypes[filetype]
+
+	for _, o := range []struct {
+		name  string
+		token *string
+	}{
+		{lineOption, &t.Line},
+		{blockBeginOption, &t.BlockBegin},
+		{blockEndOption, &t.BlockEnd},
+	} {
+		v, err := kak.Option(o.name)
+		if err != nil {
+			return Tokens{}, err
+		}
+		if v != "" {
+			*o.token = v
+		}
+	}
+	return t, nil
+}
+
+// wholeLines returns the expansion selecting the whole lines of the
+// selections within a draft context, in which the expansions run.
+//
+// NOTE(leeola): the draft context restores the selections once done, and
+// Kakoune adjusts them to the replaced lines, so the selections the user
+// made are kept.
+func wholeLines(exps ...api.Expansion) api.Evaluate {
+	return api.Draft(append([]api.Expansion{api.Raw("execute-keys x")}, exps...)...)
+}
+
+// Register the comment commands, with the tokens of the given filetypes
+// merged over those of Filetypes.
+//
+// The following commands are defined:
+//
+//    comment-toggle-line   toggle line comments of the lines of the selections
+//    comment-toggle-block  toggle block comments around the selections
+//
+// They are not named comment-line and comment-block, as the comment.kak
+// bundled with Kakoune defines those.
+//
+// The tokens of a buffer are those of its filetype, unless the
+// comment_toggle_line, comment_toggle_block_begin and
+// comment_toggle_block_end options are set, such as:
+//
+//    hook global WinSetOption filetype=nim %{
+//        set-option buffer comment_toggle_line '#'
+//    }
+//
+// comment-toggle-line comments with a block comment per line, for filetypes
+// without a line token.
+func Register(k *api.Kak, filetypes map[string]Tokens) error {
+	merged := map[string]Tokens{}
+	for ft, t := range Filetypes {
+		merged[ft] = t
+	}
+	for ft, t := range filetypes {
+		merged[ft] = t
+	}
+
+	for _, o := range []struct {
+		name, docstring string
+	}{
+		{lineOption, "token starting line comments, over that of the filetype"},
+		{blockBeginOption, "token beginning block comments, over that of the filetype"},
+		{blockEndOption, "token ending block comments, over that of the filetype"},
+	} {
+		err := k.Expansion(api.Option{Name: o.name, Type: api.OptStr, Docstring: o.docstring})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := k.DefineCommand("comment-toggle-line", api.DefineCommandOptions{
+		Docstring: "toggle line comments of the lines of the selections",
+	}, wholeLines(api.Func{
+		ExportVars: append([]string{vars.QuotedSelections}, tokenVars...),
+		Func: func(kak *api.Kak) error {
+			t, err := tokens(kak, merged)
+			if err != nil {
+				return err
+			}
+
+			toggle := func(text string) string { return ToggleLines(text, t.Line) }
+			if t.Line == "" {
+				if t.BlockBegin == "" || t.BlockEnd == "" {
+					return noTokens(kak)
+				}
+				toggle = func(text string) string {
+					return toggleBlockLines(text, t.BlockBegin, t.BlockEnd)
+				}
+			}
+			return replace(kak, toggle)
+		},
+	}))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("comment-toggle-block", api.DefineCommandOptions{
+		Docstring: "toggle block comments around the selections",
+	}, api.Func{
+		ExportVars: append([]string{vars.QuotedSelections}, tokenVars...),
+		Func: func(kak *api.Kak) error {
+			t, err := tokens(kak, merged)
+			if err != nil {
+				return err
+			}
+			if t.BlockBegin == "" || t.BlockEnd == "" {
+				return noTokens(kak)
+			}
+
+			return replace(kak, func(text string) string {
+				return ToggleBlock(text, t.BlockBegin, t.BlockEnd)
+			})
+		},
+	})
+}
+
+// toggleBlockLines toggles a block comment around each line of the text.
+func toggleBlockLines(text, begin, end string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		lines[i] = ToggleBlock(line, begin, end)
+	}
+	return strings.Join(lines, "")
+}
+
+// noTokens returns the error of a buffer without the comment tokens
+// needed.
+func noTokens(kak *api.Kak) error {
+	filetype, _ := kak.Var(vars.OptFiletype)
+	return fmt.Errorf("no comment tokens for filetype: %q", filetype)
+}
+
+// replace replaces each selection with the text returned by f.
+func replace(kak *api.Kak, f func(string) string) error {
+	sels, err := kak.SelectionTexts()
+	if err != nil {
+		return err
+	}
+
+	values := make([]string, len(sels))
+	for i, sel := range sels {
+		values[i] = f(sel)
+	}
+
+	kak.ReplaceSelections(values)
+	return nil
+}
@@ -0,0 +1,42 @@
+package comment
+
+import "testing"
+
+func TestToggleLines(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"foo()\n", "// foo()\n"},
+		{"\tif x {\n\t\ty()\n\n\t}\n", "\t// if x {\n\t// \ty()\n\n\t// }\n"},
+		{"\t// if x {\n\t// \ty()\n\n\t// }\n", "\tif x {\n\t\ty()\n\n\t}\n"},
+		{"//foo\n", "foo\n"},
+		{"// a\nb\n", "// // a\n// b\n"},
+		{"\n  \n", "\n  \n"},
+	}
+	for _, test := range tests {
+		if got := ToggleLines(test.in, "//"); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+}
+
+func TestToggleBlock(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"foo", "/* foo */"},
+		{"  foo\n", "  /* foo */\n"},
+		{"/* foo */", "foo"},
+		{"  /*foo*/\n", "  foo\n"},
+		{" \n", " \n"},
+	}
+	for _, test := range tests {
+		if got := ToggleBlock(test.in, "/*", "*/"); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+
+	if got, want := toggleBlockLines("a {\n  b\n", "<!--", "-->"), "<!-- a { -->\n  <!-- b -->\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/cmds"
 	"github.com/leeola/gokakoune/api/vars"
 	"github.com/leeola/gokakoune/errorlines"
 )
@@ -28,8 +29,8 @@ var CompileCheckExpressions = api.Expansions{
 
 			// if there are no lines, clear the err output
 			if len(errLines) == 0 {
-				kak.Command("set-option", "buffer", "code_err", "false")
-				kak.Command("remove-highlighter", "window/flag-lines_default_code_errors")
+				kak.Command(cmds.SetOption, "buffer", "code_err", "false")
+				kak.Command(cmds.RemoveHighlighter, "window/flag-lines_default_code_errors")
 				return nil
 			}
 
@@ -65,16 +66,16 @@ var CompileCheckExpressions = api.Expansions{
 			// is just assuming the same file, but that's faulty.
 
 			// TODO(leeola): make all these commands native Go commands.
-			kak.Command("set-option", "buffer", "code_err_file", first_file)
-			kak.Command("set-option", "buffer", "code_err_line", first_line)
-			kak.Command("set-option", "buffer", "code_err_desc", first_desc)
+			kak.Command(cmds.SetOption, "buffer", "code_err_file", first_file)
+			kak.Command(cmds.SetOption, "buffer", "code_err_line", first_line)
+			kak.Command(cmds.SetOption, "buffer", "code_err_desc", first_desc)
 
 			// Clear previously assigned hightlighter. Otherwise kak fails.
-			kak.Command("remove-highlighter", "window/flag-lines_default_code_errors")
+			kak.Command(cmds.RemoveHighlighter, "window/flag-lines_default_code_errors")
 
-			kak.Command("add-highlighter", "window/", "flag-lines", "default", "code_errors")
-			kak.Command("set-option", "buffer", "code_err", "true")
-			kak.Command("set-option", append([]interface{}{"global", "code_errors", time.Now().Unix()}, code_errors_line...)...)
+			kak.Command(cmds.AddHighlighter, "window/", "flag-lines", "default", "code_errors")
+			kak.Command(cmds.SetOption, "buffer", "code_err", "true")
+			kak.Command(cmds.SetOption, append([]interface{}{"global", "code_errors", time.Now().Unix()}, code_errors_line...)...)
 
 			return nil
 		},
@@ -0,0 +1,219 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/plugins/lsp"
+)
+
+// daemonEnv marks the process as the daemon, when dap-start reruns itself.
+const daemonEnv = "GOKAKOUNE_DAP_DAEMON"
+
+// daemon owns the debug adapter of the session, serving the Funcs of the
+// debugger commands over a unix socket.
+//
+// The daemon protocol is JSON-RPC, through lsp.Conn, while the adapter is
+// spoken to with the debug adapter protocol.
+type daemon struct {
+	adapter Adapter
+	session string
+	conn    *Conn
+
+	// breakpoints are sent once the adapter is initialized.
+	breakpoints map[string][]int
+
+	mu sync.Mutex
+	// thread is the last stopped thread, and frame the frame selected
+	// within it, if any.
+	thread int
+	frame  int
+}
+
+// daemonRequest is the params of the request daemon method.
+type daemonRequest struct {
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// serve runs the daemon until the adapter exits, listening on sock.
+func serve(a Adapter, session, sock string, args interface{}, breakpoints map[string][]int) error {
+	os.Remove(sock)
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sock)
+
+	cmd := exec.Command(a.Command[0], a.Command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	d := &daemon{
+		adapter:     a,
+		session:     session,
+		breakpoints: breakpoints,
+	}
+	d.conn = NewConn(stdout, stdin, d.onEvent)
+
+	err = d.conn.Request("initialize", map[string]interface{}{
+		"clientID":        "gokakoune",
+		"adapterID":       a.Name,
+		"linesStartAt1":   true,
+		"columnsStartAt1": true,
+		"pathFormat":      "path",
+	}, nil)
+	if err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+
+	// NOTE(leeola): adapters commonly respond to launch only after the
+	// configuration is done, which follows the initialized event, so the
+	// launch must not block.
+	go func() {
+		if err := d.conn.Request(a.request(), args, nil); err != nil {
+			api.Send(d.session, "echo -markup "+api.Quote("{Error}"+err.Error()))
+		}
+	}()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			lsp.NewConn(c, c, d.handleClient)
+		}
+	}()
+
+	<-d.conn.Done()
+	api.Send(d.session, "dap-on-terminated")
+	return cmd.Wait()
+}
+
+// onEvent handles the events of the adapter. This is within the read loop
+// of the adapter conn, so requests are made from goroutines.
+func (d *daemon) onEvent(event string, body json.RawMessage) {
+	switch event {
+	case "initialized":
+		go func() {
+			for file, lines := range d.breakpoints {
+				if err := setBreakpoints(d.conn, file, lines); err != nil {
+					api.Send(d.session, "echo -debug -- "+api.Quote("dap: "+err.Error()))
+				}
+			}
+			d.conn.Request("configurationDone", nil, nil)
+		}()
+
+	case "stopped":
+		var e StoppedEvent
+		if err := json.Unmarshal(body, &e); err != nil {
+			return
+		}
+
+		d.mu.Lock()
+		d.thread, d.frame = e.ThreadID, 0
+		d.mu.Unlock()
+
+		go api.Send(d.session, "evaluate-commands -try-client %opt{jumpclient} dap-on-stopped")
+
+	case "continued":
+		go api.Send(d.session, "dap-on-continued")
+
+	case "output":
+		var e OutputEvent
+		if err := json.Unmarshal(body, &e); err != nil || e.Category == "telemetry" {
+			return
+		}
+
+		go api.Send(d.session, "echo -debug -- "+api.Quote(strings.TrimSuffix(e.Output, "\n")))
+
+	case "terminated":
+		go d.conn.Request("disconnect", nil, nil)
+	}
+}
+
+// handleClient handles the daemon methods called by Funcs.
+func (d *daemon) handleClient(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "request":
+		var r daemonRequest
+		if err := json.Unmarshal(params, &r); err != nil {
+			return nil, err
+		}
+
+		var args interface{}
+		if len(r.Arguments) != 0 {
+			args = r.Arguments
+		}
+
+		var body json.RawMessage
+		if err := d.conn.Request(r.Command, args, &body); err != nil {
+			return nil, err
+		}
+		return body, nil
+
+	case "thread":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.thread, nil
+
+	case "frame":
+		// a frame id selects the frame, null returns the selected.
+		var frame *int
+		if len(params) != 0 {
+			if err := json.Unmarshal(params, &frame); err != nil {
+				return nil, err
+			}
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if frame != nil {
+			d.frame = *frame
+		}
+		return d.frame, nil
+
+	default:
+		return nil, &lsp.ResponseError{Code: -32601, Message: "method not found: " + method}
+	}
+}
+
+// setBreakpoints replaces the breakpoints of the file with the given lines.
+func setBreakpoints(r requester, file string, lines []int) error {
+	bps := make([]SourceBreakpoint, len(lines))
+	for i, l := range lines {
+		bps[i] = SourceBreakpoint{Line: l}
+	}
+
+	err := r.Request("setBreakpoints", map[string]interface{}{
+		"source":      Source{Path: file},
+		"breakpoints": bps,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("breakpoints of %s: %s", file, err)
+	}
+
+	return nil
+}
+
+// requester makes adapter requests, either directly or through the daemon.
+type requester interface {
+	Request(command string, args, body interface{}) error
+}
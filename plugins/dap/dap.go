@@ -0,0 +1,662 @@
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/lsp"
+)
+
+const (
+	// breakpointsKey is the global State key of the breakpoints, by
+	// absolute file.
+	breakpointsKey = "dap_breakpoints"
+
+	stackBuffer     = "*dap-stack*"
+	variablesBuffer = "*dap-variables*"
+)
+
+// Adapter is a debug adapter for a single filetype.
+type Adapter struct {
+	Name     string
+	Filetype string
+
+	// Command runs the adapter, speaking the protocol over stdio.
+	Command []string
+
+	// Attach attaches to a running process, rather than launching one.
+	Attach bool
+
+	// Arguments returns the arguments of the launch or attach request,
+	// which are specific to each adapter. ExportVars are exported to it.
+	Arguments  func(kak *api.Kak) (interface{}, error)
+	ExportVars []string
+}
+
+func (a Adapter) request() string {
+	if a.Attach {
+		return "attach"
+	}
+	return "launch"
+}
+
+// Register the given adapters, defining the debugger commands.
+//
+// The following commands are defined:
+//
+//	dap-start              start debugging with the adapter of the filetype
+//	dap-stop               stop debugging
+//	dap-continue           continue the stopped thread
+//	dap-next               step over the current line
+//	dap-step-in            step into the current line
+//	dap-step-out           step out of the current function
+//	dap-breakpoint-toggle  toggle the breakpoint of the cursor line
+//	dap-stack              list the stack of the stopped thread in *dap-stack*
+//	dap-frame              select the frame of the given index
+//	dap-variables          list the variables of the frame in *dap-variables*
+//
+// Breakpoints and the current location are shown as gutter flags, and
+// the current line is highlighted while stepping. A single debug session
+// runs per Kakoune session.
+func Register(k *api.Kak, adapters ...Adapter) error {
+	byFiletype := map[string]Adapter{}
+	for _, a := range adapters {
+		if a.Filetype == "" || len(a.Command) == 0 || a.Arguments == nil {
+			return errors.New("adapter needs a Filetype, Command and Arguments")
+		}
+
+		if _, ok := byFiletype[a.Filetype]; ok {
+			return fmt.Errorf("duplicate adapter for %s", a.Filetype)
+		}
+
+		byFiletype[a.Filetype] = a
+	}
+
+	setup := `declare-option -hidden line-specs dap_breakpoint_flags
+declare-option -hidden line-specs dap_location_flags
+declare-option -hidden range-specs dap_location_range
+set-face global DapBreakpoint red
+set-face global DapLocation default,blue
+try %{ add-highlighter global/dap-breakpoints flag-lines default dap_breakpoint_flags }
+try %{ add-highlighter global/dap-location flag-lines default dap_location_flags }
+try %{ add-highlighter global/dap-location-range ranges dap_location_range }`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHighlighter("global/dap-breakpoints", "debugger breakpoint flags")
+	k.RecordHighlighter("global/dap-location", "debugger location flag")
+	k.RecordHighlighter("global/dap-location-range", "debugger current line")
+
+	err := k.DefineCommand("dap-start", api.DefineCommandOptions{
+		Docstring: "start debugging with the adapter of the buffer filetype",
+	}, api.Func{
+		ExportVars: startVars(k, adapters),
+		Func: func(kak *api.Kak) error {
+			filetype, err := kak.Var(vars.OptFiletype)
+			if err != nil {
+				return err
+			}
+
+			a, ok := byFiletype[filetype]
+			if !ok {
+				return fmt.Errorf("no debug adapter for filetype: %q", filetype)
+			}
+
+			return start(kak, a)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-stop", api.DefineCommandOptions{
+		Docstring: "stop debugging",
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func: withClient(func(kak *api.Kak, c *client) error {
+			return c.Request("disconnect", map[string]bool{"terminateDebuggee": true}, nil)
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	steps := []struct {
+		name, command, doc string
+	}{
+		{"dap-continue", "continue", "continue the stopped thread"},
+		{"dap-next", "next", "step over the current line"},
+		{"dap-step-in", "stepIn", "step into the current line"},
+		{"dap-step-out", "stepOut", "step out of the current function"},
+	}
+	for _, s := range steps {
+		command := s.command
+		err := k.DefineCommand(s.name, api.DefineCommandOptions{
+			Docstring: s.doc,
+		}, api.Func{
+			ExportVars: []string{vars.Session},
+			Func: withClient(func(kak *api.Kak, c *client) error {
+				thread, err := c.thread()
+				if err != nil {
+					return err
+				}
+				return c.Request(command, map[string]int{"threadId": thread}, nil)
+			}),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = k.DefineCommand("dap-breakpoint-toggle", api.DefineCommandOptions{
+		Docstring: "toggle the breakpoint of the cursor line",
+	}, api.Func{
+		ExportVars: []string{
+			vars.BufFile,
+			vars.CursorLine,
+			vars.Session,
+			vars.Timestamp,
+			k.StateVar(breakpointsKey),
+		},
+		Func: toggleBreakpoint,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-on-stopped", api.DefineCommandOptions{
+		Docstring: "jump to the location of the stopped thread",
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func: withClient(func(kak *api.Kak, c *client) error {
+			frames, err := c.stack()
+			if err != nil {
+				return err
+			}
+			if len(frames) == 0 {
+				return errors.New("stopped without a stack")
+			}
+
+			kak.Printf("echo -- %s\n", api.Quote("stopped in "+frames[0].Name))
+			return jump(kak, frames[0])
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	clearLocation := `try %{ evaluate-commands -buffer * %{
+    unset-option buffer dap_location_flags
+    unset-option buffer dap_location_range
+  } }`
+
+	err = k.DefineCommand("dap-on-continued", api.DefineCommandOptions{
+		Docstring: "clear the location of the continued thread",
+	}, api.Raw(clearLocation))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-on-terminated", api.DefineCommandOptions{
+		Docstring: "clear the location of the terminated debuggee",
+	}, api.Raw(clearLocation+"\n  echo 'debugging terminated'"))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-stack", api.DefineCommandOptions{
+		Docstring: "list the stack of the stopped thread in " + stackBuffer,
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func:       withClient(renderStack),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-stack-select", api.DefineCommandOptions{
+		Docstring: "select the frame under the cursor of " + stackBuffer,
+	}, api.Raw(`evaluate-commands -save-regs 1 %{
+    execute-keys 'xs^#(\d+)<ret>'
+    dap-frame %reg{1}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-frame", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "select the frame of the given index within the stack",
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func: withClient(func(kak *api.Kak, c *client) error {
+			arg, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			i, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("failed to frame to int: %q", arg)
+			}
+
+			frames, err := c.stack()
+			if err != nil {
+				return err
+			}
+			if i < 0 || i >= len(frames) {
+				return fmt.Errorf("no frame %d", i)
+			}
+
+			if err := c.conn.Call("frame", frames[i].ID, nil); err != nil {
+				return err
+			}
+
+			kak.Printf("evaluate-commands -try-client %%opt{jumpclient} %%{\n")
+			if err := jump(kak, frames[i]); err != nil {
+				return err
+			}
+			kak.Println("}")
+			return nil
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-variables", api.DefineCommandOptions{
+		Docstring: "list the variables of the selected frame in " + variablesBuffer,
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func:       withClient(renderVariables),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("dap-variables-expand", api.DefineCommandOptions{
+		Docstring: "expand the variable under the cursor of " + variablesBuffer,
+	}, api.Raw(`evaluate-commands -save-regs 12 %{
+    execute-keys 'xs^(\s*).*@(\d+)$<ret>'
+    dap-variable-children %reg{2} %reg{1}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("dap-variable-children", api.DefineCommandOptions{
+		Params:    2,
+		Docstring: "insert the children of the given variable reference below the cursor",
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func: withClient(func(kak *api.Kak, c *client) error {
+			arg, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			ref, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("failed to reference to int: %q", arg)
+			}
+
+			indent, err := kak.Arg(1)
+			if err != nil {
+				return err
+			}
+
+			lines, err := c.variables(ref, indent+"  ")
+			if err != nil {
+				return err
+			}
+
+			kak.Printf("set-register z %s\n", api.Quote(strings.Join(lines, "\n")+"\n"))
+			kak.Println(`execute-keys 'x"zp'`)
+			return nil
+		}),
+	})
+}
+
+// startVars returns the vars exported to dap-start, which are exported to
+// the Arguments of every adapter.
+func startVars(k *api.Kak, adapters []Adapter) []string {
+	exportVars := []string{
+		vars.BufFile,
+		vars.OptFiletype,
+		vars.Session,
+		k.StateVar(breakpointsKey),
+	}
+	for _, a := range adapters {
+		exportVars = append(exportVars, a.ExportVars...)
+	}
+	return exportVars
+}
+
+// client is a connection to the daemon.
+type client struct {
+	conn *lsp.Conn
+	nc   net.Conn
+}
+
+// Request makes the given adapter request through the daemon.
+func (c *client) Request(command string, args, body interface{}) error {
+	var raw json.RawMessage
+	if args != nil {
+		b, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+
+	return c.conn.Call("request", daemonRequest{Command: command, Arguments: raw}, body)
+}
+
+// thread returns the last stopped thread.
+func (c *client) thread() (int, error) {
+	var thread int
+	if err := c.conn.Call("thread", nil, &thread); err != nil {
+		return 0, err
+	}
+	return thread, nil
+}
+
+// stack returns the frames of the stopped thread.
+func (c *client) stack() ([]StackFrame, error) {
+	thread, err := c.thread()
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+	if err := c.Request("stackTrace", map[string]int{"threadId": thread}, &body); err != nil {
+		return nil, err
+	}
+
+	return body.StackFrames, nil
+}
+
+// variables returns the rendered lines of the variables of the given
+// reference. Variables with children end in `@<reference>`.
+func (c *client) variables(ref int, indent string) ([]string, error) {
+	var body struct {
+		Variables []Variable `json:"variables"`
+	}
+	if err := c.Request("variables", map[string]int{"variablesReference": ref}, &body); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, v := range body.Variables {
+		line := indent + v.Name
+		if v.Type != "" {
+			line += " (" + v.Type + ")"
+		}
+		line += " = " + strings.Replace(v.Value, "\n", `\n`, -1)
+		if v.VariablesReference != 0 {
+			line += fmt.Sprintf("  @%d", v.VariablesReference)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// withClient runs f with a client of the daemon. vars.Session must be
+// exported to the Subproc.
+func withClient(f func(*api.Kak, *client) error) func(*api.Kak) error {
+	return func(kak *api.Kak) error {
+		c, err := dial(kak)
+		if err != nil {
+			return err
+		}
+		defer c.nc.Close()
+
+		return f(kak, c)
+	}
+}
+
+func dial(kak *api.Kak) (*client, error) {
+	sock, err := socket(kak)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, errors.New("not debugging, see dap-start")
+	}
+
+	return &client{conn: lsp.NewConn(nc, nc, nil), nc: nc}, nil
+}
+
+// socket returns the socket of the daemon.
+func socket(kak *api.Kak) (string, error) {
+	dir, err := kak.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dap.sock"), nil
+}
+
+// start starts debugging with the adapter, failing if already debugging.
+// The rerun Func serves as the daemon, launching the adapter with the
+// breakpoints set so far and owning it until the debuggee terminates.
+func start(kak *api.Kak, a Adapter) error {
+	session, err := kak.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+
+	sock, err := socket(kak)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(daemonEnv) != "" {
+		args, err := a.Arguments(kak)
+		if err != nil {
+			return err
+		}
+
+		var bps map[string][]int
+		if err := kak.State().Get(breakpointsKey, &bps); err != nil && err != api.ErrStateNotFound {
+			return err
+		}
+
+		return serve(a, session, sock, args, bps)
+	}
+
+	if nc, err := net.Dial("unix", sock); err == nil {
+		nc.Close()
+		return errors.New("already debugging, see dap-stop")
+	}
+
+	if err := kak.SpawnDaemon(daemonEnv, sock); err != nil {
+		return fmt.Errorf("%s debug adapter failed to start: %s", a.Name, err)
+	}
+	kak.Printf("echo -- %s\n", api.Quote("debugging with "+a.Name))
+	return nil
+}
+
+// toggleBreakpoint toggles the breakpoint of the cursor line, updating the
+// adapter if debugging.
+func toggleBreakpoint(kak *api.Kak) error {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return err
+	}
+
+	file, err := filepath.Abs(buffile)
+	if err != nil {
+		return err
+	}
+
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return err
+	}
+
+	bps := map[string][]int{}
+	if err := kak.State().Get(breakpointsKey, &bps); err != nil && err != api.ErrStateNotFound {
+		return err
+	}
+
+	var lines []int
+	removed := false
+	for _, l := range bps[file] {
+		if l == line {
+			removed = true
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if !removed {
+		lines = append(lines, line)
+		sort.Ints(lines)
+	}
+
+	if len(lines) == 0 {
+		delete(bps, file)
+	} else {
+		bps[file] = lines
+	}
+
+	if err := kak.State().Set(api.ScopeGlobal, breakpointsKey, bps); err != nil {
+		return err
+	}
+
+	flags := make([]api.LineSpec, len(lines))
+	for i, l := range lines {
+		flags[i] = api.Flag(l, "DapBreakpoint", "●")
+	}
+	kak.SetLineSpecs("dap_breakpoint_flags", flags)
+
+	// update the adapter, if debugging.
+	if c, err := dial(kak); err == nil {
+		defer c.nc.Close()
+		return setBreakpoints(c, file, lines)
+	}
+
+	return nil
+}
+
+// jump opens the source of the frame, marking it as the current location.
+func jump(kak *api.Kak, f StackFrame) error {
+	if f.Source == nil || f.Source.Path == "" {
+		return fmt.Errorf("frame %s has no source", f.Name)
+	}
+
+	column := f.Column
+	if column < 1 {
+		column = 1
+	}
+
+	// the current line is highlighted to its end, known from the file.
+	end := 1
+	if b, err := ioutil.ReadFile(f.Source.Path); err == nil {
+		if lines := lsp.Lines(string(b)); f.Line >= 1 && f.Line <= len(lines) && len(lines[f.Line-1]) > 0 {
+			end = len(lines[f.Line-1])
+		}
+	}
+
+	kak.Println(`try %{ evaluate-commands -buffer * %{
+  unset-option buffer dap_location_flags
+  unset-option buffer dap_location_range
+} }`)
+	kak.Println(paths.EditExisting(f.Source.Path, api.Coord{Line: f.Line, Column: column}))
+	kak.SetLineSpecs("dap_location_flags", []api.LineSpec{api.Flag(f.Line, "DapLocation", "▶")})
+	kak.SetRangeSpecs("dap_location_range", []api.RangeSpec{api.Highlight(
+		api.Range{Begin: api.Coord{Line: f.Line, Column: 1}, End: api.Coord{Line: f.Line, Column: end}},
+		"DapLocation",
+	)})
+
+	return nil
+}
+
+// renderStack renders the frames of the stopped thread into the stack
+// buffer, where dap-stack-select selects the frame under the cursor.
+func renderStack(kak *api.Kak, c *client) error {
+	frames, err := c.stack()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for i, f := range frames {
+		location := "?"
+		if f.Source != nil {
+			location = fmt.Sprintf("%s:%d", f.Source.Path, f.Line)
+		}
+		lines = append(lines, fmt.Sprintf("#%d %s %s", i, f.Name, location))
+	}
+
+	scratch(kak, stackBuffer, lines)
+	kak.Println("map buffer normal <ret> :dap-stack-select<ret>")
+	return nil
+}
+
+// renderVariables renders the scopes of the selected frame, or the top
+// frame, into the variables buffer.
+func renderVariables(kak *api.Kak, c *client) error {
+	var frame int
+	if err := c.conn.Call("frame", nil, &frame); err != nil {
+		return err
+	}
+
+	if frame == 0 {
+		frames, err := c.stack()
+		if err != nil {
+			return err
+		}
+		if len(frames) == 0 {
+			return errors.New("no stack")
+		}
+		frame = frames[0].ID
+	}
+
+	var body struct {
+		Scopes []Scope `json:"scopes"`
+	}
+	if err := c.Request("scopes", map[string]int{"frameId": frame}, &body); err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, s := range body.Scopes {
+		lines = append(lines, fmt.Sprintf("%s  @%d", s.Name, s.VariablesReference))
+		if s.Expensive {
+			continue
+		}
+
+		vs, err := c.variables(s.VariablesReference, "  ")
+		if err != nil {
+			return err
+		}
+		lines = append(lines, vs...)
+	}
+
+	scratch(kak, variablesBuffer, lines)
+	kak.Println("map buffer normal <ret> :dap-variables-expand<ret>")
+	return nil
+}
+
+// scratch replaces the content of the given scratch buffer, creating it if
+// needed.
+func scratch(kak *api.Kak, buffer string, lines []string) {
+	kak.Printf("edit -scratch %s\n", buffer)
+	kak.Printf("set-register z %s\n", api.Quote(strings.Join(lines, "\n")+"\n"))
+	kak.Println(`execute-keys '%"zRgg'`)
+}
@@ -0,0 +1,232 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/leeola/gokakoune/plugins/lsp"
+)
+
+// ErrClosed is returned by requests on a closed Conn.
+var ErrClosed = errors.New("dap: connection closed")
+
+// Message is any debug adapter protocol message: a request, response or
+// event.
+type Message struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+
+	// requests
+	Command   string          `json:"command,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	// responses
+	RequestSeq int    `json:"request_seq,omitempty"`
+	Success    bool   `json:"success"`
+	Message    string `json:"message,omitempty"`
+
+	// events
+	Event string `json:"event,omitempty"`
+
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Conn is a connection to a debug adapter.
+//
+// Requests may be made concurrently. Events are given to OnEvent in order,
+// within the read loop.
+type Conn struct {
+	w       io.Writer
+	onEvent func(event string, body json.RawMessage)
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	seq     int
+	pending map[int]chan *Message
+	err     error
+
+	done chan struct{}
+}
+
+// NewConn returns a Conn reading from r and writing to w, giving events to
+// onEvent, which may be nil.
+func NewConn(r io.Reader, w io.Writer, onEvent func(event string, body json.RawMessage)) *Conn {
+	c := &Conn{
+		w:       w,
+		onEvent: onEvent,
+		pending: map[int]chan *Message{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(r))
+	return c
+}
+
+// Done is closed once the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Request sends the given request, decoding the body of the response into
+// body if not nil.
+func (c *Conn) Request(command string, args, body interface{}) error {
+	m := &Message{Type: "request", Command: command}
+	if args != nil {
+		b, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		m.Arguments = b
+	}
+
+	ch := make(chan *Message, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.seq++
+	m.Seq = c.seq
+	c.pending[m.Seq] = ch
+	c.mu.Unlock()
+
+	if err := c.write(m); err != nil {
+		c.mu.Lock()
+		delete(c.pending, m.Seq)
+		c.mu.Unlock()
+		return err
+	}
+
+	resp, ok := <-ch
+	if !ok {
+		return c.err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("dap %s: %s", command, resp.Message)
+	}
+
+	if body == nil || len(resp.Body) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Body, body)
+}
+
+func (c *Conn) write(m *Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return lsp.WriteFrame(c.w, b)
+}
+
+func (c *Conn) readLoop(r *bufio.Reader) {
+	var err error
+	for {
+		var b []byte
+		if b, err = lsp.ReadFrame(r); err != nil {
+			break
+		}
+
+		var m Message
+		if err = json.Unmarshal(b, &m); err != nil {
+			break
+		}
+
+		switch m.Type {
+		case "response":
+			c.mu.Lock()
+			ch, ok := c.pending[m.RequestSeq]
+			delete(c.pending, m.RequestSeq)
+			c.mu.Unlock()
+
+			if ok {
+				ch <- &m
+			}
+		case "event":
+			if c.onEvent != nil {
+				c.onEvent(m.Event, m.Body)
+			}
+		case "request":
+			// NOTE(leeola): reverse requests such as runInTerminal are not
+			// supported, adapters fall back when they fail.
+			c.mu.Lock()
+			c.seq++
+			seq := c.seq
+			c.mu.Unlock()
+
+			c.write(&Message{
+				Seq:        seq,
+				Type:       "response",
+				RequestSeq: m.Seq,
+				Command:    m.Command,
+				Message:    "not supported",
+			})
+		}
+	}
+
+	c.mu.Lock()
+	c.err = ErrClosed
+	if err != io.EOF {
+		c.err = fmt.Errorf("dap: %s", err)
+	}
+	for seq, ch := range c.pending {
+		close(ch)
+		delete(c.pending, seq)
+	}
+	c.mu.Unlock()
+
+	close(c.done)
+}
+
+// The subset of the protocol types used by the commands.
+
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type SourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type StoppedEvent struct {
+	Reason   string `json:"reason"`
+	ThreadID int    `json:"threadId"`
+}
+
+type OutputEvent struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
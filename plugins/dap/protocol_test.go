@@ -0,0 +1,80 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/leeola/gokakoune/plugins/lsp"
+)
+
+func TestConn(t *testing.T) {
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+
+	events := make(chan string, 1)
+	c := NewConn(ar, aw, func(event string, body json.RawMessage) {
+		events <- event
+	})
+
+	// a fake adapter, answering threads and failing anything else.
+	go func() {
+		r := bufio.NewReader(br)
+		seq := 0
+		send := func(m Message) {
+			seq++
+			m.Seq = seq
+			b, _ := json.Marshal(m)
+			lsp.WriteFrame(bw, b)
+		}
+
+		for {
+			b, err := lsp.ReadFrame(r)
+			if err != nil {
+				return
+			}
+
+			var req Message
+			json.Unmarshal(b, &req)
+
+			if req.Command == "threads" {
+				send(Message{Type: "event", Event: "stopped", Body: json.RawMessage(`{"threadId":1}`)})
+				send(Message{Type: "response", RequestSeq: req.Seq, Command: req.Command,
+					Success: true, Body: json.RawMessage(`{"threads":[{"id":1,"name":"main"}]}`)})
+				continue
+			}
+
+			send(Message{Type: "response", RequestSeq: req.Seq, Command: req.Command,
+				Message: "unsupported"})
+		}
+	}()
+
+	var body struct {
+		Threads []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"threads"`
+	}
+	if err := c.Request("threads", nil, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Threads) != 1 || body.Threads[0].Name != "main" {
+		t.Errorf("unexpected threads: %+v", body)
+	}
+
+	if e := <-events; e != "stopped" {
+		t.Errorf("want stopped event, got %q", e)
+	}
+
+	if err := c.Request("evaluate", map[string]string{"expression": "x"}, nil); err == nil {
+		t.Error("want error for failed request")
+	}
+
+	bw.Close()
+	<-c.Done()
+	if err := c.Request("threads", nil, nil); err == nil {
+		t.Error("want error requesting closed conn")
+	}
+}
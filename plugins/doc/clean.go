@@ -0,0 +1,32 @@
+package doc
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiRe matches the SGR escapes of programs colorizing their output.
+var ansiRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Clean removes terminal formatting from the given output: the backspace
+// overstriking of man and nroff, used for bold and underline, and color
+// escapes.
+func Clean(s string) string {
+	s = ansiRe.ReplaceAllString(s, "")
+	if !strings.ContainsRune(s, '\b') {
+		return s
+	}
+
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '\b' {
+			// the overstruck character is replaced by the one following.
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
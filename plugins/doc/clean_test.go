@@ -0,0 +1,21 @@
+package doc
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"plain", "plain"},
+		{"N\bNA\bAM\bME\bE", "NAME"},
+		{"_\bf_\bi_\bl_\be", "file"},
+		{"\x1b[1mbold\x1b[0m and \x1b[31;1mred\x1b[m", "bold and red"},
+		{"é\bé", "é"},
+	}
+
+	for _, test := range tests {
+		if got := Clean(test.input); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.input, test.want, got)
+		}
+	}
+}
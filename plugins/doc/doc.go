@@ -0,0 +1,285 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/keys"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	// historyKey is the global State key of the viewed topics.
+	historyKey = "docs_history"
+
+	docBuffer = "*docs*"
+
+	// maxHistory bounds the history, dropping the oldest topics.
+	maxHistory = 50
+)
+
+// Source renders the documentation of topics, such as man pages.
+type Source struct {
+	Name string
+
+	// Filetypes are the filetypes the source is used for. A source without
+	// filetypes is the fallback of every other filetype.
+	Filetypes []string
+
+	// Command returns the command rendering the given topic to stdout.
+	Command func(topic string) []string
+
+	// Highlighters are added to the doc buffer, as the arguments of
+	// add-highlighter following the path, such as `regex '^\w+' 0:header`.
+	Highlighters []string
+}
+
+// Man renders man pages, and is the fallback of every filetype.
+var Man = Source{
+	Name: "man",
+	Command: func(topic string) []string {
+		return []string{"man", topic}
+	},
+	Highlighters: []string{
+		`regex '^[A-Z][A-Z0-9 ]+$' 0:header`,
+		`regex '^\h+(-{1,2}[\w-]+)' 1:attribute`,
+	},
+}
+
+// GoDoc renders the documentation of Go packages and symbols.
+var GoDoc = Source{
+	Name:      "go doc",
+	Filetypes: []string{"go"},
+	Command: func(topic string) []string {
+		return []string{"go", "doc", topic}
+	},
+	Highlighters: []string{
+		`regex '^(?:package|func|type|var|const)\b' 0:keyword`,
+		`regex '^[A-Z][A-Z0-9 ]+$' 0:header`,
+		`regex '//[^\n]*' 0:comment`,
+	},
+}
+
+// Sources are the sources registered if none are given to Register.
+var Sources = []Source{Man, GoDoc}
+
+// history is the stored history of viewed topics.
+type history struct {
+	Topics []topic
+	// Index is the index of the viewed topic within Topics.
+	Index int
+}
+
+type topic struct {
+	Source string
+	Topic  string
+}
+
+// Register the doc viewer commands with the given sources, or Sources if
+// none are given.
+//
+// The following commands are defined:
+//
+//    docs          view the documentation of the given topic
+//    docs-word     view the documentation of the word under the cursor
+//    docs-back     view the previous topic of the history
+//    docs-forward  view the next topic of the history
+//
+// Documentation is rendered into the readonly *docs* buffer, where <ret>
+// follows the word under the cursor and <c-o> and <tab> move through the
+// history. The names avoid the doc command bundled with Kakoune.
+func Register(k *api.Kak, sources ...Source) error {
+	if len(sources) == 0 {
+		sources = Sources
+	}
+
+	byName := map[string]Source{}
+	byFiletype := map[string]Source{}
+	var fallback *Source
+	for i, s := range sources {
+		if s.Name == "" || s.Command == nil {
+			return errors.New("doc source needs a Name and Command")
+		}
+		byName[s.Name] = s
+
+		if len(s.Filetypes) == 0 {
+			fallback = &sources[i]
+		}
+		for _, ft := range s.Filetypes {
+			byFiletype[ft] = s
+		}
+	}
+
+	find := func(filetype string) (Source, error) {
+		if s, ok := byFiletype[filetype]; ok {
+			return s, nil
+		}
+		if fallback != nil {
+			return *fallback, nil
+		}
+		return Source{}, fmt.Errorf("no doc source for filetype: %q", filetype)
+	}
+
+	k.RecordHighlighter("buffer/docs", "docs buffer highlighting")
+
+	historyVars := []string{k.StateVar(historyKey)}
+
+	err := k.DefineCommand("docs", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "view the documentation of the given topic in " + docBuffer,
+	}, api.Func{
+		ExportVars: append([]string{vars.BufName, vars.OptFiletype}, historyVars...),
+		Func: func(kak *api.Kak) error {
+			name, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			filetype, err := kak.Var(vars.OptFiletype)
+			if err != nil {
+				return err
+			}
+
+			bufname, err := kak.Var(vars.BufName)
+			if err != nil {
+				return err
+			}
+
+			h, err := load(kak)
+			if err != nil {
+				return err
+			}
+
+			// topics followed within the doc buffer keep the source.
+			var s Source
+			if bufname == docBuffer && len(h.Topics) != 0 {
+				s = byName[h.Topics[h.Index].Source]
+			} else if s, err = find(filetype); err != nil {
+				return err
+			}
+
+			if err := view(kak, s, name); err != nil {
+				return err
+			}
+
+			// viewing a new topic drops the topics forward of the current.
+			if len(h.Topics) != 0 {
+				h.Topics = h.Topics[:h.Index+1]
+			}
+			h.Topics = append(h.Topics, topic{Source: s.Name, Topic: name})
+			if len(h.Topics) > maxHistory {
+				h.Topics = h.Topics[len(h.Topics)-maxHistory:]
+			}
+			h.Index = len(h.Topics) - 1
+
+			return kak.State().Set(api.ScopeGlobal, historyKey, h)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("docs-word", api.DefineCommandOptions{
+		Docstring: "view the documentation of the word under the cursor",
+	}, api.Raw(`evaluate-commands -save-regs d %{
+    execute-keys -draft '<a-i>w"dy'
+    docs %reg{d}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range []struct {
+		name, doc string
+		offset    int
+	}{
+		{"docs-back", "view the previous topic of the docs history", -1},
+		{"docs-forward", "view the next topic of the docs history", 1},
+	} {
+		offset := m.offset
+		err := k.DefineCommand(m.name, api.DefineCommandOptions{
+			Docstring: m.doc,
+		}, api.Func{
+			ExportVars: historyVars,
+			Func: func(kak *api.Kak) error {
+				h, err := load(kak)
+				if err != nil {
+					return err
+				}
+
+				i := h.Index + offset
+				if i < 0 || i >= len(h.Topics) {
+					return errors.New("no more docs history")
+				}
+
+				t := h.Topics[i]
+				s, ok := byName[t.Source]
+				if !ok {
+					return fmt.Errorf("unknown doc source: %q", t.Source)
+				}
+
+				if err := view(kak, s, t.Topic); err != nil {
+					return err
+				}
+
+				h.Index = i
+				return kak.State().Set(api.ScopeGlobal, historyKey, h)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func load(kak *api.Kak) (history, error) {
+	var h history
+	if err := kak.State().Get(historyKey, &h); err != nil && err != api.ErrStateNotFound {
+		return history{}, err
+	}
+	if h.Index >= len(h.Topics) {
+		h.Index = len(h.Topics) - 1
+	}
+	return h, nil
+}
+
+// view renders the topic of the source into the doc buffer.
+func view(kak *api.Kak, s Source, name string) error {
+	args := s.Command(name)
+
+	// render without a pager, at a width fitting most windows.
+	out, err := kak.ExecWith(api.ExecOptions{
+		Env: []string{"MANPAGER=cat", "PAGER=cat", "MANWIDTH=80"},
+	}, args[0], args[1:]...)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", s.Name, name, err)
+	}
+
+	kak.Printf("edit -scratch %s\n", docBuffer)
+	kak.Println("set-option buffer readonly false")
+	kak.Printf("set-register z %s\n", api.Quote(Clean(out)))
+	kak.Println(`execute-keys '%"zRgg'`)
+	kak.Println("set-option buffer readonly true")
+
+	kak.Println("try %{ remove-highlighter buffer/docs }")
+	kak.Println("add-highlighter buffer/docs group")
+	for i, h := range s.Highlighters {
+		kak.Printf("add-highlighter buffer/docs/%d %s\n", i, h)
+	}
+
+	for _, m := range [][2]string{
+		{keys.Ret, "docs-word"},
+		{keys.Ctrl("o"), "docs-back"},
+		{keys.Tab, "docs-forward"},
+	} {
+		if err := kak.Map("buffer", "normal", m[0], keys.Command(m[1]), api.MapOptions{}); err != nil {
+			return err
+		}
+	}
+	kak.Printf("echo -- %s\n", api.Quote(s.Name+" "+name))
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package editorconfig
+
+import (
+	"strconv"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	hookGroup = "editorconfig"
+
+	// trimGroup is the buffer hook group trimming trailing whitespace.
+	trimGroup = "editorconfig-trim"
+
+	highlighter = "buffer/editorconfig-column"
+)
+
+// Register the EditorConfig hooks and commands.
+//
+// The properties of each file buffer are applied when it is opened or
+// created, from the .editorconfig files of its directory and those above
+// it. The following command is defined:
+//
+//    editorconfig-apply  apply the EditorConfig properties to the buffer
+//
+// The indent_style, indent_size and tab_width properties set indentwidth
+// and tabstop, max_line_length highlights the column following it, and
+// trim_trailing_whitespace trims lines before the buffer is written. The
+// name avoids the editorconfig-load command bundled with Kakoune.
+func Register(k *api.Kak) error {
+	setup := `set-face global EditorconfigColumn default,red
+remove-hooks global ` + hookGroup + `
+hook -group ` + hookGroup + ` global BufOpenFile .* editorconfig-apply
+hook -group ` + hookGroup + ` global BufNewFile .* editorconfig-apply`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "apply EditorConfig properties to new buffers")
+	k.RecordHookGroup(trimGroup, "trim trailing whitespace of the buffer when written")
+	k.RecordHighlighter(highlighter, "the column following max_line_length")
+
+	return k.DefineCommand("editorconfig-apply", api.DefineCommandOptions{
+		Docstring: "apply the EditorConfig properties to the buffer",
+	}, api.Func{
+		ExportVars: []string{vars.BufFile},
+		Func: func(kak *api.Kak) error {
+			buffile, err := kak.Var(vars.BufFile)
+			if err != nil {
+				return err
+			}
+
+			props, err := Properties(buffile)
+			if err != nil {
+				return err
+			}
+
+			kak.Print(Script(props))
+			return nil
+		},
+	})
+}
+
+// Script returns the commands applying the properties to the buffer.
+//
+// Invalid and unset values are ignored, as are properties not supported,
+// leaving the options of the buffer as they were.
+func Script(props map[string]string) string {
+	var s string
+	option := func(name string, value int) {
+		s += "set-option buffer " + name + " " + strconv.Itoa(value) + "\n"
+	}
+	number := func(key string) (int, bool) {
+		n, err := strconv.Atoi(props[key])
+		return n, err == nil && n > 0
+	}
+
+	indentSize, hasIndentSize := number("indent_size")
+	tabWidth, hasTabWidth := number("tab_width")
+
+	// an indent_size of tab uses the tab_width, and tab_width defaults to
+	// the indent_size.
+	if props["indent_size"] == "tab" && hasTabWidth {
+		indentSize, hasIndentSize = tabWidth, true
+	}
+	if !hasTabWidth && hasIndentSize {
+		tabWidth, hasTabWidth = indentSize, true
+	}
+
+	switch props["indent_style"] {
+	case "tab":
+		// an indentwidth of zero indents with tabs.
+		option("indentwidth", 0)
+	case "space":
+		if hasIndentSize {
+			option("indentwidth", indentSize)
+		}
+	}
+	if hasTabWidth {
+		option("tabstop", tabWidth)
+	}
+
+	s += "try %{ remove-highlighter " + highlighter + " }\n"
+	if n, ok := number("max_line_length"); ok {
+		s += "add-highlighter " + highlighter + " column " + strconv.Itoa(n+1) + " EditorconfigColumn\n"
+	}
+
+	s += "remove-hooks buffer " + trimGroup + "\n"
+	if props["trim_trailing_whitespace"] == "true" {
+		s += "hook -group " + trimGroup + ` buffer BufWritePre .* %{ try %{ execute-keys -draft '%s\h+$<ret>d' } }` + "\n"
+	}
+
+	return s
+}
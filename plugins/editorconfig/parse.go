@@ -0,0 +1,250 @@
+package editorconfig
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of EditorConfig files.
+const FileName = ".editorconfig"
+
+// File is a parsed EditorConfig file.
+type File struct {
+	// Root is true if the file is the topmost of the project, stopping the
+	// search for files in parent directories.
+	Root     bool
+	Sections []Section
+}
+
+// Section is a glob and the properties of the files it matches.
+type Section struct {
+	Glob string
+
+	// Properties are the lowercased names and values of the section.
+	Properties map[string]string
+}
+
+// Parse parses an EditorConfig file.
+//
+// Comments, blank lines and unknown lines are ignored, as recommended by
+// the EditorConfig specification.
+func Parse(r io.Reader) (File, error) {
+	var f File
+	var section *Section
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			f.Sections = append(f.Sections, Section{
+				Glob:       line[1 : len(line)-1],
+				Properties: map[string]string{},
+			})
+			section = &f.Sections[len(f.Sections)-1]
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i == -1 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.ToLower(strings.TrimSpace(line[i+1:]))
+
+		// properties before the first section apply to the file itself.
+		if section == nil {
+			if key == "root" {
+				f.Root = value == "true"
+			}
+			continue
+		}
+
+		section.Properties[key] = value
+	}
+
+	return f, scanner.Err()
+}
+
+// Properties returns the properties of the given file, from the
+// EditorConfig files of its directory and the directories above it.
+//
+// Files closer to the path take precedence, as do later sections within a
+// file.
+func Properties(path string) (map[string]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	type dirFile struct {
+		dir string
+		f   File
+	}
+
+	var files []dirFile
+	for dir := filepath.Dir(abs); ; {
+		f, err := readFile(filepath.Join(dir, FileName))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			files = append(files, dirFile{dir: dir, f: f})
+			if f.Root {
+				break
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	props := map[string]string{}
+	for i := len(files) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(files[i].dir, abs)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, s := range files[i].f.Sections {
+			if !Match(s.Glob, rel) {
+				continue
+			}
+			for k, v := range s.Properties {
+				props[k] = v
+			}
+		}
+	}
+
+	return props, nil
+}
+
+func readFile(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Match reports whether the section glob matches the slash separated
+// path, relative to the directory of the EditorConfig file.
+//
+// Globs without a slash match the file name in any directory. Globs
+// support `*`, `**`, `?`, `[chars]`, `[!chars]`, `{a,b}` and `{1..9}`.
+func Match(glob, path string) bool {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	} else {
+		glob = strings.TrimPrefix(glob, "/")
+	}
+
+	re, ranges, err := compileGlob(glob)
+	if err != nil {
+		return false
+	}
+
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return false
+	}
+
+	// numeric ranges match any number, and are checked here.
+	for i, r := range ranges {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil || n < r[0] || n > r[1] {
+			return false
+		}
+	}
+	return true
+}
+
+var rangeRe = regexp.MustCompile(`^\{([+-]?\d+)\.\.([+-]?\d+)\}`)
+
+// compileGlob returns the regexp of the glob, and the bounds of each of its
+// numeric ranges, which are the groups of the regexp.
+func compileGlob(glob string) (*regexp.Regexp, [][2]int, error) {
+	var b strings.Builder
+	var ranges [][2]int
+	var braces int
+
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			// matches any directories, including none.
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end == -1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end
+
+		case c == '{':
+			if m := rangeRe.FindStringSubmatch(glob[i:]); m != nil {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[2])
+				ranges = append(ranges, [2]int{lo, hi})
+				b.WriteString(`([+-]?\d+)`)
+				i += len(m[0]) - 1
+				continue
+			}
+
+			// braces without a comma are literal.
+			end := strings.IndexByte(glob[i:], '}')
+			if end == -1 || !strings.Contains(glob[i:i+end], ",") {
+				b.WriteString(`\{`)
+				continue
+			}
+			b.WriteString("(?:")
+			braces++
+		case c == ',' && braces > 0:
+			b.WriteString("|")
+		case c == '}' && braces > 0:
+			b.WriteString(")")
+			braces--
+
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	return re, ranges, err
+}
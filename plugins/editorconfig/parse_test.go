@@ -0,0 +1,124 @@
+package editorconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(`# comment
+root = true
+
+[*]
+indent_style = Space
+; comment
+trim_trailing_whitespace: true
+
+[*.{go,mod}]
+indent_style = tab
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := File{
+		Root: true,
+		Sections: []Section{
+			{Glob: "*", Properties: map[string]string{
+				"indent_style":             "space",
+				"trim_trailing_whitespace": "true",
+			}},
+			{Glob: "*.{go,mod}", Properties: map[string]string{
+				"indent_style": "tab",
+			}},
+		},
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Fatalf("want %+v, got %+v", want, f)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"*", "main.go", true},
+		{"*.go", "api/kak.go", true},
+		{"*.go", "api/kak.gox", false},
+		{"*.{go,mod}", "go.mod", true},
+		{"*.{go,mod}", "go.sum", false},
+		{"Makefile", "build/Makefile", true},
+		{"/Makefile", "build/Makefile", false},
+		{"lib/*.js", "lib/a.js", true},
+		{"lib/*.js", "lib/sub/a.js", false},
+		{"lib/**.js", "lib/sub/a.js", true},
+		{"file[0-9].txt", "file3.txt", true},
+		{"file[!0-9].txt", "file3.txt", false},
+		{"file{1..10}.txt", "file10.txt", true},
+		{"file{1..10}.txt", "file11.txt", false},
+		{"{single}.txt", "{single}.txt", true},
+		{"?.c", "a.c", true},
+		{"?.c", "ab.c", false},
+	}
+
+	for _, test := range tests {
+		if got := Match(test.glob, test.path); got != test.want {
+			t.Errorf("%q %q: want %v, got %v", test.glob, test.path, test.want, got)
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	dir, err := ioutil.TempDir("", "editorconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(path, s string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(FileName, "root = true\n[*]\nindent_size = 4\ntab_width = 8\n")
+	write("sub/"+FileName, "[*.go]\nindent_size = 2\n[sub/*.go]\nindent_size = 3\n")
+
+	got, err := Properties(filepath.Join(dir, "sub", "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"indent_size": "2", "tab_width": "8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestScript(t *testing.T) {
+	got := Script(map[string]string{
+		"indent_style":    "space",
+		"indent_size":     "tab",
+		"tab_width":       "4",
+		"max_line_length": "80",
+	})
+
+	want := `set-option buffer indentwidth 4
+set-option buffer tabstop 4
+try %{ remove-highlighter buffer/editorconfig-column }
+add-highlighter buffer/editorconfig-column column 81 EditorconfigColumn
+remove-hooks buffer editorconfig-trim
+`
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
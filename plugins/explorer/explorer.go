@@ -0,0 +1,401 @@
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	// stateKey is the global State key of the explorer tree.
+	stateKey = "explorer"
+
+	explorerBuffer = "*explorer*"
+)
+
+// Action is a command of the explorer buffer, given the entry under the
+// cursor.
+//
+// An Action named "open" defines the explorer-open command, mapped to Key
+// within the explorer buffer.
+type Action struct {
+	Name      string
+	Key       string
+	Docstring string
+
+	// Prompt, if not empty, prompts before running Func, giving it the
+	// text entered. Init optionally returns the initial text.
+	Prompt string
+	Init   func(e Entry) string
+
+	// Refresh renders the tree again after Func.
+	Refresh bool
+
+	Func func(kak *api.Kak, t *Tree, e Entry, text string) error
+}
+
+// Tree is the state of the explorer buffer.
+type Tree struct {
+	Root     string
+	Expanded map[string]bool
+
+	// Entries are the entries of the buffer, by line.
+	Entries []Entry
+}
+
+// Actions are the default actions of the explorer buffer.
+var Actions = []Action{
+	{
+		Name:      "open",
+		Key:       "<ret>",
+		Docstring: "open the file, or toggle the directory, under the cursor",
+		Func: func(kak *api.Kak, t *Tree, e Entry, _ string) error {
+			if e.Dir {
+				toggle(t, e)
+				return render(kak, t)
+			}
+
+			kak.Printf("evaluate-commands -try-client %%opt{jumpclient} %%{ %s }\n", paths.Edit(e.Path, api.Coord{}))
+			return nil
+		},
+	},
+	{
+		Name:      "toggle",
+		Key:       "<tab>",
+		Docstring: "expand or collapse the directory under the cursor",
+		Refresh:   true,
+		Func: func(kak *api.Kak, t *Tree, e Entry, _ string) error {
+			toggle(t, e)
+			return nil
+		},
+	},
+	{
+		Name:      "up",
+		Key:       "-",
+		Docstring: "move the root of the explorer to its parent",
+		Refresh:   true,
+		Func: func(kak *api.Kak, t *Tree, _ Entry, _ string) error {
+			t.Expanded[t.Root] = true
+			t.Root = filepath.Dir(t.Root)
+			return nil
+		},
+	},
+	{
+		Name:      "refresh",
+		Key:       "R",
+		Docstring: "render the explorer again",
+		Refresh:   true,
+		Func: func(kak *api.Kak, t *Tree, _ Entry, _ string) error {
+			return nil
+		},
+	},
+	{
+		Name:      "create",
+		Key:       "a",
+		Docstring: "create a file, or directory if ending in /, next to the cursor",
+		Prompt:    "create: ",
+		Refresh:   true,
+		Func: func(kak *api.Kak, t *Tree, e Entry, text string) error {
+			if text == "" {
+				return nil
+			}
+
+			dir := e.Path
+			if !e.Dir {
+				dir = filepath.Dir(e.Path)
+			}
+			t.Expanded[dir] = true
+
+			path := filepath.Join(dir, text)
+			if strings.HasSuffix(text, "/") {
+				return os.MkdirAll(path, 0755)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			return f.Close()
+		},
+	},
+	{
+		Name:      "rename",
+		Key:       "r",
+		Docstring: "rename the entry under the cursor",
+		Prompt:    "rename: ",
+		Init: func(e Entry) string {
+			return filepath.Base(e.Path)
+		},
+		Refresh: true,
+		Func: func(kak *api.Kak, t *Tree, e Entry, text string) error {
+			if text == "" || text == filepath.Base(e.Path) {
+				return nil
+			}
+
+			if e.Path == t.Root {
+				return errors.New("cannot rename the explorer root")
+			}
+
+			to := filepath.Join(filepath.Dir(e.Path), text)
+			if _, err := os.Stat(to); err == nil {
+				return fmt.Errorf("already exists: %q", to)
+			}
+
+			return os.Rename(e.Path, to)
+		},
+	},
+	{
+		Name:      "delete",
+		Key:       "d",
+		Docstring: "delete the entry under the cursor, after confirming",
+		Prompt:    "delete? (y/N) ",
+		Refresh:   true,
+		Func: func(kak *api.Kak, t *Tree, e Entry, text string) error {
+			if text != "y" {
+				return nil
+			}
+
+			if e.Path == t.Root {
+				return errors.New("cannot delete the explorer root")
+			}
+
+			return os.RemoveAll(e.Path)
+		},
+	},
+}
+
+// Register the explorer command and the given actions, along with the
+// default Actions. Actions given replace any default of the same name.
+//
+// The explorer command renders the working directory as a tree into the
+// *explorer* buffer, where each action is mapped to its key.
+func Register(k *api.Kak, actions ...Action) error {
+	all := append([]Action{}, actions...)
+	given := map[string]bool{}
+	for _, a := range actions {
+		if a.Name == "" || a.Func == nil {
+			return errors.New("explorer action needs a Name and Func")
+		}
+		given[a.Name] = true
+	}
+	for _, a := range Actions {
+		if !given[a.Name] {
+			all = append(all, a)
+		}
+	}
+
+	var maps []string
+	for _, a := range all {
+		if a.Key != "" {
+			maps = append(maps, fmt.Sprintf("map buffer normal %s %s",
+				a.Key, api.Quote(":explorer-"+a.Name+"<ret>")))
+		}
+	}
+	mappings := strings.Join(maps, "\n")
+
+	err := k.Expansion(api.Raw(`set-face global ExplorerDirectory blue`))
+	if err != nil {
+		return err
+	}
+	k.RecordHighlighter("buffer/explorer", "explorer directories")
+
+	err = k.DefineCommand("explorer", api.DefineCommandOptions{
+		Docstring: "explore the working directory in " + explorerBuffer,
+	}, api.Func{
+		ExportVars: []string{k.StateVar(stateKey)},
+		Func: func(kak *api.Kak) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			t, err := load(kak)
+			if err != nil {
+				return err
+			}
+			if t.Root == "" {
+				t.Root = wd
+			}
+
+			kak.Printf("edit -scratch %s\n", explorerBuffer)
+			kak.Println("try %{ remove-highlighter buffer/explorer }")
+			err = kak.AddHighlighters("buffer/explorer", api.Regex(`^[^\n]*/$`, map[int]string{0: "ExplorerDirectory"}))
+			if err != nil {
+				return err
+			}
+			kak.Println(mappings)
+			return render(kak, t)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range all {
+		if err := defineAction(k, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defineAction defines the command of the action, and the command given
+// the prompt text if the action prompts.
+func defineAction(k *api.Kak, a Action) error {
+	name := "explorer-" + a.Name
+	exportVars := []string{vars.CursorLine, k.StateVar(stateKey)}
+
+	run := func(kak *api.Kak, text string) error {
+		t, err := load(kak)
+		if err != nil {
+			return err
+		}
+
+		e, err := under(kak, t)
+		if err != nil {
+			return err
+		}
+
+		if err := a.Func(kak, t, e, text); err != nil {
+			return err
+		}
+
+		if a.Refresh {
+			return render(kak, t)
+		}
+		return nil
+	}
+
+	if a.Prompt == "" {
+		return k.DefineCommand(name, api.DefineCommandOptions{
+			Docstring: a.Docstring,
+		}, api.Func{
+			ExportVars: exportVars,
+			Func: func(kak *api.Kak) error {
+				return run(kak, "")
+			},
+		})
+	}
+
+	err := k.DefineCommand(name, api.DefineCommandOptions{
+		Docstring: a.Docstring,
+	}, api.Func{
+		ExportVars: exportVars,
+		Func: func(kak *api.Kak) error {
+			t, err := load(kak)
+			if err != nil {
+				return err
+			}
+
+			e, err := under(kak, t)
+			if err != nil {
+				return err
+			}
+
+			var init string
+			if a.Init != nil {
+				init = a.Init(e)
+			}
+
+			kak.Printf("prompt -init %s %s %%{ %s-with %%val{text} }\n",
+				api.Quote(init), api.Quote(a.Prompt), name)
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand(name+"-with", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: a.Docstring + ", given the prompt text",
+	}, api.Func{
+		ExportVars: exportVars,
+		Func: func(kak *api.Kak) error {
+			text, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			return run(kak, text)
+		},
+	})
+}
+
+// load returns the stored tree, if any.
+func load(kak *api.Kak) (*Tree, error) {
+	t := &Tree{}
+	if err := kak.State().Get(stateKey, t); err != nil && err != api.ErrStateNotFound {
+		return nil, err
+	}
+
+	if t.Expanded == nil {
+		t.Expanded = map[string]bool{}
+	}
+
+	return t, nil
+}
+
+// under returns the entry under the cursor.
+func under(kak *api.Kak, t *Tree) (Entry, error) {
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if line < 1 || line > len(t.Entries) {
+		return Entry{}, errors.New("no entry under the cursor, see explorer")
+	}
+
+	return t.Entries[line-1], nil
+}
+
+// toggle expands or collapses the directory of the entry.
+func toggle(t *Tree, e Entry) {
+	if !e.Dir || e.Path == t.Root {
+		return
+	}
+
+	if t.Expanded[e.Path] {
+		delete(t.Expanded, e.Path)
+	} else {
+		t.Expanded[e.Path] = true
+	}
+}
+
+// render renders the tree into the current buffer, storing it for the
+// actions. The cursor stays on the same line.
+func render(kak *api.Kak, t *Tree) error {
+	t.Entries = List(t.Root, t.Expanded)
+
+	lines := make([]string, len(t.Entries))
+	for i, e := range t.Entries {
+		lines[i] = e.Line()
+	}
+	lines[0] = strings.TrimSuffix(t.Root, "/") + "/"
+
+	if err := kak.State().Set(api.ScopeGlobal, stateKey, t); err != nil {
+		return err
+	}
+
+	kak.Println("evaluate-commands -save-regs 'z^' %{")
+	kak.Println("  execute-keys -save-regs '' Z")
+	kak.Printf("  set-register z %s\n", api.Quote(strings.Join(lines, "\n")+"\n"))
+	kak.Println(`  execute-keys '%"zR'`)
+	kak.Println("  try %{ execute-keys z }")
+	kak.Println("}")
+	kak.Println("execute-keys '<space>;'")
+
+	return nil
+}
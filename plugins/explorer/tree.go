@@ -0,0 +1,61 @@
+package explorer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry is a single rendered line of the tree.
+type Entry struct {
+	Path  string
+	Depth int
+	Dir   bool
+}
+
+// Line returns the rendered line of the entry, indented by depth and with
+// directories suffixed by a slash.
+func (e Entry) Line() string {
+	name := filepath.Base(e.Path)
+	if e.Dir {
+		name += "/"
+	}
+	return strings.Repeat("  ", e.Depth) + name
+}
+
+// List lists root as a tree, descending into the expanded directories.
+//
+// Root is the first entry, followed by its children. Directories are
+// listed before files, each sorted by name. Unreadable directories are
+// listed without children.
+func List(root string, expanded map[string]bool) []Entry {
+	entries := []Entry{{Path: root, Dir: true}}
+	return walk(entries, root, 1, expanded)
+}
+
+func walk(entries []Entry, dir string, depth int, expanded map[string]bool) []Entry {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return entries
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].IsDir() && !infos[j].IsDir()
+	})
+
+	for _, info := range infos {
+		e := Entry{
+			Path:  filepath.Join(dir, info.Name()),
+			Depth: depth,
+			Dir:   info.IsDir(),
+		}
+		entries = append(entries, e)
+
+		if e.Dir && expanded[e.Path] {
+			entries = walk(entries, e.Path, depth+1, expanded)
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,50 @@
+package explorer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	root, err := ioutil.TempDir("", "explorer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, dir := range []string{"b/c", "a"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"1.go", "b/2.go", "b/c/3.go"} {
+		if err := ioutil.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := List(root, map[string]bool{filepath.Join(root, "b"): true})
+
+	var lines []string
+	for _, e := range got[1:] {
+		lines = append(lines, e.Line())
+	}
+
+	want := []string{
+		"  a/",
+		"  b/",
+		"    c/",
+		"    2.go",
+		"  1.go",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("want %q, got %q", want, lines)
+	}
+
+	if got[0].Path != root || !got[0].Dir {
+		t.Errorf("want root entry first, got %+v", got[0])
+	}
+}
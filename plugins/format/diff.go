@@ -0,0 +1,147 @@
+package format
+
+// Edit replaces a range of lines in the old text with new lines.
+type Edit struct {
+	// Line is the 1-based line of the old text the edit starts at. For
+	// insertions the new lines are inserted before it, which may be one
+	// past the last line to append.
+	Line int
+
+	// Delete is the number of old lines replaced.
+	Delete int
+
+	// Insert are the lines replacing the deleted lines, without newlines.
+	Insert []string
+}
+
+// Diff returns the minimal line edits transforming a into b, in order.
+//
+// This is the Myers O(ND) algorithm, which is fast for the small edit
+// distances formatters typically produce.
+func Diff(a, b []string) []Edit {
+	// trim the common prefix and suffix, which is the bulk of most files.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var edits []Edit
+	for _, e := range myers(a, b) {
+		e.Line += prefix
+		edits = append(edits, e)
+	}
+
+	return edits
+}
+
+// myers returns the edits transforming a into b.
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	max := n + m
+	offset := max
+	v := make([]int, 2*max+2)
+
+	// trace records v of each d, to walk the path back afterwards.
+	var trace [][]int
+
+outer:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break outer
+			}
+		}
+	}
+
+	// walk back through the trace, collecting each deleted and inserted
+	// line as its own op, in reverse.
+	type op struct {
+		del  bool
+		x, y int
+	}
+	var ops []op
+
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+		}
+
+		if x == prevX {
+			ops = append(ops, op{del: false, x: x, y: prevY})
+		} else {
+			ops = append(ops, op{del: true, x: prevX, y: y})
+		}
+
+		x, y = prevX, prevY
+	}
+
+	// merge adjacent ops into edits, walking forward.
+	var edits []Edit
+	for i := len(ops) - 1; i >= 0; i-- {
+		o := ops[i]
+
+		var last *Edit
+		if len(edits) > 0 {
+			last = &edits[len(edits)-1]
+		}
+
+		// an op continues the last edit if it starts where it ended.
+		if last == nil || last.Line-1+last.Delete != o.x {
+			edits = append(edits, Edit{Line: o.x + 1})
+			last = &edits[len(edits)-1]
+		}
+
+		if o.del {
+			last.Delete++
+		} else {
+			last.Insert = append(last.Insert, b[o.y])
+		}
+	}
+
+	return edits
+}
@@ -0,0 +1,59 @@
+package format
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply the edits to a, from the last to the first so that line numbers
+// remain valid, the same way they're applied to a buffer.
+func apply(a []string, edits []Edit) []string {
+	dst := append([]string{}, a...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		start := e.Line - 1
+		tail := append([]string{}, dst[start+e.Delete:]...)
+		dst = append(append(dst[:start], e.Insert...), tail...)
+	}
+	return dst
+}
+
+func TestDiff(t *testing.T) {
+	a := strings.Split("a b c d e f g", " ")
+	b := strings.Split("a c d x e f g h", " ")
+
+	edits := Diff(a, b)
+
+	want := []Edit{
+		{Line: 2, Delete: 1},
+		{Line: 5, Insert: []string{"x"}},
+		{Line: 8, Insert: []string{"h"}},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("unexpected edits.\n  got:%+v\n want:%+v", edits, want)
+	}
+
+	if got := apply(a, edits); !reflect.DeepEqual(got, b) {
+		t.Errorf("unexpected applied result.\n  got:%q\n want:%q", got, b)
+	}
+}
+
+func TestDiffRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 1000; i++ {
+		a, b := gen(), gen()
+		if got := apply(a, Diff(a, b)); !reflect.DeepEqual(got, b) && !(len(got) == 0 && len(b) == 0) {
+			t.Fatalf("unexpected applied result for %q -> %q. got:%q", a, b, got)
+		}
+	}
+}
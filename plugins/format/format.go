@@ -0,0 +1,176 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// NOTE(leeola): the command is goformat rather than format, as the
+// format.kak bundled with Kakoune has a global format alias, which wins
+// over a command of the same name.
+const (
+	command   = "goformat"
+	hookGroup = "goformat"
+)
+
+// Formatter formats buffers of a single filetype.
+//
+// Either Command or Func must be set.
+type Formatter struct {
+	Filetype string
+
+	// Command is given the buffer content on stdin, and must write the
+	// formatted content to stdout.
+	Command []string
+
+	// Func formats the given content natively in Go.
+	Func func(src string) (string, error)
+
+	// OnWrite formats the buffer before every write.
+	OnWrite bool
+}
+
+func (f Formatter) format(src string) (string, error) {
+	if f.Func != nil {
+		return f.Func(src)
+	}
+
+	cmd := exec.Command(f.Command[0], f.Command[1:]...)
+	cmd.Stdin = strings.NewReader(src)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %s: %s", f.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// Register the given formatters, defining the goformat command and the
+// hooks formatting on write.
+//
+// The formatted result is applied as the minimal set of line edits, so
+// selections, marks and undo history outside of the changed lines are
+// unaffected.
+func Register(k *api.Kak, formatters ...Formatter) error {
+	byFiletype := map[string]Formatter{}
+	var writeFiletypes []string
+	for _, f := range formatters {
+		if f.Func == nil && len(f.Command) == 0 {
+			return fmt.Errorf("formatter for %s needs a Func or Command", f.Filetype)
+		}
+
+		if _, ok := byFiletype[f.Filetype]; ok {
+			return fmt.Errorf("duplicate formatter for %s", f.Filetype)
+		}
+
+		byFiletype[f.Filetype] = f
+		if f.OnWrite {
+			writeFiletypes = append(writeFiletypes, f.Filetype)
+		}
+	}
+
+	setup := "remove-hooks global " + hookGroup
+	if len(writeFiletypes) != 0 {
+		setup += fmt.Sprintf(`
+hook -group %[1]s global WinSetOption filetype=(?:%[2]s) %%{
+  hook -group %[1]s window BufWritePre .* %[3]s
+  hook -once -always window WinSetOption filetype=.* %%{ remove-hooks window %[1]s }
+}`, hookGroup, strings.Join(writeFiletypes, "|"), command)
+	}
+
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "format on write")
+
+	return k.DefineCommand(command, api.DefineCommandOptions{
+		Docstring: "format the buffer with the formatter of its filetype",
+	}, api.BufferFunc{
+		// the buffer may have unsaved changes, so its content is formatted
+		// rather than the file.
+		ExportVars: []string{vars.OptFiletype},
+		Func: func(kak *api.Kak, src string) error {
+			filetype, err := kak.Var(vars.OptFiletype)
+			if err != nil {
+				return err
+			}
+
+			f, ok := byFiletype[filetype]
+			if !ok {
+				return fmt.Errorf("no formatter for filetype: %q", filetype)
+			}
+
+			dst, err := f.format(src)
+			if err != nil {
+				kak.Debugf("format: %s", err)
+				return fmt.Errorf("format failed for %s, see *debug*", filetype)
+			}
+
+			ApplyEdits(kak, splitLines(src), Diff(splitLines(src), splitLines(dst)))
+
+			return nil
+		},
+	})
+}
+
+// splitLines splits buffer content into lines, without the newlines.
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{""}
+	}
+	return strings.Split(s, "\n")
+}
+
+// ApplyEdits applies the given edits of old to the current buffer.
+//
+// The edits are applied within a draft context, from last to first so
+// that each edit's line numbers remain valid. Kakoune adjusts the
+// selections of the user around each modification, so cursors stay on the
+// content they were on.
+func ApplyEdits(kak *api.Kak, old []string, edits []Edit) {
+	if len(edits) == 0 {
+		return
+	}
+
+	// selectLines selects whole lines, including the trailing newline.
+	selectLines := func(first, last int) {
+		kak.Printf("  select %d.1,%d.%d\n", first, last, len(old[last-1])+1)
+	}
+
+	kak.Println("evaluate-commands -draft -save-regs z %{")
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+
+		if len(e.Insert) != 0 {
+			kak.Printf("  set-register z %s\n", api.Quote(strings.Join(e.Insert, "\n")+"\n"))
+		}
+
+		switch {
+		case e.Delete != 0 && len(e.Insert) != 0:
+			selectLines(e.Line, e.Line+e.Delete-1)
+			kak.Println(`  execute-keys '"zR'`)
+		case e.Delete != 0:
+			selectLines(e.Line, e.Line+e.Delete-1)
+			kak.Println("  execute-keys d")
+		case e.Line <= len(old):
+			kak.Printf("  select %d.1,%d.1\n", e.Line, e.Line)
+			kak.Println(`  execute-keys '"zP'`)
+		default:
+			// appending past the last line, paste after its newline.
+			last := len(old)
+			kak.Printf("  select %d.%d,%d.%d\n", last, len(old[last-1])+1, last, len(old[last-1])+1)
+			kak.Println(`  execute-keys '"zp'`)
+		}
+	}
+	kak.Println("}")
+}
@@ -0,0 +1,88 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// Blame is the commit which last changed a line.
+type Blame struct {
+	Line    int
+	Commit  string
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// notCommitted is the commit git blames uncommitted lines on.
+const notCommitted = "0000000000000000000000000000000000000000"
+
+// ParseBlame parses the output of `git blame --line-porcelain`.
+func ParseBlame(output string) ([]Blame, error) {
+	var (
+		blames []Blame
+		b      *Blame
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// the content of the line, ending the entry.
+			b = nil
+		case b == nil:
+			// `<commit> <orig line> <final line> [<lines in group>]`
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+
+			final, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame header: %q", line)
+			}
+
+			blames = append(blames, Blame{Line: final, Commit: fields[0]})
+			b = &blames[len(blames)-1]
+		case strings.HasPrefix(line, "author "):
+			b.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			sec, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame time: %q", line)
+			}
+			b.Time = time.Unix(sec, 0)
+		case strings.HasPrefix(line, "summary "):
+			b.Summary = strings.TrimPrefix(line, "summary ")
+		}
+	}
+
+	return blames, nil
+}
+
+// blameRanges returns the replace-ranges inserting the blame of each line
+// before its content.
+func blameRanges(blames []Blame) []string {
+	var ranges []string
+	for _, b := range blames {
+		text := "not committed yet"
+		if b.Commit != notCommitted {
+			text = fmt.Sprintf("%.8s %s %s", b.Commit, b.Time.Format("2006-01-02"), b.Author)
+		}
+
+		// pad to a fixed width, so the content of every line stays aligned.
+		const width = 40
+		r := []rune(text)
+		if len(r) > width {
+			r = r[:width]
+		}
+		text = string(r) + strings.Repeat(" ", width-len(r)+1)
+
+		ranges = append(ranges, fmt.Sprintf("%d.1+0|{Information}%s{Default}",
+			b.Line, api.EscapeMarkup(text)))
+	}
+	return ranges
+}
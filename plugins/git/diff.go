@@ -0,0 +1,135 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// Hunk is a single changed region of a unified diff.
+type Hunk struct {
+	// OldStart and NewStart are the 1-based first lines of the hunk in each
+	// side. For a side with no lines, the start is the line after which
+	// the lines are, per the unified diff format.
+	OldStart, OldCount int
+	NewStart, NewCount int
+
+	// Old and New are the lines removed and added, without the prefix.
+	Old, New []string
+}
+
+// Contains returns whether the given line of the new side is within the
+// hunk. Deletions contain the line they were deleted after.
+func (h Hunk) Contains(line int) bool {
+	if h.NewCount == 0 {
+		return line == h.firstLine()
+	}
+	return line >= h.NewStart && line < h.NewStart+h.NewCount
+}
+
+// firstLine returns the first line of the new side the hunk is shown on.
+func (h Hunk) firstLine() int {
+	if h.NewStart == 0 {
+		return 1
+	}
+	return h.NewStart
+}
+
+// ParseDiff parses the hunks of a unified diff of a single file, such as
+// the output of `git diff -U0`.
+func ParseDiff(diff string) ([]Hunk, error) {
+	var (
+		hunks []Hunk
+		h     *Hunk
+	)
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			hunk, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			hunks = append(hunks, hunk)
+			h = &hunks[len(hunks)-1]
+		case h == nil:
+			// the file headers before the first hunk.
+		case strings.HasPrefix(line, "-"):
+			h.Old = append(h.Old, line[1:])
+		case strings.HasPrefix(line, "+"):
+			h.New = append(h.New, line[1:])
+		}
+	}
+
+	return hunks, nil
+}
+
+// parseHunkHeader parses a `@@ -1,2 +1,3 @@` header.
+func parseHunkHeader(line string) (Hunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	oldStart, oldCount, err := parseRange(fields[1][1:])
+	if err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	newStart, newCount, err := parseRange(fields[2][1:])
+	if err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	return Hunk{
+		OldStart: oldStart,
+		OldCount: oldCount,
+		NewStart: newStart,
+		NewCount: newCount,
+	}, nil
+}
+
+// parseRange parses a `start,count` range, where the count defaults to 1.
+func parseRange(s string) (int, int, error) {
+	parts := strings.SplitN(s, ",", 2)
+
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count := 1
+	if len(parts) == 2 {
+		count, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return start, count, nil
+}
+
+// hunkFlags returns the line-specs of the gutter signs for the hunks.
+//
+// Added lines are flagged `+`, modified lines `~` and deletions `-`, on the
+// line the lines were deleted after.
+func hunkFlags(hunks []Hunk) []api.LineSpec {
+	var flags []api.LineSpec
+	for _, h := range hunks {
+		if h.NewCount == 0 {
+			flags = append(flags, api.LineSpec{Line: h.firstLine(), Flag: "{red}-"})
+			continue
+		}
+
+		for i := 0; i < h.NewCount; i++ {
+			sign := "{green}+"
+			if i < h.OldCount {
+				sign = "{blue}~"
+			}
+			flags = append(flags, api.LineSpec{Line: h.NewStart + i, Flag: sign})
+		}
+	}
+	return flags
+}
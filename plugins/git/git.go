@@ -0,0 +1,507 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/format"
+)
+
+const (
+	// stateKey is the BufferState key the hunks of the buffer are stored
+	// under, for the commands navigating them.
+	stateKey = "git_hunks"
+
+	hookGroup = "git-gutter"
+
+	statusBuffer = "*git-status*"
+	logBuffer    = "*git-log*"
+	showBuffer   = "*git-show*"
+)
+
+// Register the git commands.
+//
+// The following commands are defined:
+//
+//    git-gutter         show the hunks of the buffer in the gutter
+//    git-hunk-next      jump to the next hunk
+//    git-hunk-previous  jump to the previous hunk
+//    git-hunk-stage     stage the hunk under the cursor
+//    git-hunk-revert    revert the hunk under the cursor
+//    git-blame          show the blame of each line, before its content
+//    git-blame-hide     hide the blame
+//    git-status         list the status of the repository in *git-status*
+//    git-status-open    open the file under the cursor of *git-status*
+//    git-status-add     stage the file under the cursor of *git-status*
+//    git-add-file       stage the given file
+//    git-log            list the recent commits in *git-log*
+//    git-log-show       show the commit under the cursor of *git-log*
+//    git-show-commit    show the given commit in *git-show*
+//
+// The hunks are relative to the index, and include unsaved changes. Once
+// git-gutter has been run in a buffer, it is rerun on every write.
+func Register(k *api.Kak) error {
+	setup := `declare-option -hidden line-specs git_hunk_flags
+declare-option -hidden range-specs git_blame_ranges`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "update the git gutter on write")
+	k.RecordHighlighter("window/git-hunks", "git hunk gutter signs")
+	k.RecordHighlighter("window/git-blame", "git blame")
+
+	// every buffer command diffs the unsaved buffer, read from the file it
+	// is written to.
+	bufferCommand := func(name, doc string, exportVars []string, f func(*api.Kak, buffer) error) error {
+		return k.DefineCommand(name, api.DefineCommandOptions{
+			Docstring: doc,
+		}, api.BufferFunc{
+			ExportVars: exportVars,
+			FileFunc: func(kak *api.Kak, path string) error {
+				b, err := loadBuffer(kak, path)
+				if err != nil {
+					return err
+				}
+				return f(kak, b)
+			},
+		})
+	}
+
+	err := bufferCommand("git-gutter", "show the git hunks of the buffer in the gutter",
+		[]string{vars.Timestamp}, gutter)
+	if err != nil {
+		return err
+	}
+
+	err = bufferCommand("git-hunk-stage", "stage the git hunk under the cursor",
+		[]string{vars.CursorLine}, stage)
+	if err != nil {
+		return err
+	}
+
+	err = bufferCommand("git-hunk-revert", "revert the git hunk under the cursor",
+		[]string{vars.CursorLine}, revert)
+	if err != nil {
+		return err
+	}
+
+	err = bufferCommand("git-blame", "show the git blame of each line",
+		nil, blame)
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-blame-hide", api.DefineCommandOptions{
+		Docstring: "hide the git blame",
+	}, api.Raw(`try %{ remove-highlighter window/git-blame }
+  unset-option buffer git_blame_ranges`))
+	if err != nil {
+		return err
+	}
+
+	moveVars := []string{
+		vars.CursorLine,
+		k.StateVar(stateKey),
+	}
+
+	err = k.DefineCommand("git-hunk-next", api.DefineCommandOptions{
+		Docstring: "jump to the next git hunk",
+	}, api.Func{
+		ExportVars: moveVars,
+		Func: func(kak *api.Kak) error {
+			return move(kak, true)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-hunk-previous", api.DefineCommandOptions{
+		Docstring: "jump to the previous git hunk",
+	}, api.Func{
+		ExportVars: moveVars,
+		Func: func(kak *api.Kak) error {
+			return move(kak, false)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-status", api.DefineCommandOptions{
+		Docstring: "list the git status in " + statusBuffer,
+	}, api.Func{
+		Func: status,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-status-open", api.DefineCommandOptions{
+		Docstring: "open the file under the cursor of " + statusBuffer,
+	}, api.Raw(`evaluate-commands -save-regs 1 %{
+    execute-keys 'xs^.. (?:.* -> )?(.+)$<ret>'
+    edit -existing -- %reg{1}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-status-add", api.DefineCommandOptions{
+		Docstring: "stage the file under the cursor of " + statusBuffer,
+	}, api.Raw(`evaluate-commands -save-regs 1 %{
+    execute-keys 'xs^.. (?:.* -> )?(.+)$<ret>'
+    git-add-file %reg{1}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-add-file", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "stage the given file",
+	}, api.Func{
+		Func: func(kak *api.Kak) error {
+			file, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			if _, err := run("", "", "add", "--", file); err != nil {
+				return err
+			}
+
+			return status(kak)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-log", api.DefineCommandOptions{
+		Docstring: "list the recent git commits in " + logBuffer,
+	}, api.Func{
+		Func: func(kak *api.Kak) error {
+			out, err := run("", "", "log", "--oneline", "--decorate", "--no-color", "-n", "256")
+			if err != nil {
+				return err
+			}
+
+			scratch(kak, logBuffer, out)
+			kak.Println("map buffer normal <ret> :git-log-show<ret>")
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("git-log-show", api.DefineCommandOptions{
+		Docstring: "show the commit under the cursor of " + logBuffer,
+	}, api.Raw(`evaluate-commands -save-regs 1 %{
+    execute-keys 'xs^([0-9a-f]+)<ret>'
+    git-show-commit %reg{1}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("git-show-commit", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "show the given git commit in " + showBuffer,
+	}, api.Func{
+		Func: func(kak *api.Kak) error {
+			commit, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			out, err := run("", "", "show", "--no-color", commit, "--")
+			if err != nil {
+				return err
+			}
+
+			scratch(kak, showBuffer, out)
+			kak.Println("set-option buffer filetype diff")
+			return nil
+		},
+	})
+}
+
+// buffer is the unsaved content of the buffer, as diffed against the
+// index.
+type buffer struct {
+	// file is the buffer file, and tmp the content written by the first
+	// func of every buffer command.
+	file, tmp string
+	content   string
+	hunks     []Hunk
+}
+
+// dir returns the directory git is run in for the buffer.
+func (b buffer) dir() string {
+	return filepath.Dir(b.file)
+}
+
+// path returns the path of the buffer file relative to the repository
+// root, as used within patches.
+func (b buffer) path() (string, error) {
+	prefix, err := run(b.dir(), "", "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(prefix) + filepath.Base(b.file), nil
+}
+
+// hunkAt returns the hunk containing the given line.
+func (b buffer) hunkAt(line int) (Hunk, error) {
+	for _, h := range b.hunks {
+		if h.Contains(line) {
+			return h, nil
+		}
+	}
+	return Hunk{}, errors.New("no git hunk on this line")
+}
+
+// loadBuffer reads the file tmp the buffer was written to, and diffs it
+// against the version of the file in the index.
+func loadBuffer(kak *api.Kak, tmp string) (buffer, error) {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return buffer{}, err
+	}
+
+	content, err := ioutil.ReadFile(tmp)
+	if err != nil {
+		return buffer{}, err
+	}
+
+	b := buffer{file: buffile, tmp: tmp, content: string(content)}
+
+	index, err := run(b.dir(), "", "show", ":./"+filepath.Base(buffile))
+	if err != nil {
+		return buffer{}, fmt.Errorf("not tracked by git: %q", buffile)
+	}
+
+	indexTmp := tmp + ".index"
+	if err := ioutil.WriteFile(indexTmp, []byte(index), 0600); err != nil {
+		return buffer{}, err
+	}
+	defer os.Remove(indexTmp)
+
+	diff, err := run("", "", "diff", "--no-index", "--no-color", "--no-ext-diff",
+		"-U0", "--", indexTmp, tmp)
+	if err != nil {
+		return buffer{}, err
+	}
+
+	b.hunks, err = ParseDiff(diff)
+	if err != nil {
+		return buffer{}, err
+	}
+
+	return b, nil
+}
+
+// gutter shows the hunks of the buffer as gutter signs.
+func gutter(kak *api.Kak, b buffer) error {
+	kak.SetLineSpecs("git_hunk_flags", hunkFlags(b.hunks))
+	kak.Println("try %{ add-highlighter window/git-hunks flag-lines default git_hunk_flags }")
+
+	kak.Printf("remove-hooks buffer %s\n", hookGroup)
+	kak.Printf("hook -group %s buffer BufWritePost .* git-gutter\n", hookGroup)
+
+	return kak.BufferState().Set(stateKey, b.hunks)
+}
+
+// stage applies the hunk under the cursor to the index.
+func stage(kak *api.Kak, b buffer) error {
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return err
+	}
+
+	h, err := b.hunkAt(line)
+	if err != nil {
+		return err
+	}
+
+	path, err := b.path()
+	if err != nil {
+		return err
+	}
+
+	// the hunk is applied to the index alone, so its new start is relative
+	// to the index rather than the buffer.
+	newStart := h.OldStart
+	if h.OldCount == 0 {
+		newStart++
+	}
+	if h.NewCount == 0 {
+		newStart--
+	}
+
+	var patch bytes.Buffer
+	fmt.Fprintf(&patch, "diff --git a/%[1]s b/%[1]s\n--- a/%[1]s\n+++ b/%[1]s\n", path)
+	fmt.Fprintf(&patch, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldCount, newStart, h.NewCount)
+	for _, l := range h.Old {
+		fmt.Fprintf(&patch, "-%s\n", l)
+	}
+	for _, l := range h.New {
+		fmt.Fprintf(&patch, "+%s\n", l)
+	}
+
+	if _, err := run(b.dir(), patch.String(), "apply", "--cached", "--unidiff-zero", "-"); err != nil {
+		return err
+	}
+
+	kak.Println("git-gutter")
+	return nil
+}
+
+// revert replaces the hunk under the cursor with the lines of the index.
+func revert(kak *api.Kak, b buffer) error {
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return err
+	}
+
+	h, err := b.hunkAt(line)
+	if err != nil {
+		return err
+	}
+
+	edit := format.Edit{
+		Line:   h.NewStart,
+		Delete: h.NewCount,
+		Insert: h.Old,
+	}
+	// deletions are restored after the line they were deleted after.
+	if h.NewCount == 0 {
+		edit.Line++
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.content, "\n"), "\n")
+	format.ApplyEdits(kak, lines, []format.Edit{edit})
+
+	kak.Println("git-gutter")
+	return nil
+}
+
+// blame shows the blame of each line of the buffer as virtual text.
+func blame(kak *api.Kak, b buffer) error {
+	out, err := run(b.dir(), "", "blame", "--line-porcelain",
+		"--contents", b.tmp, "--", filepath.Base(b.file))
+	if err != nil {
+		return err
+	}
+
+	blames, err := ParseBlame(out)
+	if err != nil {
+		return err
+	}
+
+	kak.Printf("set-option buffer git_blame_ranges %%val{timestamp} %s\n",
+		api.QuoteArgs(blameRanges(blames)...))
+	kak.Println("try %{ add-highlighter window/git-blame replace-ranges git_blame_ranges }")
+
+	return nil
+}
+
+// move selects the first line of the next or previous hunk relative to the
+// cursor, wrapping around the buffer.
+func move(kak *api.Kak, next bool) error {
+	var hunks []Hunk
+	if err := kak.BufferState().Get(stateKey, &hunks); err != nil && err != api.ErrStateNotFound {
+		return err
+	}
+
+	if len(hunks) == 0 {
+		return errors.New("no git hunks, see git-gutter")
+	}
+
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return err
+	}
+
+	// hunks are in order, so the first after or the last before is the one
+	// to jump to.
+	target := hunks[0]
+	if next {
+		for _, h := range hunks {
+			if h.firstLine() > line {
+				target = h
+				break
+			}
+		}
+	} else {
+		target = hunks[len(hunks)-1]
+		for i := len(hunks) - 1; i >= 0; i-- {
+			if hunks[i].firstLine() < line {
+				target = hunks[i]
+				break
+			}
+		}
+	}
+
+	kak.Printf("select %d.1,%d.1\n", target.firstLine(), target.firstLine())
+	kak.Printf("echo -- %s\n", api.Quote(fmt.Sprintf("git hunk: -%d +%d",
+		target.OldCount, target.NewCount)))
+
+	return nil
+}
+
+// status renders the status of the repository into the status buffer.
+func status(kak *api.Kak) error {
+	out, err := run("", "", "status", "--short", "--branch")
+	if err != nil {
+		return err
+	}
+
+	scratch(kak, statusBuffer, out)
+	kak.Println("map buffer normal <ret> :git-status-open<ret>")
+	kak.Println("map buffer normal a :git-status-add<ret>")
+	kak.Println("map buffer normal r :git-status<ret>")
+
+	return nil
+}
+
+// scratch replaces the content of the given scratch buffer, creating it if
+// needed. The content is pasted through the z register, which is saved.
+func scratch(kak *api.Kak, buffer, content string) {
+	kak.Printf("edit -scratch %s\n", buffer)
+	kak.Printf("evaluate-commands -save-regs z %s\n", api.QuoteBlock(
+		"set-register z "+api.Quote(content)+"\nexecute-keys '%\"zRgg'"))
+}
+
+// run runs git in dir with the given stdin, returning stdout.
+//
+// Diffs exit 1 when there are differences, which is not an error.
+func run(dir, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && args[0] == "diff" && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/kaktest"
+)
+
+func TestParseDiff(t *testing.T) {
+	diff := `diff --git a/a b/b
+index 3b18e51..0c5e2a1 100644
+--- a/a
++++ b/b
+@@ -0,0 +1 @@
++first
+@@ -3 +4,2 @@ func main() {
+-old
++new
++added
+@@ -9,2 +10,0 @@
+-gone
+-also gone
+\ No newline at end of file
+`
+
+	want := []Hunk{
+		{OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 1,
+			New: []string{"first"}},
+		{OldStart: 3, OldCount: 1, NewStart: 4, NewCount: 2,
+			Old: []string{"old"}, New: []string{"new", "added"}},
+		{OldStart: 9, OldCount: 2, NewStart: 10, NewCount: 0,
+			Old: []string{"gone", "also gone"}},
+	}
+
+	got, err := ParseDiff(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	wantFlags := []api.LineSpec{
+		{Line: 1, Flag: "{green}+"}, {Line: 4, Flag: "{blue}~"},
+		{Line: 5, Flag: "{green}+"}, {Line: 10, Flag: "{red}-"},
+	}
+	if flags := hunkFlags(got); !reflect.DeepEqual(flags, wantFlags) {
+		t.Errorf("want flags %q, got %q", wantFlags, flags)
+	}
+}
+
+func TestParseBlame(t *testing.T) {
+	output := `c720e99a0c1b4a8f1d7c0e6f2d9b3a5e4f6a7b8c 1 1 2
+author Lee Olayvar
+author-mail <lee@example.com>
+author-time 1500000000
+author-tz -0700
+summary init
+filename main.go
+	package main
+c720e99a0c1b4a8f1d7c0e6f2d9b3a5e4f6a7b8c 2 2
+author Lee Olayvar
+author-time 1500000000
+summary init
+filename main.go
+	
+0000000000000000000000000000000000000000 3 3 1
+author Not Committed Yet
+author-time 1600000000
+summary Version of main.go from main.go
+filename main.go
+	func main() {}
+`
+
+	got, err := ParseBlame(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("want 3 blames, got %d: %+v", len(got), got)
+	}
+
+	for i, b := range got {
+		if b.Line != i+1 {
+			t.Errorf("blame %d: want line %d, got %d", i, i+1, b.Line)
+		}
+	}
+
+	if got[0].Author != "Lee Olayvar" || got[0].Summary != "init" || got[0].Time.Unix() != 1500000000 {
+		t.Errorf("unexpected blame: %+v", got[0])
+	}
+
+	if got[2].Commit != notCommitted {
+		t.Errorf("want uncommitted blame, got %+v", got[2])
+	}
+}
+
+func TestScratch(t *testing.T) {
+	res, err := kaktest.New().Run(api.Func{
+		Func: func(kak *api.Kak) error {
+			scratch(kak, "*git*", "## main\n M it's.go {x}\n")
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "edit -scratch *git*\n" +
+		"evaluate-commands -save-regs z %{set-register z '## main\n M it''s.go {x}\n'\nexecute-keys '%\"zRgg'}\n"
+	if res.Output != want {
+		t.Errorf("got:%q, want:%q", res.Output, want)
+	}
+}
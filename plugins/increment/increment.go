@@ -0,0 +1,265 @@
+package increment
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// cursorsRegister holds the selections of the user, while the lines of
+// their cursors are selected instead.
+const cursorsRegister = "c"
+
+// numberRe matches the numbers of a line, hex and octal ones by their
+// prefix.
+var numberRe = regexp.MustCompile(`0[xX][0-9a-fA-F]+|0[oO][0-7]+|-?[0-9]+`)
+
+// Number is a number within a line, from byte Begin to End.
+type Number struct {
+	Begin, End int
+	Text       string
+}
+
+// Find returns the number of the line at the byte offset of the cursor,
+// or the first one following it.
+func Find(line string, cursor int) (Number, bool) {
+	for _, m := range numberRe.FindAllStringIndex(line, -1) {
+		begin, end := m[0], m[1]
+		if end <= cursor {
+			continue
+		}
+
+		// a minus following a word is not a sign, as in `x-1`.
+		if line[begin] == '-' && begin > 0 && isWord(line[begin-1]) {
+			begin++
+		}
+		return Number{Begin: begin, End: end, Text: line[begin:end]}, true
+	}
+	return Number{}, false
+}
+
+func isWord(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Add returns the number with n added, in the same base. Decimals keep
+// their zero padding, and hex numbers their width and case.
+func Add(number string, n int64) (string, error) {
+	prefix, digits, base := "", number, 10
+	if len(number) > 2 && number[0] == '0' {
+		switch number[1] {
+		case 'x', 'X':
+			prefix, digits, base = number[:2], number[2:], 16
+		case 'o', 'O':
+			prefix, digits, base = number[:2], number[2:], 8
+		}
+	}
+
+	if base == 10 {
+		v, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		v += n
+
+		width := len(strings.TrimPrefix(number, "-"))
+		if !strings.HasPrefix(strings.TrimPrefix(number, "-"), "0") {
+			width = 0
+		}
+		s := strconv.FormatInt(abs(v), 10)
+		if len(s) < width {
+			s = strings.Repeat("0", width-len(s)) + s
+		}
+		if v < 0 {
+			s = "-" + s
+		}
+		return s, nil
+	}
+
+	v, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return "", err
+	}
+	// hex and octal numbers are unsigned, so stop at zero.
+	switch {
+	case n >= 0:
+		v += uint64(n)
+	case uint64(-n) > v:
+		v = 0
+	default:
+		v -= uint64(-n)
+	}
+
+	s := strconv.FormatUint(v, base)
+	if len(s) < len(digits) {
+		s = strings.Repeat("0", len(digits)-len(s)) + s
+	}
+	if digits != strings.ToLower(digits) {
+		s = strings.ToUpper(s)
+	}
+	return prefix + s, nil
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// linesDraft returns the expansion selecting the lines of the cursors
+// within a draft context, in which the expansions run. The selections of
+// the user are held within cursorsRegister.
+//
+// NOTE(leeola): the draft context restores the selections once done, and
+// Kakoune adjusts them to the replaced numbers.
+func linesDraft(exps ...api.Expansion) api.Evaluate {
+	return api.Eval(api.EvalOptions{Draft: true, SaveRegs: cursorsRegister}, append([]api.Expansion{
+		api.Raw(`set-register ` + cursorsRegister + ` "%val{selections_desc}"`),
+		api.Raw("execute-keys ';x'"),
+	}, exps...)...)
+}
+
+// Register the increment and decrement commands.
+//
+// The following commands are defined:
+//
+//    increment  add the count, 1 by default, to the number under each cursor
+//    decrement  subtract the count, 1 by default, from the number under each cursor
+//
+// The number under a cursor is the one it is on, or else the first
+// following it on its line. Map them to keys as with Vim, passing the
+// count of the mapping:
+//
+//    map global normal <c-a> ':increment %val{count}<ret>'
+//    map global normal <c-x> ':decrement %val{count}<ret>'
+func Register(k *api.Kak) error {
+	err := k.DefineCommand("increment", api.DefineCommandOptions{
+		MaxParams: 1,
+		Docstring: "add the count, 1 by default, to the number under each cursor",
+	}, linesDraft(api.Func{
+		ExportVars: exportVars,
+		Func: func(kak *api.Kak) error {
+			return apply(kak, 1)
+		},
+	}))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("decrement", api.DefineCommandOptions{
+		MaxParams: 1,
+		Docstring: "subtract the count, 1 by default, from the number under each cursor",
+	}, linesDraft(api.Func{
+		ExportVars: exportVars,
+		Func: func(kak *api.Kak) error {
+			return apply(kak, -1)
+		},
+	}))
+}
+
+var exportVars = []string{
+	api.RegVar(cursorsRegister),
+	vars.SelectionsDesc,
+	vars.QuotedSelections,
+}
+
+// count returns the count param, where 0 is no count as with %val{count}.
+func count(kak *api.Kak) (int64, error) {
+	params := kak.Params()
+	if len(params) == 0 || params[0] == "" {
+		return 1, nil
+	}
+	n, err := strconv.ParseInt(params[0], 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid count: %q", params[0])
+	}
+	if n == 0 {
+		return 1, nil
+	}
+	return n, nil
+}
+
+// apply replaces the number under each cursor with it plus the count
+// times sign, with the lines of the cursors selected.
+func apply(kak *api.Kak, sign int64) error {
+	n, err := count(kak)
+	if err != nil {
+		return err
+	}
+
+	descs, err := kak.Reg(cursorsRegister)
+	if err != nil {
+		return err
+	}
+	cursors, err := api.ParseSelections(strings.Join(descs, " "))
+	if err != nil {
+		return err
+	}
+
+	sels, err := kak.Selections()
+	if err != nil {
+		return err
+	}
+	texts, err := kak.SelectionTexts()
+	if err != nil {
+		return err
+	}
+	if len(texts) != len(sels) {
+		return errors.New("selections and their contents differ")
+	}
+
+	// lines are the contents of the lines of the cursors, by line.
+	lines := map[int]string{}
+	for i, s := range sels {
+		first := s.Range().Begin.Line
+		for j, line := range strings.Split(strings.TrimSuffix(texts[i], "\n"), "\n") {
+			lines[first+j] = line
+		}
+	}
+
+	var (
+		ranges []api.Selection
+		values []string
+		seen   = map[api.Coord]bool{}
+	)
+	for _, c := range cursors {
+		line, ok := lines[c.Cursor.Line]
+		if !ok {
+			continue
+		}
+		num, ok := Find(line, c.Cursor.Column-1)
+		if !ok {
+			continue
+		}
+		begin := api.Coord{Line: c.Cursor.Line, Column: num.Begin + 1}
+		if seen[begin] {
+			continue
+		}
+		seen[begin] = true
+
+		v, err := Add(num.Text, sign*n)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, api.Selection{
+			Anchor: begin,
+			Cursor: api.Coord{Line: c.Cursor.Line, Column: num.End},
+		})
+		values = append(values, v)
+	}
+	if len(ranges) == 0 {
+		return api.WithSeverity(errors.New("no number under the cursors"), api.SeverityWarning)
+	}
+
+	if err := kak.Select(ranges...); err != nil {
+		return err
+	}
+	kak.ReplaceSelections(values)
+	return nil
+}
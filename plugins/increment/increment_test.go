@@ -0,0 +1,59 @@
+package increment
+
+import "testing"
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		line   string
+		cursor int
+		want   string
+		ok     bool
+	}{
+		{"x := 41", 0, "41", true},
+		{"x := 41", 6, "41", true},
+		{"a 1 b 2", 3, "2", true},
+		{"y = -5", 0, "-5", true},
+		{"x-1", 0, "1", true},
+		{"color: 0xFF00ff;", 2, "0xFF00ff", true},
+		{"no numbers", 0, "", false},
+		{"1 then", 2, "", false},
+	}
+	for _, test := range tests {
+		got, ok := Find(test.line, test.cursor)
+		if ok != test.ok || got.Text != test.want {
+			t.Errorf("%q at %d: want %q, got %q", test.line, test.cursor, test.want, got.Text)
+		}
+		if ok && test.line[got.Begin:got.End] != got.Text {
+			t.Errorf("%q: wrong bounds %d:%d", test.line, got.Begin, got.End)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		number string
+		n      int64
+		want   string
+	}{
+		{"41", 1, "42"},
+		{"0", -1, "-1"},
+		{"-1", 2, "1"},
+		{"007", 1, "008"},
+		{"010", -11, "-001"},
+		{"0x0f", 1, "0x10"},
+		{"0xFF", 1, "0x100"},
+		{"0XFe", 1, "0XFF"},
+		{"0o7", 1, "0o10"},
+		{"0x01", -5, "0x00"},
+	}
+	for _, test := range tests {
+		got, err := Add(test.number, test.n)
+		if err != nil {
+			t.Errorf("%q: %s", test.number, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q + %d: want %q, got %q", test.number, test.n, test.want, got)
+		}
+	}
+}
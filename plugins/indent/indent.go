@@ -0,0 +1,259 @@
+package indent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	hookGroup = "indent"
+
+	// lineRegister and previousRegister hold the line of the cursor and
+	// the line above it, while indenting.
+	lineRegister     = "l"
+	previousRegister = "p"
+)
+
+// Line is the line indented by a Func.
+type Line struct {
+	// Text and Previous are the text of the line and the line above it,
+	// without their newlines. Previous is empty on the first line.
+	Text     string
+	Previous string
+
+	// Number is the number of the line, from 1.
+	Number int
+
+	// IndentWidth and TabStop are the options of the buffer, IndentWidth
+	// being the width of a tab if indentwidth is 0.
+	IndentWidth int
+	TabStop     int
+}
+
+// Columns returns the width of the indentation of the text, tabs being
+// TabStop wide.
+func (l Line) Columns(text string) int {
+	return Columns(text, l.TabStop)
+}
+
+// Func returns the indentation of the line, in columns, or false to leave
+// the line as is.
+type Func func(l Line) (int, bool)
+
+// Indenter indents the buffers of a filetype.
+type Indenter struct {
+	Filetype string
+
+	// Chars is a regex of the chars reindenting the line when inserted,
+	// in addition to newlines, such as `[}\])]`.
+	Chars string
+
+	Func Func
+}
+
+// Columns returns the width of the indentation of the text, tabs being
+// tabstop wide.
+func Columns(text string, tabstop int) int {
+	if tabstop < 1 {
+		tabstop = 8
+	}
+	columns := 0
+	for _, c := range text {
+		switch c {
+		case ' ':
+			columns++
+		case '\t':
+			columns += tabstop - columns%tabstop
+		default:
+			return columns
+		}
+	}
+	return columns
+}
+
+// Indentation returns the indentation of the given columns, indenting
+// with tabs of the tabstop followed by spaces if indentwidth is 0, as
+// Kakoune does, and with spaces otherwise.
+func Indentation(columns, indentwidth, tabstop int) string {
+	if columns <= 0 {
+		return ""
+	}
+	if indentwidth != 0 || tabstop < 1 {
+		return strings.Repeat(" ", columns)
+	}
+	return strings.Repeat("\t", columns/tabstop) + strings.Repeat(" ", columns%tabstop)
+}
+
+// Previous returns an indenter Func keeping the indentation of the
+// previous line, adding IndentWidth after a line ending with one of open
+// and removing it from a line starting with one of close. Eg, for braces:
+//
+//    indent.Previous("{([", "})]")
+func Previous(open, close string) Func {
+	return func(l Line) (int, bool) {
+		columns := l.Columns(l.Previous)
+		prev := strings.TrimSpace(l.Previous)
+		if prev != "" && strings.ContainsAny(prev[len(prev)-1:], open) {
+			columns += l.IndentWidth
+		}
+		text := strings.TrimSpace(l.Text)
+		if text != "" && strings.ContainsAny(text[:1], close) {
+			columns -= l.IndentWidth
+		}
+		if columns < 0 {
+			columns = 0
+		}
+		return columns, true
+	}
+}
+
+// Register the indenters, reindenting the line of each cursor when a
+// newline or one of the Chars of the filetype is inserted.
+//
+// The following command is defined:
+//
+//    indent-line  reindent the lines of the cursors by the indenter of the filetype
+//
+// NOTE(leeola): the indent hooks bundled with Kakoune indent as well, so
+// disable them for the filetypes, such as by removing their hooks:
+//
+//    hook global WinSetOption filetype=go %{ remove-hooks window go-indent }
+func Register(k *api.Kak, indenters ...Indenter) error {
+	byFiletype := map[string]Func{}
+	setup := "remove-hooks global " + hookGroup
+	for _, in := range indenters {
+		if in.Func == nil {
+			return fmt.Errorf("indenter for %s needs a Func", in.Filetype)
+		}
+		if _, ok := byFiletype[in.Filetype]; ok {
+			return fmt.Errorf("duplicate indenter for %s", in.Filetype)
+		}
+		byFiletype[in.Filetype] = in.Func
+
+		chars := `\n`
+		if in.Chars != "" {
+			chars += "|" + in.Chars
+		}
+		setup += fmt.Sprintf(`
+hook -group %[1]s global WinSetOption filetype=%[2]s %%{
+  hook -group %[1]s window InsertChar %[3]s indent-line
+  hook -once -always window WinSetOption filetype=.* %%{ remove-hooks window %[1]s }
+}`, hookGroup, in.Filetype, api.Quote(chars))
+	}
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "reindent lines as they are typed")
+
+	return k.DefineCommand("indent-line", api.DefineCommandOptions{
+		Docstring: "reindent the lines of the cursors by the indenter of the filetype",
+	}, api.Eval(api.EvalOptions{
+		Draft:    true,
+		Itersel:  true,
+		SaveRegs: lineRegister + previousRegister,
+	},
+		api.Raw(`execute-keys ';'
+evaluate-commands -draft %{ execute-keys x; set-register `+lineRegister+` %val{selection} }
+evaluate-commands -draft %{ execute-keys kx; set-register `+previousRegister+` %val{selection} }`),
+		api.Func{
+			ExportVars: []string{
+				api.RegVar(lineRegister),
+				api.RegVar(previousRegister),
+				vars.CursorLine,
+				vars.OptFiletype,
+				"opt_indentwidth",
+				"opt_tabstop",
+			},
+			Func: func(kak *api.Kak) error {
+				return indentLine(kak, byFiletype)
+			},
+		},
+	))
+}
+
+// indentLine reindents the line of the cursor, with the line and the one
+// above it held within the registers.
+func indentLine(kak *api.Kak, byFiletype map[string]Func) error {
+	filetype, err := kak.Var(vars.OptFiletype)
+	if err != nil {
+		return err
+	}
+	f, ok := byFiletype[filetype]
+	if !ok {
+		return fmt.Errorf("no indenter for filetype: %q", filetype)
+	}
+
+	l, indentwidth, err := line(kak)
+	if err != nil {
+		return err
+	}
+	columns, ok := f(l)
+	if !ok {
+		return nil
+	}
+
+	current := l.Text[:len(l.Text)-len(strings.TrimLeft(l.Text, " \t"))]
+	indent := Indentation(columns, indentwidth, l.TabStop)
+	if indent == current {
+		return nil
+	}
+
+	switch {
+	case current == "":
+		// pasted before the first char, or the newline of an empty line.
+		kak.Printf("select %d.1,%d.1\n", l.Number, l.Number)
+		kak.Println("evaluate-commands -save-regs z " + api.Quote(
+			"set-register z "+api.Quote(indent)+"\nexecute-keys '\"zP'"))
+	case indent == "":
+		kak.Printf("select %d.1,%d.%d\n", l.Number, l.Number, len(current))
+		kak.Println("execute-keys d")
+	default:
+		kak.Printf("select %d.1,%d.%d\n", l.Number, l.Number, len(current))
+		kak.ReplaceSelections([]string{indent})
+	}
+	return nil
+}
+
+// line returns the line of the cursor, from the registers and options,
+// and the indentwidth option.
+func line(kak *api.Kak) (Line, int, error) {
+	text, err := kak.Reg(lineRegister)
+	if err != nil {
+		return Line{}, 0, err
+	}
+	previous, err := kak.Reg(previousRegister)
+	if err != nil {
+		return Line{}, 0, err
+	}
+	number, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return Line{}, 0, err
+	}
+	indentwidth, err := kak.VarInt("opt_indentwidth")
+	if err != nil {
+		return Line{}, 0, err
+	}
+	tabstop, err := kak.VarInt("opt_tabstop")
+	if err != nil {
+		return Line{}, 0, err
+	}
+
+	l := Line{
+		Text:        strings.TrimSuffix(strings.Join(text, ""), "\n"),
+		Previous:    strings.TrimSuffix(strings.Join(previous, ""), "\n"),
+		Number:      number,
+		IndentWidth: indentwidth,
+		TabStop:     tabstop,
+	}
+	if l.IndentWidth == 0 {
+		l.IndentWidth = tabstop
+	}
+	if l.Number <= 1 {
+		// k stays on the first line.
+		l.Previous = ""
+	}
+	return l, indentwidth, nil
+}
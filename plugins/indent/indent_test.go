@@ -0,0 +1,81 @@
+package indent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/kaktest"
+)
+
+func TestIndentation(t *testing.T) {
+	if got := Columns("\t  x", 4); got != 6 {
+		t.Errorf("want 6, got %d", got)
+	}
+	if got := Columns("  \tx", 4); got != 4 {
+		t.Errorf("want 4, got %d", got)
+	}
+	if got := Indentation(6, 0, 4); got != "\t  " {
+		t.Errorf("got %q", got)
+	}
+	if got := Indentation(6, 2, 4); got != "      " {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	f := Previous("{", "}")
+	tests := []struct {
+		previous, text string
+		want           int
+	}{
+		{"func f() {", "", 4},
+		{"    x := 1", "", 4},
+		{"    x := 1", "}", 0},
+		{"", "}", 0},
+	}
+	for _, test := range tests {
+		got, ok := f(Line{Previous: test.previous, Text: test.text, IndentWidth: 4, TabStop: 4})
+		if !ok || got != test.want {
+			t.Errorf("%q %q: want %d, got %d", test.previous, test.text, test.want, got)
+		}
+	}
+}
+
+func TestIndentLine(t *testing.T) {
+	byFiletype := map[string]Func{"go": Previous("{", "}")}
+	run := func(previous, text string) string {
+		kak := kaktest.New()
+		kak.Vars["opt_filetype"] = "go"
+		kak.Vars["opt_indentwidth"] = "0"
+		kak.Vars["opt_tabstop"] = "4"
+		kak.Vars["cursor_line"] = "2"
+		kak.SetQuotedList("reg_"+lineRegister, text+"\n")
+		kak.SetQuotedList("reg_"+previousRegister, previous+"\n")
+
+		res, err := kak.Run(api.Func{
+			ExportVars: []string{
+				api.RegVar(lineRegister), api.RegVar(previousRegister),
+				"cursor_line", "opt_filetype", "opt_indentwidth", "opt_tabstop",
+			},
+			Func: func(kak *api.Kak) error {
+				return indentLine(kak, byFiletype)
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Output
+	}
+
+	if got := run("func f() {", ""); !strings.Contains(got, "select 2.1,2.1\n") ||
+		!strings.Contains(got, `set-register z ''`+"\t"+`''`) {
+		t.Errorf("got %q", got)
+	}
+	if got := run("\tx", "\t}"); got != "select 2.1,2.1\nexecute-keys d\n" {
+		t.Errorf("got %q", got)
+	}
+	if got := run("\tx", "\ty"); got != "" {
+		t.Errorf("got %q", got)
+	}
+}
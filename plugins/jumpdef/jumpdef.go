@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/cmds"
 	"github.com/leeola/gokakoune/api/vars"
 	"github.com/leeola/gokakoune/util"
 )
@@ -50,7 +51,7 @@ var JumpDefExpressions = api.Expansions{
 			desc := strings.TrimSpace(split[3])
 
 			// TODO(leeola): make this a native Go command.
-			kak.Command("edit", file, line, col)
+			kak.Command(cmds.Edit, file, line, col)
 
 			kak.Echo(desc)
 
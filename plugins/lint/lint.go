@@ -0,0 +1,477 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	// stateKey is the BufferState key the diagnostics of a buffer are
+	// stored under, for the commands navigating them.
+	stateKey = "lint"
+
+	// NOTE(leeola): the commands, options and highlighters are prefixed
+	// with golint rather than lint, as the lint.kak bundled with Kakoune
+	// already defines lint-buffer, lint_flags and a global lint alias,
+	// which would shadow the commands here.
+	hookGroup = "golint"
+
+	// listBuffer is the name of the diagnostics list buffer.
+	listBuffer = "*golint*"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+// Face returns the name of the face declared for the severity.
+func (s Severity) Face() string {
+	switch s {
+	case SeverityWarning:
+		return "LintWarning"
+	case SeverityInfo:
+		return "LintInfo"
+	default:
+		return "LintError"
+	}
+}
+
+// Diagnostic is a single problem reported by a linter.
+type Diagnostic struct {
+	// File is the file the diagnostic is for. An empty file means the
+	// file being linted.
+	File string
+
+	Line   int
+	Column int
+
+	// EndLine and EndColumn optionally mark the end of the problem. If not
+	// set, the diagnostic covers a single character.
+	EndLine   int
+	EndColumn int
+
+	Severity Severity
+	Message  string
+}
+
+// Range returns the range of the diagnostic, a single character unless the
+// end is set.
+func (d Diagnostic) Range() api.Range {
+	begin := api.Coord{Line: d.Line, Column: d.Column}
+	if d.EndLine == 0 {
+		return api.Range{Begin: begin, End: begin}
+	}
+	return api.Range{Begin: begin, End: api.Coord{Line: d.EndLine, Column: d.EndColumn}}
+}
+
+// Linter lints files of a single filetype.
+//
+// Either Command and Parse, or Func must be set.
+type Linter struct {
+	Filetype string
+
+	// Command is run with the path of the file to lint appended. Both
+	// stdout and stderr are given to Parse.
+	Command []string
+	Parse   Parser
+
+	// Func lints the given file natively in Go.
+	Func func(file string) ([]Diagnostic, error)
+
+	// OnIdle additionally lints the unsaved buffer when idle, rather than
+	// only when written. The buffer is written to a temporary file of the
+	// same name for linting, so the linter must not depend on the location
+	// of the file.
+	OnIdle bool
+}
+
+func (l Linter) lint(file string) ([]Diagnostic, error) {
+	if l.Func != nil {
+		return l.Func(file)
+	}
+
+	args := append(append([]string{}, l.Command[1:]...), file)
+	stdout, stderr, exit, err := util.Exec(l.Command[0], args...)
+	if err != nil {
+		return nil, err
+	}
+
+	diags, err := l.Parse(stdout + stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE(leeola): linters commonly exit non-zero when they report
+	// anything, so the exit code alone means nothing. Exiting non-zero
+	// without any parseable output however is likely a broken linter.
+	if exit != 0 && len(diags) == 0 {
+		return nil, fmt.Errorf("%s exit %d: %s",
+			l.Command[0], exit, strings.TrimSpace(stderr))
+	}
+
+	return diags, nil
+}
+
+// Register the given linters, defining the lint commands and the hooks
+// running them.
+//
+// The following commands are defined:
+//
+//    golint           lint the current file
+//    golint-buffer    lint the unsaved buffer, through a temporary file
+//    golint-next      jump to the next diagnostic
+//    golint-previous  jump to the previous diagnostic
+//    golint-list      list the diagnostics of the buffer in *golint*
+//
+// Diagnostics are shown as gutter flags and underlined ranges, in windows
+// of the registered filetypes.
+func Register(k *api.Kak, linters ...Linter) error {
+	byFiletype := map[string]Linter{}
+	var filetypes, idleFiletypes []string
+	for _, l := range linters {
+		if l.Func == nil && (len(l.Command) == 0 || l.Parse == nil) {
+			return fmt.Errorf("linter for %s needs a Func, or a Command and Parse", l.Filetype)
+		}
+
+		if _, ok := byFiletype[l.Filetype]; ok {
+			return fmt.Errorf("duplicate linter for %s", l.Filetype)
+		}
+
+		byFiletype[l.Filetype] = l
+		filetypes = append(filetypes, l.Filetype)
+		if l.OnIdle {
+			idleFiletypes = append(idleFiletypes, l.Filetype)
+		}
+	}
+
+	if err := k.Expansion(api.Raw(setupScript(filetypes, idleFiletypes))); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "lint on write and idle")
+	k.RecordHighlighter("window/golint-flags", "lint gutter flags")
+	k.RecordHighlighter("window/golint-ranges", "lint underlines")
+
+	err := k.DefineCommand("golint", api.DefineCommandOptions{
+		Docstring: "lint the current file",
+	}, api.Func{
+		ExportVars: []string{
+			vars.BufFile,
+			vars.OptFiletype,
+			vars.Session,
+			vars.Timestamp,
+		},
+		Func: func(kak *api.Kak) error {
+			buffile, err := kak.Var(vars.BufFile)
+			if err != nil {
+				return err
+			}
+
+			return lintFile(kak, byFiletype, buffile, buffile)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("golint-buffer", api.DefineCommandOptions{
+		Docstring: "lint the unsaved buffer",
+	}, api.BufferFunc{
+		ExportVars: []string{
+			vars.OptFiletype,
+			vars.Timestamp,
+			k.StateVar(stateKey),
+		},
+		// nothing to do if the buffer has not changed since last lint.
+		Skip: func(kak *api.Kak) bool {
+			var diags []Diagnostic
+			return kak.BufferState().GetFresh(stateKey, &diags) == nil
+		},
+		FileFunc: func(kak *api.Kak, path string) error {
+			return lintFile(kak, byFiletype, path, path)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	jumpVars := []string{
+		vars.CursorLine,
+		vars.CursorColumn,
+		k.StateVar(stateKey),
+	}
+
+	err = k.DefineCommand("golint-next", api.DefineCommandOptions{
+		Docstring: "jump to the next lint diagnostic",
+	}, api.Func{
+		ExportVars: jumpVars,
+		Func: func(kak *api.Kak) error {
+			return jump(kak, true)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("golint-previous", api.DefineCommandOptions{
+		Docstring: "jump to the previous lint diagnostic",
+	}, api.Func{
+		ExportVars: jumpVars,
+		Func: func(kak *api.Kak) error {
+			return jump(kak, false)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("golint-list", api.DefineCommandOptions{
+		Docstring: "list the lint diagnostics of the buffer",
+	}, api.Func{
+		ExportVars: []string{
+			vars.BufFile,
+			k.StateVar(stateKey),
+		},
+		Func: list,
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("golint-jump", api.DefineCommandOptions{
+		Docstring: "jump to the diagnostic under the cursor of the lint list",
+	}, api.Raw(`evaluate-commands -save-regs 123 %{
+    execute-keys 'xs^([^:\n]+):(\d+):(\d+):<ret>'
+    edit -existing %reg{1} %reg{2} %reg{3}
+  }`))
+}
+
+// setupScript returns the script declaring the options, faces and hooks
+// shared by every linter.
+func setupScript(filetypes, idleFiletypes []string) string {
+	window := func(fts []string, body string) string {
+		if len(fts) == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("hook -group %s global WinSetOption filetype=(?:%s) %%{\n%s\n}\n",
+			hookGroup, strings.Join(fts, "|"), body)
+	}
+
+	return `declare-option -hidden line-specs golint_flags
+declare-option -hidden range-specs golint_ranges
+set-face global LintError red+u
+set-face global LintWarning yellow+u
+set-face global LintInfo cyan+u
+remove-hooks global ` + hookGroup + `
+` + window(filetypes, `  try %{ add-highlighter window/golint-flags flag-lines default golint_flags }
+  try %{ add-highlighter window/golint-ranges ranges golint_ranges }
+  hook -group `+hookGroup+` window BufWritePost .* golint
+  hook -once -always window WinSetOption filetype=.* %{
+    remove-highlighter window/golint-flags
+    remove-highlighter window/golint-ranges
+    remove-hooks window `+hookGroup+`
+  }`) + window(idleFiletypes, `  hook -group `+hookGroup+` window NormalIdle .* golint-buffer`)
+}
+
+// lintFile lints the given file with the linter of the buffer filetype,
+// publishing the diagnostics of target to the buffer.
+func lintFile(kak *api.Kak, linters map[string]Linter, file, target string) error {
+	filetype, err := kak.Var(vars.OptFiletype)
+	if err != nil {
+		return err
+	}
+
+	l, ok := linters[filetype]
+	if !ok {
+		return fmt.Errorf("no linter for filetype: %q", filetype)
+	}
+
+	diags, err := l.lint(file)
+	if err != nil {
+		return err
+	}
+
+	targetAbs, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+
+	var own []Diagnostic
+	for _, d := range diags {
+		if d.File != "" {
+			abs, err := filepath.Abs(d.File)
+			if err != nil || abs != targetAbs {
+				continue
+			}
+		}
+		d.File = ""
+		own = append(own, d)
+	}
+
+	return Publish(kak, own)
+}
+
+// Publish the given diagnostics to the current buffer, replacing any
+// previously published.
+//
+// This is what linters registered with Register use, and is exposed for
+// other sources of diagnostics, such as language servers. vars.Timestamp
+// must be exported to the Subproc.
+func Publish(kak *api.Kak, diags []Diagnostic) error {
+	sort.Slice(diags, func(i, j int) bool {
+		return diags[i].Range().Begin.Less(diags[j].Range().Begin)
+	})
+
+	var (
+		flags    []api.LineSpec
+		ranges   []api.RangeSpec
+		lineSevs = map[int]Severity{}
+		lines    []int
+		errCount int
+		warnings int
+	)
+
+	for _, d := range diags {
+		switch d.Severity {
+		case SeverityError:
+			errCount++
+		case SeverityWarning:
+			warnings++
+		}
+
+		// only one flag per line, showing the most severe.
+		if sev, ok := lineSevs[d.Line]; !ok || d.Severity < sev {
+			if !ok {
+				lines = append(lines, d.Line)
+			}
+			lineSevs[d.Line] = d.Severity
+		}
+
+		ranges = append(ranges, api.Highlight(d.Range(), d.Severity.Face()))
+	}
+
+	for _, line := range lines {
+		flags = append(flags, api.Flag(line, lineSevs[line].Face(), "●"))
+	}
+
+	kak.SetLineSpecs("golint_flags", flags)
+	kak.SetRangeSpecs("golint_ranges", ranges)
+
+	if err := kak.BufferState().Set(stateKey, diags); err != nil {
+		return err
+	}
+
+	kak.Printf("echo -- %s\n", api.Quote(fmt.Sprintf(
+		"golint: %d errors, %d warnings", errCount, warnings)))
+
+	return nil
+}
+
+// jump selects the next or previous diagnostic relative to the cursor,
+// wrapping around the buffer.
+func jump(kak *api.Kak, next bool) error {
+	var diags []Diagnostic
+	if err := kak.BufferState().Get(stateKey, &diags); err != nil {
+		if err == api.ErrStateNotFound {
+			return errors.New("no lint diagnostics")
+		}
+		return err
+	}
+
+	if len(diags) == 0 {
+		return errors.New("no lint diagnostics")
+	}
+
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return err
+	}
+
+	col, err := kak.VarInt(vars.CursorColumn)
+	if err != nil {
+		return err
+	}
+	cursor := api.Coord{Line: line, Column: col}
+
+	after := func(d Diagnostic) bool {
+		return cursor.Less(d.Range().Begin)
+	}
+	before := func(d Diagnostic) bool {
+		return d.Range().Begin.Less(cursor)
+	}
+
+	// diagnostics are published sorted, so the first after or the last
+	// before is the one to jump to.
+	target := diags[0]
+	if next {
+		for _, d := range diags {
+			if after(d) {
+				target = d
+				break
+			}
+		}
+	} else {
+		target = diags[len(diags)-1]
+		for i := len(diags) - 1; i >= 0; i-- {
+			if before(diags[i]) {
+				target = diags[i]
+				break
+			}
+		}
+	}
+
+	begin := target.Range().Begin
+	kak.Printf("select %s\n", api.Range{Begin: begin, End: begin})
+	kak.Printf("echo -- %s\n", api.Quote(target.Severity.String()+": "+target.Message))
+
+	return nil
+}
+
+// list renders the diagnostics of the buffer into the list buffer, where
+// golint-jump jumps to the diagnostic under the cursor.
+func list(kak *api.Kak) error {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return err
+	}
+
+	var diags []Diagnostic
+	if err := kak.BufferState().Get(stateKey, &diags); err != nil && err != api.ErrStateNotFound {
+		return err
+	}
+
+	var lines []string
+	for _, d := range diags {
+		lines = append(lines, fmt.Sprintf("%s:%d:%d: %s: %s",
+			buffile, d.Line, d.Column, d.Severity, d.Message))
+	}
+
+	kak.Printf("edit -scratch %s\n", listBuffer)
+	kak.Printf("evaluate-commands -save-regs z %s\n", api.QuoteBlock(
+		"set-register z "+api.Quote(strings.Join(lines, "\n")+"\n")+"\nexecute-keys '%\"zRgg'"))
+	kak.Println("map buffer normal <ret> :golint-jump<ret>")
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parser parses the output of a linter into diagnostics.
+type Parser func(output string) ([]Diagnostic, error)
+
+// Pattern returns a Parser matching each line of output against the given
+// regular expression, in the spirit of Vim's errorformat.
+//
+// The expression uses named groups to extract each field: file, line,
+// col, severity and message. Only line and message are required. Lines
+// which do not match are ignored. Eg, for `go vet` style output:
+//
+//    lint.Pattern(`^(?P<file>[^:]+):(?P<line>\d+):(?P<col>\d+): (?P<message>.*)$`)
+func Pattern(expr string) (Parser, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]int{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = i
+		}
+	}
+
+	for _, required := range []string{"line", "message"} {
+		if _, ok := groups[required]; !ok {
+			return nil, fmt.Errorf("pattern missing %q group: %s", required, expr)
+		}
+	}
+
+	return func(output string) ([]Diagnostic, error) {
+		var diags []Diagnostic
+		for _, line := range strings.Split(output, "\n") {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+
+			group := func(name string) string {
+				i, ok := groups[name]
+				if !ok {
+					return ""
+				}
+				return m[i]
+			}
+
+			lineNo, err := strconv.Atoi(group("line"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid line: %q", group("line"))
+			}
+
+			// columns are optional, default to the start of the line.
+			col := 1
+			if s := group("col"); s != "" {
+				col, err = strconv.Atoi(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid column: %q", s)
+				}
+			}
+
+			diags = append(diags, Diagnostic{
+				File:     group("file"),
+				Line:     lineNo,
+				Column:   col,
+				Severity: ParseSeverity(group("severity")),
+				Message:  strings.TrimSpace(group("message")),
+			})
+		}
+
+		return diags, nil
+	}, nil
+}
+
+// MustPattern is like Pattern, but panics if the expression is invalid.
+func MustPattern(expr string) Parser {
+	p, err := Pattern(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// ParseSeverity parses the severity names commonly output by linters,
+// defaulting to SeverityError.
+func ParseSeverity(s string) Severity {
+	switch strings.ToLower(s) {
+	case "w", "warn", "warning":
+		return SeverityWarning
+	case "i", "info", "note", "hint":
+		return SeverityInfo
+	default:
+		return SeverityError
+	}
+}
@@ -0,0 +1,96 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPattern(t *testing.T) {
+	const vet = `^(?P<file>[^:]+):(?P<line>\d+):(?:(?P<col>\d+):)? (?:(?P<severity>\w+): )?(?P<message>.*)$`
+
+	tests := []struct {
+		name   string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			name:   "all fields",
+			output: "main.go:3:7: warning: unused x\n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 3, Column: 7, Severity: SeverityWarning, Message: "unused x"},
+			},
+		},
+		{
+			name:   "no column or severity",
+			output: "main.go:12: missing return  \n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 12, Column: 1, Severity: SeverityError, Message: "missing return"},
+			},
+		},
+		{
+			name:   "unmatched lines",
+			output: "# command-line-arguments\nmain.go:1:1: note: first\nexit status 2\nb.go:2:4: second",
+			want: []Diagnostic{
+				{File: "main.go", Line: 1, Column: 1, Severity: SeverityInfo, Message: "first"},
+				{File: "b.go", Line: 2, Column: 4, Severity: SeverityError, Message: "second"},
+			},
+		},
+		{
+			name:   "empty",
+			output: "",
+		},
+	}
+
+	p := MustPattern(vet)
+	for _, test := range tests {
+		got, err := p(test.output)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got:%+v, want:%+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	tests := []string{
+		`(?P<line>\d+`,
+		`^(?P<message>.*)$`,
+		`^(?P<line>\d+)$`,
+	}
+
+	for _, expr := range tests {
+		if _, err := Pattern(expr); err == nil {
+			t.Errorf("%q: got no error", expr)
+		}
+	}
+
+	// a line too large for an int is matched, but not parsed.
+	p := MustPattern(`^(?P<line>\d+): (?P<message>.*)$`)
+	if _, err := p("99999999999999999999: overflow"); err == nil {
+		t.Error("overflowing line: got no error")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := map[string]Severity{
+		"":        SeverityError,
+		"E":       SeverityError,
+		"error":   SeverityError,
+		"fatal":   SeverityError,
+		"w":       SeverityWarning,
+		"Warning": SeverityWarning,
+		"WARN":    SeverityWarning,
+		"i":       SeverityInfo,
+		"info":    SeverityInfo,
+		"note":    SeverityInfo,
+		"hint":    SeverityInfo,
+	}
+
+	for s, want := range tests {
+		if got := ParseSeverity(s); got != want {
+			t.Errorf("%q: got:%v, want:%v", s, got, want)
+		}
+	}
+}
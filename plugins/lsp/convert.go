@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/plugins/format"
+	"github.com/leeola/gokakoune/plugins/lint"
+)
+
+// Diagnostics converts the diagnostics of a document with the given content
+// into lint diagnostics, for lint.Publish.
+func (e Encoding) Diagnostics(content string, diags []Diagnostic) []lint.Diagnostic {
+	lines := Lines(content)
+	line := func(n int) string {
+		if n < 0 || n >= len(lines) {
+			return ""
+		}
+		return lines[n]
+	}
+
+	converted := make([]lint.Diagnostic, len(diags))
+	for i, d := range diags {
+		start, end := d.Range.Start, d.Range.End
+
+		ld := lint.Diagnostic{
+			Line:     start.Line + 1,
+			Column:   e.Column(line(start.Line), start.Character),
+			Severity: severity(d.Severity),
+			Message:  d.Message,
+		}
+		if d.Source != "" {
+			ld.Message = d.Source + ": " + d.Message
+		}
+
+		// LSP ends are exclusive and Kakoune ends inclusive, so the end is
+		// the byte before. Ranges ending at the start of a line end on the
+		// newline of the line before.
+		ld.EndLine = end.Line + 1
+		ld.EndColumn = e.Column(line(end.Line), end.Character) - 1
+		if ld.EndColumn < 1 && end.Line > start.Line {
+			ld.EndLine--
+			ld.EndColumn = len(line(end.Line-1)) + 1
+		}
+		if r := ld.Range(); r.End.Less(r.Begin) {
+			ld.EndLine, ld.EndColumn = ld.Line, ld.Column
+		}
+
+		converted[i] = ld
+	}
+
+	return converted
+}
+
+func severity(s DiagnosticSeverity) lint.Severity {
+	switch s {
+	case DiagnosticWarning:
+		return lint.SeverityWarning
+	case DiagnosticInformation, DiagnosticHint:
+		return lint.SeverityInfo
+	default:
+		return lint.SeverityError
+	}
+}
+
+// Edits converts the text edits of a document with the given content into
+// the line edits of format.ApplyEdits.
+//
+// Servers are free to express an edit however they like, often replacing
+// far more than changed, so the edits are applied to the content and the
+// result diffed to find the minimal line edits.
+func (e Encoding) Edits(content string, edits []TextEdit) ([]format.Edit, error) {
+	edited, err := e.ApplyTextEdits(content, edits)
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Diff(Lines(content), Lines(edited)), nil
+}
+
+// Completions converts completion items into the value of a Kakoune
+// completions option, completing from the given line and byte column.
+func Completions(line, column, timestamp int, items []CompletionItem) api.Completions {
+	c := api.Completions{Line: line, Column: column, Timestamp: timestamp}
+	for _, item := range items {
+		text := item.Label
+		switch {
+		case item.TextEdit != nil:
+			text = item.TextEdit.NewText
+		case item.InsertText != "":
+			text = item.InsertText
+		}
+
+		menu := api.EscapeMarkup(item.Label)
+		if item.Detail != "" {
+			menu += " {MenuInfo}" + api.EscapeMarkup(item.Detail)
+		}
+
+		c.Items = append(c.Items, api.Completion{Text: text, Menu: menu})
+	}
+
+	return c
+}
@@ -0,0 +1,320 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/plugins/lint"
+)
+
+// daemonEnv marks the process as the daemon, when lsp-start reruns itself.
+const daemonEnv = "GOKAKOUNE_LSP_DAEMON"
+
+// document is the content of an open document, as last synced.
+type document struct {
+	version int
+	text    string
+}
+
+// daemon owns the language server of one filetype within one session,
+// serving the Funcs of the bridge over a unix socket.
+//
+// Each Func is a short lived process, while the server must live for the
+// whole session, so the daemon holds the server connection and the state
+// of the open documents between Func executions.
+type daemon struct {
+	server  Server
+	session string
+
+	conn     *Conn
+	encoding Encoding
+
+	mu    sync.Mutex
+	docs  map[string]*document
+	diags map[string][]Diagnostic
+}
+
+// request is the params of the request and notify daemon methods.
+type request struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// syncParams is the params of the sync daemon method.
+type syncParams struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+// serve runs the daemon until the server exits, listening on sock.
+func serve(s Server, session, root, sock string) error {
+	os.Remove(sock)
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sock)
+
+	cmd := exec.Command(s.Command[0], s.Command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	d := &daemon{
+		server:   s,
+		session:  session,
+		encoding: UTF16,
+		docs:     map[string]*document{},
+		diags:    map[string][]Diagnostic{},
+	}
+	d.conn = NewConn(stdout, stdin, d.handleServer)
+
+	if err := d.initialize(root); err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+
+	// NOTE(leeola): Funcs dialing before now wait within the listen
+	// backlog, so they never see an uninitialized server.
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			NewConn(c, c, d.handleClient)
+		}
+	}()
+
+	<-d.conn.Done()
+	return cmd.Wait()
+}
+
+func (d *daemon) initialize(root string) error {
+	rootURI, err := URI(root)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Capabilities struct {
+			PositionEncoding Encoding `json:"positionEncoding"`
+		} `json:"capabilities"`
+	}
+
+	err = d.conn.Call("initialize", map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"capabilities": map[string]interface{}{
+			"general": map[string]interface{}{
+				"positionEncodings": []Encoding{UTF8, UTF16},
+			},
+			"textDocument": map[string]interface{}{
+				"synchronization":    map[string]interface{}{},
+				"publishDiagnostics": map[string]interface{}{},
+				"completion":         map[string]interface{}{},
+				"formatting":         map[string]interface{}{},
+				"definition":         map[string]interface{}{},
+			},
+		},
+		"initializationOptions": d.server.Settings,
+	}, &result)
+	if err != nil {
+		return err
+	}
+
+	if result.Capabilities.PositionEncoding != "" {
+		d.encoding = result.Capabilities.PositionEncoding
+	}
+
+	return d.conn.Notify("initialized", struct{}{})
+}
+
+// handleClient handles the daemon methods called by Funcs.
+func (d *daemon) handleClient(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "request":
+		var r request
+		if err := json.Unmarshal(params, &r); err != nil {
+			return nil, err
+		}
+
+		var result json.RawMessage
+		if err := d.conn.Call(r.Method, r.Params, &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case "notify":
+		var r request
+		if err := json.Unmarshal(params, &r); err != nil {
+			return nil, err
+		}
+		return nil, d.conn.Notify(r.Method, r.Params)
+
+	case "sync":
+		var p syncParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, d.sync(p.URI, p.Text)
+
+	case "close":
+		var p syncParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		_, ok := d.docs[p.URI]
+		delete(d.docs, p.URI)
+		delete(d.diags, p.URI)
+		d.mu.Unlock()
+
+		if !ok {
+			return nil, nil
+		}
+		return nil, d.conn.Notify("textDocument/didClose", map[string]interface{}{
+			"textDocument": TextDocumentIdentifier{URI: p.URI},
+		})
+
+	case "text":
+		var p syncParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		doc, ok := d.docs[p.URI]
+		if !ok {
+			return nil, fmt.Errorf("document not open: %q", p.URI)
+		}
+		return doc.text, nil
+
+	case "encoding":
+		return d.encoding, nil
+
+	case "diagnostics":
+		var p syncParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		doc, ok := d.docs[p.URI]
+		if !ok {
+			return []lint.Diagnostic{}, nil
+		}
+		return d.encoding.Diagnostics(doc.text, d.diags[p.URI]), nil
+
+	case "shutdown":
+		if err := d.conn.Call("shutdown", nil, nil); err != nil {
+			return nil, err
+		}
+		return nil, d.conn.Notify("exit", nil)
+
+	default:
+		return nil, &ResponseError{Code: -32601, Message: "method not found: " + method}
+	}
+}
+
+// sync opens the document, or sends its full content if already open.
+func (d *daemon) sync(uri, text string) error {
+	d.mu.Lock()
+	doc, ok := d.docs[uri]
+	if ok && doc.text == text {
+		d.mu.Unlock()
+		return nil
+	}
+	if !ok {
+		doc = &document{}
+		d.docs[uri] = doc
+	}
+	doc.version++
+	doc.text = text
+	version := doc.version
+	d.mu.Unlock()
+
+	if !ok {
+		return d.conn.Notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": TextDocumentItem{
+				URI:        uri,
+				LanguageID: d.server.languageID(),
+				Version:    version,
+				Text:       text,
+			},
+		})
+	}
+
+	return d.conn.Notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   VersionedTextDocumentIdentifier{URI: uri, Version: version},
+		"contentChanges": []map[string]string{{"text": text}},
+	})
+}
+
+// handleServer handles the requests and notifications of the server.
+func (d *daemon) handleServer(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		d.diags[p.URI] = p.Diagnostics
+		d.mu.Unlock()
+
+		path, err := Path(p.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		go api.Send(d.session, fmt.Sprintf("try %%{ evaluate-commands -buffer %s lsp-diagnostics }",
+			api.Quote(path)))
+
+	case "window/showMessage", "window/logMessage":
+		var p struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		go api.Send(d.session, "echo -debug -- "+api.Quote(d.server.Filetype+" lsp: "+p.Message))
+
+	case "workspace/configuration":
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		settings := make([]interface{}, len(p.Items))
+		for i := range settings {
+			settings[i] = d.server.Settings
+		}
+		return settings, nil
+	}
+
+	// NOTE(leeola): unknown requests are answered with null, as servers
+	// commonly block on requests such as window/workDoneProgress/create.
+	return nil, nil
+}
@@ -0,0 +1,272 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrClosed is returned by calls on a closed Conn.
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+// Handler handles the requests and notifications received by a Conn. The
+// result of notifications is ignored.
+type Handler func(method string, params json.RawMessage) (interface{}, error)
+
+// ResponseError is the error of a failed JSON-RPC request.
+type ResponseError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+// message is any JSON-RPC 2.0 message. Requests have an ID and Method,
+// notifications only a Method, and responses only an ID.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// Conn is a JSON-RPC 2.0 connection, framed with the Content-Length
+// headers of the language server protocol.
+//
+// Calls may be made concurrently. Incoming requests are handled in order,
+// within the read loop.
+type Conn struct {
+	w       io.Writer
+	handler Handler
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	err     error
+
+	done chan struct{}
+}
+
+// NewConn returns a Conn reading from r and writing to w, handling incoming
+// requests and notifications with h, which may be nil.
+func NewConn(r io.Reader, w io.Writer, h Handler) *Conn {
+	c := &Conn{
+		w:       w,
+		handler: h,
+		pending: map[int64]chan *message{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(r))
+	return c
+}
+
+// Done is closed once the connection is closed, such as the other side
+// exiting.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Call the given method, decoding the response into result if not nil.
+func (c *Conn) Call(method string, params, result interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.send(&message{ID: &rawID, Method: method}, params); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	resp, ok := <-ch
+	if !ok {
+		return c.err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Notify sends the given notification, which has no response.
+func (c *Conn) Notify(method string, params interface{}) error {
+	return c.send(&message{Method: method}, params)
+}
+
+func (c *Conn) send(m *message, params interface{}) error {
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		m.Params = b
+	}
+
+	return c.write(m)
+}
+
+func (c *Conn) write(m *message) error {
+	m.JSONRPC = "2.0"
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return WriteFrame(c.w, b)
+}
+
+func (c *Conn) readLoop(r *bufio.Reader) {
+	var err error
+	for {
+		var m *message
+		if m, err = readMessage(r); err != nil {
+			break
+		}
+
+		switch {
+		case m.Method != "":
+			c.handle(m)
+		case m.ID != nil:
+			id, perr := strconv.ParseInt(string(*m.ID), 10, 64)
+			if perr != nil {
+				continue
+			}
+
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+
+			if ok {
+				ch <- m
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.err = ErrClosed
+	if err != io.EOF {
+		c.err = fmt.Errorf("jsonrpc: %s", err)
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+
+	close(c.done)
+}
+
+// handle handles an incoming request or notification, responding to
+// requests.
+func (c *Conn) handle(m *message) {
+	var (
+		result interface{}
+		err    error
+	)
+	if c.handler != nil {
+		result, err = c.handler(m.Method, m.Params)
+	} else {
+		err = &ResponseError{Code: -32601, Message: "method not found: " + m.Method}
+	}
+
+	if m.ID == nil {
+		return
+	}
+
+	resp := &message{ID: m.ID, Result: json.RawMessage("null")}
+	if err != nil {
+		respErr, ok := err.(*ResponseError)
+		if !ok {
+			respErr = &ResponseError{Code: -32603, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, respErr
+	} else if result != nil {
+		b, merr := json.Marshal(result)
+		if merr != nil {
+			resp.Result, resp.Error = nil, &ResponseError{Code: -32603, Message: merr.Error()}
+		} else {
+			resp.Result = b
+		}
+	}
+
+	c.write(resp)
+}
+
+// readMessage reads a single framed message.
+func readMessage(r *bufio.Reader) (*message, error) {
+	b, err := ReadFrame(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var m message
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ReadFrame reads the body of a single Content-Length framed message.
+//
+// The framing is shared by the language server and the debug adapter
+// protocols.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %q", headers.Get("Content-Length"))
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// WriteFrame writes b as a single Content-Length framed message.
+func WriteFrame(w io.Writer, b []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
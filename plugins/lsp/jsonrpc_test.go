@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestConn(t *testing.T) {
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+
+	notified := make(chan string, 1)
+	NewConn(br, bw, func(method string, params json.RawMessage) (interface{}, error) {
+		switch method {
+		case "add":
+			var args []int
+			if err := json.Unmarshal(params, &args); err != nil {
+				return nil, err
+			}
+			return args[0] + args[1], nil
+		case "note":
+			var s string
+			json.Unmarshal(params, &s)
+			notified <- s
+			return nil, nil
+		default:
+			return nil, &ResponseError{Code: -32601, Message: "method not found"}
+		}
+	})
+	a := NewConn(ar, aw, nil)
+
+	var sum int
+	if err := a.Call("add", []int{2, 3}, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 5 {
+		t.Errorf("want 5, got %d", sum)
+	}
+
+	err := a.Call("missing", nil, nil)
+	if respErr, ok := err.(*ResponseError); !ok || respErr.Code != -32601 {
+		t.Errorf("want method not found, got %v", err)
+	}
+
+	if err := a.Notify("note", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-notified; s != "hello" {
+		t.Errorf("want hello, got %q", s)
+	}
+
+	bw.Close()
+	<-a.Done()
+	if err := a.Call("add", []int{1, 1}, &sum); err == nil {
+		t.Error("want error calling closed conn")
+	}
+}
@@ -0,0 +1,586 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/format"
+	"github.com/leeola/gokakoune/plugins/lint"
+)
+
+const (
+	hookGroup = "lsp"
+
+	// stateKey is the BufferState key marking the buffer as synced, so
+	// idle syncs of unchanged buffers do nothing.
+	stateKey = "lsp_synced"
+
+	// CodeNotRunning is the error code of commands failing as the language
+	// server of the buffer is not running, see api.CatchCode. Eg, falling
+	// back to tags:
+	//
+	//    try %{ lsp-definition } catch %{ <CatchCode(CodeNotRunning, "tag-jump")> }
+	CodeNotRunning = "lsp-not-running"
+)
+
+// Server is a language server for a single filetype.
+type Server struct {
+	Filetype string
+
+	// LanguageID is the language identifier of the documents, defaulting
+	// to the filetype.
+	LanguageID string
+
+	// Command runs the server, speaking the protocol over stdio.
+	Command []string
+
+	// Roots are the files marking the root of a project, searched for
+	// from the directory of the buffer upwards. Defaults to `.git`. If
+	// none are found, the working directory is the root.
+	Roots []string
+
+	// Settings are given as the initializationOptions, and as the result
+	// of every workspace/configuration request.
+	Settings interface{}
+}
+
+func (s Server) languageID() string {
+	if s.LanguageID != "" {
+		return s.LanguageID
+	}
+	return s.Filetype
+}
+
+// root returns the project root of the given file.
+func (s Server) root(file string) string {
+	roots := s.Roots
+	if len(roots) == 0 {
+		roots = []string{".git"}
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return "."
+	}
+
+	for {
+		for _, r := range roots {
+			if _, err := os.Stat(filepath.Join(dir, r)); err == nil {
+				return dir
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "."
+		}
+		dir = parent
+	}
+}
+
+// Register the given servers, defining the commands and hooks bridging
+// them to Kakoune.
+//
+// The following commands are defined:
+//
+//    lsp-start        start the server of the buffer filetype, if needed
+//    lsp-stop         stop the server of the buffer filetype
+//    lsp-sync         sync the buffer content to the server
+//    lsp-close        close the buffer within the server
+//    lsp-diagnostics  publish the diagnostics of the buffer
+//    lsp-definition   jump to the definition under the cursor
+//    lsp-format       format the buffer
+//    lsp-complete     complete the word before the cursor
+//
+// Windows of the registered filetypes start the server and sync the buffer
+// on idle, and complete on insert idle. Diagnostics are published with
+// lint.Publish, so lint.Register must be called for the faces and
+// highlighters of the diagnostics, even without any linters.
+//
+// Plugins build further commands on Dial and the Client.
+func Register(k *api.Kak, servers ...Server) error {
+	byFiletype := map[string]Server{}
+	var filetypes []string
+	for _, s := range servers {
+		if s.Filetype == "" || len(s.Command) == 0 {
+			return errors.New("server needs a Filetype and Command")
+		}
+
+		if _, ok := byFiletype[s.Filetype]; ok {
+			return fmt.Errorf("duplicate server for %s", s.Filetype)
+		}
+
+		byFiletype[s.Filetype] = s
+		filetypes = append(filetypes, s.Filetype)
+	}
+
+	setup := fmt.Sprintf(`declare-option -hidden completions lsp_completions
+remove-hooks global %[1]s
+hook -group %[1]s global WinSetOption filetype=(?:%[2]s) %%{
+  lsp-start
+  lsp-sync
+  set-option window completers option=lsp_completions %%opt{completers}
+  hook -group %[1]s window NormalIdle .* lsp-sync
+  hook -group %[1]s window InsertIdle .* lsp-complete
+  hook -group %[1]s buffer BufClose .* %%{ try lsp-close }
+  hook -once -always window WinSetOption filetype=.* %%{
+    remove-hooks window %[1]s
+    unset-option window completers
+  }
+}`, hookGroup, strings.Join(filetypes, "|"))
+
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "language server document sync")
+
+	server := func(kak *api.Kak) (Server, error) {
+		filetype, err := kak.Var(vars.OptFiletype)
+		if err != nil {
+			return Server{}, err
+		}
+
+		s, ok := byFiletype[filetype]
+		if !ok {
+			return Server{}, fmt.Errorf("no language server for filetype: %q", filetype)
+		}
+		return s, nil
+	}
+
+	// withClient runs f with a client of the buffer server.
+	withClient := func(f func(*api.Kak, *Client) error) func(*api.Kak) error {
+		return func(kak *api.Kak) error {
+			s, err := server(kak)
+			if err != nil {
+				return err
+			}
+
+			c, err := Dial(kak, s)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			return f(kak, c)
+		}
+	}
+
+	bufferVars := []string{
+		vars.BufFile,
+		vars.OptFiletype,
+		vars.Session,
+	}
+
+	err := k.DefineCommand("lsp-start", api.DefineCommandOptions{
+		Docstring: "start the language server of the buffer filetype",
+	}, api.Func{
+		ExportVars: bufferVars,
+		Func: func(kak *api.Kak) error {
+			s, err := server(kak)
+			if err != nil {
+				return err
+			}
+			return start(kak, s)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("lsp-stop", api.DefineCommandOptions{
+		Docstring: "stop the language server of the buffer filetype",
+	}, api.Func{
+		ExportVars: bufferVars,
+		Func: withClient(func(kak *api.Kak, c *Client) error {
+			return c.call("shutdown", nil, nil)
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the unsaved buffer is synced through the file it is written to by
+	// the commands needing the server in sync.
+	syncVars := append([]string{vars.Timestamp, k.StateVar(stateKey)}, bufferVars...)
+
+	// synced syncs the written buffer before running f.
+	synced := func(exportVars []string, f func(*api.Kak, *Client) error) api.BufferFunc {
+		return api.BufferFunc{
+			ExportVars: exportVars,
+			Func: func(kak *api.Kak, content string) error {
+				return withClient(func(kak *api.Kak, c *Client) error {
+					if err := syncBuffer(kak, c, content); err != nil {
+						return err
+					}
+					if f == nil {
+						return nil
+					}
+					return f(kak, c)
+				})(kak)
+			},
+		}
+	}
+
+	err = k.DefineCommand("lsp-sync", api.DefineCommandOptions{
+		Docstring: "sync the buffer to the language server",
+	}, synced(syncVars, nil))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("lsp-close", api.DefineCommandOptions{
+		Docstring: "close the buffer within the language server",
+	}, api.Func{
+		ExportVars: bufferVars,
+		Func: withClient(func(kak *api.Kak, c *Client) error {
+			return c.call("close", syncParams{URI: c.URI}, nil)
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("lsp-diagnostics", api.DefineCommandOptions{
+		Docstring: "publish the language server diagnostics of the buffer",
+	}, api.Func{
+		ExportVars: append([]string{vars.Timestamp}, bufferVars...),
+		Func: withClient(func(kak *api.Kak, c *Client) error {
+			var diags []lint.Diagnostic
+			if err := c.call("diagnostics", syncParams{URI: c.URI}, &diags); err != nil {
+				return err
+			}
+			return lint.Publish(kak, diags)
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	cursorVars := append([]string{vars.CursorLine, vars.CursorColumn}, syncVars...)
+
+	err = k.DefineCommand("lsp-definition", api.DefineCommandOptions{
+		Docstring: "jump to the definition under the cursor",
+	}, synced(cursorVars, definition))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("lsp-format", api.DefineCommandOptions{
+		Docstring: "format the buffer with the language server",
+	}, synced(syncVars, func(kak *api.Kak, c *Client) error {
+		var edits []TextEdit
+		err := c.Request("textDocument/formatting", map[string]interface{}{
+			"textDocument": TextDocumentIdentifier{URI: c.URI},
+			"options":      map[string]interface{}{"tabSize": 4, "insertSpaces": true},
+		}, &edits)
+		if err != nil {
+			return err
+		}
+
+		lineEdits, err := c.Encoding.Edits(c.Text, edits)
+		if err != nil {
+			return err
+		}
+
+		format.ApplyEdits(kak, Lines(c.Text), lineEdits)
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("lsp-complete", api.DefineCommandOptions{
+		Docstring: "complete the word before the cursor",
+	}, synced(cursorVars, complete))
+}
+
+// Client is a connection to the daemon of a language server, for the
+// buffer of the Func.
+type Client struct {
+	conn *Conn
+	nc   net.Conn
+
+	// URI is the document URI of the buffer.
+	URI string
+
+	// Encoding is the position encoding negotiated with the server.
+	Encoding Encoding
+
+	// Text is the buffer content as last synced, which positions are
+	// relative to. Only set within commands which sync first.
+	Text string
+}
+
+// Dial connects to the daemon of the server, which must be started.
+// vars.BufFile and vars.Session must be exported to the Subproc.
+func Dial(kak *api.Kak, s Server) (*Client, error) {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return nil, err
+	}
+
+	sock, err := socket(kak, s)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := net.Dial("unix", sock)
+	if err != nil {
+		err := fmt.Errorf("%s language server not running, see lsp-start", s.Filetype)
+		return nil, api.WithCode(err, CodeNotRunning)
+	}
+
+	uri, err := URI(buffile)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	c := &Client{
+		conn: NewConn(nc, nc, nil),
+		nc:   nc,
+		URI:  uri,
+	}
+
+	if err := c.call("encoding", nil, &c.Encoding); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Close the connection to the daemon.
+func (c *Client) Close() error {
+	return c.nc.Close()
+}
+
+// Request calls the given method of the server, decoding the result into
+// result if not nil.
+func (c *Client) Request(method string, params, result interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return c.call("request", request{Method: method, Params: b}, result)
+}
+
+// Notify sends the given notification to the server.
+func (c *Client) Notify(method string, params interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return c.call("notify", request{Method: method, Params: b}, nil)
+}
+
+// Position returns the position of the given Kakoune line and byte column
+// within the synced text.
+func (c *Client) Position(line, column int) Position {
+	lines := Lines(c.Text)
+	var text string
+	if line >= 1 && line <= len(lines) {
+		text = lines[line-1]
+	}
+
+	return Position{Line: line - 1, Character: c.Encoding.Character(text, column)}
+}
+
+// call calls the given method of the daemon.
+func (c *Client) call(method string, params, result interface{}) error {
+	return c.conn.Call(method, params, result)
+}
+
+// start starts the daemon of the language server of the buffer, unless it
+// is already running, waiting for it to listen so the commands following
+// lsp-start can dial. The rerun Func serves as the daemon, owning the
+// server for the rest of the session.
+func start(kak *api.Kak, s Server) error {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return err
+	}
+
+	session, err := kak.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+
+	sock, err := socket(kak, s)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(daemonEnv) != "" {
+		return serve(s, session, s.root(buffile), sock)
+	}
+
+	if nc, err := net.Dial("unix", sock); err == nil {
+		nc.Close()
+		return nil
+	}
+
+	if err := kak.SpawnDaemon(daemonEnv, sock); err != nil {
+		return fmt.Errorf("%s language server failed to start: %s", s.Filetype, err)
+	}
+	return nil
+}
+
+// syncBuffer sends the content of the buffer to the server.
+//
+// The content is sent even if the buffer is unchanged, as the daemon may
+// have been restarted since. The daemon ignores unchanged content.
+func syncBuffer(kak *api.Kak, c *Client, content string) error {
+	c.Text = content
+
+	if err := c.call("sync", syncParams{URI: c.URI, Text: c.Text}, nil); err != nil {
+		return err
+	}
+
+	var synced bool
+	if kak.BufferState().GetFresh(stateKey, &synced) != nil {
+		return kak.BufferState().Set(stateKey, true)
+	}
+
+	return nil
+}
+
+// definition jumps to the first definition of the symbol under the cursor.
+func definition(kak *api.Kak, c *Client) error {
+	params, err := positionParams(kak, c)
+	if err != nil {
+		return err
+	}
+
+	// the result may be a single location or a list of them.
+	var raw json.RawMessage
+	if err := c.Request("textDocument/definition", params, &raw); err != nil {
+		return err
+	}
+
+	var locs []Location
+	if err := json.Unmarshal(raw, &locs); err != nil {
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return err
+		}
+		locs = []Location{loc}
+	}
+
+	if len(locs) == 0 || locs[0].URI == "" {
+		return errors.New("no definition found")
+	}
+
+	path, err := Path(locs[0].URI)
+	if err != nil {
+		return err
+	}
+
+	// NOTE(leeola): the character is converted against the file on disk,
+	// as the target may not be open.
+	start := locs[0].Range.Start
+	column := start.Character + 1
+	if b, err := ioutil.ReadFile(path); err == nil {
+		if lines := Lines(string(b)); start.Line < len(lines) {
+			column = c.Encoding.Column(lines[start.Line], start.Character)
+		}
+	}
+
+	kak.Println(paths.EditExisting(path, api.Coord{Line: start.Line + 1, Column: column}))
+	return nil
+}
+
+// complete sets the completions of the word before the cursor.
+func complete(kak *api.Kak, c *Client) error {
+	params, err := positionParams(kak, c)
+	if err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+	if err := c.Request("textDocument/completion", params, &raw); err != nil {
+		return err
+	}
+
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil || list.Items == nil {
+		list.Items = nil
+		json.Unmarshal(raw, &list.Items)
+	}
+
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return err
+	}
+
+	column, err := kak.VarInt(vars.CursorColumn)
+	if err != nil {
+		return err
+	}
+
+	// completion starts at the start of the word before the cursor.
+	lines := Lines(c.Text)
+	start := column
+	if line >= 1 && line <= len(lines) {
+		text := lines[line-1]
+		for start > 1 && start-2 < len(text) && isWordByte(text[start-2]) {
+			start--
+		}
+	}
+
+	timestamp, err := kak.VarInt(vars.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	return kak.SetOption("buffer", "lsp_completions", Completions(line, start, timestamp, list.Items))
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// positionParams returns the params of a request at the cursor.
+func positionParams(kak *api.Kak, c *Client) (TextDocumentPositionParams, error) {
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return TextDocumentPositionParams{}, err
+	}
+
+	column, err := kak.VarInt(vars.CursorColumn)
+	if err != nil {
+		return TextDocumentPositionParams{}, err
+	}
+
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: c.URI},
+		Position:     c.Position(line, column),
+	}, nil
+}
+
+// socket returns the socket of the daemon of the server.
+func socket(kak *api.Kak, s Server) (string, error) {
+	dir, err := kak.StateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "lsp")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, s.Filetype+".sock"), nil
+}
@@ -0,0 +1,131 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding is the unit positions are counted in, as negotiated with the
+// server.
+type Encoding string
+
+const (
+	UTF8  Encoding = "utf-8"
+	UTF16 Encoding = "utf-16"
+	UTF32 Encoding = "utf-32"
+)
+
+// units returns the number of units of r in the encoding.
+func (e Encoding) units(r rune) int {
+	switch e {
+	case UTF8:
+		return utf8.RuneLen(r)
+	case UTF32:
+		return 1
+	default:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	}
+}
+
+// Column converts the character offset of a position within line into the
+// one-based byte column Kakoune uses.
+//
+// Offsets past the end of the line are clamped to just past its end.
+func (e Encoding) Column(line string, character int) int {
+	var units int
+	for i, r := range line {
+		if units >= character {
+			return i + 1
+		}
+		units += e.units(r)
+	}
+	return len(line) + 1
+}
+
+// Character converts a one-based Kakoune byte column within line into the
+// character offset of a position.
+func (e Encoding) Character(line string, column int) int {
+	var units int
+	for i, r := range line {
+		if i >= column-1 {
+			break
+		}
+		units += e.units(r)
+	}
+	return units
+}
+
+// Lines splits content into lines, without the newlines.
+func Lines(content string) []string {
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// offset returns the byte offset of the position within content.
+func (e Encoding) offset(content string, p Position) (int, error) {
+	offset := 0
+	for line := 0; line < p.Line; line++ {
+		i := strings.IndexByte(content[offset:], '\n')
+		if i == -1 {
+			return 0, fmt.Errorf("position line out of range: %d", p.Line)
+		}
+		offset += i + 1
+	}
+
+	line := content[offset:]
+	if i := strings.IndexByte(line, '\n'); i != -1 {
+		line = line[:i]
+	}
+
+	return offset + e.Column(line, p.Character) - 1, nil
+}
+
+// ApplyTextEdits returns content with the given edits applied.
+//
+// The edits must not overlap, per the protocol, and are applied as if
+// simultaneously.
+func (e Encoding) ApplyTextEdits(content string, edits []TextEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+
+	spans := make([]span, len(edits))
+	for i, edit := range edits {
+		start, err := e.offset(content, edit.Range.Start)
+		if err != nil {
+			return "", err
+		}
+
+		end, err := e.offset(content, edit.Range.End)
+		if err != nil {
+			return "", err
+		}
+
+		if end < start {
+			return "", fmt.Errorf("edit range ends before it starts: %+v", edit.Range)
+		}
+
+		spans[i] = span{start: start, end: end, text: edit.NewText}
+	}
+
+	// apply from last to first, so that each offset remains valid. Edits
+	// inserting at the same offset are applied in reverse, so the inserted
+	// text keeps their order.
+	for i, j := 0, len(spans)-1; i < j; i, j = i+1, j-1 {
+		spans[i], spans[j] = spans[j], spans[i]
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].start > spans[j].start
+	})
+
+	for _, s := range spans {
+		content = content[:s.start] + s.text + content[s.end:]
+	}
+
+	return content, nil
+}
@@ -0,0 +1,86 @@
+package lsp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leeola/gokakoune/plugins/format"
+)
+
+func TestColumn(t *testing.T) {
+	// é is 2 bytes and 1 utf-16 unit, 𝄞 is 4 bytes and 2 utf-16 units.
+	line := "aé𝄞b"
+
+	tests := []struct {
+		enc       Encoding
+		character int
+		column    int
+	}{
+		{UTF8, 0, 1},
+		{UTF8, 3, 4},
+		{UTF8, 7, 8},
+		{UTF16, 1, 2},
+		{UTF16, 2, 4},
+		{UTF16, 4, 8},
+		{UTF32, 3, 8},
+		{UTF16, 99, 9},
+	}
+
+	for _, test := range tests {
+		if got := test.enc.Column(line, test.character); got != test.column {
+			t.Errorf("%s character %d: want column %d, got %d",
+				test.enc, test.character, test.column, got)
+		}
+
+		if test.character == 99 {
+			continue
+		}
+
+		if got := test.enc.Character(line, test.column); got != test.character {
+			t.Errorf("%s column %d: want character %d, got %d",
+				test.enc, test.column, test.character, got)
+		}
+	}
+}
+
+func TestEdits(t *testing.T) {
+	content := "package main\n\nfunc  main(){\n}\n"
+	edits := []TextEdit{
+		{Range: Range{Start: Position{2, 4}, End: Position{2, 6}}, NewText: " "},
+		{Range: Range{Start: Position{2, 12}, End: Position{2, 12}}, NewText: " "},
+	}
+
+	edited, err := UTF16.ApplyTextEdits(content, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package main\n\nfunc main() {\n}\n"; edited != want {
+		t.Fatalf("want %q, got %q", want, edited)
+	}
+
+	lineEdits, err := UTF16.Edits(content, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []format.Edit{{Line: 3, Delete: 1, Insert: []string{"func main() {"}}}
+	if !reflect.DeepEqual(lineEdits, want) {
+		t.Errorf("want %+v, got %+v", want, lineEdits)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	content := "x := é\nfoo\n"
+	diags := UTF16.Diagnostics(content, []Diagnostic{
+		{Range: Range{Start: Position{0, 5}, End: Position{0, 6}}, Severity: DiagnosticWarning, Message: "w"},
+		{Range: Range{Start: Position{1, 0}, End: Position{2, 0}}, Source: "vet", Message: "e"},
+	})
+
+	if d := diags[0]; d.Line != 1 || d.Column != 6 || d.EndLine != 1 || d.EndColumn != 7 {
+		t.Errorf("unexpected multibyte range: %+v", d)
+	}
+
+	if d := diags[1]; d.EndLine != 2 || d.EndColumn != 4 || d.Message != "vet: e" {
+		t.Errorf("unexpected line range: %+v", d)
+	}
+}
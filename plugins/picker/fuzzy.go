@@ -0,0 +1,86 @@
+package picker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Score scores how well the query fuzzy matches s, returning false if the
+// query is not a subsequence of s.
+//
+// Matching is case insensitive. Consecutive matches and matches at the
+// start of words score higher, so `fb` scores `foo_bar` above `fab`.
+func Score(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	rs := []rune(s)
+	lower := []rune(strings.ToLower(s))
+
+	var (
+		score int
+		qi    int
+		prev  = -2
+	)
+
+	for i := 0; i < len(lower) && qi < len(q); i++ {
+		if lower[i] != q[qi] {
+			continue
+		}
+
+		score++
+		if prev == i-1 {
+			score += 5
+		}
+		if i == 0 || isBoundary(rs[i-1], rs[i]) {
+			score += 8
+		}
+
+		prev = i
+		qi++
+	}
+
+	if qi < len(q) {
+		return 0, false
+	}
+
+	return score, true
+}
+
+// isBoundary returns whether cur starts a word, following prev.
+func isBoundary(prev, cur rune) bool {
+	switch prev {
+	case '/', '_', '-', '.', ' ':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// Rank returns the candidates matching the query, best match first.
+//
+// Equal scores prefer shorter candidates, then the original order.
+func Rank(query string, candidates []string) []string {
+	type scored struct {
+		s     string
+		score int
+	}
+
+	var matches []scored
+	for _, c := range candidates {
+		if score, ok := Score(query, c); ok {
+			matches = append(matches, scored{s: c, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].s) < len(matches[j].s)
+	})
+
+	ranked := make([]string, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.s
+	}
+	return ranked
+}
@@ -0,0 +1,50 @@
+package picker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	if _, ok := Score("abc", "acb"); ok {
+		t.Error("want no match for out of order query")
+	}
+
+	if _, ok := Score("", "anything"); !ok {
+		t.Error("want empty query to match")
+	}
+
+	boundary, _ := Score("fb", "foo_bar")
+	inner, _ := Score("fb", "fabric")
+	if boundary <= inner {
+		t.Errorf("want word boundary match to score higher: %d <= %d", boundary, inner)
+	}
+
+	consecutive, _ := Score("api", "api/kak.go")
+	spread, _ := Score("api", "a/plugins/id.go")
+	if consecutive <= spread {
+		t.Errorf("want consecutive match to score higher: %d <= %d", consecutive, spread)
+	}
+}
+
+func TestRank(t *testing.T) {
+	candidates := []string{
+		"plugins/lint/parse.go",
+		"api/kak.go",
+		"README.md",
+		"api/kaktest/kak.go",
+	}
+
+	want := []string{
+		"api/kak.go",
+		"api/kaktest/kak.go",
+	}
+
+	if got := Rank("ak.go", candidates)[:2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if got := Rank("zzz", candidates); len(got) != 0 {
+		t.Errorf("want no matches, got %q", got)
+	}
+}
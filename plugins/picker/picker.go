@@ -0,0 +1,296 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/terminal"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+// Finders are the external fuzzy finders used if installed, in order of
+// preference. They are run in a terminal with the candidates on stdin.
+var Finders = []string{"fzf", "sk"}
+
+// Picker picks one of the candidates of Source, giving it to Action.
+//
+// Registering a Picker named "pick-file" defines the pick-file command.
+// If one of Finders is installed the candidates are picked from within a
+// new terminal of the client, see the terminal package. Otherwise, or if
+// no terminal is available, a prompt completing the candidates is shown.
+type Picker struct {
+	Name   string
+	Prompt string
+
+	// ExportVars are exported to both Source and Action.
+	ExportVars []string
+
+	Source func(kak *api.Kak) ([]string, error)
+	Action func(kak *api.Kak, selection string) error
+}
+
+// FilePicker picks a file within the working directory to edit.
+var FilePicker = Picker{
+	Name:   "pick-file",
+	Prompt: "file: ",
+	Source: Files,
+	Action: func(kak *api.Kak, file string) error {
+		kak.Println(paths.EditExisting(file, api.Coord{}))
+		return nil
+	},
+}
+
+// BufferPicker picks an open buffer to switch to.
+var BufferPicker = Picker{
+	Name:       "pick-buffer",
+	Prompt:     "buffer: ",
+	ExportVars: []string{vars.QuotedBufList},
+	Source:     Buffers,
+	Action: func(kak *api.Kak, buffer string) error {
+		kak.Printf("buffer -- %s\n", api.Quote(buffer))
+		return nil
+	},
+}
+
+// Register the commands of the given pickers.
+func Register(k *api.Kak, pickers ...Picker) error {
+	for _, p := range pickers {
+		if p.Name == "" || p.Source == nil || p.Action == nil {
+			return errors.New("picker needs a Name, Source and Action")
+		}
+
+		if err := register(k, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func register(k *api.Kak, p Picker) error {
+	err := k.DefineCommand(p.Name, api.DefineCommandOptions{
+		Docstring: "pick " + strings.TrimSpace(strings.TrimSuffix(p.Prompt, ": ")),
+	}, api.Func{
+		ExportVars: append(append([]string{vars.Client, vars.Session}, terminal.Vars...), p.ExportVars...),
+		Func: func(kak *api.Kak) error {
+			return p.open(kak)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// select is called by the prompt with the text entered, and done by
+	// the finder once the result file is written.
+	selectVars := append([]string{vars.Session}, p.ExportVars...)
+
+	err = k.DefineCommand(p.Name+"-select", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "pick the candidate best matching the given text",
+	}, api.Func{
+		ExportVars: selectVars,
+		Func: func(kak *api.Kak) error {
+			text, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			return p.pick(kak, text)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand(p.Name+"-done", api.DefineCommandOptions{
+		Docstring: "pick the candidate chosen within the finder",
+	}, api.Func{
+		ExportVars: selectVars,
+		Func: func(kak *api.Kak) error {
+			_, result, err := p.files(kak)
+			if err != nil {
+				return err
+			}
+
+			b, err := ioutil.ReadFile(result)
+			if err != nil {
+				return err
+			}
+
+			selection := strings.TrimSuffix(string(b), "\n")
+			if selection == "" {
+				return nil
+			}
+
+			return p.Action(kak, selection)
+		},
+	})
+}
+
+// files returns the files the candidates and the finder result are written
+// to.
+func (p Picker) files(kak *api.Kak) (string, string, error) {
+	dir, err := kak.StateDir()
+	if err != nil {
+		return "", "", err
+	}
+
+	dir = filepath.Join(dir, "picker")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+
+	base := filepath.Join(dir, p.Name)
+	return base + ".candidates", base + ".result", nil
+}
+
+// open writes the candidates of the source, and shows the finder or the
+// prompt to pick from them.
+func (p Picker) open(kak *api.Kak) error {
+	candidates, err := p.Source(kak)
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		return fmt.Errorf("%s: nothing to pick", p.Name)
+	}
+
+	cands, result, err := p.files(kak)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(cands, []byte(strings.Join(candidates, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	os.Remove(result)
+
+	prompt := fmt.Sprintf("prompt -shell-script-candidates %%{ cat %s } %s %%{ %s-select %%val{text} }",
+		util.ShellQuote(cands), api.Quote(p.Prompt), p.Name)
+
+	finder := p.finder()
+	if finder == "" {
+		kak.Println(prompt)
+		return nil
+	}
+
+	client, err := kak.Var(vars.Client)
+	if err != nil {
+		return err
+	}
+
+	session, err := kak.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+
+	done := fmt.Sprintf("evaluate-commands -try-client %s %s-done", api.Quote(client), p.Name)
+
+	// NOTE(leeola): the finder runs in its own terminal, so the selection
+	// is sent back to the session once picked. Cancelling the finder exits
+	// non-zero, sending nothing.
+	script := `cands=$1 result=$2 done=$3 session=$4
+shift 4
+"$@" < "$cands" > "$result" && printf '%s\n' "$done" | kak -p "$session"`
+
+	err = terminal.OpenTerminal(kak, "sh", "-c", script, "sh", cands, result, done, session,
+		finder, "--prompt", p.Prompt)
+	if err != nil {
+		// no terminal to run the finder in, so the prompt it is.
+		kak.Println(prompt)
+	}
+
+	return nil
+}
+
+// finder returns the first of Finders installed, if any.
+func (p Picker) finder() string {
+	for _, f := range Finders {
+		if _, err := exec.LookPath(f); err == nil {
+			return f
+		}
+	}
+	return ""
+}
+
+// pick gives the candidate best matching text to the Action.
+//
+// The prompt completes candidates, so text is usually a candidate as is.
+// Otherwise it is fuzzy matched, as the user may have accepted the prompt
+// before completing.
+func (p Picker) pick(kak *api.Kak, text string) error {
+	cands, _, err := p.files(kak)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(cands)
+	if err != nil {
+		return err
+	}
+	candidates := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+	for _, c := range candidates {
+		if c == text {
+			return p.Action(kak, c)
+		}
+	}
+
+	ranked := Rank(text, candidates)
+	if len(ranked) == 0 {
+		return fmt.Errorf("%s: no match for %q", p.Name, text)
+	}
+
+	return p.Action(kak, ranked[0])
+}
+
+// Files is a Source of the files within the working directory, skipping
+// hidden files and directories.
+func Files(kak *api.Kak) ([]string, error) {
+	// maxFiles bounds the walk of huge directories, such as home.
+	const maxFiles = 50000
+
+	var files []string
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if path != "." && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		files = append(files, path)
+		if len(files) >= maxFiles {
+			return errors.New("too many files")
+		}
+		return nil
+	})
+	if err != nil && len(files) < maxFiles {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Buffers is a Source of the open buffers. vars.QuotedBufList must be
+// exported to the Subproc.
+func Buffers(kak *api.Kak) ([]string, error) {
+	return kak.VarQuotedList(vars.QuotedBufList)
+}
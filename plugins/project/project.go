@@ -0,0 +1,186 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	hookGroup = "project"
+
+	rootOption    = "project_root"
+	markersOption = "project_markers"
+
+	// RootVar is the var of the project root of the buffer, to add to the
+	// ExportVars of Funcs calling Root.
+	RootVar = "opt_" + rootOption
+
+	// MarkersVar is the var of the project markers, to add to the
+	// ExportVars of Funcs calling Detect.
+	MarkersVar = "quoted_opt_" + markersOption
+)
+
+// Markers are the files and directories marking the root of a project,
+// used unless the project_markers option is set.
+var Markers = []string{".git", "go.mod", ".hg", "Cargo.toml", "package.json"}
+
+// FindRoot returns the closest directory containing one of the markers,
+// starting with the directory of path.
+func FindRoot(path string, markers []string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+
+	dir := abs
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		dir = filepath.Dir(abs)
+	}
+
+	for {
+		for _, m := range markers {
+			if _, err := os.Stat(filepath.Join(dir, m)); err == nil {
+				return dir, true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Register the project options, hooks and commands.
+//
+// The project_root option of each file buffer is set to the root of its
+// project when created, as found by the project_markers option. The
+// following commands are defined:
+//
+//    project-detect  detect the project root of the buffer again
+//    project-cd      change the working directory to the project root
+func Register(k *api.Kak) error {
+	setup := `declare-option -docstring 'project root of the buffer' str ` + rootOption + `
+declare-option -docstring 'files marking the root of a project, see project.Markers if empty' str-list ` + markersOption + `
+remove-hooks global ` + hookGroup + `
+hook -group ` + hookGroup + ` global BufCreate [^*].* project-detect`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "detect the project root of new buffers")
+
+	err := k.DefineCommand("project-detect", api.DefineCommandOptions{
+		Docstring: "detect the project root of the buffer",
+	}, api.Func{
+		ExportVars: []string{vars.BufFile, MarkersVar},
+		Func: func(kak *api.Kak) error {
+			root, ok, err := Detect(kak)
+			if err != nil || !ok {
+				return err
+			}
+
+			kak.Printf("set-option buffer %s %s\n", rootOption, api.Quote(root))
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("project-cd", api.DefineCommandOptions{
+		Docstring: "change the working directory to the project root of the buffer",
+	}, api.Func{
+		ExportVars: []string{RootVar},
+		Func: func(kak *api.Kak) error {
+			root, err := Root(kak)
+			if err != nil {
+				return err
+			}
+
+			kak.Printf("change-directory %s\n", api.Quote(root))
+			kak.Printf("echo -- %s\n", api.Quote(root))
+			return nil
+		},
+	})
+}
+
+// Detect finds the project root of the buffer file, returning false if the
+// buffer is not within a project.
+//
+// vars.BufFile and MarkersVar must be exported to the Subproc.
+func Detect(kak *api.Kak) (string, bool, error) {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return "", false, err
+	}
+
+	markers, err := kak.VarQuotedList(MarkersVar)
+	if err != nil {
+		return "", false, err
+	}
+	if len(markers) == 0 {
+		markers = Markers
+	}
+
+	root, ok := FindRoot(buffile, markers)
+	return root, ok, nil
+}
+
+// Root returns the project root of the buffer, or the working directory if
+// the buffer is not within a project.
+//
+// RootVar must be exported to the Subproc.
+func Root(kak *api.Kak) (string, error) {
+	root, err := kak.Var(RootVar)
+	if err != nil {
+		return "", err
+	}
+
+	if root != "" {
+		return root, nil
+	}
+
+	return os.Getwd()
+}
+
+// Shorten returns the path for display, relative to the project root of
+// the buffer if within it, see paths.Shorten.
+//
+// RootVar must be exported to the Subproc.
+func Shorten(kak *api.Kak, path string) (string, error) {
+	root, err := Root(kak)
+	if err != nil {
+		return "", err
+	}
+
+	return paths.Shorten(path, root), nil
+}
+
+// StateDir returns a directory within the StateDir private to the project
+// of the buffer, creating it if needed.
+//
+// vars.Session and RootVar must be exported to the Subproc.
+func StateDir(kak *api.Kak) (string, error) {
+	root, err := Root(kak)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := kak.StateDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(state, "project", util.HashString(root))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,53 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// resolve symlinked temp dirs, as roots are absolute.
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(dir, "repo")
+	nested := filepath.Join(root, "cmd", "tool")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "cmd", "go.mod"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		markers []string
+		want    string
+		ok      bool
+	}{
+		{filepath.Join(nested, "main.go"), []string{".git"}, root, true},
+		{filepath.Join(nested, "main.go"), Markers, filepath.Join(root, "cmd"), true},
+		{nested, []string{".git"}, root, true},
+		{filepath.Join(dir, "other.go"), []string{".git"}, "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := FindRoot(test.path, test.markers)
+		if ok != test.ok || got != test.want {
+			t.Errorf("%s %v: want %q %v, got %q %v",
+				test.path, test.markers, test.want, test.ok, got, ok)
+		}
+	}
+}
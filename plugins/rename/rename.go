@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/cmds"
 	"github.com/leeola/gokakoune/api/vars"
 	"github.com/leeola/gokakoune/util"
 )
@@ -56,7 +57,7 @@ var RenameExpressions = api.Expansions{
 
 					// TODO(leeola): unset any error code?
 
-					kak.Command("edit!")
+					kak.Command(cmds.EditForce)
 
 					// gorename reports what it changed, like how many files and how many
 					// renames it did. So pass that report back to the user.
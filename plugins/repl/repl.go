@@ -0,0 +1,279 @@
+package repl
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/terminal"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/results"
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	// stateKey is the buffer scoped State key of the attached Target.
+	stateKey = "repl_target"
+
+	outputBuffer = "*repl-output*"
+)
+
+// Register the repl commands, attaching terminals to buffers and sending
+// text to them.
+//
+// The following commands are defined:
+//
+//    repl-start           start the given shell command in a new terminal, attached to the buffer
+//    repl-attach          attach the given target, such as tmux:%3, to the buffer
+//    repl-detach          detach the target of the buffer
+//    repl-send            send the selections to the attached target
+//    repl-send-line       send the cursor line
+//    repl-send-paragraph  send the cursor paragraph
+//    repl-send-file       send the whole buffer
+//    repl-show            show the output of the attached target
+//
+// repl-start is not named repl-new, as the tmux, x11 and kitty repl
+// scripts bundled with Kakoune alias repl-new, and aliases win over
+// commands.
+//
+// New terminals are tmux panes within tmux, kitty windows within a remote
+// controlled kitty, and otherwise a background process streaming its output
+// into a buffer. The terminal of the client is detected by the terminal
+// package.
+func Register(k *api.Kak) error {
+	err := k.DefineCommand("repl-start", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "start the given shell command in a new terminal, attached to the buffer",
+	}, api.Func{
+		ExportVars: append([]string{vars.BufFile, vars.Session}, terminal.Vars...),
+		Func: func(kak *api.Kak) error {
+			command, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			t, err := start(kak, command)
+			if err != nil {
+				return err
+			}
+
+			// attached before streaming, which switches to the output buffer.
+			if err := attach(kak, t); err != nil {
+				return err
+			}
+
+			if t.Kind == KindFifo {
+				return stream(kak, t, command)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("repl-attach", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "attach the given target, such as tmux:%3 or kitty:12, to the buffer",
+	}, api.Func{
+		Func: func(kak *api.Kak) error {
+			arg, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			t, err := ParseTarget(arg)
+			if err != nil {
+				return err
+			}
+
+			return attach(kak, t)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("repl-detach", api.DefineCommandOptions{
+		Docstring: "detach the repl target of the buffer",
+	}, api.Func{
+		Func: func(kak *api.Kak) error {
+			return kak.State().Delete(api.ScopeBuffer, stateKey)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("repl-send", api.DefineCommandOptions{
+		Docstring: "send the selections to the repl target of the buffer",
+	}, api.Func{
+		ExportVars: []string{
+			vars.QuotedSelections,
+			k.StateVar(stateKey),
+		},
+		Func: func(kak *api.Kak) error {
+			t, err := attached(kak)
+			if err != nil {
+				return err
+			}
+
+			sels, err := kak.SelectionTexts()
+			if err != nil {
+				return err
+			}
+
+			text := strings.Join(sels, "\n")
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+
+			return t.Send(text)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	sends := []struct {
+		name, keys, doc string
+	}{
+		{"repl-send-line", "x", "send the cursor line to the repl target"},
+		{"repl-send-paragraph", "<a-a>p", "send the cursor paragraph to the repl target"},
+		{"repl-send-file", "%", "send the buffer to the repl target"},
+	}
+	for _, s := range sends {
+		err := k.DefineCommand(s.name, api.DefineCommandOptions{
+			Docstring: s.doc,
+		}, api.Raw("evaluate-commands -draft %{ execute-keys '"+s.keys+"'; repl-send }"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return k.DefineCommand("repl-show", api.DefineCommandOptions{
+		Docstring: "show the output of the repl target of the buffer",
+	}, api.Func{
+		ExportVars: []string{k.StateVar(stateKey)},
+		Func: func(kak *api.Kak) error {
+			t, err := attached(kak)
+			if err != nil {
+				return err
+			}
+
+			// fifo targets already stream into a buffer.
+			if t.Kind == KindFifo {
+				kak.Printf("buffer %s\n", api.Quote(fifoBuffer(t.ID)))
+				return nil
+			}
+
+			out, err := t.Output()
+			if err != nil {
+				return err
+			}
+
+			kak.Printf("edit -scratch %s\n", outputBuffer)
+			kak.Printf("set-register z %s\n", api.Quote(out))
+			kak.Println(`execute-keys '%"zRge'`)
+			return nil
+		},
+	})
+}
+
+// attach the given target to the buffer.
+func attach(kak *api.Kak, t Target) error {
+	if err := kak.State().Set(api.ScopeBuffer, stateKey, t.String()); err != nil {
+		return err
+	}
+
+	kak.Printf("echo -- %s\n", api.Quote("repl attached to "+t.String()))
+	return nil
+}
+
+// attached returns the target attached to the buffer.
+func attached(kak *api.Kak) (Target, error) {
+	var s string
+	if err := kak.State().Get(stateKey, &s); err != nil && err != api.ErrStateNotFound {
+		return Target{}, err
+	}
+
+	if s == "" {
+		return Target{}, errors.New("no repl attached, see repl-start and repl-attach")
+	}
+
+	return ParseTarget(s)
+}
+
+// start starts the shell command in a new terminal, returning its target.
+// Fifo targets are only created, see stream.
+func start(kak *api.Kak, command string) (Target, error) {
+	env := terminal.ClientEnv(kak)
+
+	switch env.Kind() {
+	case terminal.Tmux:
+		out, err := runIn(env.Environ(), "", "tmux", "split-window", "-d", "-h", "-P", "-F", "#{pane_id}", command)
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Kind: KindTmux, ID: strings.TrimSpace(out)}, nil
+
+	case terminal.Kitty:
+		out, err := runIn(env.Environ(), "", "kitty", "@", "--to", env["KITTY_LISTEN_ON"],
+			"launch", "--type=window", "--keep-focus", "sh", "-c", command)
+		if err != nil {
+			return Target{}, err
+		}
+		return Target{Kind: KindKitty, ID: strings.TrimSpace(out)}, nil
+
+	default:
+		return newFifo(kak)
+	}
+}
+
+// newFifo returns a fifo target for the buffer, which the shell command is
+// then streamed from.
+func newFifo(kak *api.Kak) (Target, error) {
+	buffile, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return Target{}, err
+	}
+
+	fifo, err := kak.NewFifo(filepath.Base(buffile))
+	if err != nil {
+		return Target{}, err
+	}
+
+	return Target{Kind: KindFifo, ID: fifo.Path}, nil
+}
+
+// stream starts the shell command in the background, reading its input
+// from the fifo target and streaming its output into a buffer.
+func stream(kak *api.Kak, t Target, command string) error {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		return err
+	}
+
+	// NOTE(leeola): the fifo is opened for both reading and writing, so
+	// the process never reads EOF between sends.
+	script := `exec 3<>"$0"; exec sh -c "$1" <&3`
+	if err := results.Stream(kak, fifoBuffer(t.ID), "", sh, "-c", script, t.ID, command); err != nil {
+		os.RemoveAll(filepath.Dir(t.ID))
+		return err
+	}
+
+	// the input fifo is removed along with the output, once the shell
+	// command exits.
+	kak.Printf("hook -always -once buffer BufCloseFifo .* %%{ nop %%sh{ rm -r %s } }\n",
+		util.ShellQuote(filepath.Dir(t.ID)))
+	return nil
+}
+
+// fifoBuffer returns the buffer the output of the fifo target is streamed
+// into.
+func fifoBuffer(fifo string) string {
+	return "*repl " + filepath.Base(fifo) + "*"
+}
@@ -0,0 +1,116 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+const (
+	KindTmux  = "tmux"
+	KindKitty = "kitty"
+	KindFifo  = "fifo"
+)
+
+// Target is a terminal or process text is sent to, written as
+// `<kind>:<id>`:
+//
+//    tmux:%3         a tmux pane
+//    kitty:12        a kitty window, controlled with kitty @
+//    fifo:/tmp/repl  a process reading its input from a fifo
+type Target struct {
+	Kind string
+	ID   string
+}
+
+// ParseTarget parses the `<kind>:<id>` form of a Target.
+func ParseTarget(s string) (Target, error) {
+	i := strings.IndexByte(s, ':')
+	if i <= 0 || i == len(s)-1 {
+		return Target{}, fmt.Errorf("invalid repl target: %q", s)
+	}
+
+	t := Target{Kind: s[:i], ID: s[i+1:]}
+	switch t.Kind {
+	case KindTmux, KindKitty, KindFifo:
+	default:
+		return Target{}, fmt.Errorf("unknown repl target kind: %q", t.Kind)
+	}
+
+	return t, nil
+}
+
+func (t Target) String() string {
+	return t.Kind + ":" + t.ID
+}
+
+// Send sends the given text to the target, as if typed.
+func (t Target) Send(text string) error {
+	switch t.Kind {
+	case KindTmux:
+		// NOTE(leeola): pasting through a tmux buffer, rather than
+		// send-keys, avoids any text being read as key names.
+		if _, err := run(text, "tmux", "load-buffer", "-b", "gokakoune-repl", "-"); err != nil {
+			return err
+		}
+		_, err := run("", "tmux", "paste-buffer", "-d", "-b", "gokakoune-repl", "-t", t.ID)
+		return err
+
+	case KindKitty:
+		_, err := run(text, "kitty", "@", "send-text", "--match", "id:"+t.ID, "--stdin")
+		return err
+
+	case KindFifo:
+		// nonblocking, so a process which exited fails rather than hangs.
+		f, err := os.OpenFile(t.ID, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			return fmt.Errorf("repl process not running: %s", err)
+		}
+		defer f.Close()
+
+		_, err = f.WriteString(text)
+		return err
+
+	default:
+		return fmt.Errorf("unknown repl target kind: %q", t.Kind)
+	}
+}
+
+// Output returns the visible output of the target. Fifo targets stream
+// their output into a buffer instead, and are not supported.
+func (t Target) Output() (string, error) {
+	switch t.Kind {
+	case KindTmux:
+		return run("", "tmux", "capture-pane", "-p", "-J", "-S", "-", "-t", t.ID)
+	case KindKitty:
+		return run("", "kitty", "@", "get-text", "--match", "id:"+t.ID, "--extent", "all")
+	default:
+		return "", fmt.Errorf("output of %s targets is not supported", t.Kind)
+	}
+}
+
+// run runs the given command with stdin, returning stdout.
+func run(stdin string, name string, args ...string) (string, error) {
+	return runIn(nil, stdin, name, args...)
+}
+
+// runIn runs the given command as run does, within the given environment,
+// or that of this process if nil.
+func runIn(env []string, stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %s", name, args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,39 @@
+package repl
+
+import "testing"
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Target
+		err   bool
+	}{
+		{input: "tmux:%3", want: Target{Kind: KindTmux, ID: "%3"}},
+		{input: "kitty:12", want: Target{Kind: KindKitty, ID: "12"}},
+		{input: "fifo:/tmp/a:b", want: Target{Kind: KindFifo, ID: "/tmp/a:b"}},
+		{input: "tmux:", err: true},
+		{input: ":3", err: true},
+		{input: "screen:1", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTarget(test.input)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: want error", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%q: want %+v, got %+v", test.input, test.want, got)
+		}
+		if got.String() != test.input {
+			t.Errorf("%q: round trips to %q", test.input, got.String())
+		}
+	}
+}
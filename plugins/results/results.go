@@ -0,0 +1,151 @@
+package results
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// Results describes a results buffer, listing `file:line:col: text` lines
+// which can be jumped to.
+//
+// Registering a Results named "search" defines:
+//
+//    search-jump      jump to the result under the cursor
+//    search-next      jump to the next result
+//    search-previous  jump to the previous result
+//
+// And the results are shown in the *search* buffer, of the search
+// filetype.
+type Results struct {
+	Name string
+}
+
+// Buffer returns the name of the results buffer.
+func (r Results) Buffer() string {
+	return "*" + r.Name + "*"
+}
+
+func (r Results) currentLineOption() string {
+	return api.OptionName(r.Name) + "_current_line"
+}
+
+// resultRegex matches the location of a result line.
+const resultRegex = `^((?:\w:)?[^:\n]+):(\d+):(\d+)?`
+
+// Register the commands, highlighters and mappings of the results buffer.
+//
+// This mirrors the grep script bundled with Kakoune, so the jumpclient and
+// toolsclient options are respected in the same way.
+func Register(k *api.Kak, r Results) error {
+	line := r.currentLineOption()
+	group := r.Name + "-results"
+
+	setup := fmt.Sprintf(`declare-option -hidden int %[1]s 0
+remove-hooks global %[2]s
+hook -group %[2]s global WinSetOption filetype=%[3]s %%{
+  add-highlighter window/%[3]s group
+  add-highlighter window/%[3]s/ regex '%[4]s' 1:cyan 2:green 3:green
+  add-highlighter window/%[3]s/ line %%{%%opt{%[1]s}} default+b
+  map window normal <ret> ':%[3]s-jump<ret>'
+  hook -once -always window WinSetOption filetype=.* %%{
+    remove-highlighter window/%[3]s
+    unmap window normal <ret> ':%[3]s-jump<ret>'
+  }
+}`, line, group, r.Name, resultRegex)
+
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(group, "highlight the "+r.Buffer()+" buffer")
+
+	err := k.DefineCommand(r.Name+"-jump", api.DefineCommandOptions{
+		Docstring: "jump to the result under the cursor",
+	}, api.Raw(fmt.Sprintf(`evaluate-commands -save-regs 123 %%{
+    try %%{
+      execute-keys 'xs%[2]s<ret>'
+      set-option buffer %[1]s %%val{cursor_line}
+      evaluate-commands -try-client %%opt{jumpclient} -verbatim -- edit -existing -- %%reg{1} %%reg{2} %%reg{3}
+      try %%{ focus %%opt{jumpclient} }
+    }
+  }`, line, resultRegex)))
+	if err != nil {
+		return err
+	}
+
+	// next and previous move through the results buffer in the jumpclient,
+	// then keep the toolsclient (if any) showing the current result.
+	move := func(keys string) string {
+		return fmt.Sprintf(`evaluate-commands -try-client %%opt{jumpclient} -save-regs / %%{
+    buffer %[1]s
+    set-register / "%[2]s"
+    execute-keys %[3]s
+    %[4]s-jump
+  }
+  try %%{
+    evaluate-commands -client %%opt{toolsclient} %%{
+      buffer %[1]s
+      execute-keys gg %%opt{%[5]s}g
+    }
+  }`, api.Quote(r.Buffer()), resultRegex, keys, r.Name, line)
+	}
+
+	err = k.DefineCommand(r.Name+"-next", api.DefineCommandOptions{
+		Docstring: "jump to the next result",
+	}, api.Raw(move(fmt.Sprintf(`"%%opt{%s}ggl" "/<ret>"`, line))))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand(r.Name+"-previous", api.DefineCommandOptions{
+		Docstring: "jump to the previous result",
+	}, api.Raw(move(fmt.Sprintf(`"%%opt{%s}g" "<a-/><ret>"`, line))))
+}
+
+// Open runs the given command in the background, streaming its output into
+// the results buffer.
+//
+// The command is run in dir, if not empty. vars.Session must be exported to
+// the Subproc.
+func Open(kak *api.Kak, r Results, dir string, name string, args ...string) error {
+	if err := Stream(kak, r.Buffer(), dir, name, args...); err != nil {
+		return err
+	}
+
+	kak.Printf("set-option buffer filetype %s\n", r.Name)
+	kak.Printf("set-option buffer %s 0\n", r.currentLineOption())
+
+	return nil
+}
+
+// Stream runs the given command in the background, streaming its output
+// into the given buffer, which is (re)created and shown.
+//
+// The output is streamed through a fifo, so the output is shown as it is
+// written and Kakoune is never blocked. The command is run in dir, if not
+// empty. vars.Session must be exported to the Subproc.
+func Stream(kak *api.Kak, buffer, dir string, name string, args ...string) error {
+	fifo, err := kak.NewFifo("")
+	if err != nil {
+		return err
+	}
+
+	// NOTE(leeola): the shell opens the fifo rather than this process, as
+	// opening a fifo for writing blocks until Kakoune opens it for reading,
+	// which only happens after this process exits.
+	script := `exec > "$0" 2>&1; exec "$@"`
+	cmd := exec.Command("sh", append([]string{"-c", script, fifo.Path, name}, args...)...)
+	cmd.Dir = dir
+	// detach from this process, so it survives us exiting.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		fifo.Remove()
+		return err
+	}
+
+	fifo.Edit(kak, buffer)
+	return nil
+}
@@ -0,0 +1,155 @@
+package results
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/kaktest"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+func TestRegister(t *testing.T) {
+	res, err := kaktest.New().Init(func(k *api.Kak) error {
+		return Register(k, Results{Name: "lint-go"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"declare-option -hidden int lint_go_current_line 0\n",
+		"hook -group lint-go-results global WinSetOption filetype=lint-go %{",
+		"map window normal <ret> ':lint-go-jump<ret>'",
+		" lint-go-jump %{",
+		" lint-go-next %{",
+		" lint-go-previous %{",
+		"buffer '*lint-go*'",
+	} {
+		if !strings.Contains(res.Output, want) {
+			t.Errorf("want %q within %q", want, res.Output)
+		}
+	}
+	if err := api.CheckBalanced(res.Output); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegisterSearch(t *testing.T) {
+	res, err := kaktest.New().Init(RegisterSearch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		" search-jump %{",
+		"define-command -params 1 -docstring 'search for the given pattern, listing matches in *search*' search %{",
+		"[$kak_session $kak_opt_project_root]",
+	} {
+		if !strings.Contains(res.Output, want) {
+			t.Errorf("want %q within %q", want, res.Output)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	defer withCache(t)()
+
+	dir, err := ioutil.TempDir("", "results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	kak := kaktest.New()
+	kak.Vars[vars.Session] = "test"
+	res, err := kak.Run(api.Func{
+		ExportVars: []string{vars.Session},
+		Func: func(kak *api.Kak) error {
+			return Stream(kak, "*out*", dir, "sh", "-c", "pwd; echo out; echo err >&2")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "-scroll '*out*'\n"; !strings.Contains(res.Output, want) {
+		t.Errorf("want %q within %q", want, res.Output)
+	}
+	if got, want := readFifo(t, res.Output), dir+"\nout\nerr\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	defer withCache(t)()
+
+	kak := kaktest.New()
+	kak.Vars[vars.Session] = "test"
+	res, err := kak.Run(api.Func{
+		ExportVars: []string{vars.Session},
+		Func: func(kak *api.Kak) error {
+			return Open(kak, Search, "", "echo", "main.go:1:2: match")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"-scroll '*search*'\n",
+		"set-option buffer filetype search\n",
+		"set-option buffer search_current_line 0\n",
+	} {
+		if !strings.Contains(res.Output, want) {
+			t.Errorf("want %q within %q", want, res.Output)
+		}
+	}
+	if got, want := readFifo(t, res.Output), "main.go:1:2: match\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+// withCache points the state dir at a temporary directory, returning the
+// func restoring it.
+func withCache(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "results-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, ok := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", dir)
+	return func() {
+		if ok {
+			os.Setenv("XDG_CACHE_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+var fifoRe = regexp.MustCompile(`edit! -fifo '([^']+)'`)
+
+// readFifo reads the fifo edited by the output, as Kakoune would, until
+// the command writing it exits.
+func readFifo(t *testing.T, output string) string {
+	m := fifoRe.FindStringSubmatch(output)
+	if m == nil {
+		t.Fatalf("no fifo edited in %q", output)
+	}
+
+	b, err := ioutil.ReadFile(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
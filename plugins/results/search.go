@@ -0,0 +1,58 @@
+package results
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/project"
+)
+
+// Search is the results buffer of the search command.
+var Search = Results{Name: "search"}
+
+// RegisterSearch defines the search command, searching the project of the
+// buffer for the given pattern with ripgrep, or grep if ripgrep is not
+// installed. Outside of a project, the working directory is searched.
+func RegisterSearch(k *api.Kak) error {
+	if err := Register(k, Search); err != nil {
+		return err
+	}
+
+	return k.DefineCommand("search", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "search for the given pattern, listing matches in *search*",
+	}, api.Func{
+		ExportVars: []string{
+			vars.Session,
+			project.RootVar,
+		},
+		Func: func(kak *api.Kak) error {
+			pattern, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			root, err := project.Root(kak)
+			if err != nil {
+				return err
+			}
+
+			// NOTE(leeola): matches are jumped to relative to the working
+			// directory, so other roots are searched by path, listing the
+			// matches with absolute paths.
+			path := "."
+			if wd, err := os.Getwd(); err != nil || wd != root {
+				path = root
+			}
+
+			if _, err := exec.LookPath("rg"); err == nil {
+				return Open(kak, Search, "", "rg", "--column", "--line-number",
+					"--no-heading", "--color=never", "--", pattern, path)
+			}
+
+			return Open(kak, Search, "", "grep", "-RHn", "--", pattern, path)
+		},
+	})
+}
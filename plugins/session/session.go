@@ -0,0 +1,315 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	hookGroup = "session"
+
+	cursorOption  = "session_cursor"
+	optionsOption = "session_options"
+
+	// buffersOption and valuesOption collect the buffers and option values
+	// of the session when saving, as a Func only sees the vars of the
+	// buffer it runs in.
+	buffersOption = "session_buffers"
+	valuesOption  = "session_values"
+)
+
+// AutosaveEvery is the duration between periodic saves of the session.
+var AutosaveEvery = 5 * time.Minute
+
+// Snapshot is the saved state of a session.
+type Snapshot struct {
+	Dir     string
+	Buffers []Buffer
+
+	// Current is the file of the buffer shown when saved, if any.
+	Current string
+
+	// Options are the global values of the session_options.
+	Options map[string]string
+
+	Saved time.Time
+}
+
+// Buffer is a file buffer of the session, and its cursor.
+type Buffer struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// Register the session commands and the hooks saving the session.
+//
+// The following commands are defined:
+//
+//    session-save     save the buffers, cursors and options of the session
+//    session-restore  reopen the saved buffers of the working directory
+//    session-autosave save the session, run every AutosaveEvery when idle
+//
+// The session is saved whenever a buffer is written, a client loses focus
+// or closes, periodically, and when Kakoune exits. Sessions are saved per working
+// directory, and the global values of the options listed in the
+// session_options option are saved along with them.
+func Register(k *api.Kak) error {
+	setup := `declare-option -hidden str ` + cursorOption + `
+declare-option -hidden str-list ` + buffersOption + `
+declare-option -hidden str-list ` + valuesOption + `
+declare-option -docstring 'global options saved with the session' str-list ` + optionsOption + `
+remove-hooks global ` + hookGroup + `
+hook -group ` + hookGroup + ` global NormalIdle .* %{ set-option buffer ` + cursorOption + ` "%val{cursor_line}.%val{cursor_column}" }
+hook -group ` + hookGroup + ` global BufWritePost .* session-save
+hook -group ` + hookGroup + ` global FocusOut .* session-save
+hook -group ` + hookGroup + ` global ClientClose .* session-save
+hook -group ` + hookGroup + ` global KakEnd .* session-save`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "track cursors and save the session")
+
+	err := k.DefineCommand("session-save", api.DefineCommandOptions{
+		Docstring: "save the buffers, cursors and options of the session",
+	}, api.Func{
+		ExportVars: []string{"quoted_opt_" + optionsOption},
+		Func: func(kak *api.Kak) error {
+			names, err := kak.VarQuotedList("quoted_opt_" + optionsOption)
+			if err != nil {
+				return err
+			}
+
+			kak.Printf("set-option global %s\n", buffersOption)
+			kak.Printf(`evaluate-commands -buffer * %%{ set-option -add global %s "%%val{buffile}|%%opt{%s}" }`+"\n",
+				buffersOption, cursorOption)
+			kak.Printf("set-option global %s\n", valuesOption)
+			for _, name := range names {
+				kak.Printf(`set-option -add global %s "%%opt{%s}"`+"\n", valuesOption, name)
+			}
+			return nil
+		},
+	}, api.Func{
+		ExportVars: []string{
+			vars.BufFile,
+			"quoted_opt_" + buffersOption,
+			"quoted_opt_" + valuesOption,
+			"quoted_opt_" + optionsOption,
+		},
+		Func: save,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.Schedule(api.Schedule{
+		Name:      "session-autosave",
+		Every:     AutosaveEvery,
+		Docstring: "save the session, run periodically when idle",
+	}, api.Raw("session-save"))
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("session-restore", api.DefineCommandOptions{
+		Docstring: "reopen the saved buffers of the working directory",
+	}, api.Func{
+		Func: restore,
+	})
+}
+
+// save writes the snapshot of the collected buffers and options.
+func save(kak *api.Kak) error {
+	entries, err := kak.VarQuotedList("quoted_opt_" + buffersOption)
+	if err != nil {
+		return err
+	}
+
+	names, err := kak.VarQuotedList("quoted_opt_" + optionsOption)
+	if err != nil {
+		return err
+	}
+
+	values, err := kak.VarQuotedList("quoted_opt_" + valuesOption)
+	if err != nil {
+		return err
+	}
+
+	current, err := kak.Var(vars.BufFile)
+	if err != nil {
+		return err
+	}
+	if abs, err := filepath.Abs(current); err == nil {
+		current = abs
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	s := Snapshot{
+		Dir:     wd,
+		Buffers: ParseBuffers(entries),
+		Options: map[string]string{},
+		Saved:   time.Now(),
+	}
+
+	// NOTE(leeola): a session left with only scratch buffers keeps the
+	// previous snapshot, rather than replacing it with nothing to restore.
+	if len(s.Buffers) == 0 {
+		return nil
+	}
+
+	// the current buffer may be a scratch buffer, which is not restored.
+	for _, b := range s.Buffers {
+		if b.File == current {
+			s.Current = current
+		}
+	}
+
+	for i, name := range names {
+		if i < len(values) {
+			s.Options[name] = values[i]
+		}
+	}
+
+	path, err := file(kak, wd)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// written through a rename, so a session ending mid write never
+	// leaves a truncated snapshot.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restore reopens the buffers of the snapshot of the working directory.
+func restore(kak *api.Kak) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	path, err := file(kak, wd)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no saved session for %s", wd)
+	}
+	if err != nil {
+		return err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	var restored int
+	edit := func(b Buffer) {
+		if _, err := os.Stat(b.File); err != nil {
+			return
+		}
+		kak.Printf("try %%{ %s }\n", paths.Edit(b.File, api.Coord{Line: b.Line, Column: b.Column}))
+		restored++
+	}
+
+	// the current buffer is edited last, so it is the one shown.
+	var current *Buffer
+	for i, b := range s.Buffers {
+		if b.File == s.Current {
+			current = &s.Buffers[i]
+			continue
+		}
+		edit(b)
+	}
+	if current != nil {
+		edit(*current)
+	}
+
+	for name, value := range s.Options {
+		kak.Printf("try %%{ set-option global %s %s }\n", name, api.Quote(value))
+	}
+
+	if restored == 0 {
+		return errors.New("no saved buffers exist anymore")
+	}
+
+	kak.Printf("echo -- %s\n", api.Quote(fmt.Sprintf(
+		"restored %d buffers saved %s", restored, s.Saved.Format("2006-01-02 15:04"))))
+	return nil
+}
+
+// ParseBuffers parses the collected `file|line.column` entries, skipping
+// buffers without a file.
+func ParseBuffers(entries []string) []Buffer {
+	var bufs []Buffer
+	for _, e := range entries {
+		i := strings.LastIndexByte(e, '|')
+		if i <= 0 {
+			continue
+		}
+
+		b := Buffer{File: e[:i], Line: 1, Column: 1}
+
+		// scratch and debug buffers have no file.
+		if !filepath.IsAbs(b.File) && strings.HasPrefix(b.File, "*") {
+			continue
+		}
+
+		if abs, err := filepath.Abs(b.File); err == nil {
+			b.File = abs
+		}
+
+		if parts := strings.SplitN(e[i+1:], ".", 2); len(parts) == 2 {
+			line, lerr := strconv.Atoi(parts[0])
+			col, cerr := strconv.Atoi(parts[1])
+			if lerr == nil && cerr == nil {
+				b.Line, b.Column = line, col
+			}
+		}
+
+		bufs = append(bufs, b)
+	}
+	return bufs
+}
+
+// file returns the snapshot file of the given working directory.
+func file(kak *api.Kak, wd string) (string, error) {
+	dir, err := kak.DataDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "sessions")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, util.HashString(wd)+".json"), nil
+}
@@ -0,0 +1,33 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseBuffers(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []string{
+		"/src/main.go|12.5",
+		"README|",
+		"*debug*|1.1",
+		"/src/a|b.go|3.1",
+		"|2.2",
+	}
+
+	want := []Buffer{
+		{File: "/src/main.go", Line: 12, Column: 5},
+		{File: filepath.Join(wd, "README"), Line: 1, Column: 1},
+		{File: "/src/a|b.go", Line: 3, Column: 1},
+	}
+
+	if got := ParseBuffers(entries); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/cmds"
 	"github.com/leeola/gokakoune/api/vars"
 	"github.com/leeola/gokakoune/util"
 )
@@ -43,7 +44,7 @@ var ShowDocExpressions = api.Expansions{
 			}
 
 			if exit != 0 {
-				kak.Debugf(gogetdocBin, "output:", stdout)
+				kak.Debug(gogetdocBin, "output:", stdout)
 				return fmt.Errorf("unexpected %s exit code: %d", gogetdocBin, exit)
 			}
 
@@ -60,7 +61,7 @@ var ShowDocExpressions = api.Expansions{
 			}
 			stdout = strings.Join(split, "\n")
 
-			kak.Command("info", stdout)
+			kak.Command(cmds.Info, stdout)
 
 			return nil
 		},
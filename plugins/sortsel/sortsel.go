@@ -0,0 +1,191 @@
+package sortsel
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// Less reports whether the contents of selection a sort before those of b.
+type Less func(a, b string) bool
+
+// Lexical sorts the contents by their bytes, as sort.Strings does.
+func Lexical(a, b string) bool {
+	return a < b
+}
+
+// numberRe matches the number leading the contents, ignoring whitespace.
+var numberRe = regexp.MustCompile(`^\s*[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?`)
+
+// Numeric sorts the contents by the number leading them, such as `10` of
+// `10 apples`. Contents without a number sort after those with one,
+// lexically.
+func Numeric(a, b string) bool {
+	na, aok := leadingNumber(a)
+	nb, bok := leadingNumber(b)
+	switch {
+	case aok && bok && na != nb:
+		return na < nb
+	case aok != bok:
+		return aok
+	default:
+		return a < b
+	}
+}
+
+func leadingNumber(s string) (float64, bool) {
+	m := numberRe.FindString(s)
+	if m == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(m), 64)
+	return n, err == nil
+}
+
+// Sort returns the contents sorted by less, contents sorting the same
+// keeping their order.
+func Sort(texts []string, less Less) []string {
+	sorted := append([]string(nil), texts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// Reverse returns the contents in reverse order.
+func Reverse(texts []string) []string {
+	reversed := make([]string, len(texts))
+	for i, t := range texts {
+		reversed[len(texts)-1-i] = t
+	}
+	return reversed
+}
+
+// Duplicates returns the indexes of the contents equal to earlier ones.
+func Duplicates(texts []string) []int {
+	var (
+		dups []int
+		seen = map[string]bool{}
+	)
+	for i, t := range texts {
+		if seen[t] {
+			dups = append(dups, i)
+			continue
+		}
+		seen[t] = true
+	}
+	return dups
+}
+
+// Define defines the command sorting the contents of the selections by
+// less, each selection being replaced by the content of its sorted index.
+// Eg, sorting by length:
+//
+//    sortsel.Define(k, "sort-selections-length", "sort the selections by length",
+//        func(a, b string) bool { return len(a) < len(b) })
+func Define(k *api.Kak, name, docstring string, less Less) error {
+	return k.DefineCommand(name, api.DefineCommandOptions{
+		Docstring: docstring,
+	}, api.Func{
+		ExportVars: []string{vars.QuotedSelections},
+		Func: func(kak *api.Kak) error {
+			texts, err := kak.SelectionTexts()
+			if err != nil {
+				return err
+			}
+			kak.ReplaceSelections(Sort(texts, less))
+			return nil
+		},
+	})
+}
+
+// Register the selection sorting commands.
+//
+// The following commands are defined:
+//
+//    sort-selections          sort the contents of the selections lexically
+//    sort-selections-numeric  sort the contents of the selections by their leading number
+//    reverse-selections       reverse the order of the contents of the selections
+//    unique-selections        delete the selections duplicating an earlier one
+//
+// The contents are moved between the selections, which stay where they
+// are. Eg, sorting the lines of a paragraph:
+//
+//    execute-keys '<a-i>p<a-s>'
+//    sort-selections
+//
+// See Define for sorting by other comparators.
+func Register(k *api.Kak) error {
+	err := Define(k, "sort-selections",
+		"sort the contents of the selections lexically", Lexical)
+	if err != nil {
+		return err
+	}
+
+	err = Define(k, "sort-selections-numeric",
+		"sort the contents of the selections by their leading number", Numeric)
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("reverse-selections", api.DefineCommandOptions{
+		Docstring: "reverse the order of the contents of the selections",
+	}, api.Func{
+		ExportVars: []string{vars.QuotedSelections},
+		Func: func(kak *api.Kak) error {
+			texts, err := kak.SelectionTexts()
+			if err != nil {
+				return err
+			}
+			kak.ReplaceSelections(Reverse(texts))
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("unique-selections", api.DefineCommandOptions{
+		Docstring: "delete the selections duplicating an earlier one",
+	}, api.Func{
+		ExportVars: []string{vars.QuotedSelections, vars.SelectionsDesc},
+		Func:       unique,
+	})
+}
+
+// unique deletes the selections whose contents equal those of an earlier
+// one, such as duplicate lines.
+//
+// NOTE(leeola): the duplicates are deleted within a draft context, so the
+// remaining selections are restored once done, as Kakoune adjusted them.
+func unique(kak *api.Kak) error {
+	sels, err := kak.Selections()
+	if err != nil {
+		return err
+	}
+	texts, err := kak.SelectionTexts()
+	if err != nil {
+		return err
+	}
+	if len(texts) != len(sels) {
+		return errors.New("selections and their contents differ")
+	}
+
+	dups := Duplicates(texts)
+	if len(dups) == 0 {
+		kak.Echo("no duplicate selections")
+		return nil
+	}
+
+	descs := make([]string, len(dups))
+	for i, d := range dups {
+		descs[i] = sels[d].String()
+	}
+	kak.Printf("evaluate-commands -draft %%{\n  select %s\n  execute-keys <a-d>\n}\n", strings.Join(descs, " "))
+	return nil
+}
@@ -0,0 +1,55 @@
+package sortsel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/kaktest"
+)
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		less     Less
+		in, want []string
+	}{
+		{Lexical, []string{"b", "a", "c"}, []string{"a", "b", "c"}},
+		{Numeric, []string{"10 apples", "9", "x", "-1.5", "1e1"}, []string{"-1.5", "9", "10 apples", "1e1", "x"}},
+		{Numeric, []string{"b", "a", " 2"}, []string{" 2", "a", "b"}},
+	}
+	for _, test := range tests {
+		if got := Sort(test.in, test.less); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+}
+
+func TestReverseAndDuplicates(t *testing.T) {
+	if got, want := Reverse([]string{"a", "b", "c"}), []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got, want := Duplicates([]string{"a", "b", "a", "c", "b"}), []int{2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	kak := kaktest.New()
+	kak.SetSelections([]api.Selection{
+		{Anchor: api.Coord{Line: 1, Column: 1}, Cursor: api.Coord{Line: 1, Column: 2}},
+		{Anchor: api.Coord{Line: 2, Column: 1}, Cursor: api.Coord{Line: 2, Column: 2}},
+		{Anchor: api.Coord{Line: 3, Column: 1}, Cursor: api.Coord{Line: 3, Column: 2}},
+	}, []string{"a\n", "b\n", "a\n"})
+
+	res, err := kak.Run(api.Func{
+		ExportVars: []string{"quoted_selections", "selections_desc"},
+		Func:       unique,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "select 3.1,3.2\n  execute-keys <a-d>"; !strings.Contains(res.Output, want) {
+		t.Errorf("want %q within %q", want, res.Output)
+	}
+}
@@ -0,0 +1,169 @@
+package spell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Misspelling is a single misspelled word.
+type Misspelling struct {
+	Word string
+
+	// Line and Column are the 1 indexed line and byte column of the first
+	// character of the word, as Kakoune positions.
+	Line   int
+	Column int
+
+	Suggestions []string
+}
+
+// EndColumn returns the byte column of the last character of the word.
+func (m Misspelling) EndColumn() int {
+	_, size := utf8.DecodeLastRuneInString(m.Word)
+	return m.Column + len(m.Word) - size
+}
+
+// Input returns the input of a checker in ispell pipe mode for the given
+// lines. Each line is prefixed with ^, so that no line is read as a
+// command.
+func Input(lines []string) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString("^")
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Parse parses the output of a checker in ispell pipe mode, such as
+// `aspell -a` or `hunspell -a`, given the checked lines.
+//
+// Every input line is answered by a result per word, ended by an empty
+// line. Only misspellings are returned:
+//
+//    & word count offset: suggestion, suggestion
+//    # word offset
+func Parse(r io.Reader, lines []string) ([]Misspelling, error) {
+	var (
+		ms   []Misspelling
+		line = 1
+	)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		text := s.Text()
+		if text == "" {
+			line++
+			continue
+		}
+
+		var (
+			m   Misspelling
+			err error
+		)
+		switch text[0] {
+		case '&':
+			m, err = parseSuggestions(text)
+		case '#':
+			m, err = parseNone(text)
+		default:
+			// the version banner, and correct words.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line > len(lines) {
+			return nil, fmt.Errorf("misspelling beyond the input: %q", text)
+		}
+
+		m.Line = line
+		m.Column = column(lines[line-1], m.Word, m.Column)
+		ms = append(ms, m)
+	}
+
+	return ms, s.Err()
+}
+
+// parseSuggestions parses `& word count offset: suggestion, suggestion`,
+// leaving the offset as the Column.
+func parseSuggestions(text string) (Misspelling, error) {
+	i := strings.Index(text, ": ")
+	if i == -1 {
+		return Misspelling{}, fmt.Errorf("invalid misspelling: %q", text)
+	}
+
+	fields := strings.Fields(text[:i])
+	if len(fields) != 4 {
+		return Misspelling{}, fmt.Errorf("invalid misspelling: %q", text)
+	}
+
+	offset, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return Misspelling{}, fmt.Errorf("failed to offset to int: %q", fields[3])
+	}
+
+	var suggestions []string
+	for _, s := range strings.Split(text[i+2:], ", ") {
+		if s = strings.TrimSpace(s); s != "" {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return Misspelling{
+		Word:        fields[1],
+		Column:      offset,
+		Suggestions: suggestions,
+	}, nil
+}
+
+// parseNone parses `# word offset`, leaving the offset as the Column.
+func parseNone(text string) (Misspelling, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 3 {
+		return Misspelling{}, fmt.Errorf("invalid misspelling: %q", text)
+	}
+
+	offset, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return Misspelling{}, fmt.Errorf("failed to offset to int: %q", fields[2])
+	}
+
+	return Misspelling{Word: fields[1], Column: offset}, nil
+}
+
+// column returns the byte column of word within line, given the offset
+// reported by the checker.
+//
+// NOTE(leeola): the offset counts the ^ prefix as 0, making it the 1
+// indexed character of the word. Checkers however disagree on counting
+// characters or bytes, so the word is searched for if not found at the
+// offset.
+func column(line, word string, offset int) int {
+	var runes int
+	for i := range line {
+		if runes == offset-1 {
+			if strings.HasPrefix(line[i:], word) {
+				return i + 1
+			}
+			break
+		}
+		runes++
+	}
+
+	if offset >= 1 && offset <= len(line) && strings.HasPrefix(line[offset-1:], word) {
+		return offset
+	}
+
+	if i := strings.Index(line, word); i != -1 {
+		return i + 1
+	}
+
+	return offset
+}
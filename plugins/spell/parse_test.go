@@ -0,0 +1,53 @@
+package spell
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	lines := []string{
+		"teh quick fox",
+		"",
+		"naïve wrold and zzxq",
+	}
+
+	output := `@(#) International Ispell Version 3.1.20 (but really Aspell 0.60.8)
+& teh 3 1: the, tea, ten
+*
+*
+
+
+& wrold 2 7: world, would
+*
+# zzxq 17
+
+`
+
+	want := []Misspelling{
+		{Word: "teh", Line: 1, Column: 1, Suggestions: []string{"the", "tea", "ten"}},
+		{Word: "wrold", Line: 3, Column: 8, Suggestions: []string{"world", "would"}},
+		{Word: "zzxq", Line: 3, Column: 18},
+	}
+
+	got, err := Parse(strings.NewReader(output), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	if end := got[1].EndColumn(); end != 12 {
+		t.Errorf("want end column 12, got %d", end)
+	}
+}
+
+func TestInput(t *testing.T) {
+	got := Input([]string{"*not a command", ""})
+	if want := "^*not a command\n^\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
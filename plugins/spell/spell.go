@@ -0,0 +1,416 @@
+package spell
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	// stateKey is the BufferState key of the suggestions of the checked
+	// buffer, by misspelled word.
+	stateKey = "spellcheck"
+
+	rangesOption = "spellcheck_ranges"
+	langOption   = "spellcheck_lang"
+)
+
+// Checker is a spell checker supporting the ispell pipe mode.
+type Checker struct {
+	Command string
+
+	// Args returns the arguments of Command for the given language, which
+	// may be empty for the default language.
+	Args func(lang string) []string
+}
+
+// Checkers are the spell checkers used if installed, in order of
+// preference.
+var Checkers = []Checker{
+	{
+		Command: "aspell",
+		Args: func(lang string) []string {
+			if lang == "" {
+				return []string{"-a"}
+			}
+			return []string{"-a", "--lang=" + lang}
+		},
+	},
+	{
+		Command: "hunspell",
+		Args: func(lang string) []string {
+			if lang == "" {
+				return []string{"-a"}
+			}
+			return []string{"-a", "-d", lang}
+		},
+	},
+}
+
+// Register the spell checking commands.
+//
+// The following commands are defined:
+//
+//    spellcheck             check the buffer
+//    spellcheck-selections  check the buffer, within the selections only
+//    spellcheck-clear       clear the misspellings of the buffer
+//    spellcheck-next        select the next misspelling
+//    spellcheck-previous    select the previous misspelling
+//    spellcheck-replace     replace the misspelling under the cursor with a suggestion
+//    spellcheck-add         add the given word to the personal dictionary
+//
+// Misspellings are underlined, and the language is the spellcheck_lang
+// option. The names avoid the spell script bundled with Kakoune.
+func Register(k *api.Kak) error {
+	setup := `declare-option -hidden range-specs ` + rangesOption + `
+declare-option -docstring 'language of spellcheck, the checker default if empty' str ` + langOption + `
+set-face global SpellcheckError red+u`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHighlighter("buffer/spellcheck", "spellcheck misspellings")
+
+	checkVars := []string{
+		vars.BufFile,
+		vars.Session,
+		vars.Timestamp,
+		"opt_" + langOption,
+	}
+
+	for _, c := range []struct {
+		name, doc  string
+		selections bool
+	}{
+		{"spellcheck", "check the spelling of the buffer", false},
+		{"spellcheck-selections", "check the spelling of the selections", true},
+	} {
+		selections := c.selections
+		exportVars := checkVars
+		if selections {
+			exportVars = append(append([]string{}, checkVars...), vars.SelectionsDesc)
+		}
+
+		err := k.DefineCommand(c.name, api.DefineCommandOptions{
+			Docstring: c.doc,
+		}, api.BufferFunc{
+			ExportVars: exportVars,
+			Func: func(kak *api.Kak, content string) error {
+				return check(kak, content, selections)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := k.DefineCommand("spellcheck-clear", api.DefineCommandOptions{
+		Docstring: "clear the misspellings of the buffer",
+	}, api.Raw(`unset-option buffer `+rangesOption+`
+  try %{ remove-highlighter buffer/spellcheck }`))
+	if err != nil {
+		return err
+	}
+
+	jumpVars := []string{
+		vars.CursorLine,
+		vars.CursorColumn,
+		"quoted_opt_" + rangesOption,
+	}
+
+	err = k.DefineCommand("spellcheck-next", api.DefineCommandOptions{
+		Docstring: "select the next misspelling",
+	}, api.Func{
+		ExportVars: jumpVars,
+		Func: func(kak *api.Kak) error {
+			return jump(kak, true)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("spellcheck-previous", api.DefineCommandOptions{
+		Docstring: "select the previous misspelling",
+	}, api.Func{
+		ExportVars: jumpVars,
+		Func: func(kak *api.Kak) error {
+			return jump(kak, false)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("spellcheck-replace", api.DefineCommandOptions{
+		Docstring: "replace the misspelling under the cursor with a suggestion",
+	}, api.Func{
+		ExportVars: jumpVars,
+		Func: func(kak *api.Kak) error {
+			desc, err := under(kak)
+			if err != nil {
+				return err
+			}
+
+			kak.Printf("select %s\n", desc)
+			return nil
+		},
+	}, api.Func{
+		ExportVars: []string{
+			vars.Selection,
+			vars.Timestamp,
+			k.StateVar(stateKey),
+		},
+		Func: suggest,
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("spellcheck-add", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "add the given word to the personal dictionary",
+	}, api.Func{
+		ExportVars: []string{"opt_" + langOption},
+		Func: func(kak *api.Kak) error {
+			word, err := kak.Arg(0)
+			if err != nil {
+				return err
+			}
+
+			// * adds the word, and # saves the personal dictionary.
+			if _, err := run(kak, "*"+word+"\n#\n"); err != nil {
+				return err
+			}
+
+			kak.Printf("echo -- %s\n", api.Quote("added "+word+" to the dictionary"))
+			return nil
+		},
+	})
+}
+
+// check checks the content of the buffer, publishing the misspellings,
+// optionally within the selections only.
+func check(kak *api.Kak, content string, selections bool) error {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	out, err := run(kak, Input(lines))
+	if err != nil {
+		return err
+	}
+
+	ms, err := Parse(strings.NewReader(out), lines)
+	if err != nil {
+		return err
+	}
+
+	if selections {
+		sels, err := kak.Selections()
+		if err != nil {
+			return err
+		}
+
+		var within []Misspelling
+		for _, m := range ms {
+			for _, s := range sels {
+				if s.Range().Contains(api.Coord{Line: m.Line, Column: m.Column}) {
+					within = append(within, m)
+					break
+				}
+			}
+		}
+		ms = within
+	}
+
+	suggestions := map[string][]string{}
+	ranges := make([]api.RangeSpec, len(ms))
+	for i, m := range ms {
+		suggestions[m.Word] = m.Suggestions
+		ranges[i] = api.RangeSpec{
+			Range: api.Range{
+				Begin: api.Coord{Line: m.Line, Column: m.Column},
+				End:   api.Coord{Line: m.Line, Column: m.EndColumn()},
+			},
+			Face: "SpellcheckError",
+		}
+	}
+
+	if err := kak.BufferState().Set(stateKey, suggestions); err != nil {
+		return err
+	}
+
+	kak.SetRangeSpecs(rangesOption, ranges)
+	kak.Printf("try %%{ add-highlighter buffer/spellcheck ranges %s }\n", rangesOption)
+	kak.Printf("echo -- %s\n", api.Quote(fmt.Sprintf("spellcheck: %d misspellings", len(ms))))
+
+	return nil
+}
+
+// run runs the first installed of Checkers with the given input.
+func run(kak *api.Kak, input string) (string, error) {
+	lang, err := kak.Option(langOption)
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range Checkers {
+		if _, err := exec.LookPath(c.Command); err != nil {
+			continue
+		}
+
+		cmd := exec.Command(c.Command, c.Args(lang)...)
+		cmd.Stdin = strings.NewReader(input)
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s: %s", c.Command, strings.TrimSpace(stderr.String()))
+		}
+
+		return stdout.String(), nil
+	}
+
+	return "", errors.New("no spell checker installed, see spell.Checkers")
+}
+
+// suggest shows a menu of the suggestions for the selected misspelling,
+// replacing the selection with the chosen one.
+func suggest(kak *api.Kak) error {
+	word, err := kak.Var(vars.Selection)
+	if err != nil {
+		return err
+	}
+
+	var suggestions map[string][]string
+	if err := kak.BufferState().Get(stateKey, &suggestions); err != nil && err != api.ErrStateNotFound {
+		return err
+	}
+
+	var items []api.MenuItem
+	for _, s := range suggestions[word] {
+		items = append(items, api.MenuItem{
+			Label: s,
+			Command: fmt.Sprintf("evaluate-commands -save-regs z %s",
+				api.Quote("set-register z "+api.Quote(s)+"\nexecute-keys '\"zR'")),
+		})
+	}
+	items = append(items, api.MenuItem{
+		Label:   "(add to dictionary)",
+		Command: "spellcheck-add " + api.Quote(word) + "; spellcheck",
+	})
+
+	return kak.Menu(items...)
+}
+
+// ranges returns the current misspelling ranges, as updated by Kakoune
+// through edits since checking.
+func ranges(kak *api.Kak) ([]api.Range, error) {
+	list, err := kak.VarQuotedList("quoted_opt_" + rangesOption)
+	if err != nil {
+		return nil, err
+	}
+
+	// the first element is the timestamp.
+	if len(list) < 2 {
+		return nil, errors.New("no misspellings, see spellcheck")
+	}
+
+	specs := make([]api.Range, 0, len(list)-1)
+	for _, rs := range list[1:] {
+		i := strings.IndexByte(rs, '|')
+		if i == -1 {
+			return nil, fmt.Errorf("invalid range-spec: %q", rs)
+		}
+
+		s, err := api.ParseRange(rs[:i])
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, s.Normalize())
+	}
+
+	return specs, nil
+}
+
+// under returns the misspelling range under the cursor.
+func under(kak *api.Kak) (api.Range, error) {
+	specs, err := ranges(kak)
+	if err != nil {
+		return api.Range{}, err
+	}
+
+	cursor, err := cursor(kak)
+	if err != nil {
+		return api.Range{}, err
+	}
+
+	for _, s := range specs {
+		if s.Contains(cursor) {
+			return s, nil
+		}
+	}
+
+	return api.Range{}, errors.New("no misspelling under the cursor")
+}
+
+// jump selects the next or previous misspelling relative to the cursor,
+// wrapping around the buffer.
+func jump(kak *api.Kak, next bool) error {
+	specs, err := ranges(kak)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := cursor(kak)
+	if err != nil {
+		return err
+	}
+
+	after := func(s api.Range) bool {
+		return cursor.Less(s.Begin)
+	}
+
+	// ranges are published in buffer order, so the first after or the last
+	// before is the one to jump to.
+	target := specs[0]
+	if next {
+		for _, s := range specs {
+			if after(s) {
+				target = s
+				break
+			}
+		}
+	} else {
+		target = specs[len(specs)-1]
+		for i := len(specs) - 1; i >= 0; i-- {
+			if !after(specs[i]) && !specs[i].Contains(cursor) {
+				target = specs[i]
+				break
+			}
+		}
+	}
+
+	kak.Printf("select %s\n", target)
+	return nil
+}
+
+// cursor returns the coord of the cursor.
+func cursor(kak *api.Kak) (api.Coord, error) {
+	line, err := kak.VarInt(vars.CursorLine)
+	if err != nil {
+		return api.Coord{}, err
+	}
+
+	col, err := kak.VarInt(vars.CursorColumn)
+	if err != nil {
+		return api.Coord{}, err
+	}
+
+	return api.Coord{Line: line, Column: col}, nil
+}
@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+// maxSelections is the number of selections listed by the info box, the
+// rest being counted within the total only.
+const maxSelections = 10
+
+// Counts are the statistics of a text.
+type Counts struct {
+	Lines int
+	Words int
+	Chars int
+	Bytes int
+}
+
+// Count returns the counts of the text. A last line without a newline is
+// counted, and words are separated by whitespace, as with wc.
+func Count(text string) Counts {
+	c := Counts{
+		Lines: strings.Count(text, "\n"),
+		Words: len(strings.Fields(text)),
+		Chars: utf8.RuneCountInString(text),
+		Bytes: len(text),
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		c.Lines++
+	}
+	return c
+}
+
+// Add returns the sum of the counts.
+func (c Counts) Add(o Counts) Counts {
+	return Counts{
+		Lines: c.Lines + o.Lines,
+		Words: c.Words + o.Words,
+		Chars: c.Chars + o.Chars,
+		Bytes: c.Bytes + o.Bytes,
+	}
+}
+
+// Render returns the info box of the counts of the buffer and of the
+// selections, listing each selection if there are several.
+func Render(buffer Counts, sels []api.Selection, texts []string) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\tlines\twords\tchars\tbytes")
+	row := func(name string, c Counts) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", name, c.Lines, c.Words, c.Chars, c.Bytes)
+	}
+
+	row("buffer", buffer)
+
+	var total Counts
+	counts := make([]Counts, len(texts))
+	for i, t := range texts {
+		counts[i] = Count(t)
+		total = total.Add(counts[i])
+	}
+	row(fmt.Sprintf("%d selections", len(texts)), total)
+
+	if len(texts) > 1 {
+		for i, c := range counts {
+			if i == maxSelections {
+				break
+			}
+			row(sels[i].Range().Begin.String(), c)
+		}
+	}
+	w.Flush()
+
+	s := strings.TrimRight(b.String(), "\n")
+	if len(texts) > maxSelections {
+		s += fmt.Sprintf("\n%d more selections", len(texts)-maxSelections)
+	}
+	return s
+}
+
+// Register the stats command.
+//
+// The following command is defined:
+//
+//    stats  show the counts of lines, words and chars of the buffer and selections
+//
+// Selections beyond the first ten are counted within the total only.
+func Register(k *api.Kak) error {
+	return k.DefineCommand("stats", api.DefineCommandOptions{
+		Docstring: "show the counts of lines, words and chars of the buffer and selections",
+	}, api.BufferFunc{
+		ExportVars: []string{vars.SelectionsDesc, vars.QuotedSelections},
+		Func: func(kak *api.Kak, content string) error {
+			sels, err := kak.Selections()
+			if err != nil {
+				return err
+			}
+			texts, err := kak.SelectionTexts()
+			if err != nil {
+				return err
+			}
+			if len(texts) != len(sels) {
+				return errors.New("selections and their contents differ")
+			}
+
+			return kak.Info("stats", Render(Count(content), sels, texts), api.InfoOptions{})
+		},
+	})
+}
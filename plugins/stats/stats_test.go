@@ -0,0 +1,45 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+func TestCount(t *testing.T) {
+	tests := map[string]Counts{
+		"":                {},
+		"one\n":           {Lines: 1, Words: 1, Chars: 4, Bytes: 4},
+		"a b\nc":          {Lines: 2, Words: 3, Chars: 5, Bytes: 5},
+		"héllo wörld\n\n": {Lines: 2, Words: 2, Chars: 13, Bytes: 15},
+	}
+	for in, want := range tests {
+		if got := Count(in); got != want {
+			t.Errorf("%q: want %+v, got %+v", in, want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	sels := []api.Selection{
+		{Anchor: api.Coord{Line: 1, Column: 1}, Cursor: api.Coord{Line: 1, Column: 3}},
+		{Anchor: api.Coord{Line: 2, Column: 4}, Cursor: api.Coord{Line: 2, Column: 1}},
+	}
+	got := Render(Count("foo\nbar baz\n"), sels, []string{"foo", "bar "})
+
+	want := `              lines  words  chars  bytes
+buffer        2      3      12     12
+2 selections  2      2      7      7
+1.1           1      1      3      3
+2.1           1      1      4      4`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	texts := make([]string, 12)
+	sels = make([]api.Selection, 12)
+	if got := Render(Counts{}, sels, texts); !strings.HasSuffix(got, "\n2 more selections") {
+		t.Errorf("got:\n%s", got)
+	}
+}
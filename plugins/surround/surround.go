@@ -0,0 +1,261 @@
+package surround
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/keys"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+const (
+	userMode = "surround"
+
+	// fromOption holds the first key typed when changing a surround with
+	// the user mode, while waiting for the second.
+	fromOption = "surround_from"
+)
+
+// Pairs are the closing characters of opening characters. Characters
+// without a pair surround with themselves, such as quotes.
+var Pairs = map[rune]rune{
+	'(': ')',
+	'[': ']',
+	'{': '}',
+	'<': '>',
+}
+
+// Surround is the text around a selection.
+type Surround struct {
+	Open  string
+	Close string
+
+	// Tag is the name of the element if the surround is a tag, such as
+	// `div` for `<div class="x">` and `</div>`.
+	Tag string
+}
+
+// tagRe matches a tag, with the name in group 1.
+var tagRe = regexp.MustCompile(`^<([\w:.-]+)(?:\s[^>]*)?>$`)
+
+// Parse returns the surround of the given character or tag.
+//
+// A character is either half of its pair, or a key name as given by
+// on-key, such as `<lt>`. A tag is given with its attributes, such as
+// `<div class="x">`, or as just its name if longer than a character.
+func Parse(s string) (Surround, error) {
+	if r, ok := keys.Char(s); ok {
+		s = string(r)
+	}
+
+	if r, size := utf8.DecodeRuneInString(s); size == len(s) && size != 0 {
+		if close, ok := Pairs[r]; ok {
+			return Surround{Open: s, Close: string(close)}, nil
+		}
+		for open, close := range Pairs {
+			if close == r {
+				return Surround{Open: string(open), Close: s}, nil
+			}
+		}
+		return Surround{Open: s, Close: s}, nil
+	}
+
+	if !strings.HasPrefix(s, "<") {
+		s = "<" + s + ">"
+	}
+
+	m := tagRe.FindStringSubmatch(s)
+	if m == nil {
+		return Surround{}, fmt.Errorf("invalid surround: %q", s)
+	}
+
+	return Surround{Open: s, Close: "</" + m[1] + ">", Tag: m[1]}, nil
+}
+
+// Wrap returns the text surrounded.
+func (s Surround) Wrap(text string) string {
+	return s.Open + text + s.Close
+}
+
+// Strip returns the text without the surround, which must begin and end
+// it. The open tag of a tag surround may have any attributes.
+func (s Surround) Strip(text string) (string, error) {
+	open := s.Open
+	if s.Tag != "" {
+		re := regexp.MustCompile(`^<` + regexp.QuoteMeta(s.Tag) + `(?:\s[^>]*)?>`)
+		open = re.FindString(text)
+	}
+
+	if open == "" || !strings.HasPrefix(text, open) || !strings.HasSuffix(text[len(open):], s.Close) {
+		return "", fmt.Errorf("selection not surrounded by: %q", s.Open)
+	}
+
+	return text[len(open) : len(text)-len(s.Close)], nil
+}
+
+// Object returns the keys selecting the surround around the selections,
+// including the surround.
+func (s Surround) Object() string {
+	if s.Tag != "" {
+		tag := regexp.QuoteMeta(s.Tag)
+		return "<a-a>c" + keys.Escape(`<`+tag+`(?:\s[^>]*)?>,</`+tag+`>`) + "<ret>"
+	}
+
+	// objects bundled with Kakoune respect nesting, and may be given by
+	// either half of the pair.
+	if _, ok := Pairs[[]rune(s.Open)[0]]; ok || strings.ContainsAny(s.Open, "\"'`") {
+		return "<a-a>" + keys.Escape(s.Open)
+	}
+
+	re := regexp.QuoteMeta(s.Open)
+	return "<a-a>c" + keys.Escape(re) + "," + keys.Escape(re) + "<ret>"
+}
+
+// Register the surround commands, and the surround user mode.
+//
+// The following commands are defined:
+//
+//    surround-add     surround the selections with the given character or tag
+//    surround-delete  delete the given surround around the selections
+//    surround-change  change the given surround around the selections to another
+//
+// The surround user mode maps these to keys, reading the characters with
+// on-key and tags with a prompt. Map it to a key to use it, such as:
+//
+//    map global user s ':enter-user-mode surround<ret>'
+func Register(k *api.Kak) error {
+	if err := k.Expansion(api.Raw(`declare-option -hidden str ` + fromOption)); err != nil {
+		return err
+	}
+
+	if err := k.DeclareUserMode(userMode, "add, delete and change surrounds"); err != nil {
+		return err
+	}
+
+	mappings := []struct {
+		key, target, docstring string
+	}{
+		{"a", ":on-key %{ surround-add %val{key} }<ret>", "add surrounding character"},
+		{"t", ":prompt tag: %{ surround-add %val{text} }<ret>", "add surrounding tag"},
+		{"d", ":on-key %{ surround-delete %val{key} }<ret>", "delete surrounding character"},
+		{"D", ":prompt tag: %{ surround-delete %val{text} }<ret>", "delete surrounding tag"},
+		{"c", ":on-key %{ set-option global " + fromOption + " %val{key}; on-key %{ surround-change %opt{" + fromOption + "} %val{key} } }<ret>", "change surrounding character"},
+		{"C", ":prompt tag: %{ set-option global " + fromOption + " %val{text}; prompt tag: %{ surround-change %opt{" + fromOption + "} %val{text} } }<ret>", "change surrounding tag"},
+	}
+	for _, m := range mappings {
+		if err := k.Map("global", userMode, m.key, m.target, api.MapOptions{Docstring: m.docstring}); err != nil {
+			return err
+		}
+	}
+
+	err := k.DefineCommand("surround-add", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "surround the selections with the given character or tag",
+	}, api.Func{
+		ExportVars: []string{vars.QuotedSelections},
+		Func: func(kak *api.Kak) error {
+			to, err := parseArg(kak, 0)
+			if err != nil {
+				return err
+			}
+
+			return replace(kak, func(text string) (string, error) {
+				return to.Wrap(text), nil
+			})
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("surround-delete", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "delete the given surround around the selections",
+	}, api.Func{
+		Func: selectObject,
+	}, api.Func{
+		ExportVars: []string{vars.QuotedSelections},
+		Func: func(kak *api.Kak) error {
+			from, err := parseArg(kak, 0)
+			if err != nil {
+				return err
+			}
+
+			return replace(kak, from.Strip)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("surround-change", api.DefineCommandOptions{
+		Params:    2,
+		Docstring: "change the given surround around the selections to another",
+	}, api.Func{
+		Func: selectObject,
+	}, api.Func{
+		ExportVars: []string{vars.QuotedSelections},
+		Func: func(kak *api.Kak) error {
+			from, err := parseArg(kak, 0)
+			if err != nil {
+				return err
+			}
+
+			to, err := parseArg(kak, 1)
+			if err != nil {
+				return err
+			}
+
+			return replace(kak, func(text string) (string, error) {
+				text, err := from.Strip(text)
+				if err != nil {
+					return "", err
+				}
+				return to.Wrap(text), nil
+			})
+		},
+	})
+}
+
+// selectObject selects the surround of the first argument around the
+// selections, for the following Func to replace.
+func selectObject(kak *api.Kak) error {
+	from, err := parseArg(kak, 0)
+	if err != nil {
+		return err
+	}
+
+	kak.Printf("execute-keys %s\n", api.Quote(from.Object()))
+	return nil
+}
+
+func parseArg(kak *api.Kak, i int) (Surround, error) {
+	arg, err := kak.Arg(i)
+	if err != nil {
+		return Surround{}, err
+	}
+	return Parse(arg)
+}
+
+// replace replaces each selection with the text returned by f.
+func replace(kak *api.Kak, f func(string) (string, error)) error {
+	sels, err := kak.SelectionTexts()
+	if err != nil {
+		return err
+	}
+
+	values := make([]string, len(sels))
+	for i, sel := range sels {
+		v, err := f(sel)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+
+	kak.ReplaceSelections(values)
+	return nil
+}
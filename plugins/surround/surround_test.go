@@ -0,0 +1,84 @@
+package surround
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Surround
+	}{
+		{"(", Surround{Open: "(", Close: ")"}},
+		{")", Surround{Open: "(", Close: ")"}},
+		{"<lt>", Surround{Open: "<", Close: ">"}},
+		{`"`, Surround{Open: `"`, Close: `"`}},
+		{"div", Surround{Open: "<div>", Close: "</div>", Tag: "div"}},
+		{`<a href="x">`, Surround{Open: `<a href="x">`, Close: "</a>", Tag: "a"}},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+
+	if _, err := Parse("<a b"); err == nil {
+		t.Error("want error for an unclosed tag")
+	}
+}
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		surround, in, want string
+	}{
+		{"(", "(foo)", "foo"},
+		{"'", "'foo'", "foo"},
+		{"div", `<div class="x"><div>foo</div></div>`, "<div>foo</div>"},
+	}
+
+	for _, test := range tests {
+		s, err := Parse(test.surround)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.Strip(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+
+	s, _ := Parse("div")
+	if _, err := s.Strip("<divx>foo</div>"); err == nil {
+		t.Error("want error for a different tag")
+	}
+}
+
+func TestObject(t *testing.T) {
+	tests := []struct {
+		surround, want string
+	}{
+		{")", "<a-a>("},
+		{"<", "<a-a><lt>"},
+		{"*", `<a-a>c\*,\*<ret>`},
+		{"em", `<a-a>c<lt>em(?:\s[^>]*)?>,<lt>/em><ret>`},
+	}
+
+	for _, test := range tests {
+		s, err := Parse(test.surround)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Object(); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.surround, test.want, got)
+		}
+	}
+}
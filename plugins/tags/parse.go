@@ -0,0 +1,193 @@
+package tags
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tag is a single entry of a ctags file.
+type Tag struct {
+	Name string
+
+	// File is relative to the directory of the tags file, unless absolute.
+	File string
+
+	// Line is the line of the tag, if known. Tags generated with
+	// `--fields=+n` always have lines, otherwise Pattern locates the tag.
+	Line    int
+	Pattern string
+
+	// Kind is the kind of the tag, such as `f` or `function`.
+	Kind string
+
+	// Fields are the remaining extension fields, such as `signature`.
+	Fields map[string]string
+}
+
+// Parse parses a ctags file, in the extended format output by
+// universal-ctags and exuberant-ctags.
+//
+// Pseudo tags, those starting with `!_TAG_`, are skipped.
+func Parse(r io.Reader) ([]Tag, error) {
+	var tags []Tag
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	for lineNo := 1; s.Scan(); lineNo++ {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "!_TAG_") {
+			continue
+		}
+
+		t, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("tags line %d: %s", lineNo, err)
+		}
+		tags = append(tags, t)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// parseLine parses `name<tab>file<tab>address;"<tab>fields...`.
+func parseLine(line string) (Tag, error) {
+	parts := strings.SplitN(line, "\t", 3)
+	if len(parts) < 3 {
+		return Tag{}, fmt.Errorf("malformed tag: %q", line)
+	}
+
+	t := Tag{
+		Name: parts[0],
+		File: parts[1],
+	}
+
+	// NOTE(leeola): the address is an ex command, which can itself contain
+	// tabs within a pattern, so the fields are split from after its end.
+	address, fields := parts[2], ""
+	if i := strings.LastIndex(parts[2], `;"`); i != -1 {
+		address, fields = parts[2][:i], strings.TrimPrefix(parts[2][i+2:], "\t")
+	}
+
+	if n, err := strconv.Atoi(address); err == nil {
+		t.Line = n
+	} else {
+		t.Pattern = address
+	}
+
+	for _, f := range strings.Split(fields, "\t") {
+		if f == "" {
+			continue
+		}
+
+		kv := strings.SplitN(f, ":", 2)
+		if len(kv) == 1 {
+			// a field without a name is the kind.
+			t.Kind = f
+			continue
+		}
+
+		switch kv[0] {
+		case "kind":
+			t.Kind = kv[1]
+		case "line":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return Tag{}, fmt.Errorf("failed to line to int: %q", kv[1])
+			}
+			t.Line = n
+		default:
+			if t.Fields == nil {
+				t.Fields = map[string]string{}
+			}
+			t.Fields[kv[0]] = kv[1]
+		}
+	}
+
+	return t, nil
+}
+
+// ParseFile parses the ctags file at path, resolving the file of each tag
+// relative to the directory of path.
+func ParseFile(path string) ([]Tag, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tags, err := Parse(f)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	for i, t := range tags {
+		if !filepath.IsAbs(t.File) {
+			tags[i].File = filepath.Join(dir, t.File)
+		}
+	}
+
+	return tags, nil
+}
+
+// Resolve returns the line of the tag, searching the file for its pattern
+// if the line is not known.
+func (t Tag) Resolve() (int, error) {
+	if t.Line != 0 {
+		return t.Line, nil
+	}
+
+	// patterns are `/^content$/` or `?^content$?`, with the delimiter and
+	// backslashes escaped within the content.
+	p := t.Pattern
+	if len(p) < 2 {
+		return 0, fmt.Errorf("malformed tag pattern: %q", p)
+	}
+	delim := p[:1]
+	p = strings.TrimSuffix(p[1:], delim)
+
+	anchored := strings.HasPrefix(p, "^")
+	p = strings.TrimPrefix(p, "^")
+	exact := strings.HasSuffix(p, "$") && !strings.HasSuffix(p, `\$`)
+	if exact {
+		p = strings.TrimSuffix(p, "$")
+	}
+	p = strings.NewReplacer(`\`+delim, delim, `\\`, `\`).Replace(p)
+
+	f, err := os.Open(t.File)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+
+		var ok bool
+		switch {
+		case anchored && exact:
+			ok = line == p
+		case anchored:
+			ok = strings.HasPrefix(line, p)
+		default:
+			ok = strings.Contains(line, p)
+		}
+
+		if ok {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("tag %s not found in %s", t.Name, t.File)
+}
@@ -0,0 +1,73 @@
+package tags
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `!_TAG_FILE_FORMAT	2	/extended format/
+!_TAG_FILE_SORTED	1	/0=unsorted, 1=sorted/
+Kak	api/kak.go	/^type Kak struct {$/;"	t	line:14	package:api
+New	api/kak.go	/^func New() *Kak {$/;"	kind:function	signature:()
+Quote	api/string.go	42;"	f
+`
+
+	want := []Tag{
+		{Name: "Kak", File: "api/kak.go", Line: 14, Pattern: `/^type Kak struct {$/`,
+			Kind: "t", Fields: map[string]string{"package": "api"}},
+		{Name: "New", File: "api/kak.go", Pattern: `/^func New() *Kak {$/`,
+			Kind: "function", Fields: map[string]string{"signature": "()"}},
+		{Name: "Quote", File: "api/string.go", Line: 42, Kind: "f"},
+	}
+
+	got, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "main.go")
+	src := "package main\n\n// see /usr/bin\nfunc main() {\n}\n"
+	if err := ioutil.WriteFile(file, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]int{
+		`/^func main() {$/`:   4,
+		`/^\/\/ see \/usr/`:   3,
+		`?^package main$?`:    1,
+		`/^func missing() $/`: 0,
+	}
+
+	for pattern, want := range tests {
+		line, err := Tag{Name: "main", File: file, Pattern: pattern}.Resolve()
+		if want == 0 {
+			if err == nil {
+				t.Errorf("%s: want error, got line %d", pattern, line)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", pattern, err)
+		} else if line != want {
+			t.Errorf("%s: want line %d, got %d", pattern, want, line)
+		}
+	}
+}
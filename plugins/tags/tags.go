@@ -0,0 +1,355 @@
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/paths"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/picker"
+	"github.com/leeola/gokakoune/util"
+)
+
+const (
+	// TagsFile is the name of the tags file, searched for from the
+	// directory of the buffer upwards.
+	TagsFile = "tags"
+
+	// stackKey is the global State key of the tag stack.
+	stackKey = "tag_stack"
+
+	// CodeNotFound is the error code of tag-jump-to failing to find the
+	// name, see api.CatchCode.
+	CodeNotFound = "tags-not-found"
+)
+
+// location is a position jumped from, on the tag stack.
+type location struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// Register the tag commands.
+//
+// The following commands are defined:
+//
+//    tags-generate  generate the tags file of the project with ctags
+//    tag-jump       jump to the definition of the word under the cursor
+//    tag-jump-to    jump to the definition of the given name
+//    tag-pop        jump back to where the last tag was jumped from
+//    pick-tag       pick a tag to jump to, see the picker package
+//
+// Names with multiple definitions show a menu to pick from.
+func Register(k *api.Kak) error {
+	err := k.DefineCommand("tags-generate", api.DefineCommandOptions{
+		Docstring: "generate the tags file with ctags",
+	}, api.Func{
+		ExportVars: []string{
+			vars.BufFile,
+		},
+		Func: func(kak *api.Kak) error {
+			bufdir, err := paths.BufDir(kak)
+			if err != nil {
+				return err
+			}
+
+			// regenerate an existing tags file in place, otherwise create
+			// one in the working directory.
+			dir := "."
+			if path, err := Find(bufdir); err == nil {
+				dir = filepath.Dir(path)
+			}
+
+			if err := Generate(dir); err != nil {
+				return err
+			}
+
+			kak.Printf("echo -- %s\n", api.Quote("generated "+filepath.Join(dir, TagsFile)))
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("tag-jump", api.DefineCommandOptions{
+		Docstring: "jump to the definition of the word under the cursor",
+	}, api.Raw(`evaluate-commands -save-regs t %{
+    evaluate-commands -draft %{ execute-keys '<a-i>w"ty' }
+    tag-jump-to %reg{t}
+  }`))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("tag-jump-to", api.DefineCommandOptions{
+		Params:    1,
+		Docstring: "jump to the definition of the given name",
+		Completer: &api.Completer{
+			ExportVars: []string{
+				vars.BufFile,
+			},
+			Func: completeName,
+		},
+	}, api.Func{
+		ExportVars: []string{
+			vars.BufFile,
+		},
+		Func: jumpTo,
+	})
+	if err != nil {
+		return err
+	}
+
+	stackVars := []string{
+		vars.BufFile,
+		vars.CursorLine,
+		vars.CursorColumn,
+		k.StateVar(stackKey),
+	}
+
+	err = k.DefineCommand("tag-push", api.DefineCommandOptions{
+		Docstring: "push the cursor onto the tag stack",
+	}, api.Func{
+		ExportVars: stackVars,
+		Func: func(kak *api.Kak) error {
+			var loc location
+			var err error
+			if loc.File, err = kak.Var(vars.BufFile); err != nil {
+				return err
+			}
+			if loc.Line, err = kak.VarInt(vars.CursorLine); err != nil {
+				return err
+			}
+			if loc.Column, err = kak.VarInt(vars.CursorColumn); err != nil {
+				return err
+			}
+
+			var stack []location
+			if err := kak.State().Get(stackKey, &stack); err != nil && err != api.ErrStateNotFound {
+				return err
+			}
+
+			return kak.State().Set(api.ScopeGlobal, stackKey, append(stack, loc))
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("tag-pop", api.DefineCommandOptions{
+		Docstring: "jump back to where the last tag was jumped from",
+	}, api.Func{
+		ExportVars: stackVars,
+		Func: func(kak *api.Kak) error {
+			var stack []location
+			if err := kak.State().Get(stackKey, &stack); err != nil && err != api.ErrStateNotFound {
+				return err
+			}
+
+			if len(stack) == 0 {
+				return errors.New("tag stack is empty")
+			}
+
+			loc := stack[len(stack)-1]
+			kak.Println(paths.EditExisting(loc.File, api.Coord{Line: loc.Line, Column: loc.Column}))
+
+			return kak.State().Set(api.ScopeGlobal, stackKey, stack[:len(stack)-1])
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return picker.Register(k, Picker)
+}
+
+// Picker picks from the tags of the working directory, jumping to the
+// picked tag.
+var Picker = picker.Picker{
+	Name:   "pick-tag",
+	Prompt: "tag: ",
+	Source: func(kak *api.Kak) ([]string, error) {
+		path, err := Find(".")
+		if err != nil {
+			return nil, err
+		}
+
+		tags, err := ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// the name is first, so the prompt completes by name.
+		candidates := make([]string, len(tags))
+		for i, t := range tags {
+			candidates[i] = fmt.Sprintf("%s\t%s\t%s", t.Name, t.Kind, t.File)
+		}
+		return candidates, nil
+	},
+	Action: func(kak *api.Kak, selection string) error {
+		name := strings.SplitN(selection, "\t", 2)[0]
+		kak.Printf("tag-jump-to %s\n", api.Quote(name))
+		return nil
+	},
+}
+
+// jumpTo jumps to the definition of the name given, or shows a menu of the
+// definitions if there are many.
+func jumpTo(kak *api.Kak) error {
+	name, err := kak.Arg(0)
+	if err != nil {
+		return err
+	}
+
+	bufdir, err := paths.BufDir(kak)
+	if err != nil {
+		return err
+	}
+
+	path, err := Find(bufdir)
+	if err != nil {
+		return err
+	}
+
+	tags, err := ParseFile(path)
+	if err != nil {
+		return err
+	}
+
+	matches := Lookup(tags, name)
+	if len(matches) == 0 {
+		return api.WithCode(fmt.Errorf("tag not found: %q", name), CodeNotFound)
+	}
+
+	jump := func(t Tag) (string, error) {
+		line, err := t.Resolve()
+		if err != nil {
+			return "", err
+		}
+		return "tag-push; " + paths.EditExisting(t.File, api.Coord{Line: line}), nil
+	}
+
+	if len(matches) == 1 {
+		cmd, err := jump(matches[0])
+		if err != nil {
+			return err
+		}
+		kak.Println(cmd)
+		return nil
+	}
+
+	var items []api.MenuItem
+	errs := &api.Errors{Noun: "definitions"}
+	for _, t := range matches {
+		cmd, err := jump(t)
+		if errs.Add(t.File, err) {
+			continue
+		}
+
+		rel, err := filepath.Rel(filepath.Dir(path), t.File)
+		if err != nil {
+			rel = t.File
+		}
+
+		items = append(items, api.MenuItem{
+			Label:   fmt.Sprintf("%s  %s", rel, t.Kind),
+			Command: cmd,
+		})
+	}
+
+	if len(items) == 0 {
+		return errs.Err()
+	}
+
+	if err := kak.Menu(items...); err != nil {
+		return err
+	}
+	kak.ReportErrors(errs)
+	return nil
+}
+
+// completeName completes the names of the tags of the buffer.
+func completeName(kak *api.Kak, params []string, token, pos int) ([]string, error) {
+	bufdir, err := paths.BufDir(kak)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := Find(bufdir)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, t := range tags {
+		if !seen[t.Name] {
+			seen[t.Name] = true
+			names = append(names, t.Name)
+		}
+	}
+	return names, nil
+}
+
+// Lookup returns the tags of the given name.
+func Lookup(tags []Tag, name string) []Tag {
+	var matches []Tag
+	for _, t := range tags {
+		if t.Name == name {
+			matches = append(matches, t)
+		}
+	}
+	return matches
+}
+
+// Find returns the path of the nearest tags file, searching from dir
+// upwards.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, TagsFile)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no tags file found, see tags-generate")
+		}
+		dir = parent
+	}
+}
+
+// Generate generates the tags file of dir recursively with ctags, with
+// line numbers included.
+//
+// Universal-ctags is expected, as it is the maintained ctags.
+func Generate(dir string, args ...string) error {
+	args = append([]string{"-R", "--fields=+n", "-f", TagsFile}, args...)
+
+	_, stderr, exit, err := util.ExecIn(dir, "ctags", args...)
+	if err != nil {
+		return err
+	}
+
+	if exit != 0 {
+		return fmt.Errorf("ctags exit %d: %s", exit, strings.TrimSpace(stderr))
+	}
+
+	return nil
+}
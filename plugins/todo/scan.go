@@ -0,0 +1,61 @@
+package todo
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Keywords are the annotations scanned for, unless the todo_keywords
+// option is set.
+var Keywords = []string{"TODO", "FIXME", "XXX", "HACK"}
+
+// Annotation is a keyword found within a file.
+type Annotation struct {
+	Line    int
+	Column  int
+	Keyword string
+
+	// Text is the line from the keyword onward.
+	Text string
+}
+
+// Pattern returns the regular expression of the keywords, as understood
+// by Go, ripgrep and extended grep.
+func Pattern(keywords []string) string {
+	quoted := make([]string, len(keywords))
+	for i, k := range keywords {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	return `\b(` + strings.Join(quoted, "|") + `)\b`
+}
+
+// Scan returns the annotations of the keywords within r, at most one per
+// line. Columns are byte offsets, starting at 1 as in Kakoune.
+func Scan(r io.Reader, keywords []string) ([]Annotation, error) {
+	re, err := regexp.Compile(Pattern(keywords))
+	if err != nil {
+		return nil, err
+	}
+
+	var as []Annotation
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		m := re.FindStringSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+
+		as = append(as, Annotation{
+			Line:    line,
+			Column:  m[2] + 1,
+			Keyword: text[m[2]:m[3]],
+			Text:    strings.TrimSpace(text[m[2]:]),
+		})
+	}
+
+	return as, scanner.Err()
+}
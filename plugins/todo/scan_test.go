@@ -0,0 +1,37 @@
+package todo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	src := `package foo
+
+// TODO(leeola): handle errors.
+func foo() {} // FIXME: rename, TODO too
+// TODOS and XTODO are not keywords.
+x := "HACK"
+`
+
+	got, err := Scan(strings.NewReader(src), Keywords)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Annotation{
+		{Line: 3, Column: 4, Keyword: "TODO", Text: "TODO(leeola): handle errors."},
+		{Line: 4, Column: 18, Keyword: "FIXME", Text: "FIXME: rename, TODO too"},
+		{Line: 6, Column: 7, Keyword: "HACK", Text: `HACK"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	if got, want := Pattern([]string{"TODO", "C++"}), `\b(TODO|C\+\+)\b`; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,163 @@
+package todo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/project"
+	"github.com/leeola/gokakoune/plugins/results"
+)
+
+const (
+	hookGroup = "todo"
+
+	keywordsOption = "todo_keywords"
+	keywordsVar    = "quoted_opt_" + keywordsOption
+	flagsOption    = "todo_flags"
+)
+
+// Todo is the results buffer of the todo commands.
+var Todo = results.Results{Name: "todo"}
+
+// Register the todo commands, and the hooks flagging annotations.
+//
+// The following commands are defined:
+//
+//    todo          list the annotations of the project in *todo*
+//    todo-buffers  list the annotations of the open files in *todo*
+//    todo-flags    flag the annotations of the buffer in the gutter
+//
+// Listing runs in the background, streaming into *todo*, where jumping
+// works as with the search results. Buffers are flagged when opened and
+// written. The keywords are the todo_keywords option, or Keywords if
+// empty.
+func Register(k *api.Kak) error {
+	if err := results.Register(k, Todo); err != nil {
+		return err
+	}
+
+	setup := `declare-option -docstring 'annotations listed and flagged by todo, see todo.Keywords if empty' str-list ` + keywordsOption + `
+declare-option -hidden line-specs ` + flagsOption + `
+set-face global TodoFlag yellow
+remove-hooks global ` + hookGroup + `
+hook -group ` + hookGroup + ` global BufOpenFile .* todo-flags
+hook -group ` + hookGroup + ` global BufWritePost .* todo-flags`
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "flag the annotations of opened and written buffers")
+	k.RecordHighlighter("buffer/todo-flags", "todo gutter flags")
+
+	err := k.DefineCommand("todo", api.DefineCommandOptions{
+		Docstring: "list the annotations of the project in " + Todo.Buffer(),
+	}, api.Func{
+		ExportVars: []string{vars.Session, project.RootVar, keywordsVar},
+		Func: func(kak *api.Kak) error {
+			root, err := project.Root(kak)
+			if err != nil {
+				return err
+			}
+
+			// as with search, other roots are listed by absolute path.
+			path := "."
+			if wd, err := os.Getwd(); err != nil || wd != root {
+				path = root
+			}
+
+			return list(kak, path)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("todo-buffers", api.DefineCommandOptions{
+		Docstring: "list the annotations of the open files in " + Todo.Buffer(),
+	}, api.Func{
+		ExportVars: []string{vars.Session, vars.QuotedBufList, keywordsVar},
+		Func: func(kak *api.Kak) error {
+			bufs, err := kak.VarQuotedList(vars.QuotedBufList)
+			if err != nil {
+				return err
+			}
+
+			// scratch buffers and new files have nothing on disk to list.
+			var files []string
+			for _, b := range bufs {
+				if _, err := os.Stat(b); err == nil {
+					files = append(files, b)
+				}
+			}
+			if len(files) == 0 {
+				return errors.New("no open files")
+			}
+
+			return list(kak, files...)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	flagVars := []string{vars.BufFile, vars.Session, vars.Timestamp, keywordsVar}
+
+	return k.DefineCommand("todo-flags", api.DefineCommandOptions{
+		Docstring: "flag the annotations of the buffer in the gutter",
+	}, api.BufferFunc{
+		ExportVars: flagVars,
+		Func:       flag,
+	})
+}
+
+// list streams the annotations of the given paths into the results buffer.
+func list(kak *api.Kak, paths ...string) error {
+	keywords, err := keywords(kak)
+	if err != nil {
+		return err
+	}
+	pattern := Pattern(keywords)
+
+	if _, err := exec.LookPath("rg"); err == nil {
+		return results.Open(kak, Todo, "", "rg", append([]string{"--column", "--line-number",
+			"--no-heading", "--color=never", "--", pattern}, paths...)...)
+	}
+
+	return results.Open(kak, Todo, "", "grep", append([]string{"-RHnE", "--", pattern}, paths...)...)
+}
+
+// flag flags the annotations of the content of the buffer.
+func flag(kak *api.Kak, content string) error {
+	keywords, err := keywords(kak)
+	if err != nil {
+		return err
+	}
+
+	as, err := Scan(strings.NewReader(content), keywords)
+	if err != nil {
+		return err
+	}
+
+	flags := make([]api.LineSpec, len(as))
+	for i, a := range as {
+		flags[i] = api.Flag(a.Line, "TodoFlag", "●")
+	}
+
+	kak.SetLineSpecs(flagsOption, flags)
+	kak.Printf("try %%{ add-highlighter buffer/todo-flags flag-lines default %s }\n", flagsOption)
+	return nil
+}
+
+func keywords(kak *api.Kak) ([]string, error) {
+	keywords, err := kak.VarQuotedList(keywordsVar)
+	if err != nil {
+		return nil, err
+	}
+	if len(keywords) == 0 {
+		keywords = Keywords
+	}
+	return keywords, nil
+}
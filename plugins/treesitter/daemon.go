@@ -0,0 +1,192 @@
+package treesitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/plugins/lsp"
+	"github.com/leeola/gokakoune/util"
+)
+
+// daemonEnv marks the process as the daemon, when treesitter-update
+// reruns itself.
+const daemonEnv = "GOKAKOUNE_TREESITTER_DAEMON"
+
+// update is the params of the update daemon method, the content of the
+// buffer stamped with its revision.
+type update struct {
+	api.Timestamped[string]
+	Filetype string `json:"filetype"`
+}
+
+// daemon parses buffers in the background, sending the ranges of each back
+// to the session once parsed.
+//
+// Updates are coalesced per buffer, so only the latest content is parsed
+// when edits arrive faster than parsing.
+type daemon struct {
+	languages map[string]Language
+	session   string
+	dir       string
+
+	mu      sync.Mutex
+	pending map[string]*update
+	running map[string]bool
+
+	// done is closed once the session is gone.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// serve runs the daemon until the session is gone, listening on sock.
+func serve(languages map[string]Language, session, sock string) error {
+	os.Remove(sock)
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sock)
+
+	dir, err := ioutil.TempDir(filepath.Dir(sock), "treesitter-parse")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	d := &daemon{
+		languages: languages,
+		session:   session,
+		dir:       dir,
+		pending:   map[string]*update{},
+		running:   map[string]bool{},
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			lsp.NewConn(c, c, d.handle)
+		}
+	}()
+
+	<-d.done
+	return l.Close()
+}
+
+// handle handles the daemon methods called by Funcs.
+func (d *daemon) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "update":
+		var u update
+		if err := json.Unmarshal(params, &u); err != nil {
+			return nil, err
+		}
+
+		if _, ok := d.languages[u.Filetype]; !ok {
+			return nil, fmt.Errorf("no tree-sitter language for filetype: %q", u.Filetype)
+		}
+
+		d.mu.Lock()
+		d.pending[u.Buffer] = &u
+		if !d.running[u.Buffer] {
+			d.running[u.Buffer] = true
+			go d.work(u.Buffer)
+		}
+		d.mu.Unlock()
+		return nil, nil
+
+	case "shutdown":
+		d.doneOnce.Do(func() { close(d.done) })
+		return nil, nil
+
+	default:
+		return nil, &lsp.ResponseError{Code: -32601, Message: "method not found: " + method}
+	}
+}
+
+// work parses the pending updates of the buffer, until none are left.
+func (d *daemon) work(buffer string) {
+	for {
+		d.mu.Lock()
+		u := d.pending[buffer]
+		delete(d.pending, buffer)
+		if u == nil {
+			delete(d.running, buffer)
+			d.mu.Unlock()
+			return
+		}
+		d.mu.Unlock()
+
+		commands, err := d.parse(u)
+		if err != nil {
+			commands = "echo -debug -- " + api.Quote("treesitter: "+err.Error())
+		}
+
+		script := fmt.Sprintf("try %%{ evaluate-commands -buffer %s %%{\n%s\n} }",
+			api.Quote(u.Buffer), commands)
+		if err := api.Send(d.session, script); err != nil {
+			// the session is gone, so there is no one left to highlight for.
+			d.doneOnce.Do(func() { close(d.done) })
+			return
+		}
+	}
+}
+
+// parse parses the content of the update, returning the commands setting
+// its ranges.
+func (d *daemon) parse(u *update) (string, error) {
+	lang := d.languages[u.Filetype]
+
+	// NOTE(leeola): the base name is kept, as tree-sitter picks the grammar
+	// by the file extension when no scope is given.
+	dir := filepath.Join(d.dir, util.HashString(u.Buffer))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, filepath.Base(u.Buffer))
+	if err := ioutil.WriteFile(file, []byte(u.Value), 0600); err != nil {
+		return "", err
+	}
+
+	args := []string{"query", "--captures"}
+	if lang.Scope != "" {
+		args = append(args, "--scope", lang.Scope)
+	}
+	args = append(args, lang.Query, file)
+
+	cmd := exec.Command(Command, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %s", Command, strings.TrimSpace(stderr.String()))
+	}
+
+	caps, err := Parse(&stdout)
+	if err != nil {
+		return "", err
+	}
+
+	faces := lang.Faces
+	if faces == nil {
+		faces = Faces
+	}
+
+	lines := strings.Split(u.Value, "\n")
+	specs := Ranges(lines, caps, faces)
+
+	return fmt.Sprintf("set-option buffer %s %d %s",
+		rangesOption, u.Timestamp, api.FormatRangeSpecs(specs)), nil
+}
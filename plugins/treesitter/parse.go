@@ -0,0 +1,162 @@
+package treesitter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// Capture is a single captured node. Rows and columns are 0 indexed bytes,
+// with the end exclusive, as reported by tree-sitter.
+type Capture struct {
+	Name string
+
+	StartRow, StartColumn int
+	EndRow, EndColumn     int
+}
+
+// captureRe matches the capture lines of `tree-sitter query --captures`,
+// with or without the capture index of older versions:
+//
+//    capture: 0 - keyword, start: (0, 0), end: (0, 7), text: `package`
+//    capture: keyword, start: (0, 0), end: (0, 7), text: `package`
+var captureRe = regexp.MustCompile(`capture: (?:\d+ - )?([\w.]+), start: \((\d+), (\d+)\), end: \((\d+), (\d+)\)`)
+
+// Parse parses the output of `tree-sitter query --captures`, ignoring every
+// line which is not a capture.
+func Parse(r io.Reader) ([]Capture, error) {
+	var caps []Capture
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		m := captureRe.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+
+		var n [4]int
+		for i := range n {
+			v, err := strconv.Atoi(m[i+2])
+			if err != nil {
+				return nil, fmt.Errorf("failed to position to int: %q", m[i+2])
+			}
+			n[i] = v
+		}
+
+		caps = append(caps, Capture{
+			Name:        m[1],
+			StartRow:    n[0],
+			StartColumn: n[1],
+			EndRow:      n[2],
+			EndColumn:   n[3],
+		})
+	}
+
+	return caps, s.Err()
+}
+
+// Faces maps capture names to Kakoune faces. Dotted captures fall back to
+// their parents, such that function.method uses the face of function.
+var Faces = map[string]string{
+	"attribute":        "attribute",
+	"comment":          "comment",
+	"constant":         "value",
+	"constant.builtin": "builtin",
+	"constructor":      "type",
+	"function":         "function",
+	"function.builtin": "builtin",
+	"keyword":          "keyword",
+	"label":            "meta",
+	"module":           "module",
+	"number":           "value",
+	"operator":         "operator",
+	"property":         "variable",
+	"string":           "string",
+	"type":             "type",
+	"type.builtin":     "builtin",
+	"variable.builtin": "builtin",
+}
+
+// Face returns the face of the capture within faces, if any.
+func Face(faces map[string]string, capture string) (string, bool) {
+	for {
+		if face, ok := faces[capture]; ok {
+			return face, true
+		}
+
+		i := strings.LastIndexByte(capture, '.')
+		if i == -1 {
+			return "", false
+		}
+		capture = capture[:i]
+	}
+}
+
+// Ranges returns the range-specs of the captures with a face, given the
+// lines of the parsed content.
+//
+// Kakoune ranges are inclusive, so the end is moved back to the start of
+// the last character of each capture.
+func Ranges(lines []string, caps []Capture, faces map[string]string) []api.RangeSpec {
+	var specs []api.RangeSpec
+	for _, c := range caps {
+		face, ok := Face(faces, c.Name)
+		if !ok {
+			continue
+		}
+
+		endRow, endCol, ok := inclusiveEnd(lines, c.EndRow, c.EndColumn)
+		if !ok {
+			continue
+		}
+
+		// empty captures have nothing to highlight.
+		if endRow < c.StartRow || (endRow == c.StartRow && endCol < c.StartColumn) {
+			continue
+		}
+
+		specs = append(specs, api.RangeSpec{
+			Range: api.Range{
+				Begin: api.Coord{Line: c.StartRow + 1, Column: c.StartColumn + 1},
+				End:   api.Coord{Line: endRow + 1, Column: endCol + 1},
+			},
+			Face: face,
+		})
+	}
+	return specs
+}
+
+// inclusiveEnd returns the 0 indexed position of the last byte of the
+// character before the given exclusive end.
+func inclusiveEnd(lines []string, row, col int) (int, int, bool) {
+	if col == 0 {
+		// the end is the newline of the previous line.
+		if row == 0 || row > len(lines) {
+			return 0, 0, false
+		}
+		return row - 1, len(lines[row-1]), true
+	}
+
+	if row >= len(lines) {
+		return 0, 0, false
+	}
+
+	line := lines[row]
+	if col > len(line) {
+		// the end is the newline of the line.
+		return row, len(line), true
+	}
+
+	i := col - 1
+	for i > 0 && !utf8.RuneStart(line[i]) {
+		i--
+	}
+	return row, i, true
+}
@@ -0,0 +1,54 @@
+package treesitter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+func TestParse(t *testing.T) {
+	output := `main.go
+  pattern: 3
+  capture: 0 - keyword, start: (0, 0), end: (0, 7), text: ` + "`package`" + `
+  capture: function.method, start: (2, 5), end: (2, 9), text: ` + "`main`" + `
+`
+
+	want := []Capture{
+		{Name: "keyword", StartRow: 0, StartColumn: 0, EndRow: 0, EndColumn: 7},
+		{Name: "function.method", StartRow: 2, StartColumn: 5, EndRow: 2, EndColumn: 9},
+	}
+
+	got, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	lines := []string{
+		`s := "héé"`,
+		"/* a",
+		"*/",
+	}
+
+	caps := []Capture{
+		{Name: "variable", StartRow: 0, StartColumn: 0, EndRow: 0, EndColumn: 1},
+		{Name: "string", StartRow: 0, StartColumn: 5, EndRow: 0, EndColumn: 12},
+		{Name: "string.escape", StartRow: 0, StartColumn: 6, EndRow: 0, EndColumn: 11},
+		{Name: "comment.block", StartRow: 1, StartColumn: 0, EndRow: 3, EndColumn: 0},
+		{Name: "punctuation", StartRow: 0, StartColumn: 2, EndRow: 0, EndColumn: 4},
+	}
+
+	got := api.FormatRangeSpecs(Ranges(lines, caps, Faces))
+	want := `'1.6,1.12|string' '1.7,1.10|string' '2.1,3.3|comment'`
+
+	if got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,228 @@
+package treesitter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+	"github.com/leeola/gokakoune/plugins/lsp"
+)
+
+const (
+	// stateKey is the BufferState key of the timestamp last sent, so
+	// unchanged buffers are not parsed again.
+	stateKey = "treesitter"
+
+	hookGroup    = "treesitter"
+	rangesOption = "treesitter_ranges"
+)
+
+// Command is the tree-sitter CLI, which must have the grammars of the
+// registered languages installed.
+var Command = "tree-sitter"
+
+// Language is a tree-sitter grammar of a single filetype.
+type Language struct {
+	Filetype string
+
+	// Scope is the scope of the grammar, such as source.go. If empty, the
+	// grammar is picked by the file extension.
+	Scope string
+
+	// Query is the path of the highlights query, such as highlights.scm.
+	Query string
+
+	// Faces maps captures to faces, defaulting to Faces.
+	Faces map[string]string
+}
+
+// Register the given languages, highlighting windows of their filetypes
+// through tree-sitter.
+//
+// The following commands are defined:
+//
+//    treesitter-enable   highlight the window with tree-sitter
+//    treesitter-disable  remove the tree-sitter highlighting of the window
+//    treesitter-update   parse the buffer again, if modified
+//    treesitter-stop     stop the parsing daemon
+//
+// Buffers are parsed by a daemon in the background, which sets the ranges
+// once parsed. Kakoune moves the ranges along with edits meanwhile, so
+// highlighting follows edits until the next parse.
+func Register(k *api.Kak, languages ...Language) error {
+	byFiletype := map[string]Language{}
+	var filetypes []string
+	for _, l := range languages {
+		if l.Filetype == "" || l.Query == "" {
+			return errors.New("tree-sitter language needs a Filetype and Query")
+		}
+
+		if _, ok := byFiletype[l.Filetype]; ok {
+			return fmt.Errorf("duplicate tree-sitter language for %s", l.Filetype)
+		}
+
+		byFiletype[l.Filetype] = l
+		filetypes = append(filetypes, l.Filetype)
+	}
+
+	setup := `declare-option -hidden range-specs ` + rangesOption + `
+remove-hooks global ` + hookGroup
+	if len(filetypes) != 0 {
+		setup += fmt.Sprintf("\nhook -group %s global WinSetOption filetype=(?:%s) treesitter-enable",
+			hookGroup, strings.Join(filetypes, "|"))
+	}
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "tree-sitter highlighting on idle")
+	k.RecordHighlighter("window/treesitter", "tree-sitter highlighting")
+
+	err := k.DefineCommand("treesitter-enable", api.DefineCommandOptions{
+		Docstring: "highlight the window with tree-sitter",
+	}, api.Raw(`try %{ add-highlighter window/treesitter ranges `+rangesOption+` }
+  hook -group `+hookGroup+` window NormalIdle .* treesitter-update
+  hook -group `+hookGroup+` window InsertIdle .* treesitter-update
+  hook -once -always window WinSetOption filetype=.* treesitter-disable
+  treesitter-update`))
+	if err != nil {
+		return err
+	}
+
+	err = k.DefineCommand("treesitter-disable", api.DefineCommandOptions{
+		Docstring: "remove the tree-sitter highlighting of the window",
+	}, api.Raw(`try %{ remove-highlighter window/treesitter }
+  remove-hooks window `+hookGroup))
+	if err != nil {
+		return err
+	}
+
+	updateVars := append([]string{
+		vars.OptFiletype,
+		vars.Session,
+		k.StateVar(stateKey),
+	}, api.TimestampedVars...)
+
+	err = k.DefineCommand("treesitter-update", api.DefineCommandOptions{
+		Docstring: "parse the buffer with tree-sitter again, if modified",
+	}, api.BufferFunc{
+		ExportVars: updateVars,
+		Skip: func(kak *api.Kak) bool {
+			var sent int
+			return kak.BufferState().GetFresh(stateKey, &sent) == nil
+		},
+		Func: func(kak *api.Kak, content string) error {
+			if os.Getenv(daemonEnv) != "" {
+				return start(kak, byFiletype)
+			}
+
+			if err := sendUpdate(kak, content, byFiletype); err != nil {
+				return err
+			}
+
+			ts, err := kak.VarInt(vars.Timestamp)
+			if err != nil {
+				return err
+			}
+			return kak.BufferState().Set(stateKey, ts)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("treesitter-stop", api.DefineCommandOptions{
+		Docstring: "stop the tree-sitter daemon",
+	}, api.Func{
+		ExportVars: []string{vars.Session},
+		Func: func(kak *api.Kak) error {
+			c, err := dial(kak)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			return lsp.NewConn(c, c, nil).Call("shutdown", nil, nil)
+		},
+	})
+}
+
+// sendUpdate sends the content of the buffer to the daemon, starting it
+// if it is not running.
+func sendUpdate(kak *api.Kak, text string, languages map[string]Language) error {
+	filetype, err := kak.Var(vars.OptFiletype)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := languages[filetype]; !ok {
+		return fmt.Errorf("no tree-sitter language for filetype: %q", filetype)
+	}
+
+	content, err := api.Stamp(kak, text)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial(kak)
+	if err != nil {
+		if err := start(kak, languages); err != nil {
+			return err
+		}
+		if c, err = dial(kak); err != nil {
+			return err
+		}
+	}
+	defer c.Close()
+
+	return lsp.NewConn(c, c, nil).Call("update", update{
+		Timestamped: content,
+		Filetype:    filetype,
+	}, nil)
+}
+
+func dial(kak *api.Kak) (net.Conn, error) {
+	sock, err := socket(kak)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.Dial("unix", sock)
+}
+
+// start starts the daemon parsing the buffers of the session, waiting for
+// it to listen so the update can be sent. The rerun Func serves as the
+// daemon, until the session is gone.
+func start(kak *api.Kak, languages map[string]Language) error {
+	session, err := kak.Var(vars.Session)
+	if err != nil {
+		return err
+	}
+
+	sock, err := socket(kak)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(daemonEnv) != "" {
+		return serve(languages, session, sock)
+	}
+
+	if err := kak.SpawnDaemon(daemonEnv, sock); err != nil {
+		return fmt.Errorf("tree-sitter daemon failed to start: %s", err)
+	}
+	return nil
+}
+
+// socket returns the socket of the daemon.
+func socket(kak *api.Kak) (string, error) {
+	dir, err := kak.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "treesitter.sock"), nil
+}
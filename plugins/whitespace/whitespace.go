@@ -0,0 +1,234 @@
+package whitespace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/plugins/format"
+)
+
+const (
+	hookGroup = "whitespace"
+
+	trailingHighlighter = "global/whitespace-trailing"
+	mixedHighlighter    = "buffer/whitespace-mixed"
+
+	// mixedOption flags the lines of the buffer indented unlike the rest.
+	mixedOption = "whitespace_mixed_flags"
+)
+
+// Config selects the whitespace hygiene applied to buffers.
+type Config struct {
+	// Highlight highlights trailing whitespace with the
+	// WhitespaceTrailing face.
+	Highlight bool
+
+	// TrimOnWrite trims trailing whitespace before buffers are written.
+	TrimOnWrite bool
+
+	// FinalNewline removes the blank lines ending buffers before they are
+	// written, so they end with a single newline.
+	FinalNewline bool
+
+	// DetectMixed flags the lines indented unlike the rest of the buffer,
+	// with tabs rather than spaces or the other way around, when buffers
+	// are opened and written.
+	DetectMixed bool
+}
+
+// Clean returns the content with the trailing whitespace of its lines
+// trimmed, if trim, and its ending blank lines removed, if finalNewline.
+func Clean(content string, trim, finalNewline bool) string {
+	lines := strings.SplitAfter(content, "\n")
+	if trim {
+		for i, line := range lines {
+			nl := strings.HasSuffix(line, "\n")
+			line = strings.TrimRight(line, " \t\r\n")
+			if nl {
+				line += "\n"
+			}
+			lines[i] = line
+		}
+	}
+
+	cleaned := strings.Join(lines, "")
+	if finalNewline {
+		cleaned = strings.TrimRight(cleaned, "\n")
+		if cleaned != "" {
+			cleaned += "\n"
+		}
+	}
+	return cleaned
+}
+
+// Mixed returns the lines, from 1, whose indentation differs from that of
+// most indented lines: those indented with tabs when most are indented
+// with spaces, or the other way around, and those mixing both.
+//
+// Spaces following tabs are alignment rather than indentation, and are
+// not counted as mixing.
+func Mixed(content string) []int {
+	const (
+		none = iota
+		tabs
+		spaces
+		mixed
+	)
+	style := func(line string) int {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		switch {
+		case indent == "" || strings.TrimSpace(line) == "":
+			return none
+		case strings.Trim(indent, "\t") == "":
+			return tabs
+		case strings.Trim(indent, " ") == "":
+			return spaces
+		case strings.Trim(strings.TrimLeft(indent, "\t"), " ") == "":
+			// tabs aligned with spaces.
+			return tabs
+		default:
+			return mixed
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	styles := make([]int, len(lines))
+	counts := map[int]int{}
+	for i, line := range lines {
+		styles[i] = style(line)
+		counts[styles[i]]++
+	}
+
+	common := spaces
+	if counts[tabs] > counts[spaces] {
+		common = tabs
+	}
+
+	var mixedLines []int
+	for i, s := range styles {
+		if s != none && s != common {
+			mixedLines = append(mixedLines, i+1)
+		}
+	}
+	return mixedLines
+}
+
+// splitLines splits the content into lines without their newlines, as
+// format.Diff is given them.
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{""}
+	}
+	return strings.Split(s, "\n")
+}
+
+// Register the whitespace commands, and the hooks and highlighters of the
+// config.
+//
+// The following commands are defined:
+//
+//    whitespace-clean  trim trailing whitespace and ending blank lines of the buffer
+//    whitespace-check  flag the lines of the buffer indented unlike the rest
+//
+// whitespace-clean only trims trailing whitespace given -trim, and only
+// removes ending blank lines given -final-newline.
+//
+// The buffer is cleaned with the minimal line edits, so selections, marks
+// and undo history outside of the changed lines are unaffected. Flagged
+// lines are shown in the gutter with the WhitespaceMixed face.
+func Register(k *api.Kak, c Config) error {
+	setup := `set-face global WhitespaceTrailing default,red
+set-face global WhitespaceMixed yellow
+declare-option -hidden line-specs ` + mixedOption + `
+remove-hooks global ` + hookGroup + `
+try %{ remove-highlighter ` + trailingHighlighter + ` }`
+	if c.Highlight {
+		setup += "\nadd-highlighter " + trailingHighlighter + ` regex '\h+$' 0:WhitespaceTrailing`
+	}
+	if c.TrimOnWrite || c.FinalNewline {
+		clean := "whitespace-clean"
+		switch {
+		case c.TrimOnWrite && c.FinalNewline:
+		case c.TrimOnWrite:
+			clean += " -trim"
+		default:
+			clean += " -final-newline"
+		}
+		setup += "\nhook -group " + hookGroup + " global BufWritePre .* " + clean
+	}
+	if c.DetectMixed {
+		setup += "\nhook -group " + hookGroup + " global BufOpenFile .* whitespace-check"
+		setup += "\nhook -group " + hookGroup + " global BufWritePost .* whitespace-check"
+	}
+	if err := k.Expansion(api.Raw(setup)); err != nil {
+		return err
+	}
+	k.RecordHookGroup(hookGroup, "clean and check the whitespace of buffers")
+	k.RecordHighlighter(trailingHighlighter, "trailing whitespace")
+	k.RecordHighlighter(mixedHighlighter, "the lines indented unlike the rest")
+
+	err := k.DefineCommand("whitespace-clean", api.DefineCommandOptions{
+		MaxParams: 2,
+		Docstring: "trim trailing whitespace and ending blank lines of the buffer",
+	}, api.BufferFunc{
+		Func: func(kak *api.Kak, content string) error {
+			trim, finalNewline, err := cleanSwitches(kak.Params())
+			if err != nil {
+				return err
+			}
+
+			cleaned := Clean(content, trim, finalNewline)
+			if cleaned == content {
+				return nil
+			}
+			old := splitLines(content)
+			format.ApplyEdits(kak, old, format.Diff(old, splitLines(cleaned)))
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return k.DefineCommand("whitespace-check", api.DefineCommandOptions{
+		Docstring: "flag the lines of the buffer indented unlike the rest",
+	}, api.BufferFunc{
+		Func: func(kak *api.Kak, content string) error {
+			lines := Mixed(content)
+			flags := make([]api.LineSpec, len(lines))
+			for i, l := range lines {
+				flags[i] = api.Flag(l, "WhitespaceMixed", "▌")
+			}
+			kak.SetLineSpecs(mixedOption, flags)
+			kak.Printf("try %%{ add-highlighter %s flag-lines default %s }\n", mixedHighlighter, mixedOption)
+
+			if len(lines) == 0 {
+				return nil
+			}
+			return api.WithSeverity(fmt.Errorf(
+				"mixed indentation: %d lines, the first being line %d", len(lines), lines[0]),
+				api.SeverityWarning)
+		},
+	})
+}
+
+// cleanSwitches returns what whitespace-clean cleans, by its switches,
+// both if none are given.
+func cleanSwitches(params []string) (trim, finalNewline bool, err error) {
+	if len(params) == 0 {
+		return true, true, nil
+	}
+	for _, p := range params {
+		switch p {
+		case "-trim":
+			trim = true
+		case "-final-newline":
+			finalNewline = true
+		default:
+			return false, false, fmt.Errorf("invalid switch: %q", p)
+		}
+	}
+	return trim, finalNewline, nil
+}
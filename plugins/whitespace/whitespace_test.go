@@ -0,0 +1,48 @@
+package whitespace
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in                 string
+		trim, finalNewline bool
+		want               string
+	}{
+		{"a  \nb\t\n\n\n", true, true, "a\nb\n"},
+		{"a  \nb\t\n\n\n", true, false, "a\nb\n\n\n"},
+		{"a  \nb\t\n\n\n", false, true, "a  \nb\t\n"},
+		{"a \r\nb", true, false, "a\nb"},
+		{"\n\n", true, true, ""},
+	}
+	for _, test := range tests {
+		if got := Clean(test.in, test.trim, test.finalNewline); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.in, test.want, got)
+		}
+	}
+}
+
+func TestMixed(t *testing.T) {
+	content := "func f() {\n\tif x {\n\t\ty()\n    }\n\t  aligned\n \tz\n\n}\n"
+	if got, want := Mixed(content), []int{4, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if got := Mixed("a\n  b\n    c\n"); len(got) != 0 {
+		t.Errorf("want none, got %v", got)
+	}
+}
+
+func TestCleanSwitches(t *testing.T) {
+	if trim, nl, err := cleanSwitches(nil); err != nil || !trim || !nl {
+		t.Errorf("got %v %v %v", trim, nl, err)
+	}
+	if trim, nl, err := cleanSwitches([]string{"-final-newline"}); err != nil || trim || !nl {
+		t.Errorf("got %v %v %v", trim, nl, err)
+	}
+	if _, _, err := cleanSwitches([]string{"-x"}); err == nil {
+		t.Error("expected error of invalid switch")
+	}
+}
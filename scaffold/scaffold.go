@@ -0,0 +1,191 @@
+// Package scaffold generates the skeleton of a new gokakoune plugin, see
+// Generate.
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/leeola/gokakoune/api"
+)
+
+// Plugin is the plugin generated.
+type Plugin struct {
+	// Name is the name of the plugin, and of its binary, such as
+	// `myplugin`. Its commands are prefixed with it.
+	Name string
+
+	// Module is the Go module path of the plugin, such as
+	// `github.com/user/myplugin`, the Name if empty.
+	Module string
+}
+
+// Generate writes the skeleton of the plugin into the directory, returning
+// the paths written. The directory must not exist, or be empty.
+//
+// The skeleton is laid out as plugin managers such as plug.kak expect:
+//
+//    go.mod
+//    main.go        the plugin, defining a sample command
+//    Makefile       build, install and package targets
+//    rc/<name>.kak  the loader sourced by plugin managers
+//    README.md
+//    .gitignore
+//
+// The binary is built into bin/, where rc/<name>.kak finds it, or is
+// installed into the autoload directory by `make install`.
+func Generate(dir string, p Plugin) ([]string, error) {
+	if !validName(p.Name) {
+		return nil, fmt.Errorf("invalid plugin name: %q", p.Name)
+	}
+	if p.Module == "" {
+		p.Module = p.Name
+	}
+
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) != 0 {
+		return nil, fmt.Errorf("directory not empty: %q", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	files := map[string][]byte{
+		filepath.Join("rc", p.Name+".kak"): api.PackageLoader(p.Name),
+	}
+	for name, tmpl := range templates {
+		var b bytes.Buffer
+		if err := template.Must(template.New(name).Parse(tmpl)).Execute(&b, p); err != nil {
+			return nil, err
+		}
+		files[name] = b.Bytes()
+	}
+
+	var paths []string
+	for _, name := range fileOrder(p.Name) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, files[name], 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// validName reports whether the name is a valid plugin name, usable as a
+// binary, command prefix and option prefix.
+func validName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9':
+		case r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// fileOrder returns the files of the skeleton, in the order written.
+func fileOrder(name string) []string {
+	return []string{
+		"go.mod",
+		"main.go",
+		"Makefile",
+		filepath.Join("rc", name+".kak"),
+		"README.md",
+		".gitignore",
+	}
+}
+
+var templates = map[string]string{
+	"go.mod": `module {{.Module}}
+
+go 1.16
+`,
+
+	"main.go": `package main
+
+import (
+	"github.com/leeola/gokakoune/api"
+	"github.com/leeola/gokakoune/api/vars"
+)
+
+func main() {
+	kak := api.New()
+
+	err := kak.DefineCommand("{{.Name}}-hello", api.DefineCommandOptions{
+		Docstring: "greet the buffer",
+	}, api.Func{
+		ExportVars: []string{vars.BufName},
+		Func: func(kak *api.Kak) error {
+			name, err := kak.Var(vars.BufName)
+			if err != nil {
+				return err
+			}
+			kak.Echof("hello %s, from {{.Name}}", name)
+			return nil
+		},
+	})
+	api.Exit(err)
+
+	api.Exit(kak.DefineInfoCommand())
+}
+`,
+
+	"Makefile": `.PHONY: build install install-static package
+
+# build the binary into bin/, where rc/{{.Name}}.kak finds it.
+build:
+	go build -o bin/{{.Name}} .
+
+# install a loader of the binary into the Kakoune autoload directory.
+install: build
+	./bin/{{.Name}} install
+
+# install the rendered script instead, so Kakoune starts without running
+# the binary.
+install-static: build
+	./bin/{{.Name}} install -static
+
+# regenerate rc/{{.Name}}.kak, such as after upgrading gokakoune.
+package: build
+	./bin/{{.Name}} package
+`,
+
+	"README.md": `# {{.Name}}
+
+A Kakoune plugin written with [gokakoune](https://github.com/leeola/gokakoune).
+
+## Install
+
+With plug.kak, building the binary once cloned:
+
+    plug "{{.Module}}" do %{ go build -o bin/{{.Name}} . }
+
+Or by hand, installing a loader into the autoload directory:
+
+    make install
+
+Or ` + "`make install-static`" + `, installing the script of the plugin itself so
+Kakoune starts without running the binary.
+
+## Commands
+
+    {{.Name}}-hello  greet the buffer
+    {{.Name}}-info   show the commands, options and hooks of {{.Name}}
+`,
+
+	".gitignore": `/bin/
+`,
+}
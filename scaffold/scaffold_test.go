@@ -0,0 +1,57 @@
+package scaffold
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scaffold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir = filepath.Join(dir, "myplug")
+
+	paths, err := Generate(dir, Plugin{Name: "myplug", Module: "github.com/user/myplug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 6 {
+		t.Errorf("got %q", paths)
+	}
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if got := read("go.mod"); !strings.HasPrefix(got, "module github.com/user/myplug\n") {
+		t.Errorf("got go.mod:\n%s", got)
+	}
+	if got := read("rc/myplug.kak"); !strings.Contains(got, `bin="${kak_source%/*}/../bin/myplug"`) {
+		t.Errorf("got loader:\n%s", got)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", read("main.go"), 0); err != nil {
+		t.Errorf("main.go: %v", err)
+	}
+	if got := read("main.go"); !strings.Contains(got, `"myplug-hello"`) {
+		t.Errorf("got main.go:\n%s", got)
+	}
+
+	if _, err := Generate(dir, Plugin{Name: "myplug"}); err == nil {
+		t.Error("expected error of existing files")
+	}
+	for _, name := range []string{"", "My Plug", "-x"} {
+		if _, err := Generate(filepath.Join(dir, "other"), Plugin{Name: name}); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
@@ -7,7 +7,14 @@ import (
 )
 
 func Exec(bin string, args ...string) (stdout, stderr string, exit int, err error) {
+	return ExecIn("", bin, args...)
+}
+
+// ExecIn is like Exec, but runs the bin within dir. An empty dir is the
+// working directory.
+func ExecIn(dir, bin string, args ...string) (stdout, stderr string, exit int, err error) {
 	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
 
 	var stdoutB bytes.Buffer
 	cmd.Stdout = &stdoutB
@@ -0,0 +1,13 @@
+package util
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// HashString returns the hex encoded sha1 of s, for use in file names
+// derived from arbitrary strings such as buffer names.
+func HashString(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,5 +1,7 @@
 package util
 
+import "strings"
+
 func EscapeRune(s string, r rune) string {
 	var escaped []rune
 	for _, sr := range s {
@@ -10,3 +12,9 @@ func EscapeRune(s string, r rune) string {
 	}
 	return string(escaped)
 }
+
+// ShellQuote wraps s in single quotes so that a POSIX shell reads it as a
+// single word, with no expansions.
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}